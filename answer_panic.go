@@ -0,0 +1,38 @@
+package pegomock
+
+import "fmt"
+
+// AnswerPanic is the panic value with which a mock invocation panics when its answer, e.g. a
+// ThenPanic or a Then callback, panics. It tells which invocation caused the panic, even when
+// it is only recovered far away from the mock, or not at all. If the original panic value is
+// an error, AnswerPanic wraps it, so errors.Is and errors.As still work.
+type AnswerPanic struct {
+	Mock   string // type of the mock, e.g. "*MockDisplay"
+	Method string
+	Params []Param
+	Value  interface{} // original panic value
+}
+
+func (p *AnswerPanic) Error() string {
+	return fmt.Sprintf("%v.%v(%v) panicked: %v", p.Mock, p.Method, formatParams(p.Params), p.Value)
+}
+
+func (p *AnswerPanic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// annotateAnswerPanic must be deferred. It records a panic of the answer to the invocation
+// recorded as orderingInvocationNumber and re-panics with an *AnswerPanic. Panics of nested invocations, e.g. of
+// other mocks called from a Then callback, are already annotated and re-panicked unchanged.
+func (method *mockedMethod) annotateAnswerPanic(mockName string, orderingInvocationNumber int, params []Param) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	method.updateInvocation(orderingInvocationNumber, func(invocation *MethodInvocation) { invocation.answerPanic = r })
+	if _, alreadyAnnotated := r.(*AnswerPanic); alreadyAnnotated {
+		panic(r)
+	}
+	panic(&AnswerPanic{Mock: mockName, Method: method.name, Params: params, Value: r})
+}
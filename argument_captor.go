@@ -0,0 +1,197 @@
+package pegomock
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// stubbingAware is implemented by matchers that need to know the stubbings they are used in.
+// variadic is true if they match all arguments from paramIndex on, see VariadicArgs.
+type stubbingAware interface {
+	usedInStubbing(method *mockedMethod, stubbing *Stubbing, paramIndex int, variadic bool)
+}
+
+// ArgumentCaptor captures typed arguments in stubbings and verifications. Used in stubbings, it
+// captures the arguments of all invocations answered by them:
+//
+//	names := NewArgumentCaptor[string]()
+//	When(phoneBook.GetPhoneNumber(names.Capture())).ThenReturn("123-456-789")
+//	...
+//	Expect(names.AllValues()).To(Equal([]string{"Tom", "Tim"}))
+//
+// Invocations answered by other stubbings, e.g. more recent ones with precedence, are not
+// captured. Used in a verification, it captures the arguments of all invocations matched by
+// it instead, starting over with every verification:
+//
+//	texts := NewArgumentCaptor[string]()
+//	display.VerifyWasCalled(AtLeast(1)).Show(texts.Capture())
+//	Expect(texts.AllValues()).To(Equal([]string{"Hello", "Bye"}))
+//
+// Unlike GetCapturedArguments of the generated verifiers, its values are typed per argument,
+// so no type assertions are needed.
+type ArgumentCaptor[T any] struct {
+	matcher *ArgumentCaptorMatcher
+}
+
+// NewArgumentCaptor creates an ArgumentCaptor for arguments of type T.
+func NewArgumentCaptor[T any]() *ArgumentCaptor[T] {
+	return &ArgumentCaptor[T]{matcher: &ArgumentCaptorMatcher{argType: reflect.TypeOf((*T)(nil)).Elem()}}
+}
+
+// Capture registers the matcher for the argument in which it is used.
+func (captor *ArgumentCaptor[T]) Capture() T {
+	RegisterMatcher(captor.matcher)
+	var nullValue T
+	return nullValue
+}
+
+// AllValues returns the captured arguments in the order of their invocations. Once the captor
+// was used in a verification, these are the ones captured by the last verification.
+func (captor *ArgumentCaptor[T]) AllValues() []T {
+	var values []T
+	for _, value := range captor.matcher.AllValues() {
+		if value == nil {
+			var nullValue T
+			values = append(values, nullValue)
+		} else {
+			values = append(values, value.(T))
+		}
+	}
+	return values
+}
+
+// Value returns the argument captured last, or the zero value if nothing was captured.
+func (captor *ArgumentCaptor[T]) Value() T {
+	values := captor.AllValues()
+	if len(values) == 0 {
+		var nullValue T
+		return nullValue
+	}
+	return values[len(values)-1]
+}
+
+// ArgumentCaptorMatcher matches any argument of its type and captures the arguments
+// of the invocations answered by the stubbings it is used in, or matched by the last
+// verification it is used in.
+type ArgumentCaptorMatcher struct {
+	argType        reflect.Type
+	usages         []stubbingUsage
+	verified       bool
+	verifiedValues []Param
+	hasPending     bool
+	pending        Param
+	sync.Mutex
+}
+
+type stubbingUsage struct {
+	method     *mockedMethod
+	stubbing   *Stubbing
+	paramIndex int
+	variadic   bool
+}
+
+func (matcher *ArgumentCaptorMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if !isAssignableTo(param, matcher.argType) {
+		return false
+	}
+	// Only captured in verifications, and only if all other arguments of the invocation match, too.
+	matcher.pending, matcher.hasPending = param, true
+	return true
+}
+
+func isAssignableTo(param Param, argType reflect.Type) bool {
+	if param == nil {
+		switch argType.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return true
+		}
+		return false
+	}
+	return reflect.TypeOf(param).AssignableTo(argType)
+}
+
+func (matcher *ArgumentCaptorMatcher) usedInStubbing(method *mockedMethod, stubbing *Stubbing, paramIndex int, variadic bool) {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	usage := stubbingUsage{method, stubbing, paramIndex, variadic}
+	for _, existingUsage := range matcher.usages {
+		if existingUsage == usage {
+			return
+		}
+	}
+	matcher.usages = append(matcher.usages, usage)
+}
+
+func (matcher *ArgumentCaptorMatcher) startVerificationPass() {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.verified, matcher.verifiedValues = true, nil
+	matcher.hasPending, matcher.pending = false, nil
+}
+
+func (matcher *ArgumentCaptorMatcher) invocationMatched(matched bool) {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if matched && matcher.hasPending {
+		matcher.verifiedValues = append(matcher.verifiedValues, matcher.pending)
+	}
+	matcher.hasPending, matcher.pending = false, nil
+}
+
+// AllValues returns the captured arguments in the order of their invocations. Once the matcher
+// was used in a verification, these are the ones captured by the last verification.
+func (matcher *ArgumentCaptorMatcher) AllValues() []Param {
+	matcher.Lock()
+	if matcher.verified {
+		defer matcher.Unlock()
+		return append([]Param{}, matcher.verifiedValues...)
+	}
+	usages := matcher.usages
+	matcher.Unlock()
+
+	type capturedArgument struct {
+		orderingInvocationNumber int
+		value                    Param
+	}
+	var capturedArguments []capturedArgument
+	for _, usage := range usages {
+		usage.method.Lock()
+		for _, invocation := range usage.method.invocations {
+			if invocation.answeredBy != usage.stubbing || usage.paramIndex > len(invocation.params) {
+				continue
+			}
+			if usage.variadic {
+				capturedArguments = append(capturedArguments,
+					capturedArgument{invocation.orderingInvocationNumber, sliceOf(matcher.argType, invocation.params[usage.paramIndex:])})
+			} else if usage.paramIndex < len(invocation.params) {
+				capturedArguments = append(capturedArguments,
+					capturedArgument{invocation.orderingInvocationNumber, invocation.params[usage.paramIndex]})
+			}
+		}
+		usage.method.Unlock()
+	}
+	sort.Slice(capturedArguments, func(i, j int) bool {
+		return capturedArguments[i].orderingInvocationNumber < capturedArguments[j].orderingInvocationNumber
+	})
+	values := make([]Param, len(capturedArguments))
+	for i, capturedArgument := range capturedArguments {
+		values[i] = capturedArgument.value
+	}
+	return values
+}
+
+func (matcher *ArgumentCaptorMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: an argument of type %v", matcher.argType)
+}
+
+func (matcher *ArgumentCaptorMatcher) String() string {
+	return fmt.Sprintf("Capture(%v)", matcher.argType)
+}
@@ -0,0 +1,143 @@
+package pegomock
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// verificationPassAware is implemented by matchers that keep state across all invocations
+// checked during a single verification.
+type verificationPassAware interface {
+	startVerificationPass()
+	invocationMatched(matched bool)
+}
+
+func startVerificationPass(matchers []Matcher) {
+	for _, matcher := range matchers {
+		if m, ok := matcher.(verificationPassAware); ok {
+			m.startVerificationPass()
+		}
+	}
+}
+
+func notifyInvocationMatched(matchers []Matcher, matched bool) {
+	for _, matcher := range matchers {
+		if m, ok := matcher.(verificationPassAware); ok {
+			m.invocationMatched(matched)
+		}
+	}
+}
+
+// CapturedValue is a reference to an argument value that is captured during verification.
+// See Captured.
+type CapturedValue[T any] struct {
+	matcher *CaptureOrMatchMatcher
+}
+
+// Captured creates a reference to an argument value for use in verifications. The first
+// invocation matched during a verification captures the argument passed for
+// CaptureOrMatch, and all further invocations must pass an equal argument to match:
+//
+//	token := Captured[string]()
+//	queue.VerifyWasCalled(Times(3)).Enqueue(token.CaptureOrMatch())
+//
+// Each verification starts over with nothing captured.
+func Captured[T any]() *CapturedValue[T] {
+	return &CapturedValue[T]{matcher: &CaptureOrMatchMatcher{}}
+}
+
+// CaptureOrMatch registers the matcher for the argument in which it is used.
+func (capturedValue *CapturedValue[T]) CaptureOrMatch() T {
+	RegisterMatcher(capturedValue.matcher)
+	var nullValue T
+	return nullValue
+}
+
+// Value returns the value captured by the last verification, or the zero value if
+// no invocation matched.
+func (capturedValue *CapturedValue[T]) Value() T {
+	value, captured := capturedValue.matcher.Value()
+	if !captured || value == nil {
+		var nullValue T
+		return nullValue
+	}
+	return value.(T)
+}
+
+// CaptureOrMatchMatcher captures the argument of the first invocation matched
+// during a verification and matches all further arguments against it.
+type CaptureOrMatchMatcher struct {
+	captured    bool
+	value       Param
+	hasPending  bool
+	pending     Param
+	mismatching []Param
+	sync.Mutex
+}
+
+func (matcher *CaptureOrMatchMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if !matcher.captured {
+		// Only becomes the captured value if all other arguments of the invocation match, too.
+		matcher.pending, matcher.hasPending = param, true
+		return true
+	}
+	if !reflect.DeepEqual(matcher.value, param) {
+		matcher.mismatching = append(matcher.mismatching, param)
+		return false
+	}
+	return true
+}
+
+// Value returns the captured value and whether there is one.
+func (matcher *CaptureOrMatchMatcher) Value() (Param, bool) {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	return matcher.value, matcher.captured
+}
+
+func (matcher *CaptureOrMatchMatcher) startVerificationPass() {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.captured, matcher.value = false, nil
+	matcher.hasPending, matcher.pending = false, nil
+	matcher.mismatching = nil
+}
+
+func (matcher *CaptureOrMatchMatcher) invocationMatched(matched bool) {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if matched && matcher.hasPending {
+		matcher.captured, matcher.value = true, matcher.pending
+	}
+	matcher.hasPending, matcher.pending = false, nil
+}
+
+func (matcher *CaptureOrMatchMatcher) FailureMessage() string {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if !matcher.captured {
+		return "Expected: an invocation to capture a value from; but there was none"
+	}
+	return fmt.Sprintf("Expected: %#v (captured); but got: %v", matcher.value, formatParams(matcher.mismatching))
+}
+
+func (matcher *CaptureOrMatchMatcher) String() string {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if !matcher.captured {
+		return "CaptureOrMatch()"
+	}
+	if len(matcher.mismatching) == 0 {
+		return fmt.Sprintf("CaptureOrMatch(captured: %#v)", matcher.value)
+	}
+	return fmt.Sprintf("CaptureOrMatch(captured: %#v, mismatching: %v)", matcher.value, formatParams(matcher.mismatching))
+}
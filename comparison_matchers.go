@@ -0,0 +1,69 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pegomock
+
+import (
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
+// IntGreaterThan matches int arguments greater than value.
+func IntGreaterThan(value int) int {
+	return ArgThat[int](&GreaterThanMatcher[int]{Value: value})
+}
+
+// IntLessThan matches int arguments less than value.
+func IntLessThan(value int) int {
+	return ArgThat[int](&LessThanMatcher[int]{Value: value})
+}
+
+// IntInRange matches int arguments from min to max, both inclusive.
+func IntInRange(min, max int) int {
+	return ArgThat[int](newInRangeMatcher(min, max))
+}
+
+// Float32GreaterThan matches float32 arguments greater than value.
+func Float32GreaterThan(value float32) float32 {
+	return ArgThat[float32](&GreaterThanMatcher[float32]{Value: value})
+}
+
+// Float32LessThan matches float32 arguments less than value.
+func Float32LessThan(value float32) float32 {
+	return ArgThat[float32](&LessThanMatcher[float32]{Value: value})
+}
+
+// Float32InRange matches float32 arguments from min to max, both inclusive.
+func Float32InRange(min, max float32) float32 {
+	return ArgThat[float32](newInRangeMatcher(min, max))
+}
+
+// Float64GreaterThan matches float64 arguments greater than value.
+func Float64GreaterThan(value float64) float64 {
+	return ArgThat[float64](&GreaterThanMatcher[float64]{Value: value})
+}
+
+// Float64LessThan matches float64 arguments less than value.
+func Float64LessThan(value float64) float64 {
+	return ArgThat[float64](&LessThanMatcher[float64]{Value: value})
+}
+
+// Float64InRange matches float64 arguments from min to max, both inclusive.
+func Float64InRange(min, max float64) float64 {
+	return ArgThat[float64](newInRangeMatcher(min, max))
+}
+
+func newInRangeMatcher[T ordered](min, max T) *InRangeMatcher[T] {
+	verify.Argument(min <= max, "InRange requires min <= max, but got %v and %v", min, max)
+	return &InRangeMatcher[T]{Min: min, Max: max}
+}
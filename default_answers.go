@@ -0,0 +1,270 @@
+package pegomock
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// FailAllCallsWith makes every unstubbed method of mock whose last return value is an error
+// return err, with zero values for all other return values. Explicit stubbings still take
+// precedence. Calling it again replaces the previously injected error.
+func FailAllCallsWith(mock Mock, err error) {
+	SetDefaultAnswer(mock, func(methodName string, params []Param, returnTypes []reflect.Type) (ReturnValues, string) {
+		returnValues := make(ReturnValues, len(returnTypes))
+		if len(returnTypes) > 0 && returnTypes[len(returnTypes)-1] == errorType {
+			returnValues[len(returnTypes)-1] = err
+		}
+		return returnValues, fmt.Sprintf("FailAllCallsWith(%v)", err)
+	})
+}
+
+// StrictMocks makes all mocks without another default answer strict, see Strict.
+var StrictMocks bool
+
+// Strict makes every invocation of mock without a matching stubbing fail, naming the method and
+// its arguments, instead of returning zero values. Since stubbing with When starts with such an
+// invocation, the failure is reported right before the next invocation of any mock or the next
+// verification, or when the test finishes if failures go to a testing.T registered with
+// RegisterMockTestingT.
+func Strict(mock Mock) {
+	SetDefaultAnswer(mock, strictAnswer(GetGenericMockFrom(mock)))
+}
+
+func strictAnswer(genericMock *GenericMock) DefaultAnswer {
+	return func(methodName string, params []Param, returnTypes []reflect.Type) (ReturnValues, string) {
+		goroutineID := currentGoroutineID()
+		goroutineStatesMutex.Lock()
+		state := stateOf(goroutineID)
+		state.pendingStrictFailure = fmt.Sprintf("Unexpected call %v.%v(%v) on a strict mock: there is no matching stubbing",
+			genericMock.mockName, methodName, formatParams(params))
+		state.pendingStrictFailureMock = genericMock
+		goroutineStatesMutex.Unlock()
+		return make(ReturnValues, len(returnTypes)), "Strict"
+	}
+}
+
+func reportPendingStrictFailure(goroutineID int64) {
+	goroutineStatesMutex.Lock()
+	state := stateOf(goroutineID)
+	failure, genericMock := state.pendingStrictFailure, state.pendingStrictFailureMock
+	state.pendingStrictFailure, state.pendingStrictFailureMock = "", nil
+	goroutineStatesMutex.Unlock()
+	if failure == "" {
+		return
+	}
+	genericMock.failHandlerOrGlobal()(failure)
+}
+
+// ReturnSelf makes every unstubbed method of mock return mock itself wherever a return type
+// is an interface that mock implements, and zero values otherwise. This keeps chains of
+// calls on fluent interfaces alive without stubbing every intermediate method. Return types
+// of interface{} are not considered.
+func ReturnSelf(mock Mock) {
+	mockType := reflect.TypeOf(mock)
+	SetDefaultAnswer(mock, func(methodName string, params []Param, returnTypes []reflect.Type) (ReturnValues, string) {
+		returnValues := make(ReturnValues, len(returnTypes))
+		for i, returnType := range returnTypes {
+			if returnType.Kind() == reflect.Interface && returnType.NumMethod() > 0 && mockType.Implements(returnType) {
+				returnValues[i] = mock
+			}
+		}
+		return returnValues, "ReturnSelf"
+	})
+}
+
+// ReturnDeepStubs makes every unstubbed method of mock return a new mock wherever a return type
+// is an interface with a mock registered with RegisterMockFactory, and zero values otherwise.
+// Invocations with the same method and arguments return the same mocks, which return deep stubs
+// themselves. So a call chain like When(client.Orders().List()) stubs exactly the mock the code
+// under test gets from client.Orders(), without stubbing Orders first. A mock named like the
+// interface prefixed with "Mock" is preferred if several registered mocks implement it.
+func ReturnDeepStubs(mock Mock) {
+	var (
+		mutex       sync.Mutex
+		deepStubs   = make(map[string][]deepStub)
+		genericMock = GetGenericMockFrom(mock)
+	)
+	SetDefaultAnswer(mock, func(methodName string, params []Param, returnTypes []reflect.Type) (ReturnValues, string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, stub := range deepStubs[methodName] {
+			if reflect.DeepEqual(stub.params, params) {
+				return stub.returnValues, "ReturnDeepStubs"
+			}
+		}
+		returnValues := make(ReturnValues, len(returnTypes))
+		var unmockedTypes []string
+		for i, returnType := range returnTypes {
+			if returnType.Kind() != reflect.Interface || returnType.NumMethod() == 0 {
+				continue
+			}
+			factory := mockFactoryFor(returnType)
+			if factory == nil {
+				unmockedTypes = append(unmockedTypes, returnType.String())
+				continue
+			}
+			deepStub := factory()
+			genericMock.Lock()
+			failHandler := genericMock.failHandler
+			genericMock.Unlock()
+			deepGenericMock := GetGenericMockFrom(deepStub)
+			deepGenericMock.Lock()
+			deepGenericMock.failHandler = failHandler
+			deepGenericMock.Unlock()
+			ReturnDeepStubs(deepStub)
+			returnValues[i] = deepStub
+		}
+		deepStubs[methodName] = append(deepStubs[methodName], deepStub{params, returnValues})
+		if len(unmockedTypes) != 0 {
+			return returnValues, fmt.Sprintf("ReturnDeepStubs, no mock registered for %v", strings.Join(unmockedTypes, ", "))
+		}
+		return returnValues, "ReturnDeepStubs"
+	})
+}
+
+type deepStub struct {
+	params       []Param
+	returnValues ReturnValues
+}
+
+var (
+	mockFactories      []mockFactory
+	mockFactoriesMutex sync.Mutex
+)
+
+type mockFactory struct {
+	mockType reflect.Type
+	create   func() Mock
+}
+
+// RegisterMockFactory registers create for ReturnDeepStubs to create mocks of the interfaces
+// the mocks it returns implement. Generated mocks register themselves, except generic ones.
+func RegisterMockFactory(create func() Mock) {
+	mockFactoriesMutex.Lock()
+	defer mockFactoriesMutex.Unlock()
+	mockFactories = append(mockFactories, mockFactory{reflect.TypeOf(create()), create})
+}
+
+// mockFactoryFor returns the function that creates mocks of interfaceType, or nil if there is
+// none or several registered mocks implement it without any being named after it.
+func mockFactoryFor(interfaceType reflect.Type) func() Mock {
+	mockFactoriesMutex.Lock()
+	defer mockFactoriesMutex.Unlock()
+	var candidates []mockFactory
+	for _, factory := range mockFactories {
+		if !factory.mockType.Implements(interfaceType) {
+			continue
+		}
+		mockType := factory.mockType
+		if mockType.Kind() == reflect.Ptr {
+			mockType = mockType.Elem()
+		}
+		if mockType.Name() == "Mock"+interfaceType.Name() {
+			return factory.create
+		}
+		candidates = append(candidates, factory)
+	}
+	if len(candidates) != 1 {
+		return nil
+	}
+	return candidates[0].create
+}
+
+// ReturnEmptyValues makes every unstubbed method of mock return empty instead of nil slices and
+// maps, and pointers to zero values instead of nil pointers, so code under test that ranges over
+// or dereferences results does not panic. All other return values, including errors and
+// interfaces, are zero values. Explicit stubbings still take precedence.
+func ReturnEmptyValues(mock Mock) {
+	SetDefaultAnswer(mock, func(methodName string, params []Param, returnTypes []reflect.Type) (ReturnValues, string) {
+		returnValues := make(ReturnValues, len(returnTypes))
+		for i, returnType := range returnTypes {
+			returnValues[i] = emptyValue(returnType)
+		}
+		return returnValues, "ReturnEmptyValues"
+	})
+}
+
+// emptyValue returns a non-nil empty value of t if t is a slice, map or pointer type, and nil,
+// which stands for the zero value, otherwise.
+func emptyValue(t reflect.Type) ReturnValue {
+	switch t.Kind() {
+	case reflect.Slice:
+		return reflect.MakeSlice(t, 0, 0).Interface()
+	case reflect.Map:
+		return reflect.MakeMap(t).Interface()
+	case reflect.Ptr:
+		return reflect.New(t.Elem()).Interface()
+	default:
+		return nil
+	}
+}
+
+// SeedDefaultAnswers makes every unstubbed method of mock return pseudo-random values derived
+// from seed, e.g. to get varied but reproducible data when fuzzing. It fabricates values for
+// bools, numbers and strings, including named types based on them, and returns nil and
+// non-nil errors alternately, starting with nil. Return values of other types are zero values
+// and noted in SDumpInvocationsFor. With the same seed, the same sequence of invocations
+// always yields the same values. Explicit stubbings still take precedence.
+func SeedDefaultAnswers(mock Mock, seed int64) {
+	var (
+		mutex           sync.Mutex
+		random          = rand.New(rand.NewSource(seed))
+		invocationCount int
+	)
+	SetDefaultAnswer(mock, func(methodName string, params []Param, returnTypes []reflect.Type) (ReturnValues, string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		returnValues := make(ReturnValues, len(returnTypes))
+		var unsupportedTypes []string
+		for i, returnType := range returnTypes {
+			if returnType == errorType {
+				if invocationCount%2 == 1 {
+					returnValues[i] = fmt.Errorf("seeded error %v", random.Int63())
+				}
+				continue
+			}
+			value, ok := fabricateValue(returnType, random)
+			if !ok {
+				unsupportedTypes = append(unsupportedTypes, returnType.String())
+			}
+			returnValues[i] = value
+		}
+		invocationCount++
+		if len(unsupportedTypes) != 0 {
+			return returnValues, fmt.Sprintf("SeedDefaultAnswers(%v), zero value for %v", seed, strings.Join(unsupportedTypes, ", "))
+		}
+		return returnValues, fmt.Sprintf("SeedDefaultAnswers(%v)", seed)
+	})
+}
+
+const seededStringLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// fabricateValue returns a random value of type t, or nil, which stands for the zero value,
+// and false if t is not supported.
+func fabricateValue(t reflect.Type, random *rand.Rand) (ReturnValue, bool) {
+	value := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		value.SetBool(random.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(int64(random.Uint64()) >> uint(64-t.Bits()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value.SetUint(random.Uint64() >> uint(64-t.Bits()))
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat((random.Float64() - 0.5) * 2000)
+	case reflect.String:
+		letters := make([]byte, random.Intn(16))
+		for i := range letters {
+			letters[i] = seededStringLetters[random.Intn(len(seededStringLetters))]
+		}
+		value.SetString(string(letters))
+	default:
+		return nil, false
+	}
+	return value.Interface(), true
+}
@@ -18,9 +18,12 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"regexp"
+	"runtime"
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/onsi/gomega/format"
 	"github.com/petergtz/pegomock/internal/verify"
@@ -28,46 +31,298 @@ import (
 
 var GlobalFailHandler FailHandler
 
+// RegisterMockFailHandler makes mocks report failures to handler. Failures occurring on other
+// goroutines are reported on the current one the next time mocks are used or verified there.
 func RegisterMockFailHandler(handler FailHandler) {
-	GlobalFailHandler = handler
+	GlobalFailHandler = forwardingFailHandler(handler)
 }
+
+// RegisterMockTestingT makes mocks report failures to t. Unless AggregateFailures is false,
+// identical consecutive failures are collapsed into one, and the last one is reported when
+// the test finishes at the latest. t is registered for the current goroutine only, and so are
+// the mocks first used on it, so tests can run in parallel with t.Parallel(). When t finishes,
+// it is unregistered again.
 func RegisterMockTestingT(t *testing.T) {
-	RegisterMockFailHandler(BuildTestingTGomegaFailHandler(t))
+	goroutineID := currentGoroutineID()
+	t.Cleanup(func() {
+		goroutineStatesMutex.Lock()
+		defer goroutineStatesMutex.Unlock()
+		stateOf(goroutineID).failHandler = nil
+	})
+	var handler FailHandler
+	if AggregateFailures {
+		var flush func()
+		handler, flush = BuildAggregatingTestingTFailHandler(t)
+		t.Cleanup(flush)
+	} else {
+		handler = BuildTestingTGomegaFailHandler(t)
+	}
+	goroutineStatesMutex.Lock()
+	state := stateOf(goroutineID)
+	state.failHandler = forwardingFailHandler(handler)
+	state.testStartNumber = globalInvocationCounter.peekNextNumber()
+	goroutineStatesMutex.Unlock()
+	t.Cleanup(func() { reportPendingFailuresOnCleanup(goroutineID) })
 }
 
-var (
-	lastInvocation      *invocation
-	lastInvocationMutex sync.Mutex
-)
+// RegisterMatcher registers matcher for the next argument of the stubbing or verification
+// being set up on the current goroutine.
+func RegisterMatcher(matcher Matcher) {
+	goroutineID := currentGoroutineID()
+	goroutineStatesMutex.Lock()
+	defer goroutineStatesMutex.Unlock()
+	stateOf(goroutineID).argMatchers.append(matcher)
+}
+
+// ArgThat registers matcher for the argument in which it is used and returns the zero value of
+// T, so custom matchers can be used like the built-in ones, e.g.
+//
+//	display.VerifyWasCalledOnce().Flash(AnyString(), ArgThat[int](&EvenMatcher{}))
+//
+// As with all matchers, either all arguments of an invocation have to be given by matchers or none.
+func ArgThat[T any](matcher Matcher) T {
+	RegisterMatcher(matcher)
+	var zeroValue T
+	return zeroValue
+}
 
-var globalArgMatchers Matchers
+// ArgSatisfies matches arguments for which predicate returns true, so one-off conditions
+// don't need a Matcher type of their own, e.g.
+//
+//	When(store.Get(ArgSatisfies(func(id string) bool { return strings.HasPrefix(id, "tmp-") }))).ThenReturn(nil)
+func ArgSatisfies[T any](predicate func(T) bool) T {
+	verify.Argument(predicate != nil, "ArgSatisfies requires a non-nil predicate")
+	return ArgThat[T](&PredicateMatcher[T]{Predicate: predicate})
+}
 
-func RegisterMatcher(matcher Matcher) {
-	globalArgMatchers.append(matcher)
+// NotNil matches all arguments except nil ones.
+func NotNil[T any]() T {
+	return ArgThat[T](&NotNilMatcher{})
+}
+
+// StringMatching matches string arguments that contain a match of the regular expression
+// pattern, e.g. StringMatching("^user-[0-9]+$").
+func StringMatching(pattern string) string {
+	regex, err := regexp.Compile(pattern)
+	verify.Argument(err == nil, "Invalid pattern for StringMatching: %v", err)
+	return ArgThat[string](&StringMatchingMatcher{Regexp: regex})
+}
+
+// FieldsEqual matches struct arguments, or pointers to structs, whose fields named by fieldNames
+// equal those of expected, ignoring all other fields, e.g.
+//
+//	orders.VerifyWasCalledOnce().Save(FieldsEqual(Order{Status: "open"}, "Status"))
+func FieldsEqual[T any](expected T, fieldNames ...string) T {
+	return ArgThat[T](NewFieldsEqualMatcher(expected, fieldNames...))
+}
+
+// ErrorIs matches error arguments for which errors.Is(err, target) holds, so wrapped errors
+// match, too.
+func ErrorIs(target error) error {
+	return ArgThat[error](&ErrorIsMatcher{Target: target})
+}
+
+// ErrorAs matches error arguments for which errors.As(err, target) holds, e.g.
+//
+//	var pathErr *fs.PathError
+//	logger.VerifyWasCalledOnce().LogError(ErrorAs(&pathErr))
+//
+// target must be a non-nil pointer to an interface or to a type implementing error.
+func ErrorAs(target interface{}) error {
+	return ArgThat[error](NewErrorAsMatcher(target))
+}
+
+// ArgNot matches arguments that the matcher given as its argument doesn't match, e.g.
+//
+//	When(users.Find(ArgNot(EqString("admin")))).ThenReturn(nil)
+//
+// The combinators are prefixed with Arg, so they don't collide with Gomega's Not, And and Or
+// when dot-importing both packages.
+func ArgNot[T any](T) T {
+	return ArgThat[T](&NotMatcher{Matcher: popMatchers("ArgNot", 1)[0]})
+}
+
+// ArgAnd matches arguments that all of the matchers given as its arguments match, e.g.
+//
+//	When(users.Save(ArgAnd(NotNil[*User](), ArgThat[*User](&AdminMatcher{})))).ThenReturn(nil)
+func ArgAnd[T any](matchers ...T) T {
+	return ArgThat[T](&AndMatcher{Matchers: popMatchers("ArgAnd", len(matchers))})
+}
+
+// ArgOr matches arguments that any of the matchers given as its arguments matches.
+func ArgOr[T any](matchers ...T) T {
+	return ArgThat[T](&OrMatcher{Matchers: popMatchers("ArgOr", len(matchers))})
+}
+
+// popMatchers unregisters and returns the last n matchers, which have been registered by the
+// arguments of the combinator with the given name.
+func popMatchers(combinator string, n int) []Matcher {
+	verify.Argument(n > 0, "%v requires at least one matcher", combinator)
+	argMatchers := takeArgMatchers()
+	if len(argMatchers) < n {
+		panic(fmt.Sprintf("All arguments of %v must be matchers, but only %v of %v are", combinator, len(argMatchers), n))
+	}
+	for _, matcher := range argMatchers[:len(argMatchers)-n] {
+		RegisterMatcher(matcher)
+	}
+	return append([]Matcher{}, argMatchers[len(argMatchers)-n:]...)
 }
 
 type invocation struct {
-	genericMock *GenericMock
-	MethodName  string
-	Params      []Param
-	ReturnTypes []reflect.Type
+	genericMock              *GenericMock
+	MethodName               string
+	Params                   []Param
+	ReturnTypes              []reflect.Type
+	orderingInvocationNumber int
 }
 
 type GenericMock struct {
 	sync.Mutex
 	mockedMethods map[string]*mockedMethod
+	defaultAnswer DefaultAnswer
+	expectations  *Expectations
+	mockName      string
+	failHandler   FailHandler
+	listeners     []InvocationListener
+	// The goroutine the mock was first used on.
+	creatorGoroutineID int64
+	// Tells when the mock was first used, in terms of ordering invocation numbers.
+	creationNumber int
+	// The ordering invocation numbers of the invocations matched by verifications.
+	verifiedInvocations map[int]bool
+}
+
+// Option configures a mock when it is created, e.g. NewMockDisplay(WithFailHandler(handler)).
+type Option func(mock Mock)
+
+// WithFailHandler makes mock report its failures to handler instead of the GlobalFailHandler
+// or the testing.T registered with RegisterMockTestingT, e.g. to Gomega in one test and to a
+// testing.T in another within the same test binary. Verifications spanning several mocks, like
+// VerifyZeroInteractions, still report to the current goroutine's fail handler.
+func WithFailHandler(handler FailHandler) Option {
+	return func(mock Mock) {
+		genericMock := GetGenericMockFrom(mock)
+		genericMock.Lock()
+		defer genericMock.Unlock()
+		genericMock.failHandler = forwardingFailHandler(handler)
+	}
+}
+
+// InvocationListener is called with every invocation of a mock, see OnInvocation.
+type InvocationListener func(methodName string, params []Param)
+
+// OnInvocation makes mock call listener with every invocation as it happens, e.g. to log or
+// count calls while debugging flaky asynchronous tests. listener is called on the invoking
+// goroutine after the invocation is recorded and before it is answered. This includes the
+// invocations stubbing with When starts with. Besides passing it to a mock's constructor,
+// OnInvocation(listener)(mock) adds listener to an existing mock.
+func OnInvocation(listener InvocationListener) Option {
+	return func(mock Mock) {
+		genericMock := GetGenericMockFrom(mock)
+		genericMock.Lock()
+		defer genericMock.Unlock()
+		genericMock.listeners = append(genericMock.listeners, listener)
+	}
+}
+
+// failHandlerOrGlobal returns the fail handler given to WithFailHandler, or else the one
+// registered with RegisterMockTestingT on the goroutine the mock was first used on, or on the
+// current goroutine, or else the GlobalFailHandler.
+func (genericMock *GenericMock) failHandlerOrGlobal() FailHandler {
+	genericMock.Lock()
+	failHandler := genericMock.failHandler
+	genericMock.Unlock()
+	if failHandler != nil {
+		return failHandler
+	}
+	return currentFailHandler()
+}
+
+// DefaultAnswer computes the return values for an invocation that has no matching stubbing.
+// A non-empty note is recorded with the invocation and shows up in SDumpInvocationsFor.
+type DefaultAnswer func(methodName string, params []Param, returnTypes []reflect.Type) (returnValues ReturnValues, note string)
+
+// SetDefaultAnswer makes mock use answer for all invocations without a matching stubbing.
+// It replaces any previously set default answer. A nil answer restores returning zero values.
+func SetDefaultAnswer(mock Mock, answer DefaultAnswer) {
+	genericMock := GetGenericMockFrom(mock)
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	genericMock.defaultAnswer = answer
+}
+
+// Reset makes mock forget all its stubbings and recorded invocations, so an expensive mock can
+// be reused, e.g. across table-driven subtests, without leaking interactions. A default answer
+// set with SetDefaultAnswer, e.g. by FailAllCallsWith, is kept, and so are listeners added with
+// OnInvocation and the wrapped instance of a spy.
+func Reset(mock Mock) {
+	GetGenericMockFrom(mock).Reset()
+}
+
+// Reset forgets all stubbings and recorded invocations. See the function Reset.
+func (genericMock *GenericMock) Reset() {
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	genericMock.mockedMethods = make(map[string]*mockedMethod)
+	genericMock.verifiedInvocations = nil
 }
 
 func (genericMock *GenericMock) Invoke(methodName string, params []Param, returnTypes []reflect.Type) ReturnValues {
-	lastInvocationMutex.Lock()
-	lastInvocation = &invocation{
-		genericMock: genericMock,
-		MethodName:  methodName,
-		Params:      params,
-		ReturnTypes: returnTypes,
+	goroutineID := currentGoroutineID()
+	reportForwardedFailures()
+	reportPendingStrictFailure(goroutineID)
+	method := genericMock.getOrCreateMockedMethod(methodName)
+	orderingInvocationNumber := method.recordInvocation(params)
+	// Only answered invocations become the target of When, so invocations of mocks made while
+	// answering this one, e.g. in a Then callback, are superseded by this one.
+	defer setLastInvocation(goroutineID, &invocation{
+		genericMock:              genericMock,
+		MethodName:               methodName,
+		Params:                   params,
+		ReturnTypes:              returnTypes,
+		orderingInvocationNumber: orderingInvocationNumber,
+	})
+
+	genericMock.Lock()
+	defaultAnswer, listeners := genericMock.defaultAnswer, genericMock.listeners
+	genericMock.Unlock()
+	for _, listener := range listeners {
+		listener(methodName, params)
 	}
-	lastInvocationMutex.Unlock()
-	return genericMock.getOrCreateMockedMethod(methodName).Invoke(params)
+	if defaultAnswer == nil && StrictMocks {
+		defaultAnswer = strictAnswer(genericMock)
+	}
+	return method.answer(genericMock.mockName, orderingInvocationNumber, params, returnTypes, defaultAnswer)
+}
+
+func setLastInvocation(goroutineID int64, lastInvocation *invocation) {
+	goroutineStatesMutex.Lock()
+	defer goroutineStatesMutex.Unlock()
+	stateOf(goroutineID).lastInvocation = lastInvocation
+}
+
+// Buffers for currentGoroutineID, pooled because runtime.Stack makes them escape to the heap.
+var stackBufferPool = sync.Pool{New: func() interface{} { return new([64]byte) }}
+
+// currentGoroutineID parses the ID of the calling goroutine from its stack trace,
+// which starts with "goroutine <id> [".
+func currentGoroutineID() int64 {
+	buf := stackBufferPool.Get().(*[64]byte)
+	defer stackBufferPool.Put(buf)
+	stack := buf[:runtime.Stack(buf[:], false)]
+	// Parsed by hand, because converting the digits to a string for strconv would allocate.
+	digits := bytes.TrimPrefix(stack, []byte("goroutine "))
+	var id int64
+	for i, digit := range digits {
+		if digit == ' ' && i > 0 {
+			return id
+		}
+		if digit < '0' || digit > '9' {
+			break
+		}
+		id = 10*id + int64(digit-'0')
+	}
+	panic(fmt.Errorf("Could not determine goroutine ID from stack trace %q", stack))
 }
 
 func (genericMock *GenericMock) stub(methodName string, paramMatchers []Matcher, returnValues ReturnValues) {
@@ -78,6 +333,19 @@ func (genericMock *GenericMock) stubWithCallback(methodName string, paramMatcher
 	genericMock.getOrCreateMockedMethod(methodName).stub(paramMatchers, callback)
 }
 
+// StubAllInvocations makes all invocations of methodName on mock answer with callback,
+// regardless of their arguments. Stubbings created later still take precedence.
+// It is meant for generated code, e.g. mock builders.
+func StubAllInvocations(mock Mock, methodName string, callback func([]Param) ReturnValues) {
+	method := GetGenericMockFrom(mock).getOrCreateMockedMethod(methodName)
+	method.Lock()
+	defer method.Unlock()
+	method.stubbings = append(method.stubbings, &Stubbing{
+		matchesAllParams: true,
+		callbackSequence: []func([]Param) ReturnValues{callback},
+	})
+}
+
 func (genericMock *GenericMock) getOrCreateMockedMethod(methodName string) *mockedMethod {
 	genericMock.Lock()
 	defer genericMock.Unlock()
@@ -87,6 +355,26 @@ func (genericMock *GenericMock) getOrCreateMockedMethod(methodName string) *mock
 	return genericMock.mockedMethods[methodName]
 }
 
+// mockedMethod returns the mocked method called methodName, or nil if it was neither invoked
+// nor stubbed yet.
+func (genericMock *GenericMock) mockedMethod(methodName string) *mockedMethod {
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	return genericMock.mockedMethods[methodName]
+}
+
+// mockedMethodsSnapshot returns a copy of the mocked methods, which can be iterated while other
+// goroutines invoke the mock.
+func (genericMock *GenericMock) mockedMethodsSnapshot() map[string]*mockedMethod {
+	genericMock.Lock()
+	defer genericMock.Unlock()
+	methods := make(map[string]*mockedMethod, len(genericMock.mockedMethods))
+	for methodName, method := range genericMock.mockedMethods {
+		methods[methodName] = method
+	}
+	return methods
+}
+
 func (genericMock *GenericMock) reset(methodName string, paramMatchers []Matcher) {
 	genericMock.getOrCreateMockedMethod(methodName).reset(paramMatchers)
 }
@@ -96,35 +384,83 @@ func (genericMock *GenericMock) Verify(
 	invocationCountMatcher Matcher,
 	methodName string,
 	params []Param) []MethodInvocation {
-	if GlobalFailHandler == nil {
-		panic("No GlobalFailHandler set. Please use either RegisterMockFailHandler or RegisterMockTestingT to set a fail handler.")
+	argMatchers := takeArgMatchers()
+
+	reportPendingFailures()
+	if len(argMatchers) != 0 {
+		verifyArgMatcherUse(argMatchers, params)
 	}
-	defer func() { globalArgMatchers = nil }() // We don't want a panic somewhere during verification screw our global argMatchers
+	if genericMock.recordedAsExpectation(inOrderContext, invocationCountMatcher, methodName, params, argMatchers) {
+		return nil
+	}
+	failHandler := dumpingFailHandler(genericMock.failHandlerOrGlobal())
+	if within, ok := invocationCountMatcher.(*WithinMatcher); ok {
+		genericMock.waitForInvocations(within, methodName, params, argMatchers)
+	}
+	methodInvocations := genericMock.verify(failHandler, inOrderContext, invocationCountMatcher, methodName, params, argMatchers)
+	genericMock.Lock()
+	if genericMock.verifiedInvocations == nil {
+		genericMock.verifiedInvocations = make(map[int]bool)
+	}
+	for _, methodInvocation := range methodInvocations {
+		genericMock.verifiedInvocations[methodInvocation.orderingInvocationNumber] = true
+	}
+	genericMock.Unlock()
+	return methodInvocations
+}
+
+// How often waitForInvocations checks the invocations.
+const invocationPollingInterval = 10 * time.Millisecond
 
-	if len(globalArgMatchers) != 0 {
-		verifyArgMatcherUse(globalArgMatchers, params)
+// waitForInvocations returns as soon as the invocations of methodName matching params or
+// matchers satisfy within, or once within.Timeout has passed.
+func (genericMock *GenericMock) waitForInvocations(within *WithinMatcher, methodName string, params []Param, matchers Matchers) {
+	deadline := time.Now().Add(within.Timeout)
+	for !within.Matches(genericMock.numMatchingInvocations(methodName, params, matchers)) && time.Now().Before(deadline) {
+		time.Sleep(invocationPollingInterval)
 	}
+}
+
+func (genericMock *GenericMock) numMatchingInvocations(methodName string, params []Param, matchers Matchers) int {
+	startVerificationPass(matchers)
+	return len(genericMock.methodInvocations(methodName, params, matchers))
+}
 
-	methodInvocations := genericMock.methodInvocations(methodName, params, globalArgMatchers)
+func (genericMock *GenericMock) verify(
+	failHandler FailHandler,
+	inOrderContext *InOrderContext,
+	invocationCountMatcher Matcher,
+	methodName string,
+	params []Param,
+	matchers Matchers) []MethodInvocation {
+	startVerificationPass(matchers)
+	methodInvocations := genericMock.methodInvocations(methodName, params, matchers)
 	if inOrderContext != nil {
+		inOrderContext.addVerifiedMock(genericMock)
 		for _, methodInvocation := range methodInvocations {
 			if methodInvocation.orderingInvocationNumber <= inOrderContext.invocationCounter {
-				GlobalFailHandler(fmt.Sprintf("Expected function call %v(%v) before function call %v(%v)",
-					methodName, formatParams(params), inOrderContext.lastInvokedMethodName, formatParams(inOrderContext.lastInvokedMethodParams)))
+				if inOrderContext.lastVerifiedCheckpoint != "" {
+					failHandler(fmt.Sprintf("Expected function call %v(%v) after checkpoint \"%v\"%v",
+						methodName, formatParams(params), inOrderContext.lastVerifiedCheckpoint, inOrderContext.formatTimeline()))
+				} else {
+					failHandler(fmt.Sprintf("Expected function call %v(%v) before function call %v(%v)%v",
+						methodName, formatParams(params), inOrderContext.lastInvokedMethodName, formatParams(inOrderContext.lastInvokedMethodParams), inOrderContext.formatTimeline()))
+				}
 			}
 			inOrderContext.invocationCounter = methodInvocation.orderingInvocationNumber
 			inOrderContext.lastInvokedMethodName = methodName
 			inOrderContext.lastInvokedMethodParams = params
+			inOrderContext.lastVerifiedCheckpoint = ""
 		}
 	}
 	if !invocationCountMatcher.Matches(len(methodInvocations)) {
 		var paramsOrMatchers interface{} = formatParams(params)
-		if len(globalArgMatchers) != 0 {
-			paramsOrMatchers = formatMatchers(globalArgMatchers)
+		if len(matchers) != 0 {
+			paramsOrMatchers = formatMatchers(matchers)
 		}
-		GlobalFailHandler(fmt.Sprintf(
+		failHandler(fmt.Sprintf(
 			"Mock invocation count for %v(%v) does not match expectation.\n\n\t%v\n\n\t%v",
-			methodName, paramsOrMatchers, invocationCountMatcher.FailureMessage(), formatInteractions(genericMock.allInteractions())))
+			methodName, paramsOrMatchers, invocationCountMatcher.FailureMessage(), formatVerificationHints(methodName, params, matchers, genericMock.allInteractions())))
 	}
 	return methodInvocations
 }
@@ -134,7 +470,13 @@ func (genericMock *GenericMock) GetInvocationParams(methodInvocations []MethodIn
 	if len(methodInvocations) == 0 {
 		return nil
 	}
-	result := make([][]Param, len(methodInvocations[len(methodInvocations)-1].params))
+	numParams := 0
+	for _, invocation := range methodInvocations {
+		if len(invocation.params) > numParams {
+			numParams = len(invocation.params)
+		}
+	}
+	result := make([][]Param, numParams)
 	for i, invocation := range methodInvocations {
 		for u, param := range invocation.params {
 			if result[u] == nil {
@@ -148,10 +490,12 @@ func (genericMock *GenericMock) GetInvocationParams(methodInvocations []MethodIn
 
 func (genericMock *GenericMock) methodInvocations(methodName string, params []Param, matchers []Matcher) []MethodInvocation {
 	var invocations []MethodInvocation
-	if method, exists := genericMock.mockedMethods[methodName]; exists {
-		for _, invocation := range method.invocations {
+	if method := genericMock.mockedMethod(methodName); method != nil {
+		for _, invocation := range method.invocationsSnapshot() {
 			if len(matchers) != 0 {
-				if Matchers(matchers).Matches(invocation.params) {
+				matched := Matchers(matchers).Matches(invocation.params)
+				notifyInvocationMatched(matchers, matched)
+				if matched {
 					invocations = append(invocations, invocation)
 				}
 			} else {
@@ -165,6 +509,113 @@ func (genericMock *GenericMock) methodInvocations(methodName string, params []Pa
 	return invocations
 }
 
+// formatVerificationHints lists the interactions with a mock for the failure message of a
+// verification of methodName. Invocations of methodName itself are listed first, with the one
+// matching most arguments highlighted and its mismatching arguments shown.
+func formatVerificationHints(methodName string, params []Param, matchers Matchers, interactions map[string][]MethodInvocation) string {
+	invocations := interactions[methodName]
+	if len(invocations) == 0 {
+		return formatInteractions(interactions)
+	}
+	expectations := argumentExpectations(params, matchers)
+	closest, closestMismatches := -1, []string(nil)
+	for i, invocation := range invocations {
+		mismatches := argumentMismatches(expectations, withVariadicArgsAsSlice(matchers, invocation.params))
+		if len(mismatches) != 0 && (closest == -1 || len(mismatches) < len(closestMismatches)) {
+			closest, closestMismatches = i, mismatches
+		}
+	}
+
+	result := formatInvocationList(methodName, invocations, closest)
+	if closest != -1 {
+		result += "\n\tClosest match differs in:\n"
+		for _, mismatch := range closestMismatches {
+			result += "\t  " + mismatch + "\n"
+		}
+	}
+	otherInteractions := make(map[string][]MethodInvocation)
+	for otherMethodName, otherInvocations := range interactions {
+		if otherMethodName != methodName {
+			otherInteractions[otherMethodName] = otherInvocations
+		}
+	}
+	if len(otherInteractions) != 0 {
+		result += "\n\tInteractions with other methods of this mock were:\n"
+		for _, otherMethodName := range sortedMethodNames(otherInteractions) {
+			result += formatInvocations(otherMethodName, otherInteractions[otherMethodName])
+		}
+	}
+	return result
+}
+
+// argumentExpectation is what a verification expects for a single argument.
+type argumentExpectation struct {
+	matcher Matcher
+	param   Param
+}
+
+func argumentExpectations(params []Param, matchers Matchers) []argumentExpectation {
+	var expectations []argumentExpectation
+	if len(matchers) != 0 {
+		for _, matcher := range matchers {
+			expectations = append(expectations, argumentExpectation{matcher: matcher})
+		}
+	} else {
+		for _, param := range params {
+			expectations = append(expectations, argumentExpectation{param: param})
+		}
+	}
+	return expectations
+}
+
+func (expectation argumentExpectation) String() string {
+	if expectation.matcher != nil {
+		return expectation.matcher.String()
+	}
+	return fmt.Sprintf("%#v", expectation.param)
+}
+
+func (expectation argumentExpectation) matches(param Param) bool {
+	if expectation.matcher == nil {
+		return reflect.DeepEqual(expectation.param, param)
+	}
+	matcher := expectation.matcher
+	if variadic, isVariadic := matcher.(*variadicMatcher); isVariadic {
+		matcher = variadic.Matcher
+	}
+	if _, ok := matcher.(verificationPassAware); ok {
+		// Matching again would change what the verification captured.
+		return true
+	}
+	return expectation.matcher.Matches(param)
+}
+
+func argumentMismatches(expectations []argumentExpectation, params []Param) (mismatches []string) {
+	for i := 0; i < len(expectations) || i < len(params); i++ {
+		switch {
+		case i >= len(params):
+			mismatches = append(mismatches, fmt.Sprintf("argument %v: expected %v, got none", i+1, expectations[i]))
+		case i >= len(expectations):
+			mismatches = append(mismatches, fmt.Sprintf("argument %v: expected none, got %#v", i+1, params[i]))
+		case !expectations[i].matches(params[i]):
+			mismatches = append(mismatches, fmt.Sprintf("argument %v: expected %v, got %#v", i+1, expectations[i], params[i]))
+		}
+	}
+	return
+}
+
+func formatInvocationList(methodName string, invocations []MethodInvocation, highlighted int) string {
+	result := "Invocations of " + methodName + " were:\n"
+	for i, invocation := range invocations {
+		marker := "  "
+		if i == highlighted {
+			marker = "> "
+		}
+		result += "\t" + marker + methodName + "(" + formatParams(invocation.params) + ")\n"
+	}
+	return result
+}
+
 func formatInteractions(interactions map[string][]MethodInvocation) string {
 	if len(interactions) == 0 {
 		return "There were no other interactions with this mock"
@@ -216,8 +667,8 @@ func sortedMethodNames(interactions map[string][]MethodInvocation) []string {
 
 func (genericMock *GenericMock) allInteractions() map[string][]MethodInvocation {
 	interactions := make(map[string][]MethodInvocation)
-	for methodName := range genericMock.mockedMethods {
-		for _, invocation := range genericMock.mockedMethods[methodName].invocations {
+	for methodName, method := range genericMock.mockedMethodsSnapshot() {
+		for _, invocation := range method.invocationsSnapshot() {
 			interactions[methodName] = append(interactions[methodName], invocation)
 		}
 	}
@@ -231,31 +682,94 @@ type mockedMethod struct {
 	stubbings   Stubbings
 }
 
-func (method *mockedMethod) Invoke(params []Param) ReturnValues {
+// recordInvocation records an invocation with params and returns its ordering invocation number.
+// Invocations are recorded in the order of their numbers, even if they happen concurrently.
+func (method *mockedMethod) recordInvocation(params []Param) int {
+	method.Lock()
+	defer method.Unlock()
+	orderingInvocationNumber := globalInvocationCounter.nextNumber()
+	method.invocations = append(method.invocations, MethodInvocation{params: params, orderingInvocationNumber: orderingInvocationNumber})
+	return orderingInvocationNumber
+}
+
+// answer computes the return values of the invocation recorded as orderingInvocationNumber.
+// Matchers of stubbings are called holding the lock of method, because they record the
+// arguments they get, but answers are called without holding any lock, so they may invoke
+// mocks themselves.
+func (method *mockedMethod) answer(mockName string, orderingInvocationNumber int, params []Param, returnTypes []reflect.Type, defaultAnswer DefaultAnswer) ReturnValues {
+	defer method.annotateAnswerPanic(mockName, orderingInvocationNumber, params)
 	method.Lock()
-	method.invocations = append(method.invocations, MethodInvocation{params, globalInvocationCounter.nextNumber()})
-	method.Unlock()
 	stubbing := method.stubbings.find(params)
+	method.Unlock()
+	method.updateInvocation(orderingInvocationNumber, func(invocation *MethodInvocation) { invocation.answeredBy = stubbing })
 	if stubbing == nil {
-		return ReturnValues{}
+		if defaultAnswer == nil {
+			return ReturnValues{}
+		}
+		returnValues, note := defaultAnswer(method.name, params, returnTypes)
+		method.updateInvocation(orderingInvocationNumber, func(invocation *MethodInvocation) { invocation.answerNote = note })
+		return returnValues
 	}
 	return stubbing.Invoke(params)
 }
 
+// updateInvocation calls update with the invocation recorded as orderingInvocationNumber, unless
+// it was removed in the meantime, e.g. by Reset.
+func (method *mockedMethod) updateInvocation(orderingInvocationNumber int, update func(invocation *MethodInvocation)) {
+	method.Lock()
+	defer method.Unlock()
+	for i := len(method.invocations) - 1; i >= 0; i-- {
+		if method.invocations[i].orderingInvocationNumber == orderingInvocationNumber {
+			update(&method.invocations[i])
+			return
+		}
+	}
+}
+
+// invocationsSnapshot returns a copy of the invocations recorded so far, which can be iterated
+// while other goroutines invoke the method.
+func (method *mockedMethod) invocationsSnapshot() []MethodInvocation {
+	method.Lock()
+	defer method.Unlock()
+	return append([]MethodInvocation(nil), method.invocations...)
+}
+
 func (method *mockedMethod) stub(paramMatchers Matchers, callback func([]Param) ReturnValues) {
+	method.Lock()
 	stubbing := method.stubbings.findByMatchers(paramMatchers)
-	if stubbing == nil {
-		stubbing = &Stubbing{paramMatchers: paramMatchers}
+	if stubbing != nil {
+		stubbing.Lock()
+		stubbing.callbackSequence = append(stubbing.callbackSequence, callback)
+		stubbing.Unlock()
+	} else {
+		// The stubbing gets its callback before it's added, so concurrent invocations never find it without one.
+		stubbing = &Stubbing{paramMatchers: paramMatchers, callbackSequence: []func([]Param) ReturnValues{callback}}
 		method.stubbings = append(method.stubbings, stubbing)
 	}
-	stubbing.callbackSequence = append(stubbing.callbackSequence, callback)
+	method.Unlock()
+	for i, matcher := range paramMatchers {
+		if m, ok := matcher.(stubbingAware); ok {
+			m.usedInStubbing(method, stubbing, i, false)
+		}
+	}
 }
 
-func (method *mockedMethod) removeLastInvocation() {
-	method.invocations = method.invocations[:len(method.invocations)-1]
+// removeInvocation removes the invocation recorded as orderingInvocationNumber, which
+// became the target of When.
+func (method *mockedMethod) removeInvocation(orderingInvocationNumber int) {
+	method.Lock()
+	defer method.Unlock()
+	for i := len(method.invocations) - 1; i >= 0; i-- {
+		if method.invocations[i].orderingInvocationNumber == orderingInvocationNumber {
+			method.invocations = append(method.invocations[:i], method.invocations[i+1:]...)
+			return
+		}
+	}
 }
 
 func (method *mockedMethod) reset(paramMatchers Matchers) {
+	method.Lock()
+	defer method.Unlock()
 	method.stubbings.removeByMatchers(paramMatchers)
 }
 
@@ -273,18 +787,28 @@ func (counter *Counter) nextNumber() (nextNumber int) {
 	return
 }
 
+func (counter *Counter) peekNextNumber() int {
+	counter.Lock()
+	defer counter.Unlock()
+
+	return counter.count
+}
+
 var globalInvocationCounter = Counter{count: 1}
 
 type MethodInvocation struct {
 	params                   []Param
 	orderingInvocationNumber int
+	answerNote               string
+	answeredBy               *Stubbing
+	answerPanic              interface{} // the value the answer panicked with; nil if it did not panic
 }
 
 type Stubbings []*Stubbing
 
 func (stubbings Stubbings) find(params []Param) *Stubbing {
 	for i := len(stubbings) - 1; i >= 0; i-- {
-		if stubbings[i].paramMatchers.Matches(params) {
+		if stubbings[i].matchesAllParams || stubbings[i].paramMatchers.Matches(params) {
 			return stubbings[i]
 		}
 	}
@@ -293,7 +817,7 @@ func (stubbings Stubbings) find(params []Param) *Stubbing {
 
 func (stubbings Stubbings) findByMatchers(paramMatchers Matchers) *Stubbing {
 	for _, stubbing := range stubbings {
-		if matchersEqual(stubbing.paramMatchers, paramMatchers) {
+		if !stubbing.matchesAllParams && matchersEqual(stubbing.paramMatchers, paramMatchers) {
 			return stubbing
 		}
 	}
@@ -302,7 +826,7 @@ func (stubbings Stubbings) findByMatchers(paramMatchers Matchers) *Stubbing {
 
 func (stubbings *Stubbings) removeByMatchers(paramMatchers Matchers) {
 	for i, stubbing := range *stubbings {
-		if matchersEqual(stubbing.paramMatchers, paramMatchers) {
+		if !stubbing.matchesAllParams && matchersEqual(stubbing.paramMatchers, paramMatchers) {
 			*stubbings = append((*stubbings)[:i], (*stubbings)[i+1:]...)
 		}
 	}
@@ -321,23 +845,27 @@ func matchersEqual(a, b Matchers) bool {
 }
 
 type Stubbing struct {
+	sync.Mutex
 	paramMatchers    Matchers
+	matchesAllParams bool
 	callbackSequence []func([]Param) ReturnValues
 	sequencePointer  int
 }
 
 func (stubbing *Stubbing) Invoke(params []Param) ReturnValues {
-	defer func() {
-		if stubbing.sequencePointer < len(stubbing.callbackSequence)-1 {
-			stubbing.sequencePointer++
-		}
-	}()
-	return stubbing.callbackSequence[stubbing.sequencePointer](params)
+	stubbing.Lock()
+	callback := stubbing.callbackSequence[stubbing.sequencePointer]
+	if stubbing.sequencePointer < len(stubbing.callbackSequence)-1 {
+		stubbing.sequencePointer++
+	}
+	stubbing.Unlock()
+	return callback(params)
 }
 
 type Matchers []Matcher
 
 func (matchers Matchers) Matches(params []Param) bool {
+	params = withVariadicArgsAsSlice(matchers, params)
 	if len(matchers) != len(params) { // Technically, this is not an error. Variadic arguments can cause this
 		return false
 	}
@@ -363,18 +891,19 @@ type ongoingStubbing struct {
 
 func When(invocation ...interface{}) *ongoingStubbing {
 	callIfIsFunc(invocation)
+	goroutineID := currentGoroutineID()
+	goroutineStatesMutex.Lock()
+	state := stateOf(goroutineID)
+	lastInvocation, argMatchers := state.lastInvocation, state.argMatchers
+	state.lastInvocation, state.argMatchers = nil, nil
+	// The invocation is being stubbed, so it is no failure of a strict mock.
+	state.pendingStrictFailure, state.pendingStrictFailureMock = "", nil
+	goroutineStatesMutex.Unlock()
 	verify.Argument(lastInvocation != nil,
 		"When() requires an argument which has to be 'a method call on a mock'.")
-	defer func() {
-		lastInvocationMutex.Lock()
-		lastInvocation = nil
-		lastInvocationMutex.Unlock()
+	lastInvocation.genericMock.getOrCreateMockedMethod(lastInvocation.MethodName).removeInvocation(lastInvocation.orderingInvocationNumber)
 
-		globalArgMatchers = nil
-	}()
-	lastInvocation.genericMock.mockedMethods[lastInvocation.MethodName].removeLastInvocation()
-
-	paramMatchers := paramMatchersFromArgMatchersOrParams(globalArgMatchers, lastInvocation.Params)
+	paramMatchers := paramMatchersFromArgMatchersOrParams(argMatchers, lastInvocation.Params)
 	lastInvocation.genericMock.reset(lastInvocation.MethodName, paramMatchers)
 	return &ongoingStubbing{
 		genericMock:   lastInvocation.genericMock,
@@ -412,7 +941,12 @@ func paramMatchersFromArgMatchersOrParams(argMatchers []Matcher, params []Param)
 }
 
 func verifyArgMatcherUse(argMatchers []Matcher, params []Param) {
-	verify.Argument(len(argMatchers) == len(params),
+	numMatchers := len(argMatchers)
+	if _, isVariadic := variadicMatcherOf(argMatchers); isVariadic {
+		// The arguments spread by VariadicArgs are no params.
+		numMatchers--
+	}
+	verify.Argument(numMatchers == len(params),
 		"Invalid use of matchers!\n\n %v matchers expected, %v recorded.\n\n"+
 			"This error may occur if matchers are combined with raw values:\n"+
 			"    //incorrect:\n"+
@@ -421,7 +955,7 @@ func verifyArgMatcherUse(argMatchers []Matcher, params []Param) {
 			"For example:\n"+
 			"    //correct:\n"+
 			"    someFunc(AnyInt(), EqString(\"String by matcher\"))",
-		len(params), len(argMatchers),
+		len(params), numMatchers,
 	)
 }
 
@@ -442,7 +976,14 @@ func GetGenericMockFrom(mock Mock) *GenericMock {
 	genericMocksMutex.Lock()
 	defer genericMocksMutex.Unlock()
 	if genericMocks[mock] == nil {
-		genericMocks[mock] = &GenericMock{mockedMethods: make(map[string]*mockedMethod)}
+		goroutineID := currentGoroutineID()
+		genericMocks[mock] = &GenericMock{
+			mockedMethods:      make(map[string]*mockedMethod),
+			mockName:           fmt.Sprintf("%T", mock),
+			failHandler:        goroutineFailHandler(goroutineID),
+			creatorGoroutineID: goroutineID,
+			creationNumber:     globalInvocationCounter.nextNumber(),
+		}
 	}
 	return genericMocks[mock]
 }
@@ -453,6 +994,63 @@ func (stubbing *ongoingStubbing) ThenReturn(values ...ReturnValue) *ongoingStubb
 	return stubbing
 }
 
+// ThenReturnAfter is like ThenReturn, but each invocation only returns values once delay has
+// passed, e.g. to test timeouts, deadlines and retries of the caller. For methods without
+// return values, it just delays the invocation.
+func (stubbing *ongoingStubbing) ThenReturnAfter(delay time.Duration, values ...ReturnValue) *ongoingStubbing {
+	checkAssignabilityOf(values, stubbing.returnTypes)
+	stubbing.genericMock.stubWithCallback(
+		stubbing.MethodName,
+		stubbing.ParamMatchers,
+		func([]Param) ReturnValues {
+			time.Sleep(delay)
+			return values
+		})
+	return stubbing
+}
+
+// ThenReturnConsecutively stubs consecutive invocations of a method with a single return value
+// to return one of values each, in order. Once all values are used up, the last one repeats.
+// It is the same as chaining ThenReturn for each value, e.g. ThenReturn(1).ThenReturn(2).
+func (stubbing *ongoingStubbing) ThenReturnConsecutively(values ...ReturnValue) *ongoingStubbing {
+	verify.Argument(len(stubbing.returnTypes) == 1,
+		"ThenReturnConsecutively requires a method with exactly one return value, but it has %v", len(stubbing.returnTypes))
+	verify.Argument(len(values) != 0, "ThenReturnConsecutively requires at least one value")
+	for _, value := range values {
+		stubbing.ThenReturn(value)
+	}
+	return stubbing
+}
+
+// StubbingRow is a row of a stubbing table, see ThenReturnTable.
+type StubbingRow struct {
+	Args    []Param
+	Returns ReturnValues
+}
+
+// ThenReturnTable stubs the method of the invocation passed to When to return the Returns of
+// each of rows when invoked with its Args, so table-driven tests can configure many stubbings at
+// once, e.g.
+//
+//	When(calculator.Add(AnyInt(), AnyInt())).ThenReturnTable(
+//		StubbingRow{Args: []Param{1, 2}, Returns: ReturnValues{3}},
+//		StubbingRow{Args: []Param{2, 2}, Returns: ReturnValues{4}},
+//	)
+//
+// The arguments of the invocation passed to When only select the method; they are not stubbed.
+// Args are compared for equality, with variadic arguments given individually.
+func (stubbing *ongoingStubbing) ThenReturnTable(rows ...StubbingRow) *ongoingStubbing {
+	for i, row := range rows {
+		verify.Argument(len(row.Args) == len(stubbing.ParamMatchers),
+			"Row %v of the stubbing table has %v arguments, but %v takes %v", i+1, len(row.Args), stubbing.MethodName, len(stubbing.ParamMatchers))
+		checkAssignabilityOf(row.Returns, stubbing.returnTypes)
+	}
+	for _, row := range rows {
+		stubbing.genericMock.stub(stubbing.MethodName, transformParamsIntoEqMatchers(row.Args), row.Returns)
+	}
+	return stubbing
+}
+
 func checkAssignabilityOf(stubbedReturnValues []ReturnValue, expectedReturnTypes []reflect.Type) {
 	verify.Argument(len(stubbedReturnValues) == len(expectedReturnTypes),
 		"Different number of return values")
@@ -488,33 +1086,288 @@ func (stubbing *ongoingStubbing) Then(callback func([]Param) ReturnValues) *ongo
 	return stubbing
 }
 
+// ThenAnswer stubs the invocation with answer, which computes the return values from the actual
+// arguments at call time. It is the same as Then. Generated mocks turn typed functions into
+// such answers, e.g. mock.AnswerGetPhoneNumber(func(name string) string { ... }).
+func (stubbing *ongoingStubbing) ThenAnswer(answer func(params []Param) ReturnValues) *ongoingStubbing {
+	return stubbing.Then(answer)
+}
+
 type InOrderContext struct {
 	invocationCounter       int
 	lastInvokedMethodName   string
 	lastInvokedMethodParams []Param
+	lastVerifiedCheckpoint  string
+
+	sync.Mutex
+	checkpoints   []checkpoint
+	verifiedMocks []*GenericMock
 }
 
-// Matcher ... it is guaranteed that FailureMessage will always be called after Matches
-// so an implementation can save state
+type checkpoint struct {
+	name                     string
+	orderingInvocationNumber int
+}
+
+// Checkpoint records that the event called name happened now. Checkpoints are numbered
+// like mock invocations, so VerifyCheckpoint can relate them to in-order verifications.
+// It is safe to call Checkpoint from any goroutine.
+func (ctx *InOrderContext) Checkpoint(name string) {
+	number := globalInvocationCounter.nextNumber()
+	ctx.Lock()
+	defer ctx.Unlock()
+	ctx.checkpoints = append(ctx.checkpoints, checkpoint{name: name, orderingInvocationNumber: number})
+}
+
+// VerifyCheckpoint verifies that the checkpoint called name was recorded after everything
+// verified in ctx so far. Subsequent in-order verifications then must have happened after it.
+func (ctx *InOrderContext) VerifyCheckpoint(name string) {
+	failHandler := dumpingFailHandler(currentFailHandler())
+	ctx.Lock()
+	var recorded, inOrder *checkpoint
+	for i := range ctx.checkpoints {
+		if ctx.checkpoints[i].name == name {
+			recorded = &ctx.checkpoints[i]
+			if recorded.orderingInvocationNumber > ctx.invocationCounter {
+				inOrder = recorded
+				break
+			}
+		}
+	}
+	ctx.Unlock()
+
+	switch {
+	case recorded == nil:
+		failHandler(fmt.Sprintf("Expected checkpoint \"%v\" to be reached, but it was not%v", name, ctx.formatTimeline()))
+		return
+	case inOrder == nil && ctx.lastVerifiedCheckpoint != "":
+		failHandler(fmt.Sprintf("Expected checkpoint \"%v\" after checkpoint \"%v\"%v", name, ctx.lastVerifiedCheckpoint, ctx.formatTimeline()))
+	case inOrder == nil:
+		failHandler(fmt.Sprintf("Expected checkpoint \"%v\" after function call %v(%v)%v",
+			name, ctx.lastInvokedMethodName, formatParams(ctx.lastInvokedMethodParams), ctx.formatTimeline()))
+	}
+	if inOrder == nil {
+		inOrder = recorded
+	}
+	ctx.invocationCounter = inOrder.orderingInvocationNumber
+	ctx.lastVerifiedCheckpoint = name
+}
+
+func (ctx *InOrderContext) addVerifiedMock(genericMock *GenericMock) {
+	ctx.Lock()
+	defer ctx.Unlock()
+	for _, verifiedMock := range ctx.verifiedMocks {
+		if verifiedMock == genericMock {
+			return
+		}
+	}
+	ctx.verifiedMocks = append(ctx.verifiedMocks, genericMock)
+}
+
+// formatTimeline lists the checkpoints together with all invocations of the mocks verified
+// in ctx, in the order they happened. If several mocks were verified, invocations are prefixed
+// with the mock they belong to.
+func (ctx *InOrderContext) formatTimeline() string {
+	ctx.Lock()
+	defer ctx.Unlock()
+	type event struct {
+		description              string
+		orderingInvocationNumber int
+	}
+	var events []event
+	for _, checkpoint := range ctx.checkpoints {
+		events = append(events, event{fmt.Sprintf("checkpoint \"%v\"", checkpoint.name), checkpoint.orderingInvocationNumber})
+	}
+	labels := make(map[*GenericMock]string)
+	if len(ctx.verifiedMocks) > 1 {
+		labels = mockLabels(ctx.verifiedMocks)
+	}
+	for _, verifiedMock := range ctx.verifiedMocks {
+		for _, invocation := range verifiedMock.numberedInvocationsSince(0) {
+			events = append(events, event{labels[verifiedMock] + invocation.methodName + "(" + formatParams(invocation.params) + ")", invocation.orderingInvocationNumber})
+		}
+	}
+	if len(events) == 0 {
+		return ""
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].orderingInvocationNumber < events[j].orderingInvocationNumber })
+	result := "\n\n\tTimeline:\n"
+	for _, event := range events {
+		result += "\t\t" + event.description + "\n"
+	}
+	return result
+}
+
+// mockLabels returns the prefixes that tell mocks apart in listings of their invocations, e.g.
+// "*mocks.MockDisplay#2.". Mocks of the same type are numbered in the order of mocks.
+func mockLabels(mocks []*GenericMock) map[*GenericMock]string {
+	labels := make(map[*GenericMock]string)
+	numMocksByName := make(map[string]int)
+	for _, mock := range mocks {
+		numMocksByName[mock.mockName]++
+	}
+	numLabeledByName := make(map[string]int)
+	for _, mock := range mocks {
+		name := mock.mockName
+		if numMocksByName[name] > 1 {
+			numLabeledByName[name]++
+			name = fmt.Sprintf("%v#%v", name, numLabeledByName[name])
+		}
+		labels[mock] = name + "."
+	}
+	return labels
+}
+
+// Matcher matches arguments in stubbings and verifications, and invocation counts in
+// verifications. Custom matchers implement it and are registered with RegisterMatcher or
+// ArgThat. String describes the matcher, e.g. "Eq(5)", and appears in failure messages
+// instead of the argument. FailureMessage explains a mismatch; it is guaranteed that
+// FailureMessage will always be called after Matches, so an implementation can save state.
 type Matcher interface {
 	Matches(param Param) bool
 	FailureMessage() string
 	fmt.Stringer
 }
 
+// VerifyNoInteractionsDuring runs region and fails via the current fail handler if any of
+// mocks was invoked meanwhile, listing all such invocations. Invocations from other goroutines
+// that happen while region runs count as well. If region panics, the check is still done and
+// the panic is propagated afterwards.
+func VerifyNoInteractionsDuring(region func(), mocks ...Mock) {
+	failHandler := dumpingFailHandler(currentFailHandler())
+	firstInvocationNumber := globalInvocationCounter.peekNextNumber()
+	defer func() {
+		if r := recover(); r != nil {
+			failIfInteractionsSince(failHandler, firstInvocationNumber, mocks, fmt.Sprintf(" (region panicked with: %v)", r))
+			panic(r)
+		}
+		failIfInteractionsSince(failHandler, firstInvocationNumber, mocks, "")
+	}()
+	region()
+}
+
+func failIfInteractionsSince(failHandler FailHandler, firstInvocationNumber int, mocks []Mock, note string) {
+	if result := interactionsOfMocksSince(firstInvocationNumber, mocks); result != "" {
+		failHandler(fmt.Sprintf("Expected no interactions with mocks during region%v, but there were:\n%v", note, result))
+	}
+}
+
+// VerifyZeroInteractions fails via the current fail handler if any of mocks was invoked at all,
+// listing all their invocations.
+func VerifyZeroInteractions(mocks ...Mock) {
+	failHandler := dumpingFailHandler(currentFailHandler())
+	if result := interactionsOfMocksSince(0, mocks); result != "" {
+		failHandler(fmt.Sprintf("Expected no interactions with mocks, but there were:\n%v", result))
+	}
+}
+
+// VerifyNoMoreInteractions fails via the current fail handler if any of mocks has invocations
+// that no verification matched, listing them. Call it after all other verifications.
+func VerifyNoMoreInteractions(mocks ...Mock) {
+	failHandler := dumpingFailHandler(currentFailHandler())
+	unverified := unverifiedInteractionsOf(mocks, func(genericMock *GenericMock, orderingInvocationNumber int) bool {
+		genericMock.Lock()
+		defer genericMock.Unlock()
+		return genericMock.verifiedInvocations[orderingInvocationNumber]
+	})
+	if unverified != "" {
+		failHandler(fmt.Sprintf("Expected no more interactions with mocks, but there were:\n%v", unverified))
+	}
+}
+
+// unverifiedInteractionsOf lists the invocations of mocks for which isVerified is false.
+func unverifiedInteractionsOf(mocks []Mock, isVerified func(genericMock *GenericMock, orderingInvocationNumber int) bool) string {
+	result := ""
+	for _, mock := range mocks {
+		genericMock := GetGenericMockFrom(mock)
+		invocations := ""
+		for _, invocation := range genericMock.numberedInvocationsSince(0) {
+			if !isVerified(genericMock, invocation.orderingInvocationNumber) {
+				invocations += "\t\t" + invocation.methodName + "(" + formatParams(invocation.params) + ")\n"
+			}
+		}
+		if invocations != "" {
+			result += fmt.Sprintf("\t%T:\n%v", mock, invocations)
+		}
+	}
+	return result
+}
+
+func interactionsOfMocksSince(firstInvocationNumber int, mocks []Mock) string {
+	result := ""
+	for _, mock := range mocks {
+		if invocations := GetGenericMockFrom(mock).interactionsSince(firstInvocationNumber); invocations != "" {
+			result += fmt.Sprintf("\t%T:\n%v", mock, invocations)
+		}
+	}
+	return result
+}
+
+type numberedInvocation struct {
+	methodName string
+	MethodInvocation
+}
+
+func (genericMock *GenericMock) interactionsSince(firstInvocationNumber int) string {
+	result := ""
+	for _, invocation := range genericMock.numberedInvocationsSince(firstInvocationNumber) {
+		result += "\t\t" + invocation.methodName + "(" + formatParams(invocation.params) + ")\n"
+	}
+	return result
+}
+
+func (genericMock *GenericMock) numberedInvocationsSince(firstInvocationNumber int) []numberedInvocation {
+	var invocations []numberedInvocation
+	genericMock.Lock()
+	for methodName, method := range genericMock.mockedMethods {
+		method.Lock()
+		for _, invocation := range method.invocations {
+			if invocation.orderingInvocationNumber >= firstInvocationNumber {
+				invocations = append(invocations, numberedInvocation{methodName, invocation})
+			}
+		}
+		method.Unlock()
+	}
+	genericMock.Unlock()
+
+	sort.Slice(invocations, func(i, j int) bool {
+		return invocations[i].orderingInvocationNumber < invocations[j].orderingInvocationNumber
+	})
+	return invocations
+}
+
+// InvocationParamsOf returns the params of all invocations of methodName on mock, in the order
+// they were made. It's meant for assertions that don't go through the mock's verifiers, like the
+// Gomega matchers of package github.com/petergtz/pegomock/ginkgo.
+func InvocationParamsOf(mock Mock, methodName string) [][]Param {
+	var params [][]Param
+	if method := GetGenericMockFrom(mock).mockedMethod(methodName); method != nil {
+		for _, invocation := range method.invocationsSnapshot() {
+			params = append(params, invocation.params)
+		}
+	}
+	return params
+}
+
 func DumpInvocationsFor(mock Mock) {
 	fmt.Print(SDumpInvocationsFor(mock))
 }
 
 func SDumpInvocationsFor(mock Mock) string {
 	result := &bytes.Buffer{}
-	for _, mockedMethod := range GetGenericMockFrom(mock).mockedMethods {
-		for _, invocation := range mockedMethod.invocations {
+	for _, mockedMethod := range GetGenericMockFrom(mock).mockedMethodsSnapshot() {
+		for _, invocation := range mockedMethod.invocationsSnapshot() {
 			fmt.Fprintf(result, "Method invocation: %v (\n", mockedMethod.name)
 			for _, param := range invocation.params {
 				fmt.Fprint(result, format.Object(param, 1), ",\n")
 			}
 			fmt.Fprintln(result, ")")
+			if invocation.answerNote != "" {
+				fmt.Fprintf(result, "Answered by: %v\n", invocation.answerNote)
+			}
+			if invocation.answerPanic != nil {
+				fmt.Fprintf(result, "Answer panicked with: %v\n", invocation.answerPanic)
+			}
 		}
 	}
 	return result.String()
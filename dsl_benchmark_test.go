@@ -0,0 +1,50 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pegomock_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/petergtz/pegomock"
+)
+
+func BenchmarkInvokeMethodWithoutParamsAndReturnValues(b *testing.B) {
+	display := NewMockDisplay()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		display.Clear()
+	}
+}
+
+// BenchmarkInvokeMethodWithoutParamsAndReturnValuesWithEmptySlices invokes the method the way
+// generated mocks used to, for comparison with BenchmarkInvokeMethodWithoutParamsAndReturnValues.
+func BenchmarkInvokeMethodWithoutParamsAndReturnValuesWithEmptySlices(b *testing.B) {
+	display := NewMockDisplay()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		params := []pegomock.Param{}
+		pegomock.GetGenericMockFrom(display).Invoke("Clear", params, []reflect.Type{})
+	}
+}
+
+// BenchmarkGoroutineStateLookup measures determining the state of the current goroutine, which
+// parses the goroutine's ID from its stack trace, as every invocation of a mock does.
+func BenchmarkGoroutineStateLookup(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pegomock.ResetGoroutineState()
+	}
+}
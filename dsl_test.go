@@ -18,9 +18,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock"
 	. "github.com/petergtz/pegomock/matchers"
@@ -29,10 +33,12 @@ import (
 	"github.com/onsi/gomega"
 	. "github.com/onsi/gomega"
 	"github.com/petergtz/pegomock"
+	"github.com/petergtz/pegomock/test_interface"
 )
 
 var (
 	BeforeEach = ginkgo.BeforeEach
+	AfterEach  = ginkgo.AfterEach
 	It         = ginkgo.It
 	FIt        = ginkgo.FIt
 	Describe   = ginkgo.Describe
@@ -73,6 +79,15 @@ func NeverMatchingRequest() http.Request {
 	return http.Request{}
 }
 
+type EvenMatcher struct{}
+
+func (matcher *EvenMatcher) Matches(param Param) bool {
+	i, ok := param.(int)
+	return ok && i%2 == 0
+}
+func (matcher *EvenMatcher) FailureMessage() string { return "Expected an even int" }
+func (matcher *EvenMatcher) String() string         { return "Even" }
+
 var _ = Describe("MockDisplay", func() {
 	var display *MockDisplay
 
@@ -168,6 +183,21 @@ var _ = Describe("MockDisplay", func() {
 			Expect(display.SomeValue()).To(Equal("again"))
 		})
 
+		It("returns values given all at once in order, repeating the last one", func() {
+			When(display.SomeValue()).ThenReturnConsecutively("one", "two", "three")
+
+			Expect(display.SomeValue()).To(Equal("one"))
+			Expect(display.SomeValue()).To(Equal("two"))
+			Expect(display.SomeValue()).To(Equal("three"))
+			Expect(display.SomeValue()).To(Equal("three"))
+		})
+
+		It("fails when given values for a method with multiple return values", func() {
+			Expect(func() { When(display.MultipleValues()).ThenReturnConsecutively("one", "two") }).To(PanicWithMessageTo(Equal(
+				"ThenReturnConsecutively requires a method with exactly one return value, but it has 3",
+			)))
+		})
+
 		It("can be verified that mock was called", func() {
 			display.SomeValue()
 			Expect(func() { display.VerifyWasCalledOnce().SomeValue() }).NotTo(Panic())
@@ -189,6 +219,34 @@ var _ = Describe("MockDisplay", func() {
 		})
 	})
 
+	Context("Stubbing with delayed return values", func() {
+		It("returns the stubbed values only after the delay", func() {
+			When(display.SomeValue()).ThenReturnAfter(50*time.Millisecond, "Hello")
+
+			start := time.Now()
+			Expect(display.SomeValue()).To(Equal("Hello"))
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		It("lets the caller time out", func() {
+			When(func() { display.Show("slow") }).ThenReturnAfter(100 * time.Millisecond)
+
+			returned := make(chan bool)
+			go func() {
+				display.Show("slow")
+				close(returned)
+			}()
+			Consistently(returned, 50*time.Millisecond).ShouldNot(BeClosed())
+			Eventually(returned).Should(BeClosed())
+		})
+
+		It("panics when the stubbed values don't fit the return types", func() {
+			Expect(func() { When(display.SomeValue()).ThenReturnAfter(time.Millisecond, 0) }).To(PanicWithMessageTo(HavePrefix(
+				"Return value of type int not assignable to return type string",
+			)))
+		})
+	})
+
 	Context("Stubbing with invalid return type", func() {
 		It("panics", func() {
 			Expect(func() { When(display.SomeValue()).ThenReturn("Hello").ThenReturn(0) }).To(PanicWithMessageTo(HavePrefix(
@@ -332,6 +390,21 @@ var _ = Describe("MockDisplay", func() {
 				)))
 			})
 
+			It("succeeds during verification when using AtMost(2) and Between(1, 2)", func() {
+				Expect(func() { display.VerifyWasCalled(AtMost(2)).Flash("Hello", 333) }).NotTo(Panic())
+				Expect(func() { display.VerifyWasCalled(Between(1, 2)).Flash("Hello", 333) }).NotTo(Panic())
+			})
+
+			It("fails during verification when using Between(3, 5)", func() {
+				Expect(func() { display.VerifyWasCalled(Between(3, 5)).Flash("Hello", 333) }).To(PanicWithMessageTo(HavePrefix(
+					expectation{method: "Flash(\"Hello\", 333)", expected: "between 3 and 5", actual: "2"}.string(),
+				)))
+			})
+
+			It("panics when using Between with min greater than max", func() {
+				Expect(func() { Between(2, 1) }).To(PanicWithMessageTo(Equal("Between requires min <= max, but got 2 and 1")))
+			})
+
 			It("succeeds during verification when using Never()", func() {
 				Expect(func() { display.VerifyWasCalled(Never()).Flash("Other value", 333) }).NotTo(Panic())
 			})
@@ -358,7 +431,12 @@ var _ = Describe("MockDisplay", func() {
 				ThenPanic("I'm panicking")
 			Expect(func() {
 				display.MultipleParamsAndReturnValue("Some string", 123)
-			}).To(PanicWith("I'm panicking"))
+			}).To(PanicWithMessageTo(Equal(&AnswerPanic{
+				Mock:   "*pegomock_test.MockDisplay",
+				Method: "MultipleParamsAndReturnValue",
+				Params: []Param{"Some string", 123},
+				Value:  "I'm panicking",
+			})))
 		})
 
 		It("calls back when stubbed to call back", func() {
@@ -371,6 +449,36 @@ var _ = Describe("MockDisplay", func() {
 				To(Equal("string and 123"))
 		})
 
+		It("computes the return values from the actual arguments when stubbed with ThenAnswer", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenAnswer(
+				func(params []Param) ReturnValues {
+					return []ReturnValue{fmt.Sprintf("%v%v", params[1], params[0])}
+				},
+			)
+			Expect(display.MultipleParamsAndReturnValue("a", 1)).To(Equal("1a"))
+			Expect(display.MultipleParamsAndReturnValue("b", 2)).To(Equal("2b"))
+		})
+
+		It("computes the return values with a typed answer", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenAnswer(
+				display.AnswerMultipleParamsAndReturnValue(func(s string, i int) string {
+					return strings.Repeat(s, i)
+				}),
+			)
+			Expect(display.MultipleParamsAndReturnValue("ab", 3)).To(Equal("ababab"))
+		})
+
+		It("passes variadic arguments to a typed answer", func() {
+			var captured []string
+			When(func() { display.NormalAndVariadicParam(AnyString(), AnyInt(), AnyString(), AnyString()) }).ThenAnswer(
+				display.AnswerNormalAndVariadicParam(func(s string, i int, v ...string) {
+					captured = append([]string{s}, v...)
+				}),
+			)
+			display.NormalAndVariadicParam("a", 1, "b", "c")
+			Expect(captured).To(Equal([]string{"a", "b", "c"}))
+		})
+
 	})
 
 	Context("Making calls in a specific order", func() {
@@ -410,6 +518,109 @@ var _ = Describe("MockDisplay", func() {
 			)))
 		})
 
+		It("fails when invocations of different mocks are not in order, showing the timeline of all verified mocks", func() {
+			display = NewMockDisplay()
+			otherDisplay := NewMockDisplay()
+			otherDisplay.Show("published")
+			display.Show("saved")
+
+			Expect(func() {
+				inOrder := new(InOrderContext)
+				display.VerifyWasCalledInOrder(Once(), inOrder).Show("saved")
+				otherDisplay.VerifyWasCalledInOrder(Once(), inOrder).Show("published")
+			}).To(PanicWithMessageTo(Equal(
+				"Expected function call Show(\"published\") before function call Show(\"saved\")\n\n" +
+					"\tTimeline:\n" +
+					"\t\t*pegomock_test.MockDisplay#2.Show(\"published\")\n" +
+					"\t\t*pegomock_test.MockDisplay#1.Show(\"saved\")\n",
+			)))
+		})
+
+		Context("with checkpoints", func() {
+			var inOrder *InOrderContext
+
+			BeforeEach(func() {
+				display = NewMockDisplay()
+				inOrder = new(InOrderContext)
+			})
+
+			It("succeeds when invocations and checkpoints are verified in the order they happened", func() {
+				display.Show("before")
+				inOrder.Checkpoint("cancelled")
+				display.Show("after")
+
+				Expect(func() {
+					display.VerifyWasCalledInOrder(Once(), inOrder).Show("before")
+					inOrder.VerifyCheckpoint("cancelled")
+					display.VerifyWasCalledInOrder(Once(), inOrder).Show("after")
+				}).NotTo(Panic())
+			})
+
+			It("records checkpoints from other goroutines", func() {
+				done := make(chan bool)
+				go func() {
+					inOrder.Checkpoint("cancelled")
+					display.Show("after")
+					close(done)
+				}()
+				<-done
+
+				Expect(func() {
+					inOrder.VerifyCheckpoint("cancelled")
+					display.VerifyWasCalledInOrder(Once(), inOrder).Show("after")
+				}).NotTo(Panic())
+			})
+
+			It("fails when an invocation happened before a verified checkpoint, showing the timeline", func() {
+				display.Flash("early", 1)
+				inOrder.Checkpoint("cancelled")
+				display.Show("in time")
+
+				Expect(func() {
+					inOrder.VerifyCheckpoint("cancelled")
+					display.VerifyWasCalledInOrder(Once(), inOrder).Flash("early", 1)
+				}).To(PanicWithMessageTo(Equal(
+					"Expected function call Flash(\"early\", 1) after checkpoint \"cancelled\"\n\n" +
+						"\tTimeline:\n" +
+						"\t\tFlash(\"early\", 1)\n" +
+						"\t\tcheckpoint \"cancelled\"\n" +
+						"\t\tShow(\"in time\")\n",
+				)))
+			})
+
+			It("fails when a checkpoint happened before a verified invocation", func() {
+				inOrder.Checkpoint("cancelled")
+				display.Show("late")
+
+				Expect(func() {
+					display.VerifyWasCalledInOrder(Once(), inOrder).Show("late")
+					inOrder.VerifyCheckpoint("cancelled")
+				}).To(PanicWithMessageTo(HavePrefix(
+					"Expected checkpoint \"cancelled\" after function call Show(\"late\")",
+				)))
+			})
+
+			It("fails when a checkpoint was not reached", func() {
+				inOrder.Checkpoint("started")
+
+				Expect(func() { inOrder.VerifyCheckpoint("cancelled") }).To(PanicWithMessageTo(HavePrefix(
+					"Expected checkpoint \"cancelled\" to be reached, but it was not",
+				)))
+			})
+
+			It("uses the first occurrence of a repeated checkpoint that is in order", func() {
+				inOrder.Checkpoint("tick")
+				display.Show("between")
+				inOrder.Checkpoint("tick")
+
+				Expect(func() {
+					inOrder.VerifyCheckpoint("tick")
+					display.VerifyWasCalledInOrder(Once(), inOrder).Show("between")
+					inOrder.VerifyCheckpoint("tick")
+				}).NotTo(Panic())
+			})
+		})
+
 	})
 
 	Context("Capturing arguments", func() {
@@ -570,13 +781,12 @@ var _ = Describe("MockDisplay", func() {
 		It("Fails when http.Request-parameter is passed as null value and verified as never matching http.Request", func() {
 			display.NetHttpRequestParam(http.Request{})
 			Expect(func() { display.VerifyWasCalledOnce().NetHttpRequestParam(NeverMatchingRequest()) }).
-				To(PanicWithMessageTo(Equal(`Mock invocation count for NetHttpRequestParam(NeverMatching) does not match expectation.
-
-	Expected: 1; but got: 0
-
-	But other interactions with this mock were:
-	NetHttpRequestParam(http.Request{Method:"", URL:(*url.URL)(nil), Proto:"", ProtoMajor:0, ProtoMinor:0, Header:http.Header(nil), Body:io.ReadCloser(nil), GetBody:(func() (io.ReadCloser, error))(nil), ContentLength:0, TransferEncoding:[]string(nil), Close:false, Host:"", Form:url.Values(nil), PostForm:url.Values(nil), MultipartForm:(*multipart.Form)(nil), Trailer:http.Header(nil), RemoteAddr:"", RequestURI:"", TLS:(*tls.ConnectionState)(nil), Cancel:(<-chan struct {})(nil), Response:(*http.Response)(nil), ctx:context.Context(nil)})
-`)))
+				To(PanicWithMessageTo(MatchRegexp(`(?s)^Mock invocation count for NetHttpRequestParam\(NeverMatching\) does not match expectation\.\n\n` +
+					`\tExpected: 1; but got: 0\n\n` +
+					`\tInvocations of NetHttpRequestParam were:\n` +
+					`\t> NetHttpRequestParam\(http\.Request\{Method:"", .*Host:"", .*\}\)\n\n` +
+					`\tClosest match differs in:\n` +
+					`\t  argument 1: expected NeverMatching, got http\.Request\{Method:"", .*Host:"", .*\}\n$`)))
 		})
 	})
 
@@ -609,9 +819,12 @@ var _ = Describe("MockDisplay", func() {
 			Expect(func() { display.VerifyWasCalledOnce().Flash("wrong string", -987) }).To(PanicWith(
 				"Mock invocation count for Flash(\"wrong string\", -987) " +
 					"does not match expectation.\n\n\tExpected: 1; but got: 0\n\n" +
-					"\tBut other interactions with this mock were:\n" +
-					"\tFlash(\"Hello\", 123)\n" +
-					"\tFlash(\"Again\", 456)\n",
+					"\tInvocations of Flash were:\n" +
+					"\t> Flash(\"Hello\", 123)\n" +
+					"\t  Flash(\"Again\", 456)\n" +
+					"\n\tClosest match differs in:\n" +
+					"\t  argument 1: expected \"wrong string\", got \"Hello\"\n" +
+					"\t  argument 2: expected -987, got 123\n",
 			))
 		})
 
@@ -622,25 +835,46 @@ var _ = Describe("MockDisplay", func() {
 			Expect(func() { display.VerifyWasCalledOnce().Flash("wrong string", -987) }).To(PanicWith(
 				"Mock invocation count for Flash(\"wrong string\", -987) " +
 					"does not match expectation.\n\n\tExpected: 1; but got: 0\n\n" +
-					"\tBut other interactions with this mock were:\n" +
-					"\tFlash(\"Hello\", 123)\n" +
+					"\tInvocations of Flash were:\n" +
+					"\t> Flash(\"Hello\", 123)\n" +
+					"\n\tClosest match differs in:\n" +
+					"\t  argument 1: expected \"wrong string\", got \"Hello\"\n" +
+					"\t  argument 2: expected -987, got 123\n" +
+					"\n\tInteractions with other methods of this mock were:\n" +
 					"\tShow(\"Again\")\n"),
 			)
 		})
 
-		It("formats params in interactions with Go syntax for better readability", func() {
-			display.NetHttpRequestParam(http.Request{Host: "x.com"})
-			Expect(func() { display.VerifyWasCalledOnce().NetHttpRequestParam(http.Request{Host: "y.com"}) }).To(PanicWith(
-				`Mock invocation count for NetHttpRequestParam(http.Request{Method:"", URL:(*url.URL)(nil), Proto:"", ProtoMajor:0, ProtoMinor:0, Header:http.Header(nil), Body:io.ReadCloser(nil), GetBody:(func() (io.ReadCloser, error))(nil), ContentLength:0, TransferEncoding:[]string(nil), Close:false, Host:"y.com", Form:url.Values(nil), PostForm:url.Values(nil), MultipartForm:(*multipart.Form)(nil), Trailer:http.Header(nil), RemoteAddr:"", RequestURI:"", TLS:(*tls.ConnectionState)(nil), Cancel:(<-chan struct {})(nil), Response:(*http.Response)(nil), ctx:context.Context(nil)}) does not match expectation.
-
-	Expected: 1; but got: 0
+		It("highlights the invocation matching most arguments and shows matchers in its differences", func() {
+			display.Flash("Bye", 1)
+			display.Flash("Hello", 2)
+			display.Flash("Hello", 3)
 
-	But other interactions with this mock were:
-	NetHttpRequestParam(http.Request{Method:"", URL:(*url.URL)(nil), Proto:"", ProtoMajor:0, ProtoMinor:0, Header:http.Header(nil), Body:io.ReadCloser(nil), GetBody:(func() (io.ReadCloser, error))(nil), ContentLength:0, TransferEncoding:[]string(nil), Close:false, Host:"x.com", Form:url.Values(nil), PostForm:url.Values(nil), MultipartForm:(*multipart.Form)(nil), Trailer:http.Header(nil), RemoteAddr:"", RequestURI:"", TLS:(*tls.ConnectionState)(nil), Cancel:(<-chan struct {})(nil), Response:(*http.Response)(nil), ctx:context.Context(nil)})
-`,
+			Expect(func() { display.VerifyWasCalledOnce().Flash(EqString("Hello"), IntGreaterThan(5)) }).To(PanicWith(
+				"Mock invocation count for Flash(Eq(Hello), GreaterThan(5)) " +
+					"does not match expectation.\n\n\tExpected: 1; but got: 0\n\n" +
+					"\tInvocations of Flash were:\n" +
+					"\t  Flash(\"Bye\", 1)\n" +
+					"\t> Flash(\"Hello\", 2)\n" +
+					"\t  Flash(\"Hello\", 3)\n" +
+					"\n\tClosest match differs in:\n" +
+					"\t  argument 2: expected GreaterThan(5), got 2\n",
 			))
 		})
 
+		It("formats params in interactions with Go syntax for better readability", func() {
+			display.NetHttpRequestParam(http.Request{Host: "x.com"})
+			// The fields of http.Request vary between Go versions, so only the ones that differ are matched.
+			Expect(func() { display.VerifyWasCalledOnce().NetHttpRequestParam(http.Request{Host: "y.com"}) }).To(PanicWithMessageTo(MatchRegexp(
+				`(?s)^Mock invocation count for NetHttpRequestParam\(http\.Request\{Method:"", .*Host:"y\.com", .*\}\) does not match expectation\.\n\n` +
+					`\tExpected: 1; but got: 0\n\n` +
+					`\tInvocations of NetHttpRequestParam were:\n` +
+					`\t> NetHttpRequestParam\(http\.Request\{Method:"", .*Host:"x\.com", .*\}\)\n\n` +
+					`\tClosest match differs in:\n` +
+					`\t  argument 1: expected http\.Request\{Method:"", .*Host:"y\.com", .*\}, got http\.Request\{Method:"", .*Host:"x\.com", .*\}\n$`,
+			)))
+		})
+
 		It("shows no interactions if there were none", func() {
 			Expect(func() { display.VerifyWasCalledOnce().Flash("wrong string", -987) }).To(PanicWith(
 				"Mock invocation count for Flash(\"wrong string\", -987) " +
@@ -650,10 +884,59 @@ var _ = Describe("MockDisplay", func() {
 		})
 	})
 
+	Describe("Panicking answers", func() {
+		It("wraps error panic values, so they can still be inspected with errors.As", func() {
+			When(display.SomeValue()).ThenPanic(&customError{"boom"})
+
+			var recovered interface{}
+			func() {
+				defer func() { recovered = recover() }()
+				display.SomeValue()
+			}()
+
+			var target *customError
+			Expect(errors.As(recovered.(error), &target)).To(BeTrue())
+			Expect(target.message).To(Equal("boom"))
+		})
+
+		It("annotates panics of Then callbacks", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).Then(func(params []Param) ReturnValues {
+				panic("out of range")
+			})
+
+			Expect(func() { display.MultipleParamsAndReturnValue("Hello", 2) }).To(PanicWithMessageTo(
+				MatchError(`*pegomock_test.MockDisplay.MultipleParamsAndReturnValue("Hello", 2) panicked: out of range`)))
+		})
+
+		It("annotates panics only once when they pass through answers of other mocks", func() {
+			otherDisplay := NewMockDisplay()
+			When(func() { otherDisplay.Show("inner") }).ThenPanic("boom")
+			When(func() { display.Show("outer") }).Then(func([]Param) ReturnValues {
+				otherDisplay.Show("inner")
+				return nil
+			})
+
+			Expect(func() { display.Show("outer") }).To(PanicWithMessageTo(
+				MatchError(`*pegomock_test.MockDisplay.Show("inner") panicked: boom`)))
+		})
+
+		It("records the panic in the invocation history", func() {
+			When(func() { display.Show("Hello") }).ThenPanic("boom")
+
+			func() {
+				defer func() { recover() }()
+				display.Show("Hello")
+			}()
+
+			Expect(SDumpInvocationsFor(display)).To(ContainSubstring("Answer panicked with: boom\n"))
+		})
+	})
+
 	Describe("Stubbing methods that have no return value", func() {
 		It("Can be stubbed with Panic", func() {
 			When(func() { display.Show(AnyString()) }).ThenPanic("bla")
-			Expect(func() { display.Show("Hello") }).To(PanicWith("bla"))
+			Expect(func() { display.Show("Hello") }).To(PanicWithMessageTo(
+				MatchError(`*pegomock_test.MockDisplay.Show("Hello") panicked: bla`)))
 		})
 
 		It("Can still work with methods returning a func", func() {
@@ -758,6 +1041,125 @@ var _ = Describe("MockDisplay", func() {
 			})
 		})
 
+		Context("All variadic arguments matched at once", func() {
+			It("stubs invocations with any number of variadic arguments", func() {
+				var captured [][]string
+				When(func() { display.NormalAndVariadicParam(EqString("a"), AnyInt(), VariadicArgs(AnyStringSlice())...) }).ThenAnswer(
+					display.AnswerNormalAndVariadicParam(func(s string, i int, v ...string) {
+						captured = append(captured, v)
+					}),
+				)
+
+				display.NormalAndVariadicParam("a", 1)
+				display.NormalAndVariadicParam("a", 2, "b")
+				display.NormalAndVariadicParam("a", 3, "b", "c")
+				display.NormalAndVariadicParam("x", 4, "b")
+
+				Expect(captured).To(Equal([][]string{nil, {"b"}, {"b", "c"}}))
+			})
+
+			It("verifies the variadic arguments as a slice", func() {
+				display.VariadicParam("one", "two")
+				display.VariadicParam("three", "four", "five")
+
+				display.VerifyWasCalled(Times(2)).VariadicParam(VariadicArgs(AnyStringSlice())...)
+				display.VerifyWasCalledOnce().VariadicParam(VariadicArgs(ArgSatisfies(func(v []string) bool { return len(v) == 3 }))...)
+				display.VerifyWasCalled(Never()).VariadicParam(VariadicArgs(ArgSatisfies(func(v []string) bool { return len(v) == 0 }))...)
+			})
+
+			It("reports the variadic arguments as a slice when verification fails", func() {
+				display.NormalAndVariadicParam("a", 1, "b", "c")
+
+				Expect(func() {
+					display.VerifyWasCalledOnce().NormalAndVariadicParam(AnyString(), AnyInt(), VariadicArgs(ArgThat[[]string](&EqMatcher{Value: []string{"b"}}))...)
+				}).To(PanicWithMessageTo(SatisfyAll(
+					ContainSubstring("NormalAndVariadicParam(Any(string), Any(int), Eq([b])...)"),
+					ContainSubstring(`argument 3: expected Eq([b])..., got []string{"b", "c"}`),
+				)))
+			})
+
+			It("captures the variadic arguments of each invocation as a slice", func() {
+				display.NormalAndVariadicParam("a", 1, "b", "c")
+				display.NormalAndVariadicParam("d", 2)
+
+				captor := NewArgumentCaptor[[]string]()
+				display.VerifyWasCalled(Times(2)).NormalAndVariadicParam(AnyString(), AnyInt(), VariadicArgs(captor.Capture())...)
+				Expect(captor.AllValues()).To(Equal([][]string{{"b", "c"}, nil}))
+
+				names := NewArgumentCaptor[[]string]()
+				When(func() { display.VariadicParam(VariadicArgs(names.Capture())...) }).ThenReturn()
+				display.VariadicParam("e")
+				display.VariadicParam("f", "g")
+				Expect(names.AllValues()).To(Equal([][]string{{"e"}, {"f", "g"}}))
+			})
+
+			It("returns all captured arguments however many variadic ones each invocation had", func() {
+				display.NormalAndVariadicParam("a", 1, "b", "c")
+				display.NormalAndVariadicParam("d", 2)
+				display.NormalAndVariadicParam("e", 3, "f", "g", "h")
+
+				stringArgs, intArgs, varArgs := display.VerifyWasCalled(Times(3)).NormalAndVariadicParam(AnyString(), AnyInt(), VariadicArgs(AnyStringSlice())...).GetAllCapturedArguments()
+				Expect(stringArgs).To(Equal([]string{"a", "d", "e"}))
+				Expect(intArgs).To(Equal([]int{1, 2, 3}))
+				Expect(varArgs).To(Equal([][]string{{"b", "c"}, nil, {"f", "g", "h"}}))
+			})
+
+			It("fails when VariadicArgs is combined with raw values", func() {
+				Expect(func() {
+					display.VerifyWasCalledOnce().NormalAndVariadicParam("a", AnyInt(), VariadicArgs(AnyStringSlice())...)
+				}).To(PanicWithMessageTo(HavePrefix("Invalid use of matchers!\n\n 2 matchers expected, 1 recorded.")))
+			})
+		})
+
+		Context("Variadic matchers", func() {
+			BeforeEach(func() {
+				display.VariadicParam()
+				display.VariadicParam("one", "two")
+				display.VariadicParam("three", "one", "two")
+			})
+
+			It("matches any variadic arguments with AnyVarargs", func() {
+				display.VerifyWasCalled(Times(3)).VariadicParam(AnyVarargs[string]()...)
+			})
+
+			It("matches variadic arguments equal to a sequence with VarargsEq", func() {
+				display.VerifyWasCalledOnce().VariadicParam(VarargsEq("one", "two")...)
+				display.VerifyWasCalledOnce().VariadicParam(VarargsEq[string]()...)
+				display.VerifyWasCalled(Never()).VariadicParam(VarargsEq("two", "one")...)
+				display.VerifyWasCalled(Never()).VariadicParam(VarargsEq("one")...)
+			})
+
+			It("matches variadic arguments that include values with VarargsContaining", func() {
+				display.VerifyWasCalled(Times(2)).VariadicParam(VarargsContaining("two", "one")...)
+				display.VerifyWasCalledOnce().VariadicParam(VarargsContaining("three")...)
+				display.VerifyWasCalled(Times(3)).VariadicParam(VarargsContaining[string]()...)
+				display.VerifyWasCalled(Never()).VariadicParam(VarargsContaining("four")...)
+			})
+
+			It("reports the expected variadic arguments when verification fails", func() {
+				Expect(func() {
+					display.VerifyWasCalledOnce().VariadicParam(VarargsContaining("one", "four")...)
+				}).To(PanicWithMessageTo(SatisfyAll(
+					ContainSubstring(`VariadicParam(VarargsContaining("one", "four")...)`),
+					ContainSubstring(`argument 1: expected VarargsContaining("one", "four")..., got []string(nil)`),
+				)))
+			})
+
+			It("stubs invocations by their variadic arguments", func() {
+				var captured [][]string
+				When(func() { display.VariadicParam(VarargsEq("a", "b")...) }).ThenAnswer(
+					display.AnswerVariadicParam(func(v ...string) {
+						captured = append(captured, v)
+					}),
+				)
+
+				display.VariadicParam("a", "b")
+				display.VariadicParam("a")
+
+				Expect(captured).To(Equal([][]string{{"a", "b"}}))
+			})
+		})
+
 		Context("Concurrent access to mock", func() {
 			It("does not panic", func() {
 				Expect(func() {
@@ -825,7 +1227,1402 @@ var _ = Describe("MockDisplay", func() {
 
 	})
 
-})
+	Describe("Stubbing calls that involve calls on other mocks", func() {
+		var inner *MockDisplay
+
+		BeforeEach(func() {
+			inner = NewMockDisplay()
+			When(inner.SomeValue()).ThenReturn("inner value")
+			When(inner.MultipleParamsAndReturnValue("inner value", 1)).ThenReturn("intermediate value")
+		})
+
+		It("stubs only the outermost call", func() {
+			When(display.MultipleParamsAndReturnValue(inner.MultipleParamsAndReturnValue(inner.SomeValue(), 1), 2)).ThenReturn("outer value")
+
+			Expect(display.MultipleParamsAndReturnValue("intermediate value", 2)).To(Equal("outer value"))
+			Expect(inner.SomeValue()).To(Equal("inner value"))
+			Expect(inner.MultipleParamsAndReturnValue("inner value", 1)).To(Equal("intermediate value"))
+		})
+
+		It("records the inner calls normally", func() {
+			When(display.MultipleParamsAndReturnValue(inner.MultipleParamsAndReturnValue(inner.SomeValue(), 1), 2)).ThenReturn("outer value")
+
+			inner.VerifyWasCalledOnce().SomeValue()
+			inner.VerifyWasCalledOnce().MultipleParamsAndReturnValue("inner value", 1)
+			display.VerifyWasCalled(Never()).MultipleParamsAndReturnValue(AnyString(), AnyInt())
+		})
+
+		Context("when answering the stubbed call invokes other mocks", func() {
+			BeforeEach(func() {
+				When(inner.SomeValue()).Then(func([]Param) ReturnValues {
+					return ReturnValues{"inner " + display.SomeValue()}
+				})
+				When(display.MultipleParamsAndReturnValue("a", 1)).Then(func([]Param) ReturnValues {
+					return ReturnValues{"outer " + inner.SomeValue()}
+				})
+			})
+
+			It("stubs the outermost call", func() {
+				Expect(display.MultipleParamsAndReturnValue("a", 1)).To(Equal("outer inner "))
+
+				When(display.MultipleParamsAndReturnValue("a", 1)).ThenReturn("restubbed")
+
+				Expect(display.MultipleParamsAndReturnValue("a", 1)).To(Equal("restubbed"))
+				Expect(inner.SomeValue()).To(Equal("inner "))
+				Expect(display.SomeValue()).To(Equal(""))
+			})
+
+			It("records the nested calls normally", func() {
+				When(display.MultipleParamsAndReturnValue("a", 1)).ThenReturn("restubbed")
+
+				display.VerifyWasCalled(Never()).MultipleParamsAndReturnValue(AnyString(), AnyInt())
+				inner.VerifyWasCalledOnce().SomeValue()
+				display.VerifyWasCalledOnce().SomeValue()
+			})
+		})
+	})
+
+	Describe("ArgumentCaptor", func() {
+		var names *ArgumentCaptor[string]
+
+		BeforeEach(func() { names = NewArgumentCaptor[string]() })
+
+		It("captures the arguments of all invocations answered by its stubbing", func() {
+			When(display.MultipleParamsAndReturnValue(names.Capture(), AnyInt())).ThenReturn("stubbed")
+
+			Expect(display.MultipleParamsAndReturnValue("Tom", 1)).To(Equal("stubbed"))
+			Expect(display.MultipleParamsAndReturnValue("Tim", 2)).To(Equal("stubbed"))
+
+			Expect(names.AllValues()).To(Equal([]string{"Tom", "Tim"}))
+			Expect(names.Value()).To(Equal("Tim"))
+		})
+
+		It("does not capture invocations not matching its stubbing", func() {
+			When(display.MultipleParamsAndReturnValue(names.Capture(), EqInt(1))).ThenReturn("stubbed")
+
+			display.MultipleParamsAndReturnValue("Tom", 1)
+			display.MultipleParamsAndReturnValue("Tim", 2)
+
+			Expect(names.AllValues()).To(Equal([]string{"Tom"}))
+		})
+
+		It("does not capture invocations answered by other stubbings", func() {
+			When(display.MultipleParamsAndReturnValue(names.Capture(), AnyInt())).ThenReturn("captured")
+			When(display.MultipleParamsAndReturnValue(EqString("Tim"), AnyInt())).ThenReturn("not captured")
+
+			display.MultipleParamsAndReturnValue("Tom", 1)
+			display.MultipleParamsAndReturnValue("Tim", 2)
+
+			Expect(names.AllValues()).To(Equal([]string{"Tom"}))
+		})
+
+		It("captures arguments before answering with a callback and across consecutive stubbings", func() {
+			When(display.MultipleParamsAndReturnValue(names.Capture(), AnyInt())).
+				Then(func(params []Param) ReturnValues { return ReturnValues{names.Value()} }).
+				ThenReturn("last")
+
+			Expect(display.MultipleParamsAndReturnValue("Tom", 1)).To(Equal("Tom"))
+			Expect(display.MultipleParamsAndReturnValue("Tim", 2)).To(Equal("last"))
+			Expect(names.AllValues()).To(Equal([]string{"Tom", "Tim"}))
+		})
+
+		It("does not capture the invocations made for stubbing and verification", func() {
+			When(display.MultipleParamsAndReturnValue(names.Capture(), AnyInt())).ThenReturn("first")
+			display.MultipleParamsAndReturnValue("Tom", 1)
+			When(display.MultipleParamsAndReturnValue(names.Capture(), AnyInt())).ThenReturn("second")
+
+			display.VerifyWasCalledOnce().MultipleParamsAndReturnValue(names.Capture(), AnyInt())
+
+			Expect(names.AllValues()).To(Equal([]string{"Tom"}))
+		})
+
+		It("matches only arguments of its type in verifications", func() {
+			display.InterfaceParam("Tom")
+			display.InterfaceParam(1)
+
+			display.VerifyWasCalledOnce().InterfaceParam(NewArgumentCaptor[int]().Capture())
+		})
+
+		It("captures typed arguments of all invocations matched by a verification", func() {
+			display.Flash("Hello", 1)
+			display.Flash("Bye", 2)
+			display.Flash("Hello again", 3)
+
+			texts, numbers := NewArgumentCaptor[string](), NewArgumentCaptor[int]()
+			display.VerifyWasCalled(Twice()).Flash(texts.Capture(), ArgNot(EqInt(2)))
+			display.VerifyWasCalled(Times(3)).Flash(AnyString(), numbers.Capture())
+
+			Expect(texts.AllValues()).To(Equal([]string{"Hello", "Hello again"}))
+			Expect(texts.Value()).To(Equal("Hello again"))
+			Expect(numbers.AllValues()).To(Equal([]int{1, 2, 3}))
+		})
+
+		It("starts over with every verification", func() {
+			display.Show("Hello")
+			texts := NewArgumentCaptor[string]()
+			display.VerifyWasCalledOnce().Show(texts.Capture())
+
+			display.VerifyWasCalled(Never()).Flash(texts.Capture(), AnyInt())
+
+			Expect(texts.AllValues()).To(BeEmpty())
+			Expect(texts.Value()).To(Equal(""))
+		})
+
+		It("captures the arguments of the last verification once used in one", func() {
+			When(display.MultipleParamsAndReturnValue(names.Capture(), AnyInt())).ThenReturn("stubbed")
+			display.MultipleParamsAndReturnValue("Tom", 1)
+			display.MultipleParamsAndReturnValue("Tim", 2)
+			Expect(names.AllValues()).To(Equal([]string{"Tom", "Tim"}))
+
+			display.VerifyWasCalledOnce().MultipleParamsAndReturnValue(names.Capture(), EqInt(2))
+
+			Expect(names.AllValues()).To(Equal([]string{"Tim"}))
+		})
+	})
+
+	Describe("Captured", func() {
+		It("matches when all invocations pass the captured value", func() {
+			display.Show("token")
+			display.Show("token")
+			display.Show("token")
+
+			token := Captured[string]()
+			display.VerifyWasCalled(Times(3)).Show(token.CaptureOrMatch())
+
+			Expect(token.Value()).To(Equal("token"))
+		})
+
+		It("captures from the first invocation that matches all arguments", func() {
+			display.Flash("other", 1)
+			display.Flash("token", 2)
+			display.Flash("token", 2)
+
+			token := Captured[string]()
+			display.VerifyWasCalled(Times(2)).Flash(token.CaptureOrMatch(), EqInt(2))
+
+			Expect(token.Value()).To(Equal("token"))
+		})
+
+		It("shows the captured value and all mismatching values in the failure message", func() {
+			display.Show("token")
+			display.Show("other")
+			display.Show("token")
+			display.Show("yet another")
+
+			token := Captured[string]()
+			Expect(func() { display.VerifyWasCalled(Times(4)).Show(token.CaptureOrMatch()) }).To(PanicWith(
+				"Mock invocation count for Show(CaptureOrMatch(captured: \"token\", mismatching: \"other\", \"yet another\")) " +
+					"does not match expectation.\n\n\tExpected: 4; but got: 2\n\n" +
+					"\tInvocations of Show were:\n" +
+					"\t  Show(\"token\")\n" +
+					"\t  Show(\"other\")\n" +
+					"\t  Show(\"token\")\n" +
+					"\t  Show(\"yet another\")\n",
+			))
+		})
+
+		It("starts over with every verification", func() {
+			display.Show("token")
+			display.Flash("other token", 1)
+
+			token := Captured[string]()
+			display.VerifyWasCalledOnce().Show(token.CaptureOrMatch())
+			Expect(token.Value()).To(Equal("token"))
+
+			display.VerifyWasCalledOnce().Flash(token.CaptureOrMatch(), AnyInt())
+			Expect(token.Value()).To(Equal("other token"))
+		})
+
+		It("can be used to relate arguments of different methods", func() {
+			display.Show("id")
+			display.Flash("id", 1)
+
+			id := Captured[string]()
+			display.VerifyWasCalledOnce().Show(id.CaptureOrMatch())
+			display.VerifyWasCalledOnce().Flash(EqString(id.Value()), AnyInt())
+		})
+	})
+
+	Describe("BuildAggregatingTestingTFailHandler", func() {
+		var (
+			t     *recordingT
+			flush func()
+		)
+
+		BeforeEach(func() {
+			t = &recordingT{}
+			var handler FailHandler
+			handler, flush = BuildAggregatingTestingTFailHandler(t)
+			RegisterMockFailHandler(handler)
+		})
+
+		AfterEach(func() {
+			RegisterMockFailHandler(func(message string, callerSkip ...int) { panic(message) })
+		})
+
+		It("collapses identical consecutive failures and reports them on the next differing one", func() {
+			display.Show("wrong")
+			for i := 0; i < 50; i++ {
+				display.VerifyWasCalled(Never()).Show("wrong")
+			}
+			Expect(t.errors).To(BeEmpty())
+
+			display.VerifyWasCalledOnce().Flash("missing", 1)
+
+			Expect(t.errors).To(HaveLen(1))
+			Expect(t.errors[0]).To(SatisfyAll(
+				ContainSubstring("Mock invocation count for Show(\"wrong\") does not match expectation."),
+				HaveSuffix("\n\n(x50)")))
+		})
+
+		It("reports the last failure when flushed", func() {
+			display.VerifyWasCalledOnce().Show("missing")
+			display.VerifyWasCalledOnce().Show("missing")
+			display.VerifyWasCalledOnce().Flash("missing", 1)
+			flush()
+
+			Expect(t.errors).To(HaveLen(2))
+			Expect(t.errors[0]).To(HaveSuffix("(x2)"))
+			Expect(t.errors[1]).To(ContainSubstring("Mock invocation count for Flash(\"missing\", 1) does not match expectation."))
+			Expect(t.errors[1]).NotTo(ContainSubstring("(x"))
+
+			flush()
+			Expect(t.errors).To(HaveLen(2))
+		})
+
+		It("does not collapse failures that are identical, but not consecutive", func() {
+			display.VerifyWasCalledOnce().Show("missing")
+			display.VerifyWasCalledOnce().Flash("missing", 1)
+			display.VerifyWasCalledOnce().Show("missing")
+			flush()
+
+			Expect(t.errors).To(HaveLen(3))
+		})
+	})
+
+	Describe("Expectations", func() {
+		var (
+			t            *recordingT
+			otherDisplay *MockDisplay
+			exp          *Expectations
+		)
+
+		BeforeEach(func() {
+			t = &recordingT{}
+			otherDisplay = NewMockDisplay()
+			exp = ExpectationsFor(t, display, otherDisplay)
+		})
+
+		It("verifies expectations declared up front when the test finishes", func() {
+			exp.Expect(display.VerifyWasCalledOnce().Show(AnyString()))
+			exp.Expect(otherDisplay.VerifyWasCalled(Never()).Flash(AnyString(), AnyInt()))
+
+			display.Show("Hello")
+			Expect(t.errors).To(BeEmpty())
+
+			t.finish()
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("reports all unmet expectations at once", func() {
+			exp.Expect(display.VerifyWasCalledOnce().Show("Hello"))
+			exp.Expect(otherDisplay.VerifyWasCalled(Never()).Flash(AnyString(), AnyInt()))
+			exp.Expect(otherDisplay.VerifyWasCalled(AtLeast(1)).SomeValue())
+
+			otherDisplay.Flash("Hello", 1)
+			t.finish()
+
+			Expect(t.errors).To(HaveLen(1))
+			Expect(t.errors[0]).To(SatisfyAll(
+				HavePrefix("Unmet expectations:\n\n"),
+				ContainSubstring("1) Mock invocation count for Show(\"Hello\") does not match expectation."),
+				ContainSubstring("2) Mock invocation count for Flash(Any(string), Any(int)) does not match expectation."),
+				ContainSubstring("3) Mock invocation count for SomeValue() does not match expectation."),
+			))
+		})
+
+		It("keeps recording expectations while the mocks are only stubbed", func() {
+			When(display.SomeValue()).ThenReturn("stubbed")
+			exp.Expect(display.VerifyWasCalledOnce().SomeValue())
+
+			Expect(display.SomeValue()).To(Equal("stubbed"))
+			t.finish()
+
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("verifies right away once one of the mocks has been called", func() {
+			otherDisplay.Show("Hello")
+
+			Expect(func() { display.VerifyWasCalledOnce().Show("Hello") }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Show(\"Hello\") does not match expectation.")))
+		})
+
+		It("reports interactions not matched by any expectation when expecting no more interactions", func() {
+			exp.Expect(display.VerifyWasCalledOnce().Show("Hello"))
+			exp.ExpectNoMoreInteractions()
+
+			display.Show("Hello")
+			otherDisplay.Flash("Hi", 2)
+			t.finish()
+
+			Expect(t.errors).To(ConsistOf(SatisfyAll(
+				ContainSubstring("1) Expected no more interactions with mocks, but there were:"),
+				ContainSubstring("*pegomock_test.MockDisplay:\n\t\tFlash(\"Hi\", 2)"),
+				Not(ContainSubstring("Show(\"Hello\")")),
+			)))
+		})
+
+		It("reports an Expect without a recorded verification", func() {
+			display.Show("Hello")
+			exp.Expect(nil)
+
+			Expect(t.errors).To(ConsistOf(HavePrefix("Expect must be called with a verification")))
+		})
+
+		It("reports an Expect of something else than the verification recorded last", func() {
+			verification := otherDisplay.VerifyWasCalledOnce().Show("Hello")
+			exp.Expect(42)
+			display.VerifyWasCalledOnce().Show("Hello")
+			exp.Expect(verification)
+
+			Expect(t.errors).To(ConsistOf(
+				HavePrefix("Expect must be called with a verification"),
+				HavePrefix("Expect must be called with a verification"),
+			))
+		})
+	})
+
+	Describe("Custom matchers", func() {
+		It("can be used with ArgThat in stubbings", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), ArgThat[int](&EvenMatcher{}))).ThenReturn("even")
+
+			Expect(display.MultipleParamsAndReturnValue("a", 2)).To(Equal("even"))
+			Expect(display.MultipleParamsAndReturnValue("a", 3)).To(Equal(""))
+		})
+
+		It("appear with their descriptions in failure messages", func() {
+			display.Flash("Hello", 3)
+
+			Expect(func() { display.VerifyWasCalledOnce().Flash(AnyString(), ArgThat[int](&EvenMatcher{})) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Flash(Any(string), Even) does not match expectation.",
+			)))
+		})
+	})
+
+	Describe("Matcher combinators", func() {
+		It("negates a matcher with ArgNot", func() {
+			When(display.MultipleParamsAndReturnValue(ArgNot(EqString("admin")), AnyInt())).ThenReturn("user")
+
+			Expect(display.MultipleParamsAndReturnValue("guest", 1)).To(Equal("user"))
+			Expect(display.MultipleParamsAndReturnValue("admin", 1)).To(Equal(""))
+		})
+
+		It("requires all matchers to match with ArgAnd", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), ArgAnd(ArgThat[int](&EvenMatcher{}), ArgNot(EqInt(8))))).ThenReturn("even but not 8")
+
+			Expect(display.MultipleParamsAndReturnValue("a", 12)).To(Equal("even but not 8"))
+			Expect(display.MultipleParamsAndReturnValue("a", 11)).To(Equal(""))
+			Expect(display.MultipleParamsAndReturnValue("a", 8)).To(Equal(""))
+		})
+
+		It("requires any matcher to match with ArgOr", func() {
+			When(display.MultipleParamsAndReturnValue(ArgOr(EqString("a"), EqString("b")), AnyInt())).ThenReturn("a or b")
+
+			Expect(display.MultipleParamsAndReturnValue("a", 1)).To(Equal("a or b"))
+			Expect(display.MultipleParamsAndReturnValue("b", 1)).To(Equal("a or b"))
+			Expect(display.MultipleParamsAndReturnValue("c", 1)).To(Equal(""))
+		})
+
+		It("matches non-nil arguments with NotNil", func() {
+			display.InterfaceParam(nil)
+			display.InterfaceParam(3)
+
+			display.VerifyWasCalledOnce().InterfaceParam(NotNil[interface{}]())
+		})
+
+		It("describes combined matchers in failure messages", func() {
+			display.Flash("Hello", 3)
+
+			Expect(func() {
+				display.VerifyWasCalledOnce().Flash(ArgNot(EqString("Hello")), ArgOr(EqInt(1), ArgThat[int](&EvenMatcher{})))
+			}).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Flash(Not(Eq(Hello)), Or(Eq(1), Even)) does not match expectation.",
+			)))
+		})
+
+		It("panics when arguments are not matchers", func() {
+			Expect(func() { ArgNot("admin") }).To(PanicWith("All arguments of ArgNot must be matchers, but only 0 of 1 are"))
+		})
+	})
+
+	Describe("ThenReturnTable", func() {
+		It("stubs each row of the table", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturnTable(
+				StubbingRow{Args: []Param{"one", 1}, Returns: ReturnValues{"first"}},
+				StubbingRow{Args: []Param{"two", 2}, Returns: ReturnValues{"second"}},
+			)
+
+			Expect(display.MultipleParamsAndReturnValue("one", 1)).To(Equal("first"))
+			Expect(display.MultipleParamsAndReturnValue("two", 2)).To(Equal("second"))
+			Expect(display.MultipleParamsAndReturnValue("one", 2)).To(Equal(""))
+		})
+
+		It("can be combined with other stubbings of the same method", func() {
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturn("any")
+			When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturnTable(
+				StubbingRow{Args: []Param{"one", 1}, Returns: ReturnValues{"first"}},
+			)
+
+			Expect(display.MultipleParamsAndReturnValue("one", 1)).To(Equal("first"))
+			Expect(display.MultipleParamsAndReturnValue("two", 2)).To(Equal("any"))
+		})
+
+		It("panics for rows with the wrong number of arguments", func() {
+			Expect(func() {
+				When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturnTable(
+					StubbingRow{Args: []Param{"one", 1}, Returns: ReturnValues{"first"}},
+					StubbingRow{Args: []Param{"two"}, Returns: ReturnValues{"second"}},
+				)
+			}).To(PanicWithMessageTo(Equal("Row 2 of the stubbing table has 1 arguments, but MultipleParamsAndReturnValue takes 2")))
+			Expect(display.MultipleParamsAndReturnValue("one", 1)).To(Equal(""))
+		})
+
+		It("panics for rows with return values of the wrong type", func() {
+			Expect(func() {
+				When(display.MultipleParamsAndReturnValue(AnyString(), AnyInt())).ThenReturnTable(
+					StubbingRow{Args: []Param{"one", 1}, Returns: ReturnValues{1}},
+				)
+			}).To(PanicWithMessageTo(Equal("Return value of type int not assignable to return type string")))
+		})
+	})
+
+	Describe("ArgSatisfies", func() {
+		It("matches arguments for which the predicate holds in stubbings", func() {
+			When(display.MultipleParamsAndReturnValue(ArgSatisfies(func(s string) bool { return strings.HasPrefix(s, "tmp-") }), AnyInt())).ThenReturn("temporary")
+
+			Expect(display.MultipleParamsAndReturnValue("tmp-42", 1)).To(Equal("temporary"))
+			Expect(display.MultipleParamsAndReturnValue("42", 1)).To(Equal(""))
+		})
+
+		It("passes nil arguments as zero values", func() {
+			display.ErrorParam(nil)
+
+			display.VerifyWasCalledOnce().ErrorParam(ArgSatisfies(func(e error) bool { return e == nil }))
+		})
+
+		It("describes the predicate's type in failure messages", func() {
+			display.Flash("Hello", 3)
+
+			Expect(func() {
+				display.VerifyWasCalledOnce().Flash(AnyString(), ArgSatisfies(func(i int) bool { return i%2 == 0 }))
+			}).To(PanicWithMessageTo(ContainSubstring(
+				"Mock invocation count for Flash(Any(string), ArgSatisfies(func(int) bool)) does not match expectation.",
+			)))
+		})
+
+		It("panics for nil predicates", func() {
+			Expect(func() { ArgSatisfies[string](nil) }).To(PanicWithMessageTo(Equal("ArgSatisfies requires a non-nil predicate")))
+		})
+	})
+
+	Describe("StringMatching", func() {
+		It("matches string arguments by pattern in stubbings", func() {
+			When(display.MultipleParamsAndReturnValue(StringMatching("^user-[0-9]+$"), AnyInt())).ThenReturn("user")
+
+			Expect(display.MultipleParamsAndReturnValue("user-42", 1)).To(Equal("user"))
+			Expect(display.MultipleParamsAndReturnValue("user-x", 1)).To(Equal(""))
+			Expect(display.MultipleParamsAndReturnValue("the user-42", 1)).To(Equal(""))
+		})
+
+		It("describes the pattern in failure messages", func() {
+			display.Show("Hello")
+
+			Expect(func() { display.VerifyWasCalledOnce().Show(StringMatching("^Bye")) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Show(StringMatching(^Bye)) does not match expectation.",
+			)))
+		})
+
+		It("panics for invalid patterns", func() {
+			Expect(func() { StringMatching("(") }).To(PanicWithMessageTo(HavePrefix("Invalid pattern for StringMatching: ")))
+		})
+	})
+
+	Describe("FieldsEqual", func() {
+		type order struct {
+			ID     int
+			Status string
+		}
+
+		It("only compares the given fields", func() {
+			display.InterfaceParam(order{ID: 1, Status: "open"})
+			display.InterfaceParam(&order{ID: 2, Status: "open"})
+
+			display.VerifyWasCalledOnce().InterfaceParam(FieldsEqual(order{Status: "open"}, "Status"))
+			display.VerifyWasCalledOnce().InterfaceParam(FieldsEqual(&order{Status: "open"}, "Status"))
+			display.VerifyWasCalled(Never()).InterfaceParam(FieldsEqual(order{ID: 3, Status: "open"}, "ID", "Status"))
+		})
+
+		It("describes the given fields in failure messages", func() {
+			display.InterfaceParam(order{ID: 1, Status: "closed"})
+
+			Expect(func() {
+				display.VerifyWasCalledOnce().InterfaceParam(FieldsEqual(order{Status: "open"}, "Status"))
+			}).To(PanicWithMessageTo(HavePrefix(
+				`Mock invocation count for InterfaceParam(FieldsEqual(pegomock_test.order{Status: "open"})) does not match expectation.`,
+			)))
+		})
+
+		It("panics for unknown fields", func() {
+			Expect(func() { FieldsEqual(order{}, "Name") }).To(PanicWith("pegomock_test.order has no field Name"))
+		})
+	})
+
+	Describe("Comparison matchers", func() {
+		It("matches ints by range", func() {
+			display.Flash("Hello", 3)
+
+			display.VerifyWasCalledOnce().Flash(AnyString(), IntGreaterThan(2))
+			display.VerifyWasCalledOnce().Flash(AnyString(), IntLessThan(4))
+			display.VerifyWasCalledOnce().Flash(AnyString(), IntInRange(3, 3))
+			display.VerifyWasCalled(Never()).Flash(AnyString(), IntGreaterThan(3))
+			display.VerifyWasCalled(Never()).Flash(AnyString(), IntInRange(4, 10))
+		})
+
+		It("matches floats by range", func() {
+			display.FloatParam(1.5)
+
+			display.VerifyWasCalledOnce().FloatParam(Float32GreaterThan(1))
+			display.VerifyWasCalledOnce().FloatParam(Float32InRange(1, 2))
+			display.VerifyWasCalled(Never()).FloatParam(Float32LessThan(1.5))
+		})
+
+		It("describes the range in failure messages", func() {
+			display.Flash("Hello", 3)
+
+			Expect(func() { display.VerifyWasCalledOnce().Flash(AnyString(), IntInRange(5, 10)) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for Flash(Any(string), InRange(5, 10)) does not match expectation.",
+			)))
+		})
+
+		It("panics when min is greater than max", func() {
+			Expect(func() { IntInRange(2, 1) }).To(PanicWith("InRange requires min <= max, but got 2 and 1"))
+		})
+	})
+
+	Describe("Error matchers", func() {
+		var errNotFound = errors.New("not found")
+
+		It("matches wrapped errors with ErrorIs", func() {
+			display.ErrorParam(fmt.Errorf("loading user: %w", errNotFound))
+
+			display.VerifyWasCalledOnce().ErrorParam(ErrorIs(errNotFound))
+			display.VerifyWasCalled(Never()).ErrorParam(ErrorIs(errors.New("not found")))
+		})
+
+		It("matches and assigns errors of a type with ErrorAs", func() {
+			display.ErrorParam(fmt.Errorf("loading user: %w", &os.PathError{Op: "open", Path: "users.db", Err: errNotFound}))
+
+			var pathErr *os.PathError
+			display.VerifyWasCalledOnce().ErrorParam(ErrorAs(&pathErr))
+			Expect(pathErr.Path).To(Equal("users.db"))
+
+			var numErr *strconv.NumError
+			display.VerifyWasCalled(Never()).ErrorParam(ErrorAs(&numErr))
+		})
+
+		It("describes the target in failure messages", func() {
+			display.ErrorParam(errors.New("other"))
+
+			Expect(func() { display.VerifyWasCalledOnce().ErrorParam(ErrorIs(errNotFound)) }).To(PanicWithMessageTo(HavePrefix(
+				"Mock invocation count for ErrorParam(ErrorIs(not found)) does not match expectation.",
+			)))
+		})
+
+		It("panics when the target of ErrorAs is not a pointer", func() {
+			Expect(func() { ErrorAs(os.PathError{}) }).To(PanicWithMessageTo(HavePrefix("Target must be a non-nil pointer")))
+		})
+	})
+
+	Describe("Failures on other goroutines", func() {
+		It("are reported the next time mocks are verified on the test's goroutine", func() {
+			done := make(chan bool)
+			go func() {
+				defer close(done)
+				display.VerifyWasCalledOnce().Show("Hello")
+			}()
+			<-done
+
+			Expect(func() { display.VerifyWasCalled(Never()).Show("Hello") }).To(PanicWithMessageTo(SatisfyAll(
+				HavePrefix("Mock invocation count for Show(\"Hello\") does not match expectation."),
+				HaveSuffix("(This failure occurred on another goroutine and is reported on the test's goroutine.)"),
+			)))
+		})
+
+		It("are reported the next time mocks are used on the test's goroutine", func() {
+			Strict(display)
+			When(func() { display.Flash(AnyString(), AnyInt()) }).ThenReturn()
+			done := make(chan bool)
+			go func() {
+				defer close(done)
+				display.Show("Hello")
+				display.Flash("reports the failure of Show", 1)
+			}()
+			<-done
+
+			Expect(func() { display.Flash("Hello", 1) }).To(PanicWithMessageTo(
+				HavePrefix("Unexpected call *pegomock_test.MockDisplay.Show(\"Hello\") on a strict mock"),
+			))
+		})
+
+		It("are reported by ReportPendingFailures", func() {
+			Strict(display)
+			done := make(chan bool)
+			go func() {
+				defer close(done)
+				display.Show("Hello")
+			}()
+			<-done
+
+			Expect(ReportPendingFailures).To(PanicWithMessageTo(
+				HavePrefix("Unexpected call *pegomock_test.MockDisplay.Show(\"Hello\") on a strict mock"),
+			))
+			Expect(ReportPendingFailures).NotTo(Panic())
+		})
+	})
+
+	Describe("Concurrent invocations", func() {
+		It("records every invocation from many goroutines", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						display.Flash("Hello", i*100+j)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			display.VerifyWasCalled(Times(5000)).Flash(AnyString(), AnyInt())
+			display.VerifyWasCalledOnce().Flash("Hello", 4999)
+		})
+
+		It("answers with stubbings added while other goroutines invoke the mock", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						if result := display.MultipleParamsAndReturnValue("Hello", j); result != "" && result != strconv.Itoa(j) {
+							panic(fmt.Sprintf("Unexpected result %v for %v", result, j))
+						}
+					}
+				}(i)
+			}
+			for j := 0; j < 100; j++ {
+				When(display.MultipleParamsAndReturnValue(EqString("Hello"), EqInt(j))).ThenReturn(strconv.Itoa(j))
+			}
+			wg.Wait()
+
+			Expect(display.MultipleParamsAndReturnValue("Hello", 99)).To(Equal("99"))
+		})
+
+		It("hands out each consecutive return value once", func() {
+			stubbing := When(display.SomeValue())
+			for i := 0; i < 100; i++ {
+				stubbing = stubbing.ThenReturn(strconv.Itoa(i))
+			}
+
+			results := make(chan string, 100)
+			var wg sync.WaitGroup
+			for i := 0; i < 100; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					results <- display.SomeValue()
+				}()
+			}
+			wg.Wait()
+			close(results)
+
+			seen := make(map[string]bool)
+			for result := range results {
+				seen[result] = true
+			}
+			Expect(seen).To(HaveLen(100))
+		})
+
+		It("stubs the invocation of the test's goroutine while other goroutines invoke the same method", func() {
+			stop := make(chan bool)
+			done := make(chan bool)
+			display.MultipleParamsAndReturnValue("other", 0)
+			go func() {
+				defer close(done)
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						display.MultipleParamsAndReturnValue("other", 0)
+					}
+				}
+			}()
+			for i := 0; i < 100; i++ {
+				When(display.MultipleParamsAndReturnValue("mine", i)).ThenReturn("stubbed")
+			}
+			close(stop)
+			<-done
+
+			display.VerifyWasCalled(Never()).MultipleParamsAndReturnValue(EqString("mine"), AnyInt())
+			display.VerifyWasCalled(AtLeast(1)).MultipleParamsAndReturnValue("other", 0)
+			Expect(display.MultipleParamsAndReturnValue("mine", 1)).To(Equal("stubbed"))
+		})
+	})
+
+	Describe("OnInvocation", func() {
+		It("calls the listeners with every invocation as it happens", func() {
+			var calls, moreCalls []string
+			display := NewMockDisplay(OnInvocation(func(methodName string, params []Param) {
+				calls = append(calls, fmt.Sprintf("%v%v", methodName, params))
+			}))
+			OnInvocation(func(methodName string, params []Param) { moreCalls = append(moreCalls, methodName) })(display)
+
+			When(display.SomeValue()).Then(func([]Param) ReturnValues {
+				Expect(calls).To(Equal([]string{"SomeValue[]", "Flash[Hello 1]", "SomeValue[]"}))
+				return ReturnValues{"value"}
+			})
+			display.Flash("Hello", 1)
+			display.SomeValue()
+
+			Expect(calls).To(Equal([]string{"SomeValue[]", "Flash[Hello 1]", "SomeValue[]"}))
+			Expect(moreCalls).To(Equal([]string{"SomeValue", "Flash", "SomeValue"}))
+		})
+
+		It("keeps the listeners when the mock is reset", func() {
+			count := 0
+			display := NewMockDisplay(OnInvocation(func(string, []Param) { count++ }))
+			display.Show("Hello")
+			Reset(display)
+			display.Show("Hello")
+
+			Expect(count).To(Equal(2))
+		})
+	})
+
+	Describe("WithFailHandler", func() {
+		var (
+			failures       []string
+			failingDisplay *MockDisplay
+		)
+
+		BeforeEach(func() {
+			failures = nil
+			failingDisplay = NewMockDisplay(WithFailHandler(func(message string, callerSkip ...int) {
+				failures = append(failures, message)
+			}))
+		})
+
+		It("reports failed verifications to the mock's fail handler instead of the global one", func() {
+			failingDisplay.VerifyWasCalledOnce().Show("Hello")
+
+			Expect(failures).To(ConsistOf(HavePrefix("Mock invocation count for Show(\"Hello\") does not match expectation.")))
+		})
+
+		It("reports unexpected calls of strict mocks to the mock's fail handler", func() {
+			Strict(failingDisplay)
+			failingDisplay.Show("Hello")
+			display.Show("Hello")
+
+			Expect(failures).To(ConsistOf(HavePrefix("Unexpected call *pegomock_test.MockDisplay.Show(\"Hello\") on a strict mock")))
+		})
+
+		It("does not affect other mocks", func() {
+			Expect(func() { display.VerifyWasCalledOnce().Show("Hello") }).To(Panic())
+			Expect(failures).To(BeEmpty())
+		})
+	})
+
+	Describe("VerifyZeroInteractions", func() {
+		It("succeeds when the mocks were never invoked", func() {
+			When(display.SomeValue()).ThenReturn("stubbing is no interaction")
+
+			VerifyZeroInteractions(display, NewMockDisplay())
+		})
+
+		It("fails listing all interactions of all mocks", func() {
+			otherDisplay := NewMockDisplay()
+			display.Show("one")
+			otherDisplay.Flash("two", 2)
+			display.Flash("three", 3)
+
+			Expect(func() { VerifyZeroInteractions(display, otherDisplay) }).To(PanicWith(
+				"Expected no interactions with mocks, but there were:\n" +
+					"\t*pegomock_test.MockDisplay:\n" +
+					"\t\tShow(\"one\")\n" +
+					"\t\tFlash(\"three\", 3)\n" +
+					"\t*pegomock_test.MockDisplay:\n" +
+					"\t\tFlash(\"two\", 2)\n",
+			))
+		})
+	})
+
+	Describe("VerifyNoMoreInteractions", func() {
+		It("succeeds when all invocations were verified", func() {
+			display.Show("one")
+			display.Flash("two", 2)
+			display.VerifyWasCalledOnce().Show("one")
+			display.VerifyWasCalledOnce().Flash(AnyString(), AnyInt())
+
+			VerifyNoMoreInteractions(display)
+		})
+
+		It("fails listing the unverified invocations", func() {
+			display.Show("one")
+			display.Flash("two", 2)
+			display.Show("three")
+			display.VerifyWasCalledOnce().Show("one")
+
+			Expect(func() { VerifyNoMoreInteractions(display) }).To(PanicWith(
+				"Expected no more interactions with mocks, but there were:\n" +
+					"\t*pegomock_test.MockDisplay:\n" +
+					"\t\tFlash(\"two\", 2)\n" +
+					"\t\tShow(\"three\")\n",
+			))
+		})
+
+		It("forgets verified invocations when the mock is reset", func() {
+			display.Show("one")
+			display.VerifyWasCalledOnce().Show("one")
+
+			Reset(display)
+			display.Show("one")
+
+			Expect(func() { VerifyNoMoreInteractions(display) }).To(Panic())
+		})
+	})
+
+	Describe("VerifyNoInteractionsDuring", func() {
+		var otherDisplay *MockDisplay
+
+		BeforeEach(func() {
+			otherDisplay = NewMockDisplay()
+		})
+
+		It("succeeds when mocks are only used before and after the region", func() {
+			display.Show("before")
+
+			VerifyNoInteractionsDuring(func() { otherDisplay.Show("unrelated") }, display)
+
+			display.Show("after")
+			display.VerifyWasCalled(Times(2)).Show(AnyString())
+		})
+
+		It("fails listing all interactions during the region", func() {
+			display.Show("before")
+
+			Expect(func() {
+				VerifyNoInteractionsDuring(func() {
+					display.Show("during")
+					otherDisplay.Flash("also during", 1)
+					display.Flash("during", 2)
+				}, display, otherDisplay)
+			}).To(PanicWith(
+				"Expected no interactions with mocks during region, but there were:\n" +
+					"\t*pegomock_test.MockDisplay:\n" +
+					"\t\tShow(\"during\")\n" +
+					"\t\tFlash(\"during\", 2)\n" +
+					"\t*pegomock_test.MockDisplay:\n" +
+					"\t\tFlash(\"also during\", 1)\n",
+			))
+		})
+
+		It("counts interactions from other goroutines", func() {
+			Expect(func() {
+				VerifyNoInteractionsDuring(func() {
+					var wg sync.WaitGroup
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						display.Show("from goroutine")
+					}()
+					wg.Wait()
+				}, display)
+			}).To(PanicWithMessageTo(ContainSubstring("Show(\"from goroutine\")")))
+		})
+
+		It("propagates a panic of the region", func() {
+			Expect(func() {
+				VerifyNoInteractionsDuring(func() { panic("region failed") }, display)
+			}).To(PanicWith("region failed"))
+		})
+
+		It("still checks for interactions when the region panics", func() {
+			Expect(func() {
+				VerifyNoInteractionsDuring(func() {
+					display.Show("during")
+					panic("region failed")
+				}, display)
+			}).To(PanicWith(
+				"Expected no interactions with mocks during region (region panicked with: region failed), but there were:\n" +
+					"\t*pegomock_test.MockDisplay:\n" +
+					"\t\tShow(\"during\")\n",
+			))
+		})
+	})
+
+	Describe("Methods without params and return values", func() {
+		It("counts invocations for verification", func() {
+			display.Clear()
+			display.Clear()
+
+			display.VerifyWasCalled(Times(2)).Clear()
+			Expect(func() { display.VerifyWasCalledOnce().Clear() }).To(Panic())
+		})
+
+		It("allocates at most once per invocation", func() {
+			Expect(testing.AllocsPerRun(100, func() { display.Clear() })).To(BeNumerically("<=", 1))
+		})
+	})
+
+	Describe("Generated builders", func() {
+		It("stubs return values for all invocations", func() {
+			display := NewMockDisplayBuilder().
+				ReturningOnSomeValue("some value").
+				ReturningOnMultipleValues("a", 1, 2.5).
+				Build()
+
+			Expect(display.SomeValue()).To(Equal("some value"))
+			s, i, f := display.MultipleValues()
+			Expect(s).To(Equal("a"))
+			Expect(i).To(Equal(1))
+			Expect(f).To(Equal(float32(2.5)))
+		})
+
+		It("stubs answers with typed arguments", func() {
+			var variadicArgs []string
+			display := NewMockDisplayBuilder().
+				WithMultipleParamsAndReturnValue(func(s string, i int) string { return fmt.Sprintf("%v-%v", s, i) }).
+				WithNormalAndVariadicParam(func(s string, i int, v ...string) { variadicArgs = v }).
+				Build()
+
+			Expect(display.MultipleParamsAndReturnValue("a", 1)).To(Equal("a-1"))
+			Expect(display.MultipleParamsAndReturnValue("b", 2)).To(Equal("b-2"))
+			display.NormalAndVariadicParam("a", 1, "x", "y")
+			Expect(variadicArgs).To(Equal([]string{"x", "y"}))
+		})
+
+		It("gives precedence to stubbings made after building", func() {
+			display := NewMockDisplayBuilder().ReturningOnSomeValue("from builder").Build()
+
+			When(display.SomeValue()).ThenReturn("from When")
+
+			Expect(display.SomeValue()).To(Equal("from When"))
+		})
+
+		It("builds independent mocks with the same stubbings", func() {
+			builder := NewMockDisplayBuilder().ReturningOnSomeValue("some value")
+			display1, display2 := builder.Build(), builder.Build()
+
+			Expect(display1.SomeValue()).To(Equal("some value"))
+			Expect(display2.SomeValue()).To(Equal("some value"))
+			display1.VerifyWasCalledOnce().SomeValue()
+			display2.VerifyWasCalledOnce().SomeValue()
+		})
+	})
+
+	Describe("VerifyWasCalledEventually", func() {
+		It("waits for invocations from other goroutines", func() {
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				display.Show("Hello")
+			}()
+
+			display.VerifyWasCalledEventually(Once(), time.Second).Show("Hello")
+		})
+
+		It("fails once the timeout has passed", func() {
+			start := time.Now()
+			Expect(func() { display.VerifyWasCalledEventually(Once(), 50*time.Millisecond).Show("Hello") }).To(PanicWithMessageTo(SatisfyAll(
+				HavePrefix(expectation{method: "Show(\"Hello\")", expected: "1", actual: "0"}.string()),
+				ContainSubstring("(waited 50ms)"),
+			)))
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		It("works with argument matchers", func() {
+			go display.Show("Hello")
+
+			display.VerifyWasCalled(Within(AtLeast(1), time.Second)).Show(AnyString())
+		})
+	})
+
+	Describe("Reset", func() {
+		It("forgets all stubbings", func() {
+			When(display.SomeValue()).ThenReturn("Hello")
+
+			Reset(display)
+
+			Expect(display.SomeValue()).To(Equal(""))
+		})
+
+		It("forgets all recorded invocations", func() {
+			display.Show("before reset")
+
+			Reset(display)
+
+			display.VerifyWasCalled(Never()).Show("before reset")
+			display.Show("after reset")
+			display.VerifyWasCalledOnce().Show("after reset")
+		})
+
+		It("keeps the default answer", func() {
+			FailAllCallsWith(display, errors.New("injected"))
+
+			GetGenericMockFrom(display).Reset()
+
+			Expect(display.ErrorReturnValue()).To(MatchError("injected"))
+		})
+	})
+
+	Describe("Registry", func() {
+		var registry *Registry
+
+		BeforeEach(func() {
+			registry = NewRegistry()
+		})
+
+		It("creates a mock on first use and returns the same mock later", func() {
+			mock := GetMock[test_interface.Display](registry)
+
+			Expect(mock).To(BeAssignableToTypeOf(&MockDisplay{}))
+			Expect(GetMock[test_interface.Display](registry)).To(BeIdenticalTo(mock))
+			Expect(registry.Mocks()).To(Equal([]Mock{mock}))
+		})
+
+		It("creates mocks with its options", func() {
+			var failures []string
+			registry = NewRegistry(WithFailHandler(func(message string, callerSkip ...int) { failures = append(failures, message) }))
+
+			GetMock[test_interface.Display](registry).(*MockDisplay).VerifyWasCalledOnce().Show("Hello")
+
+			Expect(failures).To(HaveLen(1))
+		})
+
+		It("returns stored mocks", func() {
+			PutMock[test_interface.Display](registry, display)
+
+			Expect(GetMock[test_interface.Display](registry)).To(BeIdenticalTo(display))
+		})
+
+		It("resets all mocks", func() {
+			PutMock[test_interface.Display](registry, display)
+			PutMock[interface{ Show(string) }](registry, NewMockDisplay())
+			When(display.SomeValue()).ThenReturn("Hello")
+
+			registry.Reset()
+
+			Expect(display.SomeValue()).To(Equal(""))
+		})
+
+		It("verifies no more interactions across all mocks", func() {
+			otherDisplay := NewMockDisplay()
+			PutMock[test_interface.Display](registry, display)
+			PutMock[interface{ Show(string) }](registry, otherDisplay)
+			display.Show("one")
+			otherDisplay.Show("two")
+			display.VerifyWasCalledOnce().Show("one")
+
+			Expect(func() { registry.VerifyNoMoreInteractions() }).To(PanicWith(
+				"Expected no more interactions with mocks, but there were:\n" +
+					"\t*pegomock_test.MockDisplay:\n" +
+					"\t\tShow(\"two\")\n",
+			))
+		})
+
+		It("panics for types that are not interfaces with methods", func() {
+			Expect(func() { GetMock[*MockDisplay](registry) }).To(PanicWith(
+				"Registry can only hold mocks of interfaces with methods, but got *pegomock_test.MockDisplay"))
+		})
+
+		It("panics for interfaces without registered mock", func() {
+			Expect(func() { GetMock[http.RoundTripper](registry) }).To(PanicWith("No mock registered for http.RoundTripper"))
+		})
+	})
+
+	Describe("ResetGoroutineState", func() {
+		It("discards matchers that were not used by a stubbing or verification", func() {
+			AnyString()
+
+			ResetGoroutineState()
+
+			When(display.SomeValue()).ThenReturn("Hello")
+			Expect(display.SomeValue()).To(Equal("Hello"))
+		})
+	})
+
+	Describe("InvocationParamsOf", func() {
+		It("returns the params of all invocations of a method in order", func() {
+			display.Flash("Hello", 1)
+			display.Show("Hello")
+			display.Flash("World", 2)
+
+			Expect(InvocationParamsOf(display, "Flash")).To(Equal([][]Param{{"Hello", 1}, {"World", 2}}))
+			Expect(InvocationParamsOf(display, "Clear")).To(BeEmpty())
+		})
+	})
+
+	Describe("FailAllCallsWith", func() {
+		BeforeEach(func() {
+			FailAllCallsWith(display, errors.New("injected"))
+		})
+
+		It("returns the error from all methods returning an error", func() {
+			Expect(display.ErrorReturnValue()).To(MatchError("injected"))
+		})
+
+		It("returns zero values for all other return values", func() {
+			s, i, e := display.MultipleValuesAndError()
+			Expect(s).To(Equal(""))
+			Expect(i).To(Equal(0))
+			Expect(e).To(MatchError("injected"))
+			Expect(display.SomeValue()).To(Equal(""))
+		})
+
+		It("gives precedence to explicit stubbings", func() {
+			When(display.ErrorReturnValue()).ThenReturn(nil)
+			Expect(display.ErrorReturnValue()).To(BeNil())
+		})
+
+		It("replaces the previously injected error when called again", func() {
+			FailAllCallsWith(display, errors.New("injected again"))
+			Expect(display.ErrorReturnValue()).To(MatchError("injected again"))
+		})
+
+		It("records the failure injection in the interaction dump", func() {
+			display.ErrorReturnValue()
+			Expect(SDumpInvocationsFor(display)).To(ContainSubstring("Answered by: FailAllCallsWith(injected)"))
+		})
+
+		It("can be verified as usual", func() {
+			display.ErrorReturnValue()
+			display.VerifyWasCalledOnce().ErrorReturnValue()
+		})
+	})
+
+	Describe("Strict", func() {
+		BeforeEach(func() {
+			Strict(display)
+		})
+
+		It("answers stubbed invocations as usual", func() {
+			When(display.SomeValue()).ThenReturn("Hello")
+			When(func() { display.Show(AnyString()) }).ThenReturn()
+
+			Expect(display.SomeValue()).To(Equal("Hello"))
+			display.Show("anything")
+			display.VerifyWasCalledOnce().Show("anything")
+		})
+
+		It("fails on the next interaction after an invocation without matching stubbing", func() {
+			When(display.MultipleParamsAndReturnValue("a", 1)).ThenReturn("stubbed")
+
+			Expect(display.MultipleParamsAndReturnValue("b", 2)).To(Equal(""))
+			Expect(func() { display.VerifyWasCalledOnce().MultipleParamsAndReturnValue("b", 2) }).To(PanicWith(
+				"Unexpected call *pegomock_test.MockDisplay.MultipleParamsAndReturnValue(\"b\", 2) on a strict mock: there is no matching stubbing",
+			))
+		})
+
+		It("makes all mocks strict with StrictMocks", func() {
+			StrictMocks = true
+			defer func() { StrictMocks = false }()
+			otherDisplay := NewMockDisplay()
+
+			otherDisplay.Show("unexpected")
+			Expect(func() { display.SomeValue() }).To(PanicWith(
+				"Unexpected call *pegomock_test.MockDisplay.Show(\"unexpected\") on a strict mock: there is no matching stubbing",
+			))
+		})
+	})
+
+	Describe("ReturnEmptyValues", func() {
+		BeforeEach(func() {
+			ReturnEmptyValues(display)
+		})
+
+		It("returns empty slices and maps and pointers to zero values", func() {
+			returnValues := GetGenericMockFrom(display).Invoke("Values", nil, []reflect.Type{
+				reflect.TypeOf([]string(nil)),
+				reflect.TypeOf(map[string]int(nil)),
+				reflect.TypeOf((*http.Request)(nil)),
+			})
+
+			Expect(returnValues).To(HaveLen(3))
+			Expect(returnValues[0]).To(SatisfyAll(Equal([]string{}), Not(BeNil())))
+			Expect(returnValues[1]).To(SatisfyAll(Equal(map[string]int{}), Not(BeNil())))
+			Expect(returnValues[2]).To(Equal(&http.Request{}))
+		})
+
+		It("returns zero values for all other types, including nil errors", func() {
+			Expect(display.SomeValue()).To(Equal(""))
+			Expect(display.InterfaceReturnValue()).To(BeNil())
+			Expect(display.ErrorReturnValue()).NotTo(HaveOccurred())
+		})
+
+		It("gives precedence to explicit stubbings", func() {
+			When(display.SomeValue()).ThenReturn("Hello")
+			Expect(display.SomeValue()).To(Equal("Hello"))
+		})
+	})
+
+	Describe("ReturnSelf", func() {
+		BeforeEach(func() {
+			ReturnSelf(display)
+		})
+
+		It("returns the mock itself from methods returning the mocked interface", func() {
+			Expect(display.WithPrefix("a")).To(BeIdenticalTo(display))
+		})
+
+		It("keeps chains of calls alive", func() {
+			display.WithPrefix("a").WithPrefix("b").Show("c")
+
+			display.VerifyWasCalled(Times(2)).WithPrefix(AnyString())
+			display.VerifyWasCalledOnce().Show("c")
+		})
+
+		It("returns zero values for all other return values", func() {
+			Expect(display.SomeValue()).To(Equal(""))
+			Expect(display.ErrorReturnValue()).To(BeNil())
+			Expect(display.InterfaceReturnValue()).To(BeNil())
+		})
+
+		It("gives precedence to explicit stubbings", func() {
+			otherDisplay := NewMockDisplay()
+			When(display.WithPrefix("a")).ThenReturn(otherDisplay)
+
+			Expect(display.WithPrefix("a")).To(BeIdenticalTo(otherDisplay))
+			Expect(display.WithPrefix("b")).To(BeIdenticalTo(display))
+		})
+	})
+
+	Describe("ReturnDeepStubs", func() {
+		BeforeEach(func() {
+			ReturnDeepStubs(display)
+		})
+
+		It("returns new mocks from methods returning mocked interfaces", func() {
+			prefixed := display.WithPrefix("a")
+
+			Expect(prefixed).To(BeAssignableToTypeOf(&MockDisplay{}))
+			Expect(prefixed).NotTo(BeIdenticalTo(display))
+		})
+
+		It("returns the same mock for the same method and arguments", func() {
+			Expect(display.WithPrefix("a")).To(BeIdenticalTo(display.WithPrefix("a")))
+			Expect(display.WithPrefix("a")).NotTo(BeIdenticalTo(display.WithPrefix("b")))
+		})
+
+		It("allows stubbing call chains", func() {
+			When(display.WithPrefix("a").WithPrefix("b").SomeValue()).ThenReturn("deep")
+
+			Expect(display.WithPrefix("a").WithPrefix("b").SomeValue()).To(Equal("deep"))
+		})
+
+		It("returns zero values for all other return values", func() {
+			Expect(display.SomeValue()).To(Equal(""))
+			Expect(display.ErrorReturnValue()).To(BeNil())
+			Expect(display.InterfaceReturnValue()).To(BeNil())
+		})
+
+		It("gives precedence to explicit stubbings", func() {
+			When(display.WithPrefix("a")).ThenReturn(display)
+
+			Expect(display.WithPrefix("a")).To(BeIdenticalTo(display))
+		})
+	})
+
+	Describe("SeedDefaultAnswers", func() {
+		valuesOf := func(display *MockDisplay) (values []interface{}) {
+			for i := 0; i < 5; i++ {
+				s, n, f := display.MultipleValues()
+				values = append(values, display.SomeValue(), s, n, f)
+			}
+			return
+		}
+
+		It("produces the same values for the same seed", func() {
+			otherDisplay := NewMockDisplay()
+			SeedDefaultAnswers(display, 42)
+			SeedDefaultAnswers(otherDisplay, 42)
+
+			Expect(valuesOf(display)).To(Equal(valuesOf(otherDisplay)))
+		})
+
+		It("produces varied values that differ for different seeds", func() {
+			otherDisplay := NewMockDisplay()
+			SeedDefaultAnswers(display, 42)
+			SeedDefaultAnswers(otherDisplay, 43)
+
+			values := valuesOf(display)
+			Expect(values).NotTo(Equal(valuesOf(otherDisplay)))
+			Expect(values[0]).NotTo(Equal(values[4]))
+		})
+
+		It("alternates between nil and non-nil errors", func() {
+			SeedDefaultAnswers(display, 42)
+
+			Expect(display.ErrorReturnValue()).To(BeNil())
+			Expect(display.ErrorReturnValue()).To(HaveOccurred())
+			_, _, e := display.MultipleValuesAndError()
+			Expect(e).To(BeNil())
+			_, _, e = display.MultipleValuesAndError()
+			Expect(e).To(HaveOccurred())
+		})
+
+		It("returns zero values for types it cannot fabricate and notes them in the interactions", func() {
+			SeedDefaultAnswers(display, 42)
+
+			Expect(display.FuncReturnValue()).To(BeNil())
+			Expect(display.WithPrefix("a")).To(BeNil())
+			Expect(SDumpInvocationsFor(display)).To(SatisfyAll(
+				ContainSubstring("Answered by: SeedDefaultAnswers(42), zero value for func()\n"),
+				ContainSubstring("Answered by: SeedDefaultAnswers(42), zero value for test_interface.Display\n"),
+			))
+		})
+
+		It("gives precedence to explicit stubbings", func() {
+			SeedDefaultAnswers(display, 42)
+			When(display.SomeValue()).ThenReturn("stubbed")
+
+			Expect(display.SomeValue()).To(Equal("stubbed"))
+		})
+	})
+
+})
+
+type customError struct{ message string }
+
+func (e *customError) Error() string { return e.message }
+
+type recordingT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Cleanup(cleanup func()) { t.cleanups = append(t.cleanups, cleanup) }
+
+func (t *recordingT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
 
 func flattenStringSliceOfSlices(sliceOfSlices [][]string) (result []string) {
 	for _, slice := range sliceOfSlices {
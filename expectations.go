@@ -0,0 +1,182 @@
+package pegomock
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type cleanupT interface {
+	testingT
+	Cleanup(func())
+}
+
+// Expectations are verifications declared up front, before the code under test runs, and
+// verified when the test finishes:
+//
+//	exp := ExpectationsFor(t, fetcher, cache)
+//	exp.Expect(fetcher.VerifyWasCalledOnce().Fetch(AnyString()))
+//	exp.Expect(cache.VerifyWasCalled(Never()).Evict(AnyString()))
+//	exp.ExpectNoMoreInteractions()
+//	...
+//
+// As long as none of the mocks has been called, verifications of them are recorded instead of
+// performed. Stubbing them with When does not count as a call. All unmet expectations are
+// reported to t at once. Failures go to t and not to the GlobalFailHandler, so parallel
+// subtests can each use their own Expectations.
+type Expectations struct {
+	mutex              sync.Mutex
+	t                  cleanupT
+	mocks              []Mock
+	expectations       []*expectation
+	numClaimed         int
+	declaring          bool
+	noMoreInteractions bool
+}
+
+type expectation struct {
+	genericMock            *GenericMock
+	inOrderContext         *InOrderContext
+	invocationCountMatcher Matcher
+	methodName             string
+	params                 []Param
+	matchers               Matchers
+}
+
+// ExpectationsFor puts mocks into expectations mode and verifies the expectations declared
+// for them when t finishes. A mock can only be part of one Expectations at a time.
+func ExpectationsFor(t cleanupT, mocks ...Mock) *Expectations {
+	exp := &Expectations{t: t, declaring: true}
+	for _, mock := range mocks {
+		genericMock := GetGenericMockFrom(mock)
+		genericMock.Lock()
+		genericMock.expectations = exp
+		genericMock.Unlock()
+	}
+	exp.mocks = mocks
+	t.Cleanup(exp.verify)
+	return exp
+}
+
+// Expect declares verification, which must be a verification of one of the mocks passed to
+// ExpectationsFor, as an expectation. Verifying such a mock records the verification, and
+// Expect checks that verification is the one recorded last.
+func (exp *Expectations) Expect(verification interface{}) {
+	exp.mutex.Lock()
+	defer exp.mutex.Unlock()
+	if exp.numClaimed == len(exp.expectations) ||
+		!exp.isOngoingVerificationOf(verification, exp.expectations[len(exp.expectations)-1].genericMock) {
+		exp.t.Errorf("Expect must be called with a verification of one of the mocks passed to ExpectationsFor, " +
+			"made right before it and before any of these mocks is called")
+	}
+	exp.numClaimed = len(exp.expectations)
+}
+
+// isOngoingVerificationOf reports whether verification is what the verifiers of generated mocks
+// return for a verification of genericMock, a pointer to a struct whose field mock refers to the
+// mock of genericMock.
+func (exp *Expectations) isOngoingVerificationOf(verification interface{}, genericMock *GenericMock) bool {
+	value := reflect.ValueOf(verification)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	mockField := value.Elem().FieldByName("mock")
+	if mockField.Kind() != reflect.Ptr {
+		return false
+	}
+	for _, mock := range exp.mocks {
+		mockValue := reflect.ValueOf(mock)
+		if mockValue.Kind() == reflect.Ptr && mockValue.Pointer() == mockField.Pointer() && GetGenericMockFrom(mock) == genericMock {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectNoMoreInteractions additionally expects that the mocks have no invocations other
+// than the ones matched by the expectations.
+func (exp *Expectations) ExpectNoMoreInteractions() {
+	exp.mutex.Lock()
+	defer exp.mutex.Unlock()
+	exp.noMoreInteractions = true
+}
+
+func (genericMock *GenericMock) recordedAsExpectation(
+	inOrderContext *InOrderContext,
+	invocationCountMatcher Matcher,
+	methodName string,
+	params []Param,
+	matchers Matchers) bool {
+	genericMock.Lock()
+	exp := genericMock.expectations
+	genericMock.Unlock()
+	if exp == nil {
+		return false
+	}
+	exp.mutex.Lock()
+	defer exp.mutex.Unlock()
+	if exp.declaring && exp.anyMockCalled() {
+		exp.declaring = false
+	}
+	if !exp.declaring {
+		return false
+	}
+	exp.expectations = append(exp.expectations, &expectation{
+		genericMock:            genericMock,
+		inOrderContext:         inOrderContext,
+		invocationCountMatcher: invocationCountMatcher,
+		methodName:             methodName,
+		params:                 params,
+		matchers:               append(Matchers(nil), matchers...),
+	})
+	return true
+}
+
+func (exp *Expectations) anyMockCalled() bool {
+	for _, mock := range exp.mocks {
+		if len(GetGenericMockFrom(mock).numberedInvocationsSince(0)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (exp *Expectations) verify() {
+	exp.mutex.Lock()
+	defer exp.mutex.Unlock()
+	for _, mock := range exp.mocks {
+		genericMock := GetGenericMockFrom(mock)
+		genericMock.Lock()
+		if genericMock.expectations == exp {
+			genericMock.expectations = nil
+		}
+		genericMock.Unlock()
+	}
+
+	var failures []string
+	verifiedInvocations := make(map[int]bool)
+	for _, e := range exp.expectations {
+		methodInvocations := e.genericMock.verify(func(message string, callerSkip ...int) {
+			failures = append(failures, message)
+		}, e.inOrderContext, e.invocationCountMatcher, e.methodName, e.params, e.matchers)
+		for _, methodInvocation := range methodInvocations {
+			verifiedInvocations[methodInvocation.orderingInvocationNumber] = true
+		}
+	}
+	if exp.noMoreInteractions {
+		unverified := unverifiedInteractionsOf(exp.mocks, func(_ *GenericMock, orderingInvocationNumber int) bool {
+			return verifiedInvocations[orderingInvocationNumber]
+		})
+		if unverified != "" {
+			failures = append(failures, "Expected no more interactions with mocks, but there were:\n"+unverified)
+		}
+	}
+	if len(failures) == 0 {
+		return
+	}
+	for i := range failures {
+		failures[i] = fmt.Sprintf("%v) %v", i+1, failures[i])
+	}
+	exp.t.Errorf("Unmet expectations:\n\n%v", strings.Join(failures, "\n\n"))
+}
@@ -0,0 +1,39 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pegomock_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/petergtz/pegomock"
+)
+
+func TestExpectationsInParallelSubtests(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		i := i
+		t.Run(fmt.Sprint("subtest ", i), func(t *testing.T) {
+			t.Parallel()
+			display := NewMockDisplay()
+			exp := pegomock.ExpectationsFor(t, display)
+			exp.Expect(display.VerifyWasCalled(pegomock.Times(i)).Show(fmt.Sprint(i)))
+			exp.ExpectNoMoreInteractions()
+
+			for n := 0; n < i; n++ {
+				display.Show(fmt.Sprint(i))
+			}
+		})
+	}
+}
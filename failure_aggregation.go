@@ -0,0 +1,60 @@
+package pegomock
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// AggregateFailures determines whether RegisterMockTestingT collapses identical consecutive
+// failures into a single one, e.g. when the code under test makes the same wrong call in a
+// loop. Set it to false to get every failure reported right away.
+var AggregateFailures = true
+
+// BuildAggregatingTestingTFailHandler is like BuildTestingTGomegaFailHandler, but reports a
+// failure only once the next differing failure occurs or flush is called. Identical consecutive
+// failures are reported once, with the stack trace of the first one and the number of
+// occurrences, e.g. "(x50)".
+func BuildAggregatingTestingTFailHandler(t testingT) (handler FailHandler, flush func()) {
+	var (
+		mutex             sync.Mutex
+		pendingMessage    string
+		pendingStackTrace string
+		occurrences       int
+	)
+	flushPending := func() {
+		if h, ok := t.(helper); ok {
+			h.Helper()
+		}
+		switch {
+		case occurrences == 1:
+			t.Errorf("\n%s\n%s", pendingStackTrace, pendingMessage)
+		case occurrences > 1:
+			t.Errorf("\n%s\n%s\n\n(x%v)", pendingStackTrace, pendingMessage, occurrences)
+		}
+		occurrences = 0
+	}
+	handler = func(message string, callerSkip ...int) {
+		if h, ok := t.(helper); ok {
+			h.Helper()
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if occurrences > 0 && message == pendingMessage {
+			occurrences++
+			return
+		}
+		flushPending()
+		skip := 1
+		if len(callerSkip) > 0 {
+			skip = callerSkip[0]
+		}
+		pendingMessage, pendingStackTrace, occurrences = message, pruneStack(string(debug.Stack()), skip), 1
+	}
+	flush = func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		flushPending()
+	}
+	return
+}
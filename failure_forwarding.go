@@ -0,0 +1,100 @@
+package pegomock
+
+import "sync"
+
+// Failures that occurred on other goroutines than the one their fail handler was registered on.
+// Fail handlers like Ginkgo's Fail or testing.T's FailNow must only be called on the goroutine
+// of the test, so these failures are reported there later.
+var (
+	forwardedFailures      []forwardedFailure
+	forwardedFailuresMutex sync.Mutex
+)
+
+type forwardedFailure struct {
+	handler     FailHandler
+	goroutineID int64
+	message     string
+}
+
+// forwardingFailHandler returns a fail handler that calls handler right away on the current
+// goroutine, and forwards failures on other goroutines to be reported on the current one by
+// reportForwardedFailures.
+func forwardingFailHandler(handler FailHandler) FailHandler {
+	if handler == nil {
+		return nil
+	}
+	goroutineID := currentGoroutineID()
+	return func(message string, callerSkip ...int) {
+		if currentGoroutineID() != goroutineID {
+			forwardedFailuresMutex.Lock()
+			defer forwardedFailuresMutex.Unlock()
+			forwardedFailures = append(forwardedFailures, forwardedFailure{handler, goroutineID,
+				message + "\n\n(This failure occurred on another goroutine and is reported on the test's goroutine.)"})
+			return
+		}
+		reportForwardedFailures()
+		skip := 1
+		if len(callerSkip) > 0 {
+			skip = callerSkip[0]
+		}
+		handler(message, skip+1)
+	}
+}
+
+// reportForwardedFailures reports all failures forwarded to the current goroutine. It's called
+// whenever mocks are used, when verifying, and when a test registered with RegisterMockTestingT
+// finishes.
+func reportForwardedFailures() {
+	forwardedFailuresMutex.Lock()
+	if len(forwardedFailures) == 0 {
+		forwardedFailuresMutex.Unlock()
+		return
+	}
+	goroutineID := currentGoroutineID()
+	var failures, remaining []forwardedFailure
+	for _, failure := range forwardedFailures {
+		if failure.goroutineID == goroutineID {
+			failures = append(failures, failure)
+		} else {
+			remaining = append(remaining, failure)
+		}
+	}
+	forwardedFailures = remaining
+	forwardedFailuresMutex.Unlock()
+
+	for _, failure := range failures {
+		failure.handler(failure.message)
+	}
+}
+
+// reportPendingFailures reports all failures that could not be reported right away.
+func reportPendingFailures() {
+	reportForwardedFailures()
+	reportPendingStrictFailure(currentGoroutineID())
+}
+
+// ReportPendingFailures reports all failures of the test running on the current goroutine that
+// could not be reported right away, like RegisterMockTestingT does when a test finishes. It's
+// meant for test frameworks without testing.T's Cleanup, e.g. to be called in Ginkgo's AfterEach.
+func ReportPendingFailures() {
+	reportPendingFailuresOnCleanup(currentGoroutineID())
+}
+
+// reportPendingFailuresOnCleanup reports all failures that could not be reported right away
+// when the test running on the goroutine with goroutineID finishes. This includes the pending
+// failures of strict mocks created by the test but invoked on other goroutines.
+func reportPendingFailuresOnCleanup(goroutineID int64) {
+	reportForwardedFailures()
+	goroutineStatesMutex.Lock()
+	var goroutineIDs []int64
+	for id, state := range goroutineStates {
+		if id == goroutineID || (state.pendingStrictFailureMock != nil && state.pendingStrictFailureMock.creatorGoroutineID == goroutineID) {
+			goroutineIDs = append(goroutineIDs, id)
+		}
+	}
+	goroutineStatesMutex.Unlock()
+	for _, id := range goroutineIDs {
+		reportPendingStrictFailure(id)
+	}
+	reportForwardedFailures()
+}
@@ -15,18 +15,34 @@
 package mockgen_test
 
 import (
-	"os"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/petergtz/pegomock/mockgen"
 	"github.com/petergtz/pegomock/pegomock/filehandling"
 )
 
-func TestMockGeneration(t *testing.T) { RunSpecs(t, "Generating mocks with GoMock-reflect") }
+func TestMockGeneration(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Generating mocks with GoMock-reflect")
+}
 
 var _ = It("Generate mocks", func() {
-	filehandling.GenerateMockFile(
+	Expect(filehandling.GenerateMockFile(
 		[]string{"github.com/petergtz/pegomock/test_interface", "Display"},
-		"../../mock_display_test.go", "pegomock_test",
-		"", false, os.Stdout, false, true, "")
+		"../../mock_display_test.go",
+		filehandling.Options{
+			Options:          mockgen.Options{PackageOut: "pegomock_test", GenerateBuilders: true},
+			GenerateMatchers: true,
+		})).To(Succeed())
+})
+
+var _ = It("Generate mocks for the ginkgo package", func() {
+	Expect(filehandling.GenerateMockFile(
+		[]string{"github.com/petergtz/pegomock/test_interface", "Display"},
+		"../../ginkgo/mock_display_test.go",
+		filehandling.Options{
+			Options: mockgen.Options{PackageOut: "ginkgo_test"},
+		})).To(Succeed())
 })
@@ -15,18 +15,34 @@
 package mockgen_test
 
 import (
-	"os"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/petergtz/pegomock/mockgen"
 	"github.com/petergtz/pegomock/pegomock/filehandling"
 )
 
-func TestMockGeneration(t *testing.T) { RunSpecs(t, "Generating mocks with GoMock-source") }
+func TestMockGeneration(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Generating mocks with GoMock-source")
+}
 
 var _ = It("Generate mocks", func() {
-	filehandling.GenerateMockFile(
+	Expect(filehandling.GenerateMockFile(
 		[]string{"../../test_interface/display.go"},
-		"../../mock_display_test.go", "pegomock_test",
-		"", false, os.Stdout, false, true, "")
+		"../../mock_display_test.go",
+		filehandling.Options{
+			Options:          mockgen.Options{PackageOut: "pegomock_test", GenerateBuilders: true},
+			GenerateMatchers: true,
+		})).To(Succeed())
+})
+
+var _ = It("Generate mocks for the ginkgo package", func() {
+	Expect(filehandling.GenerateMockFile(
+		[]string{"../../test_interface/display.go"},
+		"../../ginkgo/mock_display_test.go",
+		filehandling.Options{
+			Options: mockgen.Options{PackageOut: "ginkgo_test"},
+		})).To(Succeed())
 })
@@ -0,0 +1,11 @@
+package pegomock
+
+// SupportsGeneratedCodeVersion1 is referred to by mocks generated with version 1 of the
+// generated code, see mockgen.GeneratedCodeVersion. Mocks that need a newer version of
+// pegomock than the one they are built with thereby fail to compile with an error naming the
+// version they need, instead of failing in obscure ways.
+const SupportsGeneratedCodeVersion1 = true
+
+// SupportsGeneratedCodeVersion2 is referred to by mocks generated with version 2 of the
+// generated code, whose verifiers capture variadic arguments with GetVariadicInvocationParams.
+const SupportsGeneratedCodeVersion2 = true
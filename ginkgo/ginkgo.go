@@ -0,0 +1,120 @@
+// Package ginkgo integrates pegomock with Ginkgo and Gomega. Instead of registering Ginkgo's Fail
+// in every suite, declare
+//
+//	var _ = pegomockginkgo.Setup()
+//
+// once per suite, and assert on invocations with Gomega:
+//
+//	pegomockginkgo.ExpectInvocation(display, "Show", "Hello")
+//	Eventually(display).Should(pegomockginkgo.HaveReceived("Show", HavePrefix("Hel")))
+package ginkgo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+	"github.com/petergtz/pegomock"
+)
+
+// Setup adds a BeforeEach and an AfterEach to the top level of the suite. The BeforeEach makes
+// mocks report their failures to Ginkgo's Fail and discards the matchers and invocations a
+// previous spec left behind when it failed halfway through a stubbing. The AfterEach reports
+// the failures that could not be reported right away, e.g. unexpected calls of strict mocks on
+// other goroutines. It returns true, so it can be called in a top-level var declaration.
+func Setup() bool {
+	ginkgo.BeforeEach(func() {
+		pegomock.RegisterMockFailHandler(ginkgo.Fail)
+		pegomock.ResetGoroutineState()
+	})
+	ginkgo.AfterEach(pegomock.ReportPendingFailures)
+	return true
+}
+
+// ExpectInvocation expects that mock received at least one invocation of methodName with args.
+// See HaveReceived.
+func ExpectInvocation(mock pegomock.Mock, methodName string, args ...interface{}) {
+	gomega.ExpectWithOffset(1, mock).To(HaveReceived(methodName, args...))
+}
+
+// HaveReceived succeeds for mocks that received at least one invocation of methodName with args.
+// Each arg can be a Gomega matcher, a pegomock Matcher or a value, which must be deeply equal to
+// the param. Without args, any invocation of methodName matches. Unlike a verification, it can
+// be used with Eventually and Consistently:
+//
+//	Eventually(display).Should(HaveReceived("Show", HavePrefix("Hel")))
+func HaveReceived(methodName string, args ...interface{}) types.GomegaMatcher {
+	return &haveReceivedMatcher{methodName: methodName, args: args}
+}
+
+type haveReceivedMatcher struct {
+	methodName string
+	args       []interface{}
+}
+
+func (matcher *haveReceivedMatcher) Match(actual interface{}) (bool, error) {
+	if actual == nil || reflect.TypeOf(actual).Kind() != reflect.Ptr {
+		return false, fmt.Errorf("HaveReceived expects a mock, but got %T", actual)
+	}
+	for _, params := range pegomock.InvocationParamsOf(actual, matcher.methodName) {
+		if matched, err := matcher.matches(params); matched || err != nil {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+func (matcher *haveReceivedMatcher) matches(params []pegomock.Param) (bool, error) {
+	if len(matcher.args) == 0 {
+		return true, nil
+	}
+	if len(params) != len(matcher.args) {
+		return false, nil
+	}
+	for i, arg := range matcher.args {
+		switch argMatcher := arg.(type) {
+		case types.GomegaMatcher:
+			if matched, err := argMatcher.Match(params[i]); !matched || err != nil {
+				return false, err
+			}
+		case pegomock.Matcher:
+			if !argMatcher.Matches(params[i]) {
+				return false, nil
+			}
+		default:
+			if !reflect.DeepEqual(arg, params[i]) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func (matcher *haveReceivedMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected mock to have received %v, but it received:\n%v", matcher, invocationsOf(actual))
+}
+
+func (matcher *haveReceivedMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected mock not to have received %v, but it received:\n%v", matcher, invocationsOf(actual))
+}
+
+func (matcher *haveReceivedMatcher) String() string {
+	args := make([]string, len(matcher.args))
+	for i, arg := range matcher.args {
+		args[i] = fmt.Sprintf("%v", arg)
+		if _, isGomegaMatcher := arg.(types.GomegaMatcher); isGomegaMatcher {
+			args[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+	return fmt.Sprintf("%v(%v)", matcher.methodName, strings.Join(args, ", "))
+}
+
+func invocationsOf(actual interface{}) string {
+	if invocations := pegomock.SDumpInvocationsFor(actual); invocations != "" {
+		return invocations
+	}
+	return "no invocations"
+}
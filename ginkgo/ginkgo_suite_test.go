@@ -0,0 +1,16 @@
+package ginkgo_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	pegomockginkgo "github.com/petergtz/pegomock/ginkgo"
+
+	"testing"
+)
+
+var _ = pegomockginkgo.Setup()
+
+func TestGinkgo(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Ginkgo Suite")
+}
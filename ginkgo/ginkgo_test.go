@@ -0,0 +1,127 @@
+package ginkgo_test
+
+import (
+	"os"
+	"os/exec"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/petergtz/pegomock"
+	pegomockginkgo "github.com/petergtz/pegomock/ginkgo"
+)
+
+// runSetupSpecsEnv makes the suite run the specs of "Suites with Setup", which include a failing
+// one. The specs of Setup run the suite with it in a separate process.
+const runSetupSpecsEnv = "PEGOMOCK_GINKGO_RUN_SETUP_SPECS"
+
+var _ = Describe("Setup", func() {
+	if os.Getenv(runSetupSpecsEnv) != "" {
+		return
+	}
+
+	var output string
+
+	BeforeEach(func() {
+		suite := exec.Command(os.Args[0], "-test.run=^TestGinkgo$")
+		suite.Env = append(os.Environ(), runSetupSpecsEnv+"=true")
+		combinedOutput, e := suite.CombinedOutput()
+		Expect(e).To(HaveOccurred())
+		output = string(combinedOutput)
+	})
+
+	It("makes mocks report their failures to Ginkgo", func() {
+		Expect(output).To(MatchRegexp(`(?s)reports failures of mocks to Ginkgo.*Show\("Hello"\) on a strict mock`))
+	})
+
+	It("discards the matchers a previous spec left behind", func() {
+		Expect(output).NotTo(ContainSubstring("Invalid use of matchers"))
+	})
+})
+
+var _ = Describe("Suites with Setup", func() {
+	if os.Getenv(runSetupSpecsEnv) == "" {
+		return
+	}
+
+	It("reports failures of mocks to Ginkgo", func() {
+		display := NewMockDisplay()
+		pegomock.Strict(display)
+
+		display.Show("Hello")
+	})
+
+	It("leaves a matcher behind", func() {
+		pegomock.AnyString()
+	})
+
+	It("starts without the matchers of the previous spec", func() {
+		display := NewMockDisplay()
+
+		pegomock.When(display.MultipleParamsAndReturnValue("Hello", 1)).ThenReturn("World")
+
+		Expect(display.MultipleParamsAndReturnValue("Hello", 1)).To(Equal("World"))
+	})
+})
+
+var _ = Describe("HaveReceived", func() {
+	var display *MockDisplay
+
+	BeforeEach(func() {
+		display = NewMockDisplay()
+	})
+
+	It("succeeds for mocks with a matching invocation", func() {
+		display.Flash("Hello", 1)
+		display.Flash("World", 2)
+
+		Expect(display).To(pegomockginkgo.HaveReceived("Flash", "World", 2))
+		Expect(display).To(pegomockginkgo.HaveReceived("Flash"))
+		Expect(display).NotTo(pegomockginkgo.HaveReceived("Flash", "World", 1))
+		Expect(display).NotTo(pegomockginkgo.HaveReceived("Show"))
+	})
+
+	It("accepts Gomega and pegomock matchers as args", func() {
+		display.Flash("Hello", 1)
+
+		Expect(display).To(pegomockginkgo.HaveReceived("Flash", HavePrefix("Hel"), BeNumerically(">", 0)))
+		Expect(display).To(pegomockginkgo.HaveReceived("Flash", &pegomock.EqMatcher{Value: "Hello"}, 1))
+		Expect(display).NotTo(pegomockginkgo.HaveReceived("Flash", HavePrefix("Wor"), BeNumerically(">", 0)))
+	})
+
+	It("works with Eventually", func() {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			display.Show("Hello")
+		}()
+
+		Eventually(display).Should(pegomockginkgo.HaveReceived("Show", "Hello"))
+	})
+
+	It("lists the invocations of the mock in failure messages", func() {
+		display.Show("Hello")
+
+		matcher := pegomockginkgo.HaveReceived("Show", "World")
+		Expect(matcher.Match(display)).To(BeFalse())
+		Expect(matcher.FailureMessage(display)).To(SatisfyAll(
+			HavePrefix("Expected mock to have received Show(World), but it received:\n"),
+			ContainSubstring("Method invocation: Show"),
+		))
+	})
+
+	It("fails for values that aren't mocks", func() {
+		_, e := pegomockginkgo.HaveReceived("Show").Match("Hello")
+
+		Expect(e).To(MatchError("HaveReceived expects a mock, but got string"))
+	})
+})
+
+var _ = Describe("ExpectInvocation", func() {
+	It("expects a matching invocation", func() {
+		display := NewMockDisplay()
+
+		display.Show("Hello")
+
+		pegomockginkgo.ExpectInvocation(display, "Show", "Hello")
+	})
+})
@@ -0,0 +1,342 @@
+package pegomock
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
+// Controller provides the expectation API of gomock for mocks generated with
+// "pegomock generate --style gomock", so tests written for gomock can switch generators
+// without rewriting their expectations:
+//
+//	ctrl := pegomock.NewController(t)
+//	fetcher := NewMockFetcher(ctrl)
+//	fetcher.EXPECT().Fetch("http://example.com").Return("content", nil).Times(2)
+//
+// Calls without a matching expectation and expected calls that were not made are reported to t.
+// The mocks are still pegomock mocks, so When and VerifyWasCalled work as well.
+type Controller struct {
+	failHandler   FailHandler
+	mutex         sync.Mutex
+	expectedCalls []*Call
+	stubbed       map[stubbedMethod]bool
+	finished      bool
+}
+
+type stubbedMethod struct {
+	genericMock *GenericMock
+	methodName  string
+}
+
+// NewController creates a Controller that reports to t and checks that all expected calls
+// were made when t finishes.
+func NewController(t cleanupT) *Controller {
+	ctrl := &Controller{
+		failHandler: forwardingFailHandler(BuildTestingTGomegaFailHandler(t)),
+		stubbed:     make(map[stubbedMethod]bool),
+	}
+	t.Cleanup(ctrl.Finish)
+	return ctrl
+}
+
+// Finish reports all expected calls that were made fewer times than expected, as well as
+// failures of mocks that are still pending. Tests don't need to call it, because it's called
+// when their testing.T finishes, but calling it more than once does no harm.
+func (ctrl *Controller) Finish() {
+	reportPendingStrictFailure(currentGoroutineID())
+	reportForwardedFailures()
+	ctrl.mutex.Lock()
+	if ctrl.finished {
+		ctrl.mutex.Unlock()
+		return
+	}
+	ctrl.finished = true
+	var missing []string
+	for _, call := range ctrl.expectedCalls {
+		if call.numCalls < call.minTimes {
+			missing = append(missing, fmt.Sprintf("\t%v, expected at %v: called %v times, but expected at least %v",
+				call, call.origin, call.numCalls, call.minTimes))
+		}
+	}
+	ctrl.mutex.Unlock()
+	if len(missing) != 0 {
+		ctrl.failHandler("Missing calls:\n" + strings.Join(missing, "\n"))
+	}
+}
+
+// RegisterMock makes mock report its failures to the Controller's t and fail calls without an
+// expectation, like gomock's mocks do. It does nothing if ctrl is nil, so mocks created without
+// a Controller behave like ordinary pegomock mocks. It is meant for generated code, i.e. the
+// constructors of mocks.
+func (ctrl *Controller) RegisterMock(mock Mock) {
+	if ctrl == nil {
+		return
+	}
+	genericMock := GetGenericMockFrom(mock)
+	genericMock.Lock()
+	genericMock.failHandler = ctrl.failHandler
+	genericMock.Unlock()
+	Strict(mock)
+}
+
+// RecordCall expects a call of methodName on mock with args, which may be values, pegomock
+// matchers or matchers of gomock, like gomock.Any(). It is meant for generated code, i.e. the
+// EXPECT() recorders of mocks.
+func (ctrl *Controller) RecordCall(mock Mock, methodName string, returnTypes []reflect.Type, args ...interface{}) *Call {
+	verify.Argument(ctrl != nil, "EXPECT() requires a mock created with a Controller, e.g. NewMockX(pegomock.NewController(t))")
+	genericMock := GetGenericMockFrom(mock)
+	call := &Call{
+		ctrl:        ctrl,
+		genericMock: genericMock,
+		methodName:  methodName,
+		matchers:    matchersForArgs(args),
+		returnTypes: returnTypes,
+		origin:      callerOrigin(),
+		minTimes:    1,
+		maxTimes:    1,
+	}
+
+	ctrl.mutex.Lock()
+	defer ctrl.mutex.Unlock()
+	ctrl.expectedCalls = append(ctrl.expectedCalls, call)
+	if key := (stubbedMethod{genericMock, methodName}); !ctrl.stubbed[key] {
+		ctrl.stubbed[key] = true
+		StubAllInvocations(mock, methodName, func(params []Param) ReturnValues {
+			return ctrl.answer(genericMock, methodName, params)
+		})
+	}
+	return call
+}
+
+func matchersForArgs(args []interface{}) Matchers {
+	if argMatchers := takeArgMatchers(); len(argMatchers) != 0 {
+		params := make([]Param, len(args))
+		for i, arg := range args {
+			params[i] = arg
+		}
+		verifyArgMatcherUse(argMatchers, params)
+		return argMatchers
+	}
+	matchers := make(Matchers, len(args))
+	for i, arg := range args {
+		switch matcher := arg.(type) {
+		case Matcher:
+			matchers[i] = matcher
+		case gomockMatcher:
+			matchers[i] = &gomockMatcherAdapter{matcher: matcher}
+		default:
+			matchers[i] = &EqMatcher{Value: arg}
+		}
+	}
+	return matchers
+}
+
+// callerOrigin returns the location of the code that called the EXPECT() recorder.
+func callerOrigin() string {
+	_, file, line, ok := runtime.Caller(3)
+	if !ok {
+		return "unknown location"
+	}
+	return fmt.Sprintf("%v:%v", file[strings.LastIndex(file, "/")+1:], line)
+}
+
+// answer answers an invocation with the first expected call that matches it and was not yet
+// made the maximum number of times, like gomock does.
+func (ctrl *Controller) answer(genericMock *GenericMock, methodName string, params []Param) ReturnValues {
+	ctrl.mutex.Lock()
+	var candidates []string
+	for _, call := range ctrl.expectedCalls {
+		if call.genericMock != genericMock || call.methodName != methodName {
+			continue
+		}
+		if call.matchers.Matches(params) && call.numCalls < call.maxTimes {
+			call.numCalls++
+			do, answer := call.do, call.answer
+			ctrl.mutex.Unlock()
+			if do != nil {
+				do(params)
+			}
+			if answer == nil {
+				return nil
+			}
+			return answer(params)
+		}
+		candidates = append(candidates, fmt.Sprintf("\t%v, expected at %v: called %v times", call, call.origin, call.numCalls))
+	}
+	ctrl.mutex.Unlock()
+
+	message := fmt.Sprintf("Unexpected call to %v(%v)", methodName, formatParams(params))
+	if len(candidates) != 0 {
+		message += "\n\n\tExpected calls of " + methodName + " are:\n" + strings.Join(candidates, "\n")
+	}
+	ctrl.failHandler(message)
+	return nil
+}
+
+// Call is an expected call of a mock, created by its EXPECT() recorder. By default, it is
+// expected exactly once and returns zero values.
+type Call struct {
+	ctrl        *Controller
+	genericMock *GenericMock
+	methodName  string
+	matchers    Matchers
+	returnTypes []reflect.Type
+	origin      string
+	minTimes    int
+	maxTimes    int
+	minTimesSet bool
+	maxTimesSet bool
+	numCalls    int
+	do          func(params []Param)
+	answer      func(params []Param) ReturnValues
+}
+
+func (call *Call) String() string {
+	return fmt.Sprintf("%v(%v)", call.methodName, formatMatchers(call.matchers))
+}
+
+// Return makes the call return values.
+func (call *Call) Return(values ...interface{}) *Call {
+	returnValues := make(ReturnValues, len(values))
+	for i, value := range values {
+		returnValues[i] = value
+	}
+	checkAssignabilityOf(returnValues, call.returnTypes)
+	return call.setAnswer(func([]Param) ReturnValues { return returnValues })
+}
+
+// Do makes the call invoke f with its arguments. The call still returns the values given to
+// Return, no matter whether Return is called before or after Do.
+func (call *Call) Do(f interface{}) *Call {
+	fValue := funcValue("Do", f)
+	call.ctrl.mutex.Lock()
+	defer call.ctrl.mutex.Unlock()
+	call.do = func(params []Param) { callWithParams(fValue, params) }
+	return call
+}
+
+// DoAndReturn makes the call invoke f with its arguments and return its results.
+func (call *Call) DoAndReturn(f interface{}) *Call {
+	fValue := funcValue("DoAndReturn", f)
+	verify.Argument(fValue.Type().NumOut() == len(call.returnTypes),
+		"DoAndReturn requires a function with %v results, but got %v", len(call.returnTypes), fValue.Type())
+	return call.setAnswer(func(params []Param) ReturnValues {
+		results := callWithParams(fValue, params)
+		returnValues := make(ReturnValues, len(results))
+		for i, result := range results {
+			returnValues[i] = result.Interface()
+		}
+		return returnValues
+	})
+}
+
+// Times expects the call exactly n times.
+func (call *Call) Times(n int) *Call {
+	call.ctrl.mutex.Lock()
+	defer call.ctrl.mutex.Unlock()
+	call.minTimes, call.maxTimes = n, n
+	call.minTimesSet, call.maxTimesSet = true, true
+	return call
+}
+
+// AnyTimes allows the call any number of times, including none.
+func (call *Call) AnyTimes() *Call {
+	call.ctrl.mutex.Lock()
+	defer call.ctrl.mutex.Unlock()
+	call.minTimes, call.maxTimes = 0, math.MaxInt32
+	call.minTimesSet, call.maxTimesSet = true, true
+	return call
+}
+
+// MinTimes expects the call at least n times. Unless the maximum was set before, e.g. by
+// MaxTimes or Times, there's no upper limit.
+func (call *Call) MinTimes(n int) *Call {
+	call.ctrl.mutex.Lock()
+	defer call.ctrl.mutex.Unlock()
+	call.minTimes, call.minTimesSet = n, true
+	if !call.maxTimesSet {
+		call.maxTimes = math.MaxInt32
+	}
+	return call
+}
+
+// MaxTimes expects the call at most n times. Unless the minimum was set before, e.g. by
+// MinTimes or Times, the call may also not be made at all.
+func (call *Call) MaxTimes(n int) *Call {
+	call.ctrl.mutex.Lock()
+	defer call.ctrl.mutex.Unlock()
+	call.maxTimes, call.maxTimesSet = n, true
+	if !call.minTimesSet {
+		call.minTimes = 0
+	}
+	return call
+}
+
+// setAnswer sets what the call returns. Functions given to Do are called regardless.
+func (call *Call) setAnswer(answer func(params []Param) ReturnValues) *Call {
+	call.ctrl.mutex.Lock()
+	defer call.ctrl.mutex.Unlock()
+	call.answer = answer
+	return call
+}
+
+func funcValue(name string, f interface{}) reflect.Value {
+	fValue := reflect.ValueOf(f)
+	verify.Argument(fValue.Kind() == reflect.Func && !fValue.IsNil(), "%v requires a function, but got %T", name, f)
+	return fValue
+}
+
+// callWithParams calls f with params, passing zero values for nil params.
+func callWithParams(f reflect.Value, params []Param) []reflect.Value {
+	fType := f.Type()
+	args := make([]reflect.Value, len(params))
+	for i, param := range params {
+		var argType reflect.Type
+		if fType.IsVariadic() && i >= fType.NumIn()-1 {
+			argType = fType.In(fType.NumIn() - 1).Elem()
+		} else {
+			argType = fType.In(i)
+		}
+		if param == nil {
+			args[i] = reflect.Zero(argType)
+		} else {
+			args[i] = reflect.ValueOf(param)
+		}
+	}
+	return f.Call(args)
+}
+
+// gomockMatcher is the interface of gomock's matchers, like gomock.Any().
+type gomockMatcher interface {
+	Matches(x interface{}) bool
+	String() string
+}
+
+type gomockMatcherAdapter struct {
+	matcher gomockMatcher
+	actual  Param
+	sync.Mutex
+}
+
+func (adapter *gomockMatcherAdapter) Matches(param Param) bool {
+	adapter.Lock()
+	defer adapter.Unlock()
+	adapter.actual = param
+	return adapter.matcher.Matches(param)
+}
+
+func (adapter *gomockMatcherAdapter) FailureMessage() string {
+	adapter.Lock()
+	defer adapter.Unlock()
+	return fmt.Sprintf("Expected: %v; but got: %v", adapter.matcher, adapter.actual)
+}
+
+func (adapter *gomockMatcherAdapter) String() string {
+	return adapter.matcher.String()
+}
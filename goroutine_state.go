@@ -0,0 +1,123 @@
+package pegomock
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// goroutineState is the state of the DSL that belongs to a single goroutine. Keeping it per
+// goroutine instead of globally lets tests that use mocks run in parallel, e.g. with t.Parallel().
+type goroutineState struct {
+	// The matchers registered for the arguments of the next stubbing or verification.
+	argMatchers Matchers
+	// The last answered invocation of a mock, which is the target of When.
+	lastInvocation *invocation
+	// The failure of the last invocation of a strict mock without a matching stubbing. It is
+	// only reported later, because the invocation may still become the target of When.
+	pendingStrictFailure     string
+	pendingStrictFailureMock *GenericMock
+	// The fail handler registered with RegisterMockTestingT on this goroutine.
+	failHandler FailHandler
+	// The next ordering invocation number when RegisterMockTestingT was called on this goroutine.
+	testStartNumber int
+}
+
+var (
+	goroutineStates      = make(map[int64]*goroutineState)
+	goroutineStatesMutex sync.Mutex
+	// goroutineStates is pruned of the states of finished goroutines once it grows this big.
+	goroutineStatesPruningSize = 1000
+)
+
+// stateOf returns the state of the goroutine with goroutineID. The caller must hold
+// goroutineStatesMutex.
+func stateOf(goroutineID int64) *goroutineState {
+	state := goroutineStates[goroutineID]
+	if state == nil {
+		state = &goroutineState{}
+		goroutineStates[goroutineID] = state
+		if len(goroutineStates) >= goroutineStatesPruningSize {
+			pruneGoroutineStates()
+			goroutineStatesPruningSize = 2 * len(goroutineStates)
+		}
+	}
+	return state
+}
+
+// pruneGoroutineStates removes the states of all finished goroutines.
+func pruneGoroutineStates() {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	live := make(map[int64]bool)
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("goroutine ")) {
+			continue
+		}
+		line = bytes.TrimPrefix(line, []byte("goroutine "))
+		if end := bytes.IndexByte(line, ' '); end != -1 {
+			if id, err := strconv.ParseInt(string(line[:end]), 10, 64); err == nil {
+				live[id] = true
+			}
+		}
+	}
+	for goroutineID := range goroutineStates {
+		if !live[goroutineID] {
+			delete(goroutineStates, goroutineID)
+		}
+	}
+}
+
+// takeArgMatchers removes and returns the matchers registered on the current goroutine.
+func takeArgMatchers() Matchers {
+	goroutineID := currentGoroutineID()
+	goroutineStatesMutex.Lock()
+	defer goroutineStatesMutex.Unlock()
+	state := stateOf(goroutineID)
+	matchers := state.argMatchers
+	state.argMatchers = nil
+	return matchers
+}
+
+// ResetGoroutineState discards the matchers registered on the current goroutine but not used by
+// a stubbing or verification, and forgets its last invocation, so a test that failed halfway
+// through setting up a stubbing doesn't affect the next test on the same goroutine. It's meant
+// for test frameworks that run their tests one after another on one goroutine, like Ginkgo.
+func ResetGoroutineState() {
+	goroutineID := currentGoroutineID()
+	goroutineStatesMutex.Lock()
+	defer goroutineStatesMutex.Unlock()
+	state := stateOf(goroutineID)
+	state.argMatchers, state.lastInvocation = nil, nil
+}
+
+// goroutineFailHandler returns the fail handler registered with RegisterMockTestingT on the
+// goroutine with goroutineID, or nil if there is none.
+func goroutineFailHandler(goroutineID int64) FailHandler {
+	goroutineStatesMutex.Lock()
+	defer goroutineStatesMutex.Unlock()
+	if state := goroutineStates[goroutineID]; state != nil {
+		return state.failHandler
+	}
+	return nil
+}
+
+// currentFailHandler returns the fail handler registered with RegisterMockTestingT on the
+// current goroutine, or else the GlobalFailHandler.
+func currentFailHandler() FailHandler {
+	if failHandler := goroutineFailHandler(currentGoroutineID()); failHandler != nil {
+		return failHandler
+	}
+	if GlobalFailHandler == nil {
+		panic("No GlobalFailHandler set. Please use either RegisterMockFailHandler or RegisterMockTestingT to set a fail handler.")
+	}
+	return GlobalFailHandler
+}
@@ -0,0 +1,15 @@
+package http_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/petergtz/pegomock"
+
+	"testing"
+)
+
+func TestHTTP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	pegomock.RegisterMockFailHandler(func(message string, callerSkip ...int) { panic(message) })
+	RunSpecs(t, "HTTP Suite")
+}
@@ -0,0 +1,176 @@
+// Package http provides a mock http.RoundTripper and matchers for the requests it receives, so
+// tests can stub and verify the HTTP calls of an http.Client without starting a server:
+//
+//	transport := pegomockhttp.NewMockRoundTripper()
+//	When(transport.RoundTrip(pegomockhttp.Request(pegomockhttp.Method("POST"), pegomockhttp.URLMatching("/users$")))).
+//		Then(pegomockhttp.Respond(http.StatusCreated, `{"id": 1}`))
+//	client := &http.Client{Transport: transport}
+//	...
+//	request := transport.VerifyWasCalledOnce().RoundTrip(pegomockhttp.AnyRequest()).GetCapturedArguments()
+//	Expect(pegomockhttp.Body(request)).To(MatchJSON(`{"name": "Ada"}`))
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/petergtz/pegomock"
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
+//go:generate pegomock generate --package http --self_package github.com/petergtz/pegomock/http -o mock_round_tripper.go net/http RoundTripper
+
+// RequestCondition is a condition on requests. See Request.
+type RequestCondition struct {
+	description string
+	matches     func(request *http.Request) bool
+}
+
+func (condition RequestCondition) String() string {
+	return condition.description
+}
+
+// Method matches requests with the HTTP method method, e.g. Method("POST").
+func Method(method string) RequestCondition {
+	return RequestCondition{
+		description: fmt.Sprintf("Method(%v)", method),
+		matches:     func(request *http.Request) bool { return request.Method == method },
+	}
+}
+
+// URLMatching matches requests whose URL contains a match of the regular expression pattern,
+// e.g. URLMatching(`^https://api\.example\.com/users/[0-9]+$`).
+func URLMatching(pattern string) RequestCondition {
+	regex, err := regexp.Compile(pattern)
+	verify.Argument(err == nil, "Invalid pattern for URLMatching: %v", err)
+	return RequestCondition{
+		description: fmt.Sprintf("URLMatching(%v)", regex),
+		matches:     func(request *http.Request) bool { return request.URL != nil && regex.MatchString(request.URL.String()) },
+	}
+}
+
+// Header matches requests that have a header name with the value value among its values.
+func Header(name, value string) RequestCondition {
+	return RequestCondition{
+		description: fmt.Sprintf("Header(%v: %v)", name, value),
+		matches: func(request *http.Request) bool {
+			for _, actual := range request.Header.Values(name) {
+				if actual == value {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// BodyJSON matches requests whose body is JSON equal to expected, ignoring formatting and the
+// order of object keys.
+func BodyJSON(expected string) RequestCondition {
+	var expectedValue interface{}
+	err := json.Unmarshal([]byte(expected), &expectedValue)
+	verify.Argument(err == nil, "Invalid JSON for BodyJSON: %v", err)
+	return RequestCondition{
+		description: fmt.Sprintf("BodyJSON(%v)", expected),
+		matches: func(request *http.Request) bool {
+			var actualValue interface{}
+			if err := json.Unmarshal([]byte(Body(request)), &actualValue); err != nil {
+				return false
+			}
+			return reflect.DeepEqual(expectedValue, actualValue)
+		},
+	}
+}
+
+// Request matches requests that satisfy all conditions.
+func Request(conditions ...RequestCondition) *http.Request {
+	return pegomock.ArgThat[*http.Request](&RequestMatcher{Conditions: conditions})
+}
+
+// AnyRequest matches all requests. Use it to capture requests when verifying.
+func AnyRequest() *http.Request {
+	return Request()
+}
+
+// RequestMatcher matches non-nil requests that satisfy all Conditions. See Request.
+type RequestMatcher struct {
+	Conditions []RequestCondition
+	actual     *http.Request
+	sync.Mutex
+}
+
+func (matcher *RequestMatcher) Matches(param pegomock.Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	request, ok := param.(*http.Request)
+	matcher.actual = request
+	if !ok || request == nil {
+		return false
+	}
+	for _, condition := range matcher.Conditions {
+		if !condition.matches(request) {
+			return false
+		}
+	}
+	return true
+}
+
+func (matcher *RequestMatcher) FailureMessage() string {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	if matcher.actual == nil {
+		return fmt.Sprintf("Expected: %v; but got: nil", matcher)
+	}
+	return fmt.Sprintf("Expected: %v; but got: %v %v", matcher, matcher.actual.Method, matcher.actual.URL)
+}
+
+func (matcher *RequestMatcher) String() string {
+	descriptions := make([]string, len(matcher.Conditions))
+	for i, condition := range matcher.Conditions {
+		descriptions[i] = condition.description
+	}
+	return fmt.Sprintf("Request(%v)", strings.Join(descriptions, ", "))
+}
+
+// Body returns the body of request. It can be called any number of times, because it replaces
+// the body with a copy of what it read.
+func Body(request *http.Request) string {
+	if request.Body == nil || request.Body == http.NoBody {
+		return ""
+	}
+	body, err := io.ReadAll(request.Body)
+	verify.Argument(err == nil, "Reading request body failed: %v", err)
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body)
+}
+
+// Respond answers requests with a response with statusCode and body, e.g.
+//
+//	When(transport.RoundTrip(AnyRequest())).Then(Respond(http.StatusOK, `{"status": "up"}`))
+//
+// Each request gets a response of its own, so the body can be read once per request.
+func Respond(statusCode int, body string) func([]pegomock.Param) pegomock.ReturnValues {
+	return func(params []pegomock.Param) pegomock.ReturnValues {
+		request, _ := params[0].(*http.Request)
+		return pegomock.ReturnValues{&http.Response{
+			Status:        fmt.Sprintf("%v %v", statusCode, http.StatusText(statusCode)),
+			StatusCode:    statusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header),
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       request,
+		}, nil}
+	}
+}
@@ -0,0 +1,90 @@
+package http_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/petergtz/pegomock"
+	pegomockhttp "github.com/petergtz/pegomock/http"
+)
+
+var _ = Describe("MockRoundTripper", func() {
+	var (
+		transport *pegomockhttp.MockRoundTripper
+		client    *http.Client
+	)
+
+	BeforeEach(func() {
+		transport = pegomockhttp.NewMockRoundTripper()
+		client = &http.Client{Transport: transport}
+	})
+
+	It("answers requests matching method, URL, header and JSON body", func() {
+		pegomock.When(transport.RoundTrip(pegomockhttp.Request(
+			pegomockhttp.Method("POST"),
+			pegomockhttp.URLMatching(`/users$`),
+			pegomockhttp.Header("Content-Type", "application/json"),
+			pegomockhttp.BodyJSON(`{"name": "Ada", "admin": false}`),
+		))).Then(pegomockhttp.Respond(http.StatusCreated, `{"id": 1}`))
+
+		response, e := client.Post("https://api.example.com/users", "application/json", strings.NewReader(`{"admin":false,"name":"Ada"}`))
+
+		Expect(e).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusCreated))
+		Expect(io.ReadAll(response.Body)).To(MatchJSON(`{"id": 1}`))
+	})
+
+	It("does not answer requests that don't match all conditions", func() {
+		pegomock.When(transport.RoundTrip(pegomockhttp.Request(pegomockhttp.Method("GET"), pegomockhttp.BodyJSON(`{"name": "Ada"}`)))).
+			Then(pegomockhttp.Respond(http.StatusOK, ""))
+
+		_, e := client.Post("https://api.example.com/users", "application/json", strings.NewReader(`{"name": "Grace"}`))
+
+		Expect(e).To(HaveOccurred())
+	})
+
+	It("gives each request a response of its own", func() {
+		pegomock.When(transport.RoundTrip(pegomockhttp.AnyRequest())).Then(pegomockhttp.Respond(http.StatusOK, "up"))
+
+		for i := 0; i < 2; i++ {
+			response, e := client.Get("https://api.example.com/status")
+			Expect(e).NotTo(HaveOccurred())
+			Expect(io.ReadAll(response.Body)).To(BeEquivalentTo("up"))
+		}
+	})
+
+	It("captures requests whose bodies can be read after they were matched", func() {
+		pegomock.When(transport.RoundTrip(pegomockhttp.Request(pegomockhttp.BodyJSON(`{"name": "Ada"}`)))).
+			Then(pegomockhttp.Respond(http.StatusCreated, ""))
+
+		client.Post("https://api.example.com/users", "application/json", strings.NewReader(`{"name": "Ada"}`))
+
+		request := transport.VerifyWasCalledOnce().RoundTrip(pegomockhttp.Request(pegomockhttp.Method("POST"))).GetCapturedArguments()
+		Expect(request.URL.Path).To(Equal("/users"))
+		Expect(pegomockhttp.Body(request)).To(MatchJSON(`{"name": "Ada"}`))
+		Expect(pegomockhttp.Body(request)).To(MatchJSON(`{"name": "Ada"}`))
+	})
+
+	It("describes the conditions of requests in failure messages", func() {
+		client.Get("https://api.example.com/status")
+
+		Expect(panicMessageOf(func() {
+			transport.VerifyWasCalledOnce().RoundTrip(pegomockhttp.Request(pegomockhttp.Method("DELETE"), pegomockhttp.URLMatching(`/users/1$`)))
+		})).To(ContainSubstring("RoundTrip(Request(Method(DELETE), URLMatching(/users/1$)))"))
+	})
+
+	It("panics for invalid URL patterns and JSON", func() {
+		Expect(panicMessageOf(func() { pegomockhttp.URLMatching("(") })).To(HavePrefix("Invalid pattern for URLMatching"))
+		Expect(panicMessageOf(func() { pegomockhttp.BodyJSON("{") })).To(HavePrefix("Invalid JSON for BodyJSON"))
+	})
+})
+
+func panicMessageOf(f func()) (message string) {
+	defer func() { message = fmt.Sprint(recover()) }()
+	f()
+	return
+}
@@ -0,0 +1,67 @@
+package pegomock
+
+import "sort"
+
+// DumpAllInteractionsOnFailure makes failing verifications list every invocation of every mock
+// of the test in the order they happened, to make it obvious what actually happened. The mocks
+// of the test are those first used on the test's goroutine, and only those first used after
+// RegisterMockTestingT was called if it was.
+var DumpAllInteractionsOnFailure bool
+
+// dumpingFailHandler returns a fail handler that appends the invocations of all mocks of the
+// test running on the current goroutine to the failure messages it passes on to handler, or
+// handler itself unless DumpAllInteractionsOnFailure is set.
+func dumpingFailHandler(handler FailHandler) FailHandler {
+	if !DumpAllInteractionsOnFailure {
+		return handler
+	}
+	goroutineID := currentGoroutineID()
+	return func(message string, callerSkip ...int) {
+		skip := 1
+		if len(callerSkip) > 0 {
+			skip = callerSkip[0]
+		}
+		handler(message+formatAllInteractions(mocksOfTest(goroutineID)), skip+1)
+	}
+}
+
+// mocksOfTest returns the mocks first used on the goroutine with goroutineID since
+// RegisterMockTestingT was called on it, in the order they were first used.
+func mocksOfTest(goroutineID int64) []*GenericMock {
+	goroutineStatesMutex.Lock()
+	testStartNumber := stateOf(goroutineID).testStartNumber
+	goroutineStatesMutex.Unlock()
+
+	genericMocksMutex.Lock()
+	defer genericMocksMutex.Unlock()
+	var mocks []*GenericMock
+	for _, genericMock := range genericMocks {
+		if genericMock.creatorGoroutineID == goroutineID && genericMock.creationNumber >= testStartNumber {
+			mocks = append(mocks, genericMock)
+		}
+	}
+	sort.Slice(mocks, func(i, j int) bool { return mocks[i].creationNumber < mocks[j].creationNumber })
+	return mocks
+}
+
+func formatAllInteractions(mocks []*GenericMock) string {
+	labels := mockLabels(mocks)
+	var invocations []numberedInvocation
+	for _, genericMock := range mocks {
+		for _, invocation := range genericMock.numberedInvocationsSince(0) {
+			invocation.methodName = labels[genericMock] + invocation.methodName
+			invocations = append(invocations, invocation)
+		}
+	}
+	if len(invocations) == 0 {
+		return "\n\n\tAll interactions with mocks of this test: none\n"
+	}
+	sort.Slice(invocations, func(i, j int) bool {
+		return invocations[i].orderingInvocationNumber < invocations[j].orderingInvocationNumber
+	})
+	result := "\n\n\tAll interactions with mocks of this test:\n"
+	for _, invocation := range invocations {
+		result += "\t\t" + invocation.methodName + "(" + formatParams(invocation.params) + ")\n"
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pegomock_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petergtz/pegomock"
+)
+
+func TestFailingVerificationsListAllInteractionsOfTheTestIfEnabled(t *testing.T) {
+	pegomock.DumpAllInteractionsOnFailure = true
+	defer func() { pegomock.DumpAllInteractionsOnFailure = false }()
+
+	var failures []string
+	withFailHandler := pegomock.WithFailHandler(func(message string, callerSkip ...int) { failures = append(failures, message) })
+	display := NewMockDisplay(withFailHandler)
+	otherDisplay := NewMockDisplay(withFailHandler)
+	display.Show("Hello")
+	otherDisplay.Flash("World", 1)
+	display.Clear()
+
+	display.VerifyWasCalled(pegomock.Never()).Show("Hello")
+
+	expectedDump := "\n\n\tAll interactions with mocks of this test:\n" +
+		"\t\t*pegomock_test.MockDisplay#1.Show(\"Hello\")\n" +
+		"\t\t*pegomock_test.MockDisplay#2.Flash(\"World\", 1)\n" +
+		"\t\t*pegomock_test.MockDisplay#1.Clear()\n"
+	if len(failures) != 1 || !strings.HasSuffix(failures[0], expectedDump) {
+		t.Errorf("Expected one failure ending with %q, but got %q", expectedDump, failures)
+	}
+}
+
+func TestFailingVerificationsListNoInteractionsByDefault(t *testing.T) {
+	var failures []string
+	display := NewMockDisplay(pegomock.WithFailHandler(func(message string, callerSkip ...int) { failures = append(failures, message) }))
+	display.Show("Hello")
+
+	display.VerifyWasCalled(pegomock.Never()).Show("Hello")
+
+	if len(failures) != 1 || strings.Contains(failures[0], "All interactions with mocks of this test") {
+		t.Errorf("Expected one failure without interactions of all mocks, but got %q", failures)
+	}
+}
@@ -0,0 +1,23 @@
+// Package doccomment extracts the doc comments of declarations, which the model generators copy
+// to the model.
+package doccomment
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// OfType returns the doc comment of the type declared by spec in decl, which is the one of decl
+// if the declaration isn't parenthesized.
+func OfType(decl *ast.GenDecl, spec *ast.TypeSpec) *ast.CommentGroup {
+	if spec.Doc == nil && !decl.Lparen.IsValid() {
+		return decl.Doc
+	}
+	return spec.Doc
+}
+
+// Text returns the text of doc without comment markers and the final newline, or "" if doc is
+// nil.
+func Text(doc *ast.CommentGroup) string {
+	return strings.TrimSuffix(doc.Text(), "\n")
+}
@@ -14,6 +14,12 @@
 
 package pegomock
 
+import (
+	"time"
+
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
 func Times(numDesiredInvocations int) *EqMatcher {
 	return &EqMatcher{Value: numDesiredInvocations}
 }
@@ -26,6 +32,12 @@ func AtMost(numDesiredInvocations int) *AtMostIntMatcher {
 	return &AtMostIntMatcher{Value: numDesiredInvocations}
 }
 
+// Between matches invocation counts from min to max, both inclusive.
+func Between(min, max int) *BetweenIntMatcher {
+	verify.Argument(min <= max, "Between requires min <= max, but got %v and %v", min, max)
+	return &BetweenIntMatcher{Min: min, Max: max}
+}
+
 func Never() *EqMatcher {
 	return &EqMatcher{Value: 0}
 }
@@ -37,3 +49,11 @@ func Once() *EqMatcher {
 func Twice() *EqMatcher {
 	return &EqMatcher{Value: 2}
 }
+
+// Within makes a verification wait up to timeout for invocationCountMatcher to match, so
+// invocations made by other goroutines can be verified without sleeping, e.g.
+// display.VerifyWasCalled(Within(Once(), time.Second)).Show("Hello"). It succeeds as soon as
+// invocationCountMatcher matches, so e.g. Within(Never(), time.Second) does not wait at all.
+func Within(invocationCountMatcher Matcher, timeout time.Duration) *WithinMatcher {
+	return &WithinMatcher{InvocationCountMatcher: invocationCountMatcher, Timeout: timeout}
+}
@@ -1,11 +1,15 @@
 package pegomock
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/petergtz/pegomock/internal/verify"
 	"sync"
+	"time"
 )
 
 type EqMatcher struct {
@@ -101,3 +105,396 @@ func (matcher *AtMostIntMatcher) FailureMessage() string {
 func (matcher *AtMostIntMatcher) String() string {
 	return fmt.Sprintf("AtMost(%v)", matcher.Value)
 }
+
+type BetweenIntMatcher struct {
+	Min    int
+	Max    int
+	actual int
+}
+
+func (matcher *BetweenIntMatcher) Matches(param Param) bool {
+	matcher.actual = param.(int)
+	return param.(int) >= matcher.Min && param.(int) <= matcher.Max
+}
+
+func (matcher *BetweenIntMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: between %v and %v; but got: %v", matcher.Min, matcher.Max, matcher.actual)
+}
+
+func (matcher *BetweenIntMatcher) String() string {
+	return fmt.Sprintf("Between(%v, %v)", matcher.Min, matcher.Max)
+}
+
+// WithinMatcher matches invocation counts like InvocationCountMatcher, but makes verifications
+// wait up to Timeout for it to match. See Within.
+type WithinMatcher struct {
+	InvocationCountMatcher Matcher
+	Timeout                time.Duration
+}
+
+func (matcher *WithinMatcher) Matches(param Param) bool {
+	return matcher.InvocationCountMatcher.Matches(param)
+}
+
+func (matcher *WithinMatcher) FailureMessage() string {
+	return fmt.Sprintf("%v (waited %v)", matcher.InvocationCountMatcher.FailureMessage(), matcher.Timeout)
+}
+
+func (matcher *WithinMatcher) String() string {
+	return fmt.Sprintf("Within(%v, %v)", matcher.InvocationCountMatcher, matcher.Timeout)
+}
+
+// StringMatchingMatcher matches strings containing a match of Regexp. See StringMatching.
+type StringMatchingMatcher struct {
+	Regexp *regexp.Regexp
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *StringMatchingMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	str, ok := param.(string)
+	return ok && matcher.Regexp.MatchString(str)
+}
+
+func (matcher *StringMatchingMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: string matching %v; but got: %v", matcher.Regexp, matcher.actual)
+}
+
+func (matcher *StringMatchingMatcher) String() string {
+	return fmt.Sprintf("StringMatching(%v)", matcher.Regexp)
+}
+
+// FieldsEqualMatcher matches structs, or pointers to structs, of the type of Value whose
+// Fields are deeply equal to those of Value. See FieldsEqual.
+type FieldsEqualMatcher struct {
+	Value  Param
+	Fields []string
+	actual Param
+	sync.Mutex
+}
+
+func NewFieldsEqualMatcher(value Param, fields ...string) *FieldsEqualMatcher {
+	verify.Argument(len(fields) != 0, "Must provide at least one field name")
+	typ := reflect.TypeOf(value)
+	verify.Argument(typ != nil, "Must provide a non-nil struct")
+	if typ.Kind() == reflect.Ptr {
+		verify.Argument(!reflect.ValueOf(value).IsNil(), "Must provide a non-nil struct")
+		typ = typ.Elem()
+	}
+	verify.Argument(typ.Kind() == reflect.Struct, "Must provide a struct or a pointer to a struct, but got %v", typ)
+	for _, name := range fields {
+		field, ok := typ.FieldByName(name)
+		verify.Argument(ok, "%v has no field %v", typ, name)
+		verify.Argument(field.PkgPath == "", "Field %v of %v is not exported", name, typ)
+	}
+	return &FieldsEqualMatcher{Value: value, Fields: fields}
+}
+
+func (matcher *FieldsEqualMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	if reflect.TypeOf(param) != reflect.TypeOf(matcher.Value) {
+		return false
+	}
+	expected, actual := reflect.ValueOf(matcher.Value), reflect.ValueOf(param)
+	if actual.Kind() == reflect.Ptr {
+		if actual.IsNil() {
+			return false
+		}
+		expected, actual = expected.Elem(), actual.Elem()
+	}
+	for _, name := range matcher.Fields {
+		if !reflect.DeepEqual(expected.FieldByName(name).Interface(), actual.FieldByName(name).Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (matcher *FieldsEqualMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: %v; but got: %v", matcher, matcher.actual)
+}
+
+func (matcher *FieldsEqualMatcher) String() string {
+	expected := reflect.Indirect(reflect.ValueOf(matcher.Value))
+	var fields []string
+	for _, name := range matcher.Fields {
+		fields = append(fields, fmt.Sprintf("%v: %#v", name, expected.FieldByName(name).Interface()))
+	}
+	return fmt.Sprintf("FieldsEqual(%v{%v})", reflect.TypeOf(matcher.Value), strings.Join(fields, ", "))
+}
+
+// NotMatcher matches all params that Matcher doesn't match. See ArgNot.
+type NotMatcher struct {
+	Matcher Matcher
+	actual  Param
+	sync.Mutex
+}
+
+func (matcher *NotMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	return !matcher.Matcher.Matches(param)
+}
+
+func (matcher *NotMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: not %v; but got: %v", matcher.Matcher, matcher.actual)
+}
+
+func (matcher *NotMatcher) String() string {
+	return fmt.Sprintf("Not(%v)", matcher.Matcher)
+}
+
+// AndMatcher matches params that all of Matchers match. See ArgAnd.
+type AndMatcher struct {
+	Matchers []Matcher
+	failed   Matcher
+	sync.Mutex
+}
+
+func (matcher *AndMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	for _, m := range matcher.Matchers {
+		if !m.Matches(param) {
+			matcher.failed = m
+			return false
+		}
+	}
+	return true
+}
+
+func (matcher *AndMatcher) FailureMessage() string {
+	return matcher.failed.FailureMessage()
+}
+
+func (matcher *AndMatcher) String() string {
+	return fmt.Sprintf("And(%v)", formatMatchers(matcher.Matchers))
+}
+
+// OrMatcher matches params that any of Matchers matches. See ArgOr.
+type OrMatcher struct {
+	Matchers []Matcher
+	actual   Param
+	sync.Mutex
+}
+
+func (matcher *OrMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	for _, m := range matcher.Matchers {
+		if m.Matches(param) {
+			return true
+		}
+	}
+	return false
+}
+
+func (matcher *OrMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: %v; but got: %v", matcher, matcher.actual)
+}
+
+func (matcher *OrMatcher) String() string {
+	return fmt.Sprintf("Or(%v)", formatMatchers(matcher.Matchers))
+}
+
+// NotNilMatcher matches all params except nil. See NotNil.
+type NotNilMatcher struct{}
+
+func (matcher *NotNilMatcher) Matches(param Param) bool {
+	if param == nil {
+		return false
+	}
+	value := reflect.ValueOf(param)
+	switch value.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map,
+		reflect.Ptr, reflect.Slice, reflect.UnsafePointer:
+		return !value.IsNil()
+	default:
+		return true
+	}
+}
+
+func (matcher *NotNilMatcher) FailureMessage() string {
+	return "Expected: not nil; but got: nil"
+}
+
+func (matcher *NotNilMatcher) String() string {
+	return "NotNil"
+}
+
+type ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// PredicateMatcher matches values of type T for which Predicate returns true. See ArgSatisfies.
+type PredicateMatcher[T any] struct {
+	Predicate func(T) bool
+	actual    Param
+	sync.Mutex
+}
+
+func (matcher *PredicateMatcher[T]) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	value, ok := param.(T)
+	if !ok && param != nil {
+		return false
+	}
+	return matcher.Predicate(value)
+}
+
+func (matcher *PredicateMatcher[T]) FailureMessage() string {
+	return fmt.Sprintf("Expected: value satisfying %v; but got: %v", matcher, matcher.actual)
+}
+
+func (matcher *PredicateMatcher[T]) String() string {
+	return fmt.Sprintf("ArgSatisfies(func(%v) bool)", reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// GreaterThanMatcher matches values of type T greater than Value. See IntGreaterThan.
+type GreaterThanMatcher[T ordered] struct {
+	Value  T
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *GreaterThanMatcher[T]) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	value, ok := param.(T)
+	return ok && value > matcher.Value
+}
+
+func (matcher *GreaterThanMatcher[T]) FailureMessage() string {
+	return fmt.Sprintf("Expected: greater than %v; but got: %v", matcher.Value, matcher.actual)
+}
+
+func (matcher *GreaterThanMatcher[T]) String() string {
+	return fmt.Sprintf("GreaterThan(%v)", matcher.Value)
+}
+
+// LessThanMatcher matches values of type T less than Value. See IntLessThan.
+type LessThanMatcher[T ordered] struct {
+	Value  T
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *LessThanMatcher[T]) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	value, ok := param.(T)
+	return ok && value < matcher.Value
+}
+
+func (matcher *LessThanMatcher[T]) FailureMessage() string {
+	return fmt.Sprintf("Expected: less than %v; but got: %v", matcher.Value, matcher.actual)
+}
+
+func (matcher *LessThanMatcher[T]) String() string {
+	return fmt.Sprintf("LessThan(%v)", matcher.Value)
+}
+
+// InRangeMatcher matches values of type T from Min to Max, both inclusive. See IntInRange.
+type InRangeMatcher[T ordered] struct {
+	Min    T
+	Max    T
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *InRangeMatcher[T]) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	value, ok := param.(T)
+	return ok && value >= matcher.Min && value <= matcher.Max
+}
+
+func (matcher *InRangeMatcher[T]) FailureMessage() string {
+	return fmt.Sprintf("Expected: between %v and %v; but got: %v", matcher.Min, matcher.Max, matcher.actual)
+}
+
+func (matcher *InRangeMatcher[T]) String() string {
+	return fmt.Sprintf("InRange(%v, %v)", matcher.Min, matcher.Max)
+}
+
+// ErrorIsMatcher matches errors for which errors.Is(err, Target) holds. See ErrorIs.
+type ErrorIsMatcher struct {
+	Target error
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *ErrorIsMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	err, ok := param.(error)
+	return ok && errors.Is(err, matcher.Target)
+}
+
+func (matcher *ErrorIsMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: error wrapping %v; but got: %v", matcher.Target, matcher.actual)
+}
+
+func (matcher *ErrorIsMatcher) String() string {
+	return fmt.Sprintf("ErrorIs(%v)", matcher.Target)
+}
+
+// ErrorAsMatcher matches errors for which errors.As(err, Target) holds. Target must be a
+// non-nil pointer to a type implementing error or to an interface. On a match, the error is
+// assigned to Target like with errors.As. See ErrorAs.
+type ErrorAsMatcher struct {
+	Target interface{}
+	actual Param
+	sync.Mutex
+}
+
+func NewErrorAsMatcher(target interface{}) *ErrorAsMatcher {
+	value := reflect.ValueOf(target)
+	verify.Argument(value.Kind() == reflect.Ptr && !value.IsNil(), "Target must be a non-nil pointer, but got %#v", target)
+	verify.Argument(value.Type().Elem().Kind() == reflect.Interface ||
+		value.Type().Elem().Implements(reflect.TypeOf((*error)(nil)).Elem()),
+		"Target must be a pointer to an interface or to a type implementing error, but got %v", value.Type())
+	return &ErrorAsMatcher{Target: target}
+}
+
+func (matcher *ErrorAsMatcher) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	err, ok := param.(error)
+	return ok && errors.As(err, matcher.Target)
+}
+
+func (matcher *ErrorAsMatcher) FailureMessage() string {
+	return fmt.Sprintf("Expected: error of type %v; but got: %v", reflect.TypeOf(matcher.Target).Elem(), matcher.actual)
+}
+
+func (matcher *ErrorAsMatcher) String() string {
+	return fmt.Sprintf("ErrorAs(%v)", reflect.TypeOf(matcher.Target).Elem())
+}
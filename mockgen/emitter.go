@@ -0,0 +1,126 @@
+package mockgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync"
+
+	"github.com/petergtz/pegomock/model"
+)
+
+// Emitter generates additional files from the interfaces mocks are generated for, e.g. tracing
+// decorators or no-op implementations, in the same pass as the mocks.
+type Emitter interface {
+	// Emit returns the generated files keyed by their names, which are relative to the directory
+	// of the mocks. opts are the options the mocks are generated with.
+	Emit(pkg *model.Package, opts Options) (map[string][]byte, error)
+}
+
+// EmitterFunc turns a function into an Emitter.
+type EmitterFunc func(pkg *model.Package, opts Options) (map[string][]byte, error)
+
+func (emit EmitterFunc) Emit(pkg *model.Package, opts Options) (map[string][]byte, error) {
+	return emit(pkg, opts)
+}
+
+var (
+	emitters      = make(map[string]Emitter)
+	emittersMutex sync.Mutex
+)
+
+// RegisterEmitter makes emitter available under name to Emit, typically in the init function of
+// the package providing it. It panics if name is registered already.
+func RegisterEmitter(name string, emitter Emitter) {
+	emittersMutex.Lock()
+	defer emittersMutex.Unlock()
+	if _, exists := emitters[name]; exists {
+		panic(fmt.Sprintf("Emitter %q is registered already", name))
+	}
+	emitters[name] = emitter
+}
+
+// RegisteredEmitters returns the names of the registered emitters, sorted.
+func RegisteredEmitters() []string {
+	emittersMutex.Lock()
+	defer emittersMutex.Unlock()
+	names := make([]string, 0, len(emitters))
+	for name := range emitters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExecutableEmitterPrefix prefixes the names of executables that Emit runs for emitters that
+// are not registered, e.g. pegomock-emitter-tracing for the emitter "tracing".
+const ExecutableEmitterPrefix = "pegomock-emitter-"
+
+// Emit runs the emitters with the given names on pkg and returns the files of all of them. An
+// emitter that is not registered with RegisterEmitter is run as the executable named
+// ExecutableEmitterPrefix followed by its name, found in PATH, see ExecutableEmitter.
+func Emit(pkg *model.Package, opts Options, names []string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	emittedBy := make(map[string]string)
+	for _, name := range names {
+		emittersMutex.Lock()
+		emitter, registered := emitters[name]
+		emittersMutex.Unlock()
+		if !registered {
+			executable, err := exec.LookPath(ExecutableEmitterPrefix + name)
+			if err != nil {
+				return nil, fmt.Errorf("Unknown emitter %q: it is neither registered nor is there an executable %v%v",
+					name, ExecutableEmitterPrefix, name)
+			}
+			emitter = ExecutableEmitter(executable)
+		}
+		emitted, err := emitter.Emit(pkg, opts)
+		if err != nil {
+			return nil, fmt.Errorf("Emitter %q failed: %v", name, err)
+		}
+		for fileName, content := range emitted {
+			if other, exists := emittedBy[fileName]; exists {
+				return nil, fmt.Errorf("Emitters %q and %q both generate %v", other, name, fileName)
+			}
+			emittedBy[fileName] = name
+			files[fileName] = content
+		}
+	}
+	return files, nil
+}
+
+// ExecutableEmitter is an Emitter implemented by an executable, so third parties can ship
+// emitters without building pegomock themselves. The executable reads a JSON object from stdin
+// with the fields "model", the model of the interfaces as written by --emit-model, "source",
+// "packageOut" and "selfPackage", and writes a JSON object to stdout that maps file names to
+// their contents. Failing with a non-zero exit code, it reports its stderr.
+type ExecutableEmitter string
+
+func (executable ExecutableEmitter) Emit(pkg *model.Package, opts Options) (map[string][]byte, error) {
+	input, err := json.Marshal(struct {
+		Model       *model.Package `json:"model"`
+		Source      string         `json:"source"`
+		PackageOut  string         `json:"packageOut"`
+		SelfPackage string         `json:"selfPackage"`
+	}{pkg, opts.Source, opts.PackageOut, opts.SelfPackage})
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(string(executable))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(input), &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %v", err, stderr.String())
+	}
+	var contents map[string]string
+	if err := json.Unmarshal(stdout.Bytes(), &contents); err != nil {
+		return nil, fmt.Errorf("invalid output of %v: %v", executable, err)
+	}
+	files := make(map[string][]byte, len(contents))
+	for fileName, content := range contents {
+		files[fileName] = []byte(content)
+	}
+	return files, nil
+}
@@ -0,0 +1,221 @@
+package mockgen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/petergtz/pegomock/model"
+)
+
+// generateFakeFor generates a fake of iface in FakeStyle, modeled after the fakes of
+// counterfeiter: every method has a Stub field, and methods to set its return values and to
+// query its calls.
+func (g *generator) generateFakeFor(iface *model.Interface, pkgPath, selfPackage string, inSamePackage bool) {
+	fakeTypeName := g.names.mock(iface.Name)
+	g.typeParams = model.TypeParamsString(iface.TypeParams, g.packageMap, selfPackage)
+	g.typeArgs = model.TypeArgsString(iface.TypeParams)
+
+	g.emptyLine().generateTypeDoc(fakeTypeName, "a fake", iface).p("type %v%v struct {", fakeTypeName, g.typeParams)
+	for _, method := range iface.Methods {
+		args, _, argTypes, returnTypes := argDataFor(method, g.packageMap, selfPackage)
+		field := lowerFirst(method.Name)
+		g.
+			p("%vStub func(%v) (%v)", method.Name, join(args), join(returnTypes)).
+			p("%vMutex sync.RWMutex", field).
+			p("%vArgsForCall []struct {", field)
+		for i, argType := range argTypes {
+			g.p("arg%v %v", i+1, argType)
+		}
+		g.p("}")
+		if len(returnTypes) > 0 {
+			g.
+				p("%vReturns %v", field, resultsStruct(returnTypes)).
+				p("%vReturnsOnCall map[int]%v", field, resultsStruct(returnTypes))
+		}
+	}
+	g.generateEmbeddedType(iface, selfPackage)
+	g.
+		p("invocations map[string][][]interface{}").
+		p("invocationsMutex sync.RWMutex").
+		p("}").
+		emptyLine()
+	if canAssertImplementationOf(iface, pkgPath) {
+		interfaceType := &model.NamedType{Package: pkgPath, Type: iface.Name}
+		g.p("var _ %v = (*%v)(nil)", interfaceType.String(g.packageMap, selfPackage), fakeTypeName)
+		g.emptyLine()
+	}
+	if iface.IsFuncType && canReferTo(iface, pkgPath, inSamePackage) {
+		g.generateFuncMethod(iface, fakeTypeName, pkgPath, selfPackage)
+	}
+	for _, method := range iface.Methods {
+		g.generateFakeMethods(fakeTypeName, method, selfPackage)
+	}
+	g.
+		p("func (fake *%v%v) Invocations() map[string][][]interface{} {", fakeTypeName, g.typeArgs).
+		p("	fake.invocationsMutex.RLock()").
+		p("	defer fake.invocationsMutex.RUnlock()").
+		p("	copiedInvocations := map[string][][]interface{}{}").
+		p("	for key, value := range fake.invocations {").
+		p("		copiedInvocations[key] = value").
+		p("	}").
+		p("	return copiedInvocations").
+		p("}").
+		emptyLine().
+		p("func (fake *%v%v) recordInvocation(key string, args []interface{}) {", fakeTypeName, g.typeArgs).
+		p("	fake.invocationsMutex.Lock()").
+		p("	defer fake.invocationsMutex.Unlock()").
+		p("	if fake.invocations == nil {").
+		p("		fake.invocations = map[string][][]interface{}{}").
+		p("	}").
+		p("	fake.invocations[key] = append(fake.invocations[key], args)").
+		p("}").
+		emptyLine()
+}
+
+// generateFakeMethods generates the implementation of method and the methods to configure and
+// query it, e.g. GetReturns and GetArgsForCall for a method Get.
+func (g *generator) generateFakeMethods(fakeTypeName string, method *model.Method, selfPackage string) {
+	args, argNames, argTypes, returnTypes := argDataFor(method, g.packageMap, selfPackage)
+	receiver := fmt.Sprintf("fake *%v%v", fakeTypeName, g.typeArgs)
+	field := lowerFirst(method.Name)
+	signature := fmt.Sprintf("func(%v) (%v)", join(args), join(returnTypes))
+
+	callArgs := join(argNames)
+	if method.Variadic != nil {
+		callArgs += "..."
+	}
+	g.generateDoc(method.Doc)
+	g.p("func (%v) %v(%v) (%v) {", receiver, method.Name, join(args), join(returnTypes))
+	recordedArgs := make([]string, len(argNames))
+	for i, argName := range argNames {
+		recordedArgs[i] = argName
+		// Slices are copied, so later modifications by the caller don't change the recorded arguments.
+		if strings.HasPrefix(argTypes[i], "[]") {
+			recordedArgs[i] = argName + "Copy"
+			g.
+				p("var %vCopy %v", argName, argTypes[i]).
+				p("if %v != nil {", argName).
+				p("%vCopy = make(%v, len(%v))", argName, argTypes[i], argName).
+				p("copy(%vCopy, %v)", argName, argName).
+				p("}")
+		}
+	}
+	g.p("fake.%vMutex.Lock()", field)
+	if len(returnTypes) > 0 {
+		g.p("ret, specificReturn := fake.%vReturnsOnCall[len(fake.%vArgsForCall)]", field, field)
+	}
+	g.
+		p("fake.%vArgsForCall = append(fake.%vArgsForCall, struct {", field, field)
+	for i, argType := range argTypes {
+		g.p("arg%v %v", i+1, argType)
+	}
+	g.
+		p("}{%v})", join(recordedArgs)).
+		p("stub := fake.%vStub", method.Name)
+	if len(returnTypes) > 0 {
+		g.p("fakeReturns := fake.%vReturns", field)
+	}
+	g.
+		p("fake.recordInvocation(%q, []interface{}{%v})", method.Name, join(recordedArgs)).
+		p("fake.%vMutex.Unlock()", field).
+		p("if stub != nil {")
+	if len(returnTypes) > 0 {
+		g.
+			p("return stub(%v)", callArgs).
+			p("}").
+			p("if specificReturn {").
+			p("return %v", resultFields("ret", len(returnTypes))).
+			p("}").
+			p("return %v", resultFields("fakeReturns", len(returnTypes)))
+	} else {
+		g.
+			p("stub(%v)", callArgs).
+			p("}")
+	}
+	g.
+		p("}").
+		emptyLine().
+		p("func (%v) %vCallCount() int {", receiver, method.Name).
+		p("	fake.%vMutex.RLock()", field).
+		p("	defer fake.%vMutex.RUnlock()", field).
+		p("	return len(fake.%vArgsForCall)", field).
+		p("}").
+		emptyLine().
+		p("func (%v) %vCalls(stub %v) {", receiver, method.Name, signature).
+		p("	fake.%vMutex.Lock()", field).
+		p("	defer fake.%vMutex.Unlock()", field).
+		p("	fake.%vStub = stub", method.Name).
+		p("}").
+		emptyLine()
+
+	if len(argTypes) > 0 {
+		g.
+			p("func (%v) %vArgsForCall(i int) (%v) {", receiver, method.Name, join(argTypes)).
+			p("	fake.%vMutex.RLock()", field).
+			p("	defer fake.%vMutex.RUnlock()", field).
+			p("	argsForCall := fake.%vArgsForCall[i]", field)
+		argFields := make([]string, len(argTypes))
+		for i := range argTypes {
+			argFields[i] = fmt.Sprintf("argsForCall.arg%v", i+1)
+		}
+		g.
+			p("	return %v", join(argFields)).
+			p("}").
+			emptyLine()
+	}
+
+	if len(returnTypes) == 0 {
+		return
+	}
+	resultParams := make([]string, len(returnTypes))
+	for i, returnType := range returnTypes {
+		resultParams[i] = fmt.Sprintf("result%v %v", i+1, returnType)
+	}
+	resultValues := resultFields("", len(returnTypes))
+	g.
+		p("func (%v) %vReturns(%v) {", receiver, method.Name, join(resultParams)).
+		p("	fake.%vMutex.Lock()", field).
+		p("	defer fake.%vMutex.Unlock()", field).
+		p("	fake.%vStub = nil", method.Name).
+		p("	fake.%vReturns = %v{%v}", field, resultsStruct(returnTypes), resultValues).
+		p("}").
+		emptyLine().
+		p("func (%v) %vReturnsOnCall(i int, %v) {", receiver, method.Name, join(resultParams)).
+		p("	fake.%vMutex.Lock()", field).
+		p("	defer fake.%vMutex.Unlock()", field).
+		p("	fake.%vStub = nil", method.Name).
+		p("	if fake.%vReturnsOnCall == nil {", field).
+		p("		fake.%vReturnsOnCall = make(map[int]%v)", field, resultsStruct(returnTypes)).
+		p("	}").
+		p("	fake.%vReturnsOnCall[i] = %v{%v}", field, resultsStruct(returnTypes), resultValues).
+		p("}").
+		emptyLine()
+}
+
+// resultsStruct returns an anonymous struct type with the fields result1, result2, etc.
+func resultsStruct(returnTypes []string) string {
+	fields := make([]string, len(returnTypes))
+	for i, returnType := range returnTypes {
+		fields[i] = fmt.Sprintf("result%v %v", i+1, returnType)
+	}
+	return "struct {" + strings.Join(fields, "; ") + "}"
+}
+
+// resultFields returns "result1, result2, ..." with each result qualified by value, if given.
+func resultFields(value string, n int) string {
+	fields := make([]string, n)
+	for i := range fields {
+		fields[i] = fmt.Sprintf("result%v", i+1)
+		if value != "" {
+			fields[i] = value + "." + fields[i]
+		}
+	}
+	return join(fields)
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -0,0 +1,109 @@
+package mockgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/petergtz/pegomock/model"
+	"github.com/petergtz/pegomock/modelgen/gomock"
+)
+
+// Mode determines where a Generator takes the interfaces from.
+type Mode int
+
+const (
+	// ReflectMode loads the interfaces from the type information of a package, given by its
+	// import path or a relative path like ./display.
+	ReflectMode Mode = iota
+	// SourceMode parses the interfaces from a single Go source file.
+	SourceMode
+)
+
+// GeneratorOptions configures a Generator.
+type GeneratorOptions struct {
+	// Mode determines whether the source passed to the Generator is a package or a file.
+	Mode Mode
+	// Options configures the generated code. If Options.Source is empty, it is derived from
+	// the source passed to the Generator.
+	Options
+}
+
+// Generator generates mocks the way "pegomock generate" does, so other tools can embed the
+// generation without running the pegomock command.
+type Generator struct {
+	options GeneratorOptions
+}
+
+// NewGenerator returns a Generator for options.
+func NewGenerator(options GeneratorOptions) *Generator {
+	return &Generator{options: options}
+}
+
+// Generate generates the source code of mocks for the interfaces of source. In ReflectMode,
+// source is a package and interfaces names the interfaces to mock; all exported interfaces
+// of the package if empty. In SourceMode, source is a file and mocks are generated for all
+// its interfaces. Like the package-level Generate, it skips interfaces using constructs that
+// cannot be mocked and reports them as model.UnsupportedConstructErrors along with the code
+// for the remaining interfaces.
+func (g *Generator) Generate(source string, interfaces ...string) ([]byte, error) {
+	output, _, err := g.GenerateWithMatchers(source, interfaces...)
+	return output, err
+}
+
+// GenerateWithMatchers is like Generate, but additionally returns the source code of
+// matchers for all parameter and return types, keyed by file name without extension.
+func (g *Generator) GenerateWithMatchers(source string, interfaces ...string) ([]byte, map[string]string, error) {
+	pkg, err := g.Model(source, interfaces...)
+	unsupported, _ := err.(model.UnsupportedConstructErrors)
+	if err != nil && unsupported == nil {
+		return nil, nil, err
+	}
+	opts := g.options.Options
+	if opts.Source == "" {
+		opts.Source = sourceDescription(g.options.Mode, source, interfaces)
+	}
+	output, matchers, err := GenerateWithMatchers(pkg, opts)
+	if generatorUnsupported, ok := err.(model.UnsupportedConstructErrors); ok {
+		unsupported = append(unsupported, generatorUnsupported...)
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if len(unsupported) != 0 {
+		return output, matchers, unsupported
+	}
+	return output, matchers, nil
+}
+
+// Model returns the model of the interfaces that Generate generates mocks for. Interfaces using
+// constructs that cannot be mocked are reported as model.UnsupportedConstructErrors along with
+// the model.
+func (g *Generator) Model(source string, interfaces ...string) (*model.Package, error) {
+	switch g.options.Mode {
+	case SourceMode:
+		if len(interfaces) != 0 {
+			return nil, fmt.Errorf("SourceMode generates mocks for all interfaces in %v, but got interfaces %v", source, strings.Join(interfaces, ", "))
+		}
+		return gomock.ParseFile(source)
+	case ReflectMode:
+		if len(interfaces) == 0 {
+			importPath, names, err := gomock.ExportedInterfaces(source)
+			if err != nil {
+				return nil, err
+			}
+			if len(names) == 0 {
+				return nil, fmt.Errorf("%v declares no exported interfaces", source)
+			}
+			source, interfaces = importPath, names
+		}
+		return gomock.Reflect(source, interfaces)
+	default:
+		return nil, fmt.Errorf("Unknown Mode %v", g.options.Mode)
+	}
+}
+
+func sourceDescription(mode Mode, source string, interfaces []string) string {
+	if mode == SourceMode || len(interfaces) == 0 {
+		return source
+	}
+	return fmt.Sprintf("%v (interfaces: %v)", source, strings.Join(interfaces, ","))
+}
@@ -0,0 +1,85 @@
+package mockgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/petergtz/pegomock/model"
+)
+
+// generateControlledMockType generates the type of a mock in GomockStyle, which is created with
+// a pegomock.Controller like gomock's mocks.
+func (g *generator) generateControlledMockType(iface *model.Interface, mockTypeName, selfPackage string) {
+	g.
+		emptyLine().
+		generateTypeDoc(mockTypeName, "a mock", iface).
+		p("type %v%v struct {", mockTypeName, g.typeParams).
+		p("	fail func(message string, callerSkip ...int)").
+		p("	ctrl *pegomock.Controller")
+	g.generateEmbeddedType(iface, selfPackage)
+	g.
+		p("}").
+		emptyLine().
+		p("func New%v%v(ctrl *pegomock.Controller, options ...pegomock.Option) *%v%v {", mockTypeName, g.typeParams, mockTypeName, g.typeArgs).
+		p("	mock := &%v%v{fail: pegomock.GlobalFailHandler, ctrl: ctrl}", mockTypeName, g.typeArgs).
+		p("	ctrl.RegisterMock(mock)").
+		p("	for _, option := range options {").
+		p("		option(mock)").
+		p("	}").
+		p("	return mock").
+		p("}").
+		emptyLine()
+	if g.typeParams == "" {
+		g.
+			p("func init() {").
+			p("	pegomock.RegisterMockFactory(func() pegomock.Mock { return New%v(nil) })", mockTypeName).
+			p("}").
+			emptyLine()
+	}
+}
+
+// generateRecorderFor generates the EXPECT() method of a mock in GomockStyle and the recorder
+// it returns, whose methods take the same arguments as gomock's recorders.
+func (g *generator) generateRecorderFor(iface *model.Interface, mockTypeName, selfPackage string) {
+	recorderTypeName := mockTypeName + "MockRecorder"
+	g.
+		p("type %v%v struct {", recorderTypeName, g.typeParams).
+		p("	mock *%v%v", mockTypeName, g.typeArgs).
+		p("}").
+		emptyLine().
+		p("func (mock *%v%v) EXPECT() *%v%v {", mockTypeName, g.typeArgs, recorderTypeName, g.typeArgs).
+		p("	return &%v%v{mock: mock}", recorderTypeName, g.typeArgs).
+		p("}").
+		emptyLine()
+	for _, method := range iface.Methods {
+		_, argNames, _, returnTypes := argDataFor(method, g.packageMap, selfPackage)
+		recorderArgs := make([]string, len(argNames))
+		for i, argName := range argNames {
+			recorderArgs[i] = argName + " interface{}"
+		}
+		args := join(argNames)
+		if method.Variadic != nil {
+			recorderArgs[len(recorderArgs)-1] = argNames[len(argNames)-1] + " ...interface{}"
+			args = argNames[len(argNames)-1] + "..."
+			if len(argNames) > 1 {
+				args = fmt.Sprintf("append([]interface{}{%v}, %v)...", join(argNames[:len(argNames)-1]), args)
+			}
+		}
+		reflectReturnTypes := "nil"
+		if len(returnTypes) > 0 {
+			reflectReturnTypeList := make([]string, len(returnTypes))
+			for i, returnType := range returnTypes {
+				reflectReturnTypeList[i] = fmt.Sprintf("reflect.TypeOf((*%v)(nil)).Elem()", returnType)
+			}
+			reflectReturnTypes = fmt.Sprintf("[]reflect.Type{%v}", strings.Join(reflectReturnTypeList, ", "))
+		}
+		if args != "" {
+			args = ", " + args
+		}
+		g.
+			p("func (recorder *%v%v) %v(%v) *pegomock.Call {", recorderTypeName, g.typeArgs, method.Name, join(recorderArgs)).
+			p("	return recorder.mock.ctrl.RecordCall(recorder.mock, \"%v\", %v%v)", method.Name, reflectReturnTypes, args).
+			p("}").
+			emptyLine()
+	}
+}
@@ -0,0 +1,55 @@
+package mockgen
+
+import (
+	"github.com/petergtz/pegomock/model"
+)
+
+var injectionImportPaths = map[string]string{
+	WireInjection: "github.com/google/wire",
+	FxInjection:   "go.uber.org/fx",
+}
+
+// injectedInterfaces returns the interfaces whose mocks get dependency injection glue, i.e. all
+// of them if Options.DependencyInjection is set, except for those the generated code cannot
+// refer to and generic ones.
+func (g *generator) injectedInterfaces(ifaces []*model.Interface, pkgPath string) []*model.Interface {
+	if g.injection == "" {
+		return nil
+	}
+	var injected []*model.Interface
+	for _, iface := range ifaces {
+		if canAssertImplementationOf(iface, pkgPath) {
+			injected = append(injected, iface)
+		}
+	}
+	return injected
+}
+
+// generateInjectionFor generates the glue that provides the mock of iface to the dependency
+// injection framework, as the mock itself, so tests can stub and verify it, and as iface.
+func (g *generator) generateInjectionFor(iface *model.Interface, pkgPath, selfPackage string) {
+	mockTypeName := g.names.mock(iface.Name)
+	interfaceType := (&model.NamedType{Package: pkgPath, Type: iface.Name}).String(g.packageMap, selfPackage)
+	framework := g.packageMap[injectionImportPaths[g.injection]]
+	switch g.injection {
+	case WireInjection:
+		g.
+			p("// Provide%v provides a new %v to wire injectors.", mockTypeName, mockTypeName).
+			p("func Provide%v() *%v {", mockTypeName, mockTypeName).
+			p("	return New%v()", mockTypeName).
+			p("}").
+			emptyLine().
+			p("// %vProviderSet provides a new %v as both *%v and %v.", mockTypeName, mockTypeName, mockTypeName, interfaceType).
+			p("var %vProviderSet = %v.NewSet(Provide%v, %v.Bind(new(%v), new(*%v)))",
+				mockTypeName, framework, mockTypeName, framework, interfaceType, mockTypeName).
+			emptyLine()
+	case FxInjection:
+		g.
+			p("// %vModule provides a new %v as both *%v and %v.", mockTypeName, mockTypeName, mockTypeName, interfaceType).
+			p("var %vModule = %v.Provide(", mockTypeName, framework).
+			p("	func() *%v { return New%v() },", mockTypeName, mockTypeName).
+			p("	func(mock *%v) %v { return mock },", mockTypeName, interfaceType).
+			p(")").
+			emptyLine()
+	}
+}
@@ -0,0 +1,139 @@
+package mockgen
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/petergtz/pegomock/model"
+)
+
+// InputHash returns a hash of everything the mocks generated for pkg with opts depend on: the
+// interfaces with their methods, opts and the generator itself. With Options.RecordInputHash, it
+// is recorded in the generated code, so mocks whose input didn't change need not be generated
+// again. See RecordedInputHash.
+func InputHash(pkg *model.Package, opts Options) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "generator %v\n", generatorVersion())
+	fmt.Fprintf(hash, "options %#v\n", opts)
+	writeInterfaces(hash, pkg)
+	writeDocs(hash, pkg)
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// InterfaceHash returns a hash of the interfaces of pkg with their methods. It is recorded in
+// the header of all generated code, so mocks of interfaces that changed since can be detected.
+// See RecordedInterfaceHash.
+func InterfaceHash(pkg *model.Package) string {
+	hash := sha256.New()
+	writeInterfaces(hash, pkg)
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+func writeInterfaces(hash io.Writer, pkg *model.Package) {
+	fmt.Fprintf(hash, "package %v %v %v\n", pkg.Name, pkg.PkgPath, pkg.DotImports)
+
+	// Types are rendered with their full import paths, so moving a type to another package changes the hash.
+	packageMap := make(map[string]string)
+	for importPath := range pkg.Imports() {
+		packageMap[importPath] = strconv.Quote(importPath)
+	}
+	for _, iface := range pkg.Interfaces {
+		fmt.Fprintf(hash, "interface %v func=%v struct=%v\n", iface.Name, iface.IsFuncType, iface.IsStruct)
+		for _, typeParam := range iface.TypeParams {
+			fmt.Fprintf(hash, "type param %v %v\n", typeParam.Name, hashedTypeString(typeParam.Constraint, packageMap))
+		}
+		for _, method := range iface.Methods {
+			fmt.Fprintf(hash, "method %v\n", method.Name)
+			writeParams(hash, "in", method.In, packageMap)
+			if method.Variadic != nil {
+				writeParams(hash, "variadic", []*model.Parameter{method.Variadic}, packageMap)
+			}
+			writeParams(hash, "out", method.Out, packageMap)
+		}
+	}
+}
+
+// writeDocs writes the doc comments of the interfaces of pkg and their methods, which end up in the
+// mocks, but are not part of the interfaces the mocks have to implement.
+func writeDocs(hash io.Writer, pkg *model.Package) {
+	for _, iface := range pkg.Interfaces {
+		fmt.Fprintf(hash, "doc %v %q\n", iface.Name, iface.Doc)
+		for _, method := range iface.Methods {
+			fmt.Fprintf(hash, "doc %v.%v %q\n", iface.Name, method.Name, method.Doc)
+		}
+	}
+}
+
+func writeParams(w io.Writer, kind string, params []*model.Parameter, packageMap map[string]string) {
+	for _, param := range params {
+		fmt.Fprintf(w, "%v %v %v\n", kind, param.Name, hashedTypeString(param.Type, packageMap))
+	}
+}
+
+// hashedTypeString renders t like model.Type.String, but also renders unsupported types,
+// which are reported by the generator, not by the hashes.
+func hashedTypeString(t model.Type, packageMap map[string]string) string {
+	s, err := model.TypeString(t, packageMap, "")
+	if err != nil {
+		return err.Error()
+	}
+	return s
+}
+
+var (
+	inputHashPattern     = regexp.MustCompile(`(?m)^// Input hash: ([0-9a-f]+)$`)
+	interfaceHashPattern = regexp.MustCompile(`(?m)^// Interface hash: ([0-9a-f]+)\r?$`)
+)
+
+// RecordedInputHash returns the input hash recorded in source, code generated with
+// Options.RecordInputHash, or "" if there is none.
+func RecordedInputHash(source []byte) string {
+	match := inputHashPattern.FindSubmatch(source)
+	if match == nil {
+		return ""
+	}
+	return string(match[1])
+}
+
+// RecordedInterfaceHash returns the InterfaceHash recorded in source, code generated by
+// pegomock, or "" if there is none.
+func RecordedInterfaceHash(source []byte) string {
+	match := interfaceHashPattern.FindSubmatch(source)
+	if match == nil {
+		return ""
+	}
+	return string(match[1])
+}
+
+var (
+	generatorVersionOnce  sync.Once
+	generatorVersionValue string
+)
+
+// generatorVersion identifies the running generator by a hash of its executable, so
+// any change to it, like an update of pegomock, changes the input hashes.
+func generatorVersion() string {
+	generatorVersionOnce.Do(func() {
+		generatorVersionValue = "unknown"
+		executable, err := os.Executable()
+		if err != nil {
+			return
+		}
+		file, err := os.Open(executable)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return
+		}
+		generatorVersionValue = fmt.Sprintf("%x", hash.Sum(nil))
+	})
+	return generatorVersionValue
+}
@@ -19,69 +19,629 @@
 package mockgen
 
 // TODO: This does not support recursive embedded interfaces.
-// TODO: This does not support embedding package-local interfaces in a separate file.
 
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
+	"go/build/constraint"
 	"go/format"
+	"go/parser"
 	"go/token"
+	"io/ioutil"
 	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"unicode"
 
 	"github.com/petergtz/pegomock/model"
 )
 
-const mockFrameworkImportPath = "github.com/petergtz/pegomock"
+const (
+	mockFrameworkImportPath = "github.com/petergtz/pegomock"
+	defaultMockNameFormat   = "Mock%s"
+	defaultFakeNameFormat   = "Fake%s"
+	defaultMatchersPackage  = "matchers"
+	generatedCodeComment    = "// Code generated by pegomock. DO NOT EDIT."
 
-func GenerateOutput(ast *model.Package, source, packageOut, selfPackage string) ([]byte, map[string]string) {
-	g := generator{typesSet: make(map[string]string)}
-	g.generateCode(source, ast, packageOut, selfPackage)
-	return g.formattedOutput(), g.typesSet
+	defaultVerifierNameTemplate            = "Verifier{{.Interface}}"
+	defaultOngoingVerificationNameTemplate = "{{.Interface}}_{{.Method}}_OngoingVerification"
+)
+
+// Options configures Generate. Regardless of them, the header of the generated code records
+// the Version of pegomock, the GeneratedCodeVersion and the InterfaceHash.
+type Options struct {
+	// Source describes where the interfaces come from. It only appears in the header of the generated code.
+	Source string
+	// PackageOut is the package of the generated code. Required.
+	PackageOut string
+	// SelfPackage is the import path of PackageOut. Types from this package are not qualified.
+	// If empty and PackageOut is the package of the interfaces, it defaults to model.Package.PkgPath.
+	SelfPackage string
+	// MockNameFormat determines the mock type names from the interface names. It must contain
+	// exactly one %s. Defaults to "Mock%s".
+	MockNameFormat string
+	// NameTemplates determine the names of the generated types.
+	NameTemplates NameTemplates
+	// GenerateBuilders additionally generates a builder for every mock, e.g. MockDisplayBuilder,
+	// with chainable methods to stub all invocations of a method and a Build method.
+	GenerateBuilders bool
+	// Command is the command that generated the code. If set, it appears in the header of the
+	// generated code, so users of the mocks can regenerate them.
+	Command string
+	// MatchersPackage is the package of the generated matchers. Defaults to "matchers".
+	MatchersPackage string
+	// Header replaces the "Code generated by pegomock" comment at the top of the generated
+	// mocks and matchers, e.g. with a license banner. Lines that are not comments yet are
+	// turned into // comments. Since tools recognize generated code by a line like
+	// "// Code generated ... DO NOT EDIT.", the default one is appended if Header lacks it.
+	Header string
+	// BuildTags is a build constraint expression like "integration && !windows". It is written
+	// at the top of the generated mocks and matchers as //go:build and // +build lines.
+	BuildTags string
+	// Style is the style of the generated mocks, PegomockStyle, TestifyStyle, GomockStyle or
+	// FakeStyle. Defaults to PegomockStyle.
+	Style string
+	// DependencyInjection additionally generates glue for a dependency injection framework,
+	// WireInjection or FxInjection, that provides each mock as itself and as its interface.
+	// Only supported with PegomockStyle.
+	DependencyInjection string
+	// RecordInputHash records the InputHash in the header of the generated code, so callers can
+	// skip generating mocks whose input didn't change. It is not recorded if some interfaces
+	// were skipped, so they are reported again.
+	RecordInputHash bool
+	// Template is a Go text/template that replaces the built-in styles, e.g. to generate
+	// another kind of mocks, stubs or documentation. It is executed with TemplateData; output
+	// that is Go code is formatted. Style, GenerateBuilders and DependencyInjection don't apply,
+	// and no matchers are generated.
+	Template string
+	// Methods restricts the mocks to the methods with these names, e.g. the few methods of a
+	// gigantic interface that a test exercises. The mocks embed their interfaces to implement
+	// the other methods, so calling them panics. Interfaces that cannot be embedded, like
+	// function types, are skipped. Cannot be used with Template.
+	Methods []string
+}
+
+// Styles of the generated mocks, see Options.Style.
+const (
+	// PegomockStyle mocks are used with When and VerifyWasCalled.
+	PegomockStyle = "pegomock"
+	// TestifyStyle mocks embed testify's mock.Mock and are used with On and AssertExpectations,
+	// e.g. by teams migrating between the two. Spies and builders are not supported.
+	TestifyStyle = "testify"
+	// GomockStyle mocks are pegomock mocks that are created with a pegomock.Controller and
+	// additionally have gomock's EXPECT() recorder API, so gomock-based tests can switch to
+	// pegomock without rewriting their expectations. Spies and builders are not supported.
+	GomockStyle = "gomock"
+	// FakeStyle generates counterfeiter-like fakes, named Fake<Interface> by default, with
+	// Returns, Stub, CallCount and ArgsForCall per method. They don't depend on the pegomock
+	// runtime. Spies and builders are not supported.
+	FakeStyle = "fake"
+)
+
+// Dependency injection frameworks, see Options.DependencyInjection.
+const (
+	// WireInjection generates a Provide<Mock> function and a <Mock>ProviderSet for
+	// github.com/google/wire.
+	WireInjection = "wire"
+	// FxInjection generates a <Mock>Module, an fx.Option for go.uber.org/fx.
+	FxInjection = "fx"
+)
+
+// NameTemplates are Go text/templates for the names of the generated types. The field
+// .Interface holds the name of the interface and, for OngoingVerification, .Method the name
+// of the method. Empty templates keep the default names.
+type NameTemplates struct {
+	// Mock, e.g. "{{.Interface}}Fake", takes precedence over Options.MockNameFormat.
+	Mock string
+	// Verifier defaults to "Verifier{{.Interface}}".
+	Verifier string
+	// OngoingVerification defaults to "{{.Interface}}_{{.Method}}_OngoingVerification".
+	OngoingVerification string
+}
+
+// Generate generates the source code of mocks for all interfaces in pkg. Like GenerateOutput,
+// it skips interfaces using constructs that cannot be mocked and reports them as
+// model.UnsupportedConstructErrors along with the code for the remaining interfaces.
+func Generate(pkg *model.Package, opts Options) ([]byte, error) {
+	output, _, err := GenerateWithMatchers(pkg, opts)
+	return output, err
+}
+
+// GenerateOutput generates mocks for all interfaces in ast. Interfaces using constructs
+// that cannot be mocked are skipped and reported together as model.UnsupportedConstructErrors.
+// The output for all other interfaces is still returned; it is nil if no mock could be generated.
+// The returned map contains the source code of matchers for all parameter and return types.
+func GenerateOutput(ast *model.Package, source, packageOut, selfPackage string) ([]byte, map[string]string, error) {
+	return GenerateWithMatchers(ast, Options{Source: source, PackageOut: packageOut, SelfPackage: selfPackage})
+}
+
+// GenerateWithMatchers is like Generate, but additionally returns the source code of
+// matchers for all parameter and return types, keyed by file name without extension.
+func GenerateWithMatchers(pkg *model.Package, opts Options) ([]byte, map[string]string, error) {
+	inputHash := ""
+	if opts.RecordInputHash {
+		inputHash = InputHash(pkg, opts)
+	}
+	if opts.MockNameFormat == "" && opts.Style == FakeStyle {
+		opts.MockNameFormat = defaultFakeNameFormat
+	}
+	if opts.MockNameFormat == "" {
+		opts.MockNameFormat = defaultMockNameFormat
+	}
+	if opts.MatchersPackage == "" {
+		opts.MatchersPackage = defaultMatchersPackage
+	}
+	if opts.Style == "" {
+		opts.Style = PegomockStyle
+	}
+	if err := validate(opts); err != nil {
+		return nil, nil, err
+	}
+	if err := checkMethodsDeclared(pkg, opts.Methods); err != nil {
+		return nil, nil, err
+	}
+	names, err := newTypeNames(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	buildConstraint, err := buildConstraintComment(opts.BuildTags)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := generator{
+		typesSet:         make(map[string]string),
+		names:            names,
+		generateBuilders: opts.GenerateBuilders,
+		command:          opts.Command,
+		matchersPackage:  opts.MatchersPackage,
+		header:           buildConstraint + headerComment(opts.Header),
+		style:            opts.Style,
+		injection:        opts.DependencyInjection,
+		inputHash:        inputHash,
+		interfaceHash:    InterfaceHash(pkg),
+		methods:          opts.Methods,
+	}
+	if opts.Template != "" {
+		output, unsupported, err := g.generateFromTemplate(opts.Template, opts.Source, pkg, opts.PackageOut, opts.SelfPackage)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(unsupported) != 0 {
+			return output, nil, unsupported
+		}
+		return output, nil, nil
+	}
+	numMocks, unsupported := g.generateCode(opts.Source, pkg, opts.PackageOut, opts.SelfPackage)
+	if len(unsupported) != 0 {
+		if numMocks == 0 {
+			return nil, nil, unsupported
+		}
+		return g.formattedOutput(), g.typesSet, unsupported
+	}
+	return g.formattedOutput(), g.typesSet, nil
+}
+
+func validate(opts Options) error {
+	if opts.PackageOut == "" {
+		return fmt.Errorf("Options.PackageOut must not be empty")
+	}
+	if opts.Style != PegomockStyle && opts.Style != TestifyStyle && opts.Style != GomockStyle && opts.Style != FakeStyle {
+		return fmt.Errorf("Options.Style must be %q, %q, %q or %q, but is %q",
+			PegomockStyle, TestifyStyle, GomockStyle, FakeStyle, opts.Style)
+	}
+	if opts.Style != PegomockStyle && opts.GenerateBuilders {
+		return fmt.Errorf("Options.GenerateBuilders is not supported with Options.Style %q", opts.Style)
+	}
+	if opts.DependencyInjection != "" && opts.DependencyInjection != WireInjection && opts.DependencyInjection != FxInjection {
+		return fmt.Errorf("Options.DependencyInjection must be empty, %q or %q, but is %q",
+			WireInjection, FxInjection, opts.DependencyInjection)
+	}
+	if opts.Style != PegomockStyle && opts.DependencyInjection != "" {
+		return fmt.Errorf("Options.DependencyInjection is not supported with Options.Style %q", opts.Style)
+	}
+	if opts.Template != "" && (opts.Style != PegomockStyle || opts.GenerateBuilders || opts.DependencyInjection != "") {
+		return fmt.Errorf("Options.Template cannot be combined with Options.Style, Options.GenerateBuilders or Options.DependencyInjection")
+	}
+	if opts.Template != "" && len(opts.Methods) != 0 {
+		return fmt.Errorf("Options.Template cannot be combined with Options.Methods")
+	}
+	if strings.Count(opts.MockNameFormat, "%s") != 1 || strings.Count(opts.MockNameFormat, "%") != 1 {
+		return fmt.Errorf("Options.MockNameFormat must contain exactly one %%s, but is %q", opts.MockNameFormat)
+	}
+	if mockName := fmt.Sprintf(opts.MockNameFormat, "X"); !isIdentifier(mockName) {
+		return fmt.Errorf("Options.MockNameFormat %q does not produce valid identifiers, e.g. %q", opts.MockNameFormat, mockName)
+	}
+	return nil
+}
+
+// typeNames determines the names of the generated types from NameTemplates.
+type typeNames struct {
+	mockTemplate, verifierTemplate, ongoingVerificationTemplate *template.Template
+}
+
+type typeNameData struct {
+	Interface string
+	Method    string
+}
+
+func newTypeNames(opts Options) (*typeNames, error) {
+	mockTemplate := opts.NameTemplates.Mock
+	if mockTemplate == "" {
+		mockTemplate = strings.Replace(opts.MockNameFormat, "%s", "{{.Interface}}", 1)
+	}
+	verifierTemplate := opts.NameTemplates.Verifier
+	if verifierTemplate == "" {
+		verifierTemplate = defaultVerifierNameTemplate
+	}
+	ongoingVerificationTemplate := opts.NameTemplates.OngoingVerification
+	if ongoingVerificationTemplate == "" {
+		ongoingVerificationTemplate = defaultOngoingVerificationNameTemplate
+	}
+
+	var names typeNames
+	var err error
+	if names.mockTemplate, err = parseNameTemplate("Mock", mockTemplate); err != nil {
+		return nil, err
+	}
+	if names.verifierTemplate, err = parseNameTemplate("Verifier", verifierTemplate); err != nil {
+		return nil, err
+	}
+	if names.ongoingVerificationTemplate, err = parseNameTemplate("OngoingVerification", ongoingVerificationTemplate); err != nil {
+		return nil, err
+	}
+
+	// Different interfaces and methods must result in different names, and the kinds of types must not collide either.
+	if names.mock("X") == names.mock("Y") {
+		return nil, fmt.Errorf("Options.NameTemplates.Mock %q must use .Interface", mockTemplate)
+	}
+	if names.verifier("X") == names.verifier("Y") {
+		return nil, fmt.Errorf("Options.NameTemplates.Verifier %q must use .Interface", verifierTemplate)
+	}
+	if names.ongoingVerification("X", "A") == names.ongoingVerification("Y", "A") ||
+		names.ongoingVerification("X", "A") == names.ongoingVerification("X", "B") {
+		return nil, fmt.Errorf("Options.NameTemplates.OngoingVerification %q must use .Interface and .Method", ongoingVerificationTemplate)
+	}
+	if names.mock("X") == names.verifier("X") {
+		return nil, fmt.Errorf("Options.NameTemplates result in the same name %q for mocks and verifiers", names.mock("X"))
+	}
+	for _, name := range []string{names.mock("X"), names.verifier("X"), names.ongoingVerification("X", "A")} {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("Options.NameTemplates do not produce valid identifiers, e.g. %q", name)
+		}
+	}
+	return &names, nil
+}
+
+func parseNameTemplate(kind string, text string) (*template.Template, error) {
+	tmpl, err := template.New(kind).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Options.NameTemplates.%v: %v", kind, err)
+	}
+	if err := tmpl.Execute(ioutil.Discard, typeNameData{"X", "A"}); err != nil {
+		return nil, fmt.Errorf("Invalid Options.NameTemplates.%v: %v", kind, err)
+	}
+	return tmpl, nil
+}
+
+func (names *typeNames) mock(interfaceName string) string {
+	return executeNameTemplate(names.mockTemplate, typeNameData{Interface: interfaceName})
+}
+
+func (names *typeNames) verifier(interfaceName string) string {
+	return executeNameTemplate(names.verifierTemplate, typeNameData{Interface: interfaceName})
+}
+
+func (names *typeNames) ongoingVerification(interfaceName string, methodName string) string {
+	return executeNameTemplate(names.ongoingVerificationTemplate, typeNameData{Interface: interfaceName, Method: methodName})
+}
+
+func executeNameTemplate(tmpl *template.Template, data typeNameData) string {
+	var name bytes.Buffer
+	if err := tmpl.Execute(&name, data); err != nil {
+		// Templates are executed once when parsed, so this is a programming error.
+		panic(err)
+	}
+	return name.String()
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !(unicode.IsLetter(r) || r == '_' || i > 0 && unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != "" && !token.Lookup(s).IsKeyword()
 }
 
 type generator struct {
-	buf        bytes.Buffer
-	packageMap map[string]string // map from import path to package name
-	typesSet   map[string]string
+	buf              bytes.Buffer
+	packageMap       map[string]string // map from import path to package name
+	typesSet         map[string]string
+	names            *typeNames
+	generateBuilders bool
+	typeNamesInUse   map[string]bool
+	command          string
+	matchersPackage  string
+	header           string // including build constraints
+	style            string
+	injection        string
+	inputHash        string
+	interfaceHash    string
+	// Type parameters of the interface whose mock is being generated, as declared,
+	// e.g. "[T any]", and as used, e.g. "[T]". Both are empty for non-generic interfaces.
+	typeParams string
+	typeArgs   string
+	// Types the mocks of interfaces must embed, keyed by interface name, see grpcServerEmbedding
+	// and Options.Methods.
+	embeddedTypes map[string]*model.NamedType
+	methods       []string
 }
 
-func (g *generator) generateCode(source string, pkg *model.Package, pkgName, selfPackage string) {
-	g.p("// Code generated by pegomock. DO NOT EDIT.")
+func (g *generator) generateCode(source string, pkg *model.Package, pkgName, selfPackage string) (int, model.UnsupportedConstructErrors) {
+	if selfPackage == "" && pkgName == pkg.Name {
+		// The mocks live in the package of the interfaces, which must not import itself.
+		selfPackage = pkg.PkgPath
+	}
+
+	// External test packages cannot be imported, so mocks outside of them cannot refer to their interfaces.
+	interfacesPkgPath := pkg.PkgPath
+	if pkgName != pkg.Name && strings.HasSuffix(pkg.Name, "_test") {
+		interfacesPkgPath = ""
+	}
+
+	var supportedInterfaces []*model.Interface
+	var unsupported model.UnsupportedConstructErrors
+	g.embeddedTypes = make(map[string]*model.NamedType)
+	for _, iface := range pkg.Interfaces {
+		if errs := unsupportedConstructsIn(iface); len(errs) != 0 {
+			unsupported = append(unsupported, errs...)
+			continue
+		}
+		if iface.IsStruct && g.style != PegomockStyle {
+			unsupported = append(unsupported, &model.UnsupportedConstructError{
+				Interface: iface.Name,
+				Position:  "struct type",
+				Reason:    fmt.Sprintf("spies of struct types are not supported in the %v style", g.style),
+			})
+			continue
+		}
+		if iface.IsStruct && !canReferTo(iface, interfacesPkgPath, pkgName == pkg.Name) {
+			unsupported = append(unsupported, &model.UnsupportedConstructError{
+				Interface: iface.Name,
+				Position:  "struct type",
+				Reason:    "a spy must refer to the struct type it wraps, which is unexported or in an unknown package",
+			})
+			continue
+		}
+		if embeddedType, methods := grpcServerEmbedding(iface, interfacesPkgPath); embeddedType != nil {
+			g.embeddedTypes[iface.Name] = embeddedType
+			iface = &model.Interface{Name: iface.Name, Methods: methods}
+		}
+		if len(g.methods) != 0 && !iface.IsStruct {
+			if g.embeddedTypes[iface.Name] == nil {
+				if iface.IsFuncType || !canReferTo(iface, interfacesPkgPath, pkgName == pkg.Name) {
+					unsupported = append(unsupported, &model.UnsupportedConstructError{
+						Interface: iface.Name,
+						Position:  "method subset",
+						Reason:    "a mock of a subset of the methods must embed the interface, which is a function type, unexported or in an unknown package",
+					})
+					continue
+				}
+				g.embeddedTypes[iface.Name] = typeOf(iface, interfacesPkgPath)
+			}
+			iface = &model.Interface{Name: iface.Name, TypeParams: iface.TypeParams, Methods: methodsNamed(iface.Methods, g.methods)}
+		}
+		supportedInterfaces = append(supportedInterfaces, iface)
+	}
+
+	g.p("%v", g.header)
 	g.p("// Source: %v", source)
+	if g.command != "" {
+		g.p("// Command: %v", g.command)
+	}
+	g.p("// Generator: pegomock %v, generated code version %v", Version(), GeneratedCodeVersion)
+	g.p("// Interface hash: %v", g.interfaceHash)
+	if g.inputHash != "" && len(unsupported) == 0 {
+		g.p("// Input hash: %v", g.inputHash)
+	}
 	g.emptyLine()
 
-	importPaths := pkg.Imports()
-	importPaths[mockFrameworkImportPath] = true
+	importPaths := (&model.Package{Interfaces: supportedInterfaces}).Imports()
+	switch g.style {
+	case TestifyStyle:
+		importPaths[testifyMockImportPath] = true
+	case FakeStyle:
+	default:
+		importPaths[mockFrameworkImportPath] = true
+	}
+	for _, iface := range supportedInterfaces {
+		if canAssertImplementationOf(iface, interfacesPkgPath) ||
+			(iface.IsFuncType || iface.IsStruct) && canReferTo(iface, interfacesPkgPath, pkgName == pkg.Name) {
+			importPaths[interfacesPkgPath] = true
+		}
+	}
+	if len(g.embeddedTypes) != 0 {
+		importPaths[interfacesPkgPath] = true
+	}
+	injectedInterfaces := g.injectedInterfaces(supportedInterfaces, interfacesPkgPath)
+	if len(injectedInterfaces) != 0 {
+		importPaths[injectionImportPaths[g.injection]] = true
+	}
 	packageMap, nonVendorPackageMap := generateUniquePackageNamesFor(importPaths)
 	g.packageMap = packageMap
 
 	g.p("package %v", pkgName)
 	g.emptyLine()
 	g.p("import (")
-	g.p("\"reflect\"")
-	for packagePath, packageName := range nonVendorPackageMap {
+	switch g.style {
+	case TestifyStyle:
+	case FakeStyle:
+		g.p("\"sync\"")
+	default:
+		if anyMethodReturnsValues(supportedInterfaces) {
+			g.p("\"reflect\"")
+		}
+		g.p("\"time\"")
+	}
+	for _, packagePath := range sortedKeys(nonVendorPackageMap) {
 		if packagePath != selfPackage {
-			g.p("%v %q", packageName, packagePath)
+			g.p("%v %q", nonVendorPackageMap[packagePath], packagePath)
 		}
 	}
 	for _, packagePath := range pkg.DotImports {
 		g.p(". %q", packagePath)
 	}
 	g.p(")")
+	if g.style != TestifyStyle && g.style != FakeStyle {
+		g.emptyLine()
+		g.p("const _ = pegomock.SupportsGeneratedCodeVersion%v", GeneratedCodeVersion)
+	}
 
-	for _, iface := range pkg.Interfaces {
-		g.generateMockFor(iface, selfPackage)
+	g.typeNamesInUse = make(map[string]bool)
+	for _, iface := range supportedInterfaces {
+		g.typeNamesInUse[g.names.mock(iface.Name)] = true
+	}
+	for _, iface := range supportedInterfaces {
+		switch g.style {
+		case TestifyStyle:
+			g.generateTestifyMockFor(iface, interfacesPkgPath, selfPackage, pkgName == pkg.Name)
+		case FakeStyle:
+			g.generateFakeFor(iface, interfacesPkgPath, selfPackage, pkgName == pkg.Name)
+		default:
+			g.generateMockFor(iface, interfacesPkgPath, selfPackage, pkgName == pkg.Name)
+		}
 	}
+	for _, iface := range injectedInterfaces {
+		g.generateInjectionFor(iface, interfacesPkgPath, selfPackage)
+	}
+	return len(supportedInterfaces), unsupported
+}
+
+// grpcServerEmbedding returns the type a mock of iface must embed, and the methods it must
+// implement itself, if iface is a gRPC server interface generated by protoc-gen-go-grpc. These
+// require their implementations to embed Unimplemented<Server>, which implements the unexported
+// method mustEmbedUnimplemented<Server>.
+func grpcServerEmbedding(iface *model.Interface, pkgPath string) (*model.NamedType, []*model.Method) {
+	if pkgPath == "" || iface.IsFuncType || iface.IsStruct || len(iface.TypeParams) != 0 || !ast.IsExported(iface.Name) {
+		return nil, nil
+	}
+	for i, method := range iface.Methods {
+		if method.Name == "mustEmbedUnimplemented"+iface.Name && len(method.In) == 0 && method.Variadic == nil && len(method.Out) == 0 {
+			methods := append(append([]*model.Method{}, iface.Methods[:i]...), iface.Methods[i+1:]...)
+			return &model.NamedType{Package: pkgPath, Type: "Unimplemented" + iface.Name}, methods
+		}
+	}
+	return nil, nil
+}
+
+// checkMethodsDeclared returns an error if one of methods is not declared by any interface of pkg,
+// e.g. because of a typo.
+func checkMethodsDeclared(pkg *model.Package, methods []string) error {
+	for _, name := range methods {
+		declared := false
+		for _, iface := range pkg.Interfaces {
+			declared = declared || len(methodsNamed(iface.Methods, []string{name})) != 0
+		}
+		if !declared {
+			return fmt.Errorf("None of the interfaces declares a method %v", name)
+		}
+	}
+	return nil
+}
+
+// methodsNamed returns those of methods whose names are in names.
+func methodsNamed(methods []*model.Method, names []string) []*model.Method {
+	var named []*model.Method
+	for _, method := range methods {
+		for _, name := range names {
+			if method.Name == name {
+				named = append(named, method)
+				break
+			}
+		}
+	}
+	return named
+}
+
+// generateEmbeddedType generates the embedded field of the mock of iface, if it needs one.
+func (g *generator) generateEmbeddedType(iface *model.Interface, selfPackage string) {
+	if embeddedType := g.embeddedTypes[iface.Name]; embeddedType != nil {
+		g.p("%v", embeddedType.String(g.packageMap, selfPackage))
+	}
+}
+
+// canAssertImplementationOf reports whether the generated code can refer to iface
+// to assert at compile time that its mock implements it.
+func canAssertImplementationOf(iface *model.Interface, pkgPath string) bool {
+	if pkgPath == "" || iface.IsFuncType || iface.IsStruct || !ast.IsExported(iface.Name) || len(iface.TypeParams) != 0 {
+		return false
+	}
+	for _, method := range iface.Methods {
+		if !ast.IsExported(method.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// canReferTo reports whether the generated code can refer to the type iface stands for, e.g.
+// to return the mock of a function type as that function type.
+func canReferTo(iface *model.Interface, pkgPath string, inSamePackage bool) bool {
+	return inSamePackage || pkgPath != "" && ast.IsExported(iface.Name)
+}
+
+func unsupportedConstructsIn(iface *model.Interface) (errs model.UnsupportedConstructErrors) {
+	for _, tp := range iface.TypeParams {
+		if err := model.CheckType(tp.Constraint); err != nil {
+			errs = append(errs, &model.UnsupportedConstructError{
+				Interface: iface.Name,
+				Position:  "type parameter " + tp.Name,
+				Reason:    err.Error(),
+			})
+		}
+	}
+	for _, method := range iface.Methods {
+		for i, param := range method.In {
+			errs = appendIfUnsupported(errs, iface, method, fmt.Sprintf("parameter %v", i), param.Type)
+		}
+		if method.Variadic != nil {
+			errs = appendIfUnsupported(errs, iface, method, "variadic parameter", method.Variadic.Type)
+		}
+		for i, param := range method.Out {
+			errs = appendIfUnsupported(errs, iface, method, fmt.Sprintf("return value %v", i), param.Type)
+		}
+	}
+	return
+}
+
+func appendIfUnsupported(errs model.UnsupportedConstructErrors, iface *model.Interface, method *model.Method, position string, t model.Type) model.UnsupportedConstructErrors {
+	if err := model.CheckType(t); err != nil {
+		return append(errs, &model.UnsupportedConstructError{
+			Interface: iface.Name,
+			Method:    method.Name,
+			Position:  position,
+			Reason:    err.Error(),
+		})
+	}
+	return errs
 }
 
 func generateUniquePackageNamesFor(importPaths map[string]bool) (packageMap, nonVendorPackageMap map[string]string) {
 	packageMap = make(map[string]string, len(importPaths))
 	nonVendorPackageMap = make(map[string]string, len(importPaths))
 	packageNamesAlreadyUsed := make(map[string]bool, len(importPaths))
+	// Ranging over importPaths directly would make the names of colliding packages, and hence
+	// the generated code, differ from run to run.
+	sortedImportPaths := make([]string, 0, len(importPaths))
 	for importPath := range importPaths {
+		sortedImportPaths = append(sortedImportPaths, importPath)
+	}
+	sort.Strings(sortedImportPaths)
+	for _, importPath := range sortedImportPaths {
 		sanitizedPackagePathBaseName := sanitize(path.Base(importPath))
 
 		// Local names for an imported package can usually be the basename of the import path.
@@ -102,11 +662,23 @@ func generateUniquePackageNamesFor(importPaths map[string]bool) (packageMap, non
 	return
 }
 
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// vendorCleaned returns importPath without the vendor directory it is in, if any, e.g.
+// "github.com/x/y" for "example.com/app/vendor/github.com/x/y". For nested vendor directories,
+// the innermost one counts, like for the go command.
 func vendorCleaned(importPath string) string {
-	if split := strings.Split(importPath, "/vendor/"); len(split) > 1 {
-		return split[1]
+	if i := strings.LastIndex(importPath, "/vendor/"); i != -1 {
+		return importPath[i+len("/vendor/"):]
 	}
-	return importPath
+	return strings.TrimPrefix(importPath, "vendor/")
 }
 
 // sanitize cleans up a string to make a suitable package name.
@@ -134,56 +706,325 @@ func sanitize(s string) string {
 	return t
 }
 
-func (g *generator) generateMockFor(iface *model.Interface, selfPackage string) {
-	mockTypeName := "Mock" + iface.Name
-	g.generateMockType(mockTypeName)
+func (g *generator) generateMockFor(iface *model.Interface, pkgPath, selfPackage string, inSamePackage bool) {
+	mockTypeName := g.names.mock(iface.Name)
+	g.typeParams = model.TypeParamsString(iface.TypeParams, g.packageMap, selfPackage)
+	g.typeArgs = model.TypeArgsString(iface.TypeParams)
+	if iface.IsStruct {
+		g.generateSpyType(iface, mockTypeName, pkgPath, selfPackage)
+	} else if g.style == GomockStyle {
+		g.generateControlledMockType(iface, mockTypeName, selfPackage)
+	} else {
+		g.generateMockType(iface, mockTypeName, selfPackage)
+	}
+	if canAssertImplementationOf(iface, pkgPath) {
+		interfaceType := &model.NamedType{Package: pkgPath, Type: iface.Name}
+		g.p("var _ %v = (*%v)(nil)", interfaceType.String(g.packageMap, selfPackage), mockTypeName)
+		g.emptyLine()
+	}
+	if iface.IsFuncType && canReferTo(iface, pkgPath, inSamePackage) {
+		g.generateFuncMethod(iface, mockTypeName, pkgPath, selfPackage)
+	}
+	methodNames := make(map[string]bool, len(iface.Methods))
+	for _, method := range iface.Methods {
+		methodNames[method.Name] = true
+	}
 	for _, method := range iface.Methods {
 		g.generateMockMethod(mockTypeName, method, selfPackage)
 		g.emptyLine()
+		if !methodNames["Answer"+method.Name] {
+			g.generateAnswerMethod(mockTypeName, method, selfPackage)
+		}
 
-		addTypesFromMethodParamsTo(g.typesSet, method.In, g.packageMap)
-		addTypesFromMethodParamsTo(g.typesSet, method.Out, g.packageMap)
+		addTypesFromMethodParamsTo(g.typesSet, method.In, g.packageMap, g.matchersPackage, g.header)
+		addTypesFromMethodParamsTo(g.typesSet, method.Out, g.packageMap, g.matchersPackage, g.header)
 	}
-	g.generateMockVerifyMethods(iface.Name)
-	g.generateVerifierType(iface.Name)
+	verifierTypeName := g.names.verifier(iface.Name)
+	g.generateMockVerifyMethods(verifierTypeName, mockTypeName)
+	g.generateVerifierType(verifierTypeName, mockTypeName)
 	for _, method := range iface.Methods {
-		ongoingVerificationTypeName := fmt.Sprintf("%v_%v_OngoingVerification", iface.Name, method.Name)
+		ongoingVerificationTypeName := g.names.ongoingVerification(iface.Name, method.Name)
 		args, argNames, argTypes, _ := argDataFor(method, g.packageMap, selfPackage)
-		g.generateVerifierMethod(iface.Name, method, selfPackage, ongoingVerificationTypeName, args, argNames)
-		g.generateOngoingVerificationType(iface.Name, ongoingVerificationTypeName)
+		g.generateVerifierMethod(verifierTypeName, method, selfPackage, ongoingVerificationTypeName, args, argNames)
+		g.generateOngoingVerificationType(mockTypeName, ongoingVerificationTypeName)
 		g.generateOngoingVerificationGetCapturedArguments(ongoingVerificationTypeName, argNames, argTypes)
 		g.generateOngoingVerificationGetAllCapturedArguments(ongoingVerificationTypeName, argTypes, method.Variadic != nil)
 	}
+	if g.generateBuilders && !iface.IsStruct {
+		g.generateBuilderFor(iface, mockTypeName, selfPackage)
+	}
+	if g.style == GomockStyle {
+		g.generateRecorderFor(iface, mockTypeName, selfPackage)
+	}
+}
+
+// generateFuncMethod generates the method that returns the mock of a function type as that
+// function type, so it can be passed to the code under test.
+func (g *generator) generateFuncMethod(iface *model.Interface, mockTypeName, pkgPath, selfPackage string) {
+	g.
+		p("func (mock *%v%v) Func() %v {", mockTypeName, g.typeArgs, typeOf(iface, pkgPath).String(g.packageMap, selfPackage)).
+		p("	return mock.%v", model.FuncTypeMethod).
+		p("}").
+		emptyLine()
+}
+
+// typeOf returns the type iface stands for, instantiated with its own type parameters.
+func typeOf(iface *model.Interface, pkgPath string) *model.NamedType {
+	namedType := &model.NamedType{Package: pkgPath, Type: iface.Name}
+	for _, tp := range iface.TypeParams {
+		namedType.TypeArgs = append(namedType.TypeArgs, model.TypeParamType(tp.Name))
+	}
+	return namedType
 }
 
-func (g *generator) generateMockType(mockTypeName string) {
+// uniqueTypeName returns name, or name suffixed with a number if name is already in use,
+// e.g. because there is also an interface DisplayBuilder next to Display.
+func (g *generator) uniqueTypeName(name string) string {
+	uniqueName := name
+	for i := 0; g.typeNamesInUse[uniqueName]; i++ {
+		uniqueName = name + strconv.Itoa(i)
+	}
+	g.typeNamesInUse[uniqueName] = true
+	return uniqueName
+}
+
+func (g *generator) generateBuilderFor(iface *model.Interface, mockTypeName string, pkgOverride string) {
+	builderTypeName := g.uniqueTypeName(mockTypeName + "Builder")
 	g.
+		p("type %v%v struct {", builderTypeName, g.typeParams).
+		p("	stubbings []func(mock *%v%v)", mockTypeName, g.typeArgs).
+		p("}").
 		emptyLine().
-		p("type %v struct {", mockTypeName).
+		p("func New%v%v() *%v%v {", builderTypeName, g.typeParams, builderTypeName, g.typeArgs).
+		p("	return &%v%v{}", builderTypeName, g.typeArgs).
+		p("}").
+		emptyLine()
+	for _, method := range iface.Methods {
+		args, _, argTypes, returnTypes := argDataFor(method, g.packageMap, pkgOverride)
+		g.
+			p("func (builder *%v%v) With%v(answer func(%v) (%v)) *%v%v {", builderTypeName, g.typeArgs, method.Name, join(args), join(returnTypes), builderTypeName, g.typeArgs).
+			p("builder.stubbings = append(builder.stubbings, func(mock *%v%v) {", mockTypeName, g.typeArgs).
+			p("pegomock.StubAllInvocations(mock, %q, func(params []pegomock.Param) pegomock.ReturnValues {", method.Name)
+		g.generateAnswerInvocation(method, argTypes, returnTypes, pkgOverride)
+		g.
+			p("})").
+			p("})").
+			p("return builder").
+			p("}").
+			emptyLine()
+
+		if len(returnTypes) > 0 {
+			returnParams := make([]string, len(returnTypes))
+			returnValues := make([]string, len(returnTypes))
+			for i, returnType := range returnTypes {
+				returnValues[i] = fmt.Sprintf("ret%v", i)
+				returnParams[i] = returnValues[i] + " " + returnType
+			}
+			g.
+				p("func (builder *%v%v) ReturningOn%v(%v) *%v%v {", builderTypeName, g.typeArgs, method.Name, join(returnParams), builderTypeName, g.typeArgs).
+				p("builder.stubbings = append(builder.stubbings, func(mock *%v%v) {", mockTypeName, g.typeArgs).
+				p("pegomock.StubAllInvocations(mock, %q, func([]pegomock.Param) pegomock.ReturnValues {", method.Name).
+				p("return pegomock.ReturnValues{%v}", join(returnValues)).
+				p("})").
+				p("})").
+				p("return builder").
+				p("}").
+				emptyLine()
+		}
+	}
+	g.
+		p("func (builder *%v%v) Build() *%v%v {", builderTypeName, g.typeArgs, mockTypeName, g.typeArgs).
+		p("mock := New%v%v()", mockTypeName, g.typeArgs).
+		p("for _, stub := range builder.stubbings {").
+		p("stub(mock)").
+		p("}").
+		p("return mock").
+		p("}").
+		emptyLine()
+}
+
+// generateAnswerMethod generates a method that turns a typed answer for method into the
+// callback taken by ThenAnswer, e.g. When(mock.Show(AnyString())).ThenAnswer(mock.AnswerShow(...)).
+// It is left out if the interface itself has a method of that name.
+func (g *generator) generateAnswerMethod(mockTypeName string, method *model.Method, pkgOverride string) {
+	args, _, argTypes, returnTypes := argDataFor(method, g.packageMap, pkgOverride)
+	g.
+		p("func (mock *%v%v) Answer%v(answer func(%v) (%v)) func([]pegomock.Param) pegomock.ReturnValues {", mockTypeName, g.typeArgs, method.Name, join(args), join(returnTypes)).
+		p("return func(params []pegomock.Param) pegomock.ReturnValues {")
+	g.generateAnswerInvocation(method, argTypes, returnTypes, pkgOverride)
+	g.
+		p("}").
+		p("}").
+		emptyLine()
+}
+
+// generateAnswerInvocation generates the body of a callback that invokes answer with the
+// arguments converted from params and returns its results as pegomock.ReturnValues.
+func (g *generator) generateAnswerInvocation(method *model.Method, argTypes []string, returnTypes []string, pkgOverride string) {
+	callArgs := g.generateArgsFromParams(method, argTypes, pkgOverride)
+	if len(returnTypes) == 0 {
+		g.
+			p("answer(%v)", join(callArgs)).
+			p("return nil")
+		return
+	}
+	returnValues := make([]string, len(returnTypes))
+	for i := range returnTypes {
+		returnValues[i] = fmt.Sprintf("ret%v", i)
+	}
+	g.
+		p("%v := answer(%v)", join(returnValues), join(callArgs)).
+		p("return pegomock.ReturnValues{%v}", join(returnValues))
+}
+
+// generateArgsFromParams generates the variables _arg0, _arg1, ... holding the arguments of an
+// invocation of method, converted from params. It returns the expressions to pass them on.
+func (g *generator) generateArgsFromParams(method *model.Method, argTypes []string, pkgOverride string) []string {
+	callArgs := make([]string, len(argTypes))
+	for i, argType := range argTypes {
+		callArgs[i] = fmt.Sprintf("_arg%v", i)
+		if method.Variadic != nil && i == len(argTypes)-1 {
+			variadicType := method.Variadic.Type.String(g.packageMap, pkgOverride)
+			// Like Go, pass nil if there are no variadic arguments.
+			g.
+				p("var _arg%v %v", i, argType).
+				p("if len(params) > %v {", i).
+				p("_arg%v = make(%v, len(params)-%v)", i, argType, i).
+				p("for u, param := range params[%v:] {", i).
+				p("if param != nil {").
+				p("_arg%v[u] = param.(%v)", i, variadicType).
+				p("}").
+				p("}").
+				p("}")
+			callArgs[i] += "..."
+		} else {
+			g.
+				p("var _arg%v %v", i, argType).
+				p("if params[%v] != nil {", i).
+				p("_arg%v = params[%v].(%v)", i, i, argType).
+				p("}")
+		}
+	}
+	return callArgs
+}
+
+func (g *generator) generateMockType(iface *model.Interface, mockTypeName, selfPackage string) {
+	g.
+		emptyLine().
+		generateTypeDoc(mockTypeName, "a mock", iface).
+		p("type %v%v struct {", mockTypeName, g.typeParams).
+		p("	fail func(message string, callerSkip ...int)")
+	g.generateEmbeddedType(iface, selfPackage)
+	g.
+		p("}").
+		emptyLine().
+		p("func New%v%v(options ...pegomock.Option) *%v%v {", mockTypeName, g.typeParams, mockTypeName, g.typeArgs).
+		p("	mock := &%v%v{fail: pegomock.GlobalFailHandler}", mockTypeName, g.typeArgs).
+		p("	for _, option := range options {").
+		p("		option(mock)").
+		p("	}").
+		p("	return mock").
+		p("}").
+		emptyLine()
+	// Generic mocks cannot be registered, because a factory would have to pick type arguments.
+	if g.typeParams == "" {
+		g.
+			p("func init() {").
+			p("	pegomock.RegisterMockFactory(func() pegomock.Mock { return New%v() })", mockTypeName).
+			p("}").
+			emptyLine()
+	}
+}
+
+// generateSpyType generates a mock that answers all invocations without a matching stubbing
+// by invoking the same method on the wrapped instance of the struct type.
+func (g *generator) generateSpyType(iface *model.Interface, mockTypeName, pkgPath, selfPackage string) {
+	wrappedType := (&model.PointerType{Type: typeOf(iface, pkgPath)}).String(g.packageMap, selfPackage)
+	g.
+		emptyLine().
+		generateTypeDoc(mockTypeName, "a spy", iface).
+		p("type %v%v struct {", mockTypeName, g.typeParams).
 		p("	fail func(message string, callerSkip ...int)").
+		p("	wrapped %v", wrappedType).
+		p("}").
+		emptyLine().
+		p("func New%v%v(wrapped %v, options ...pegomock.Option) *%v%v {", mockTypeName, g.typeParams, wrappedType, mockTypeName, g.typeArgs).
+		p("	mock := &%v%v{fail: pegomock.GlobalFailHandler, wrapped: wrapped}", mockTypeName, g.typeArgs).
+		p("	pegomock.SetDefaultAnswer(mock, mock.callWrapped)").
+		p("	for _, option := range options {").
+		p("		option(mock)").
+		p("	}").
+		p("	return mock").
 		p("}").
 		emptyLine().
-		p("func New%v() *%v {", mockTypeName, mockTypeName).
-		p("	return &%v{fail: pegomock.GlobalFailHandler}", mockTypeName).
+		p("func (mock *%v%v) callWrapped(methodName string, params []pegomock.Param, returnTypes []reflect.Type) (pegomock.ReturnValues, string) {", mockTypeName, g.typeArgs).
+		p("switch methodName {")
+	for _, method := range iface.Methods {
+		_, _, argTypes, returnTypes := argDataFor(method, g.packageMap, selfPackage)
+		g.p("case %q:", method.Name)
+		callArgs := g.generateArgsFromParams(method, argTypes, selfPackage)
+		if len(returnTypes) == 0 {
+			g.
+				p("mock.wrapped.%v(%v)", method.Name, join(callArgs)).
+				p("return nil, \"wrapped instance\"")
+		} else {
+			returnValues := make([]string, len(returnTypes))
+			for i := range returnTypes {
+				returnValues[i] = fmt.Sprintf("ret%v", i)
+			}
+			g.
+				p("%v := mock.wrapped.%v(%v)", join(returnValues), method.Name, join(callArgs)).
+				p("return pegomock.ReturnValues{%v}, \"wrapped instance\"", join(returnValues))
+		}
+	}
+	g.
+		p("}").
+		p("return nil, \"\"").
 		p("}").
 		emptyLine()
 }
 
+// anyMethodReturnsValues tells whether the mocks need "reflect" for the return types of their
+// methods, or for answering invocations of spies.
+func anyMethodReturnsValues(interfaces []*model.Interface) bool {
+	for _, iface := range interfaces {
+		if iface.IsStruct {
+			return true
+		}
+		for _, method := range iface.Methods {
+			if len(method.Out) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // If non-empty, pkgOverride is the package in which unqualified types reside.
 func (g *generator) generateMockMethod(mockType string, method *model.Method, pkgOverride string) *generator {
 	args, argNames, _, returnTypes := argDataFor(method, g.packageMap, pkgOverride)
-	g.p("func (mock *%v) %v(%v) (%v) {", mockType, method.Name, join(args), join(returnTypes))
-	g.GenerateParamsDeclaration(argNames, method.Variadic != nil)
-	reflectReturnTypes := make([]string, len(returnTypes))
-	for i, returnType := range returnTypes {
-		reflectReturnTypes[i] = fmt.Sprintf("reflect.TypeOf((*%v)(nil)).Elem()", returnType)
+	g.generateDoc(method.Doc)
+	g.p("func (mock *%v%v) %v(%v) (%v) {", mockType, g.typeArgs, method.Name, join(args), join(returnTypes))
+	// Methods without params or return values pass nil, so invoking them does not allocate slices.
+	params := "nil"
+	if len(argNames) > 0 {
+		g.GenerateParamsDeclaration(argNames, method.Variadic != nil)
+		params = "params"
+	}
+	reflectReturnTypes := "nil"
+	if len(returnTypes) > 0 {
+		reflectReturnTypeList := make([]string, len(returnTypes))
+		for i, returnType := range returnTypes {
+			reflectReturnTypeList[i] = fmt.Sprintf("reflect.TypeOf((*%v)(nil)).Elem()", returnType)
+		}
+		reflectReturnTypes = fmt.Sprintf("[]reflect.Type{%v}", strings.Join(reflectReturnTypeList, ", "))
 	}
 	resultAssignment := ""
 	if len(method.Out) > 0 {
 		resultAssignment = "result :="
 	}
-	g.p("%v pegomock.GetGenericMockFrom(mock).Invoke(\"%v\", params, []reflect.Type{%v})",
-		resultAssignment, method.Name, strings.Join(reflectReturnTypes, ", "))
+	g.p("%v pegomock.GetGenericMockFrom(mock).Invoke(\"%v\", %v, %v)",
+		resultAssignment, method.Name, params, reflectReturnTypes)
 	if len(method.Out) > 0 {
 		// TODO: translate LastInvocation into a Matcher so it can be used as key for Stubbings
 		for i, returnType := range returnTypes {
@@ -204,41 +1045,71 @@ func (g *generator) generateMockMethod(mockType string, method *model.Method, pk
 	return g
 }
 
-func (g *generator) generateVerifierType(interfaceName string) *generator {
+func (g *generator) generateVerifierType(verifierTypeName string, mockTypeName string) *generator {
 	return g.
-		p("type Verifier%v struct {", interfaceName).
-		p("	mock *Mock%v", interfaceName).
+		p("type %v%v struct {", verifierTypeName, g.typeParams).
+		p("	mock *%v%v", mockTypeName, g.typeArgs).
 		p("	invocationCountMatcher pegomock.Matcher").
 		p("	inOrderContext *pegomock.InOrderContext").
 		p("}").
 		emptyLine()
 }
 
-func (g *generator) generateMockVerifyMethods(interfaceName string) {
+func (g *generator) generateMockVerifyMethods(verifierTypeName string, mockTypeName string) {
 	g.
-		p("func (mock *Mock%v) VerifyWasCalledOnce() *Verifier%v {", interfaceName, interfaceName).
-		p("	return &Verifier%v{mock, pegomock.Times(1), nil}", interfaceName).
+		p("func (mock *%v%v) VerifyWasCalledOnce() *%v%v {", mockTypeName, g.typeArgs, verifierTypeName, g.typeArgs).
+		p("	return &%v%v{mock, pegomock.Times(1), nil}", verifierTypeName, g.typeArgs).
 		p("}").
 		emptyLine().
-		p("func (mock *Mock%v) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *Verifier%v {", interfaceName, interfaceName).
-		p("	return &Verifier%v{mock, invocationCountMatcher, nil}", interfaceName).
+		p("func (mock *%v%v) VerifyWasCalled(invocationCountMatcher pegomock.Matcher) *%v%v {", mockTypeName, g.typeArgs, verifierTypeName, g.typeArgs).
+		p("	return &%v%v{mock, invocationCountMatcher, nil}", verifierTypeName, g.typeArgs).
 		p("}").
 		emptyLine().
-		p("func (mock *Mock%v) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *Verifier%v {", interfaceName, interfaceName).
-		p("	return &Verifier%v{mock, invocationCountMatcher, inOrderContext}", interfaceName).
+		p("func (mock *%v%v) VerifyWasCalledInOrder(invocationCountMatcher pegomock.Matcher, inOrderContext *pegomock.InOrderContext) *%v%v {", mockTypeName, g.typeArgs, verifierTypeName, g.typeArgs).
+		p("	return &%v%v{mock, invocationCountMatcher, inOrderContext}", verifierTypeName, g.typeArgs).
+		p("}").
+		emptyLine().
+		p("func (mock *%v%v) VerifyWasCalledEventually(invocationCountMatcher pegomock.Matcher, timeout time.Duration) *%v%v {", mockTypeName, g.typeArgs, verifierTypeName, g.typeArgs).
+		p("	return &%v%v{mock, pegomock.Within(invocationCountMatcher, timeout), nil}", verifierTypeName, g.typeArgs).
 		p("}").
 		emptyLine()
 }
 
-func (g *generator) generateVerifierMethod(interfaceName string, method *model.Method, pkgOverride string, returnTypeString string, args []string, argNames []string) *generator {
+func (g *generator) generateVerifierMethod(verifierTypeName string, method *model.Method, pkgOverride string, returnTypeString string, args []string, argNames []string) *generator {
 	return g.
-		p("func (verifier *Verifier%v) %v(%v) *%v {", interfaceName, method.Name, join(args), returnTypeString).
+		p("func (verifier *%v%v) %v(%v) *%v%v {", verifierTypeName, g.typeArgs, method.Name, join(args), returnTypeString, g.typeArgs).
 		GenerateParamsDeclaration(argNames, method.Variadic != nil).
 		p("methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, \"%v\", params)", method.Name).
-		p("return &%v{mock: verifier.mock, methodInvocations: methodInvocations}", returnTypeString).
+		p("return &%v%v{mock: verifier.mock, methodInvocations: methodInvocations}", returnTypeString, g.typeArgs).
 		p("}")
 }
 
+// generateTypeDoc generates the doc comment of typeName, which is description of iface, e.g.
+// "a mock". It consists of a sentence naming typeName, as golint expects, followed by the doc
+// comment of iface. Interfaces without doc comment get none either.
+func (g *generator) generateTypeDoc(typeName, description string, iface *model.Interface) *generator {
+	if iface.Doc == "" {
+		return g
+	}
+	return g.generateDoc(fmt.Sprintf("%v is %v of %v.\n\n%v", typeName, description, iface.Name, iface.Doc))
+}
+
+// generateDoc generates doc, the text of a doc comment of the mocked code, as comment, so the
+// mocks are documented like the interfaces and methods they mock.
+func (g *generator) generateDoc(doc string) *generator {
+	if doc == "" {
+		return g
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" {
+			g.p("//")
+		} else {
+			g.p("// %v", line)
+		}
+	}
+	return g
+}
+
 func (g *generator) GenerateParamsDeclaration(argNames []string, isVariadic bool) *generator {
 	if isVariadic {
 		return g.
@@ -251,17 +1122,17 @@ func (g *generator) GenerateParamsDeclaration(argNames []string, isVariadic bool
 	}
 }
 
-func (g *generator) generateOngoingVerificationType(interfaceName string, ongoingVerificationStructName string) *generator {
+func (g *generator) generateOngoingVerificationType(mockTypeName string, ongoingVerificationStructName string) *generator {
 	return g.
-		p("type %v struct {", ongoingVerificationStructName).
-		p("mock *Mock%v", interfaceName).
+		p("type %v%v struct {", ongoingVerificationStructName, g.typeParams).
+		p("mock *%v%v", mockTypeName, g.typeArgs).
 		p("	methodInvocations []pegomock.MethodInvocation").
 		p("}").
 		emptyLine()
 }
 
 func (g *generator) generateOngoingVerificationGetCapturedArguments(ongoingVerificationStructName string, argNames []string, argTypes []string) *generator {
-	g.p("func (c *%v) GetCapturedArguments() (%v) {", ongoingVerificationStructName, join(argTypes))
+	g.p("func (c *%v%v) GetCapturedArguments() (%v) {", ongoingVerificationStructName, g.typeArgs, join(argTypes))
 	if len(argNames) > 0 {
 		indexedArgNames := make([]string, len(argNames))
 		for i, argName := range argNames {
@@ -280,30 +1151,42 @@ func (g *generator) generateOngoingVerificationGetAllCapturedArguments(ongoingVe
 	for i, argType := range argTypes {
 		argsAsArray[i] = fmt.Sprintf("_param%v []%v", i, argType)
 	}
-	g.p("func (c *%v) GetAllCapturedArguments() (%v) {", ongoingVerificationStructName, strings.Join(argsAsArray, ", "))
+	g.p("func (c *%v%v) GetAllCapturedArguments() (%v) {", ongoingVerificationStructName, g.typeArgs, strings.Join(argsAsArray, ", "))
 	if len(argTypes) > 0 {
-		g.p("params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)")
-		g.p("if len(params) > 0 {")
-		for i, argType := range argTypes {
-			if isVariadic && i == len(argTypes)-1 {
-				variadicBasicType := strings.Replace(argType, "[]", "", 1)
-				g.
-					p("_param%v = make([]%v, len(params[%v]))", i, argType, i).
-					p("for u := range params[0] {"). // the number of invocations and hence len(params[x]) is equal for all x
-					p("_param%v[u] = make([]%v, len(params)-%v)", i, variadicBasicType, i).
-					p("for x := %v; x < len(params); x++ {", i).
-					p("if params[x][u] != nil {").
-					p("_param%v[u][x-%v] = params[x][u].(%v)", i, i, variadicBasicType).
-					p("}").
-					p("}").
-					p("}")
-				break
-			} else {
-				g.p("_param%v = make([]%v, len(params[%v]))", i, argType, i)
-				g.p("for u, param := range params[%v] {", i)
-				g.p("_param%v[u]=param.(%v)", i, argType)
-				g.p("}")
+		fixedArgTypes := argTypes
+		if isVariadic {
+			// The number of variadic arguments may differ between invocations, so they come separately.
+			fixedArgTypes = argTypes[:len(argTypes)-1]
+			paramsVar := "params"
+			if len(fixedArgTypes) == 0 {
+				paramsVar = "_"
 			}
+			g.p("%v, variadicArgs := pegomock.GetVariadicInvocationParams(c.methodInvocations, %v)", paramsVar, len(fixedArgTypes))
+			g.p("if len(variadicArgs) > 0 {")
+		} else {
+			g.p("params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)")
+			g.p("if len(params) > 0 {")
+		}
+		for i, argType := range fixedArgTypes {
+			g.p("_param%v = make([]%v, len(params[%v]))", i, argType, i)
+			g.p("for u, param := range params[%v] {", i)
+			g.p("_param%v[u]=param.(%v)", i, argType)
+			g.p("}")
+		}
+		if isVariadic {
+			i, variadicType := len(fixedArgTypes), argTypes[len(fixedArgTypes)]
+			g.
+				p("_param%v = make([]%v, len(variadicArgs))", i, variadicType).
+				p("for u, args := range variadicArgs {").
+				p("if len(args) > 0 {").
+				p("_param%v[u] = make(%v, len(args))", i, variadicType).
+				p("for x, arg := range args {").
+				p("if arg != nil {").
+				p("_param%v[u][x] = arg.(%v)", i, strings.Replace(variadicType, "[]", "", 1)).
+				p("}").
+				p("}").
+				p("}").
+				p("}")
 		}
 		g.p("}")
 		g.p("return")
@@ -349,27 +1232,55 @@ func argDataFor(method *model.Method, packageMap map[string]string, pkgOverride
 	return
 }
 
-func addTypesFromMethodParamsTo(typesSet map[string]string, params []*model.Parameter, packageMap map[string]string) {
+func addTypesFromMethodParamsTo(typesSet map[string]string, params []*model.Parameter, packageMap map[string]string, matchersPackage string, header string) {
 	for _, param := range params {
 		switch typedType := param.Type.(type) {
 		case *model.NamedType, *model.PointerType, *model.ArrayType, *model.MapType, *model.ChanType:
+			if mentionsTypeParams(typedType) {
+				// Matchers are functions outside of the generic mock, so they cannot refer to its type parameters.
+				continue
+			}
 			if _, exists := typesSet[underscoreNameFor(typedType, packageMap)]; !exists {
-				typesSet[underscoreNameFor(typedType, packageMap)] = generateMatcherSourceCode(typedType, packageMap)
+				typesSet[underscoreNameFor(typedType, packageMap)] = generateMatcherSourceCode(typedType, packageMap, matchersPackage, header)
 			}
 		case *model.FuncType:
 			// matcher generation for funcs not supported yet
 			// TODO implement
 		case model.PredeclaredType:
 			// skip. These come as part of pegomock.
+		case model.TypeParamType:
+			// skip. See above.
 		default:
 			panic("Should not get here")
 		}
 	}
 }
 
-func generateMatcherSourceCode(t model.Type, packageMap map[string]string) string {
-	return fmt.Sprintf(`// Code generated by pegomock. DO NOT EDIT.
-package matchers
+func mentionsTypeParams(t model.Type) bool {
+	switch typedType := t.(type) {
+	case model.TypeParamType:
+		return true
+	case *model.NamedType:
+		for _, arg := range typedType.TypeArgs {
+			if mentionsTypeParams(arg) {
+				return true
+			}
+		}
+	case *model.PointerType:
+		return mentionsTypeParams(typedType.Type)
+	case *model.ArrayType:
+		return mentionsTypeParams(typedType.Type)
+	case *model.MapType:
+		return mentionsTypeParams(typedType.Key) || mentionsTypeParams(typedType.Value)
+	case *model.ChanType:
+		return mentionsTypeParams(typedType.Type)
+	}
+	return false
+}
+
+func generateMatcherSourceCode(t model.Type, packageMap map[string]string, matchersPackage string, header string) string {
+	sourceCode := fmt.Sprintf(`%v
+package %v
 
 import (
 	"reflect"
@@ -389,7 +1300,9 @@ func Eq%v(value %v) %v {
 	return nullValue
 }
 `,
-		optionalPackageOf(t, packageMap),
+		header,
+		matchersPackage,
+		strings.Join(uniqueImportsOf(t, packageMap), "\n"),
 		camelcaseNameFor(t, packageMap),
 		t.String(packageMap, ""),
 		t.String(packageMap, ""),
@@ -400,6 +1313,22 @@ func Eq%v(value %v) %v {
 		t.String(packageMap, ""),
 		t.String(packageMap, ""),
 	)
+	formattedSourceCode, err := formatSource([]byte(sourceCode))
+	if err != nil {
+		return sourceCode
+	}
+	return string(formattedSourceCode)
+}
+
+func uniqueImportsOf(t model.Type, packageMap map[string]string) (imports []string) {
+	seen := make(map[string]bool)
+	for _, imp := range strings.Split(optionalPackageOf(t, packageMap), "\n") {
+		if imp != "" && !seen[imp] {
+			seen[imp] = true
+			imports = append(imports, imp)
+		}
+	}
+	return
 }
 
 func optionalPackageOf(t model.Type, packageMap map[string]string) string {
@@ -407,7 +1336,14 @@ func optionalPackageOf(t model.Type, packageMap map[string]string) string {
 	case model.PredeclaredType:
 		return ""
 	case *model.NamedType:
-		return fmt.Sprintf("%v \"%v\"", packageMap[typedType.Package], vendorCleaned(typedType.Package))
+		if typedType.Package == "" {
+			return ""
+		}
+		imports := fmt.Sprintf("%v \"%v\"", packageMap[typedType.Package], vendorCleaned(typedType.Package))
+		for _, arg := range typedType.TypeArgs {
+			imports += "\n" + optionalPackageOf(arg, packageMap)
+		}
+		return imports
 	case *model.PointerType:
 		return optionalPackageOf(typedType.Type, packageMap)
 	case *model.ArrayType:
@@ -434,7 +1370,18 @@ func spaceSeparatedNameFor(t model.Type, packageMap map[string]string) string {
 		}
 		return tt
 	case *model.NamedType:
-		return strings.Replace((typedType.String(packageMap, "")), ".", " ", -1)
+		name := typedType.Type
+		if typedType.Package != "" {
+			name = packageMap[typedType.Package] + " " + name
+		}
+		if len(typedType.TypeArgs) == 0 {
+			return name
+		}
+		args := make([]string, len(typedType.TypeArgs))
+		for i, arg := range typedType.TypeArgs {
+			args[i] = spaceSeparatedNameFor(arg, packageMap)
+		}
+		return name + " of " + strings.Join(args, " and ")
 	case *model.PointerType:
 		return "ptr to " + spaceSeparatedNameFor(typedType.Type, packageMap)
 	case *model.ArrayType:
@@ -462,6 +1409,77 @@ func underscoreNameFor(t model.Type, packageMap map[string]string) string {
 	return strings.ToLower(strings.Replace(spaceSeparatedNameFor(t, packageMap), " ", "_", -1))
 }
 
+var generatedCodePattern = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+// GeneratedByPegomock tells whether source was generated by pegomock, i.e. has pegomock's
+// "Code generated" comment before its package clause. Code whose Options.Header has a
+// "Code generated" comment of its own cannot be told apart from other generated code.
+func GeneratedByPegomock(source []byte) bool {
+	for _, line := range strings.Split(string(source), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == generatedCodeComment {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
+
+var sourcePattern = regexp.MustCompile(`(?m)^// Source: (.+)$`)
+
+// RecordedSource returns the Options.Source recorded in the header of source, code generated
+// by pegomock, or "" if there is none, as in matchers.
+func RecordedSource(source []byte) string {
+	match := sourcePattern.FindSubmatch(source)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimRight(string(match[1]), "\r")
+}
+
+// buildConstraintComment returns the build constraint lines for buildTags followed by an empty
+// line, or "" if buildTags is empty.
+func buildConstraintComment(buildTags string) (string, error) {
+	if strings.TrimSpace(buildTags) == "" {
+		return "", nil
+	}
+	expr, err := constraint.Parse("//go:build " + buildTags)
+	if err != nil {
+		return "", fmt.Errorf("Options.BuildTags %q is not a valid build constraint: %v", buildTags, err)
+	}
+	lines := []string{"//go:build " + expr.String()}
+	if plusBuildLines, err := constraint.PlusBuildLines(expr); err == nil {
+		lines = append(lines, plusBuildLines...)
+	}
+	return strings.Join(lines, "\n") + "\n\n", nil
+}
+
+// headerComment turns header into // comments that mark the code as generated.
+func headerComment(header string) string {
+	if strings.TrimSpace(header) == "" {
+		return generatedCodeComment
+	}
+	lines := strings.Split(strings.TrimRight(header, "\r\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "//"):
+			lines[i] = line
+		case strings.TrimSpace(line) == "":
+			lines[i] = "//"
+		default:
+			lines[i] = "// " + line
+		}
+	}
+	comment := strings.Join(lines, "\n")
+	if !generatedCodePattern.MatchString(comment) {
+		comment += "\n" + generatedCodeComment
+	}
+	return comment
+}
+
 func (g *generator) p(format string, args ...interface{}) *generator {
 	fmt.Fprintf(&g.buf, format+"\n", args...)
 	return g
@@ -470,11 +1488,94 @@ func (g *generator) p(format string, args ...interface{}) *generator {
 func (g *generator) emptyLine() *generator { return g.p("") }
 
 func (g *generator) formattedOutput() []byte {
-	src, err := format.Source(g.buf.Bytes())
+	src, err := formatSource(g.buf.Bytes())
 	if err != nil {
 		panic(fmt.Errorf("Failed to format generated source code: %s\n%s", err, g.buf.String()))
 	}
 	return src
 }
 
+// formatSource formats src and removes the imports it doesn't use, so generated code that
+// imports more than it uses still compiles and passes go vet. Unlike goimports, it never adds
+// imports, whose choice would depend on the packages found in the environment.
+func formatSource(src []byte) ([]byte, error) {
+	src, err := withoutUnusedImports(src)
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(src)
+}
+
+// withoutUnusedImports removes the lines of the imports that src doesn't refer to. Only imports
+// on lines of their own whose names are certain are removed: named ones and unnamed ones of
+// the standard library.
+func withoutUnusedImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	// References to imported packages are the identifiers the parser cannot resolve.
+	referenced := make(map[string]bool)
+	for _, ident := range file.Unresolved {
+		referenced[ident.Name] = true
+	}
+	// Removing lines from the end keeps the offsets of the lines before them valid.
+	for i := len(file.Imports) - 1; i >= 0; i-- {
+		spec := file.Imports[i]
+		name := importName(spec)
+		if name == "" || name == "_" || name == "." || referenced[name] {
+			continue
+		}
+		start, end := fset.Position(spec.Pos()).Offset, fset.Position(spec.End()).Offset
+		if decl := importDeclOf(file, spec); !decl.Lparen.IsValid() {
+			start, end = fset.Position(decl.Pos()).Offset, fset.Position(decl.End()).Offset
+		}
+		lineStart, lineEnd := bytes.LastIndexByte(src[:start], '\n')+1, len(src)
+		if newline := bytes.IndexByte(src[end:], '\n'); newline != -1 {
+			lineEnd = end + newline + 1
+		}
+		if len(bytes.TrimSpace(src[lineStart:start])) != 0 || len(bytes.TrimSpace(src[end:lineEnd])) != 0 {
+			continue
+		}
+		src = append(src[:lineStart:lineStart], src[lineEnd:]...)
+	}
+	return src, nil
+}
+
+// importName returns the name by which code refers to the package imported by spec, or "" if
+// it cannot be told without loading the package.
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	importPath, err := strconv.Unquote(spec.Path.Value)
+	if err != nil || strings.Contains(strings.Split(importPath, "/")[0], ".") {
+		return ""
+	}
+	elements := strings.Split(importPath, "/")
+	if last := elements[len(elements)-1]; len(elements) > 1 && isMajorVersion(last) {
+		return elements[len(elements)-2]
+	}
+	return elements[len(elements)-1]
+}
+
+func isMajorVersion(element string) bool {
+	_, err := strconv.Atoi(strings.TrimPrefix(element, "v"))
+	return strings.HasPrefix(element, "v") && err == nil
+}
+
+func importDeclOf(file *ast.File, spec *ast.ImportSpec) *ast.GenDecl {
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+			for _, s := range genDecl.Specs {
+				if s == spec {
+					return genDecl
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func join(s []string) string { return strings.Join(s, ", ") }
@@ -1,8 +1,18 @@
 package mockgen_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
 	"github.com/petergtz/pegomock/mockgen"
+	"github.com/petergtz/pegomock/model"
 	"github.com/petergtz/pegomock/modelgen/loader"
+	"github.com/petergtz/pegomock/pegomock/filehandling"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,10 +23,11 @@ var _ = Describe("Mockgen", func() {
 		It("uses correct naming pattern with underscores for keys, and correct types etc. in source code", func() {
 			ast, e := loader.GenerateModel("github.com/petergtz/pegomock/test_interface", "Display")
 			Expect(e).NotTo(HaveOccurred())
-			_, matcherSourceCodes := mockgen.GenerateOutput(ast, "irrelevant", "test_package", "")
+			_, matcherSourceCodes, e := mockgen.GenerateOutput(ast, "irrelevant", "test_package", "")
+			Expect(e).NotTo(HaveOccurred())
 
 			Expect(matcherSourceCodes).To(SatisfyAll(
-				HaveLen(6),
+				HaveLen(7),
 				HaveKeyWithValue("http_request", SatisfyAll(
 					ContainSubstring("http \"net/http\""),
 					ContainSubstring("func AnyHttpRequest() http.Request"),
@@ -38,7 +49,1632 @@ var _ = Describe("Mockgen", func() {
 				HaveKeyWithValue("map_of_string_to_interface", SatisfyAll(
 					ContainSubstring("func AnyMapOfStringToInterface() map[string]interface{}"),
 				)),
+				HaveKeyWithValue("test_interface_display", SatisfyAll(
+					ContainSubstring("test_interface \"github.com/petergtz/pegomock/test_interface\""),
+					ContainSubstring("func AnyTestInterfaceDisplay() test_interface.Display"),
+				)),
+			))
+		})
+	})
+
+	Context("interfaces with unsupported constructs", func() {
+		It("reports all unsupported constructs at once and still generates mocks for the other interfaces", func() {
+			ast := &model.Package{
+				Name: "test_package",
+				Interfaces: []*model.Interface{
+					&model.Interface{Name: "Bad", Methods: []*model.Method{
+						&model.Method{Name: "Do", In: []*model.Parameter{
+							&model.Parameter{Type: model.PredeclaredType("string")},
+							&model.Parameter{Type: &model.UnsupportedType{Description: "struct{ x int }"}},
+						}},
+						&model.Method{Name: "Get", Out: []*model.Parameter{
+							&model.Parameter{Type: &model.ArrayType{Len: -1, Type: &model.UnsupportedType{Description: "struct{ y int }"}}},
+						}},
+					}},
+					&model.Interface{Name: "Good", Methods: []*model.Method{
+						&model.Method{Name: "Show", In: []*model.Parameter{&model.Parameter{Type: model.PredeclaredType("string")}}},
+					}},
+				},
+			}
+
+			output, _, e := mockgen.GenerateOutput(ast, "irrelevant", "test_package", "")
+
+			Expect(e).To(Equal(model.UnsupportedConstructErrors{
+				&model.UnsupportedConstructError{Interface: "Bad", Method: "Do", Position: "parameter 1", Reason: "unsupported type struct{ x int }"},
+				&model.UnsupportedConstructError{Interface: "Bad", Method: "Get", Position: "return value 0", Reason: "unsupported type struct{ y int }"},
+			}))
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("type MockGood struct"),
+				Not(ContainSubstring("MockBad")),
+			))
+		})
+
+		It("returns no output when no interface can be mocked", func() {
+			ast := &model.Package{
+				Name: "test_package",
+				Interfaces: []*model.Interface{
+					&model.Interface{Name: "Bad", Methods: []*model.Method{
+						&model.Method{Name: "Do", Variadic: &model.Parameter{Type: &model.UnsupportedType{Description: "struct{ x int }"}}},
+					}},
+				},
+			}
+
+			output, _, e := mockgen.GenerateOutput(ast, "irrelevant", "test_package", "")
+
+			Expect(e).To(MatchError(ContainSubstring("Bad.Do, variadic parameter: unsupported type struct{ x int }")))
+			Expect(output).To(BeNil())
+		})
+
+		It("reports unsupported type constraints and prints them in the model, e.g. for --debug", func() {
+			ast := &model.Package{
+				Name: "test_package",
+				Interfaces: []*model.Interface{
+					&model.Interface{
+						Name: "Bad",
+						TypeParams: []*model.TypeParam{&model.TypeParam{Name: "T", Constraint: &model.UnionType{Terms: []*model.UnionTerm{
+							&model.UnionTerm{Type: model.PredeclaredType("int")},
+							&model.UnionTerm{Tilde: true, Type: &model.UnsupportedType{Description: "struct{ x int }"}},
+						}}}},
+						Methods: []*model.Method{&model.Method{Name: "Do", In: []*model.Parameter{
+							&model.Parameter{Name: "m", Type: &model.MapType{Key: model.PredeclaredType("string"), Value: &model.UnsupportedType{Description: "struct{ y int }"}}},
+						}}},
+					},
+				},
+			}
+
+			_, _, e := mockgen.GenerateOutput(ast, "irrelevant", "test_package", "")
+
+			Expect(e).To(Equal(model.UnsupportedConstructErrors{
+				&model.UnsupportedConstructError{Interface: "Bad", Position: "type parameter T", Reason: "unsupported type struct{ x int }"},
+				&model.UnsupportedConstructError{Interface: "Bad", Method: "Do", Position: "parameter 0", Reason: "unsupported type struct{ y int }"},
+			}))
+			var printed bytes.Buffer
+			ast.Print(&printed)
+			Expect(printed.String()).To(SatisfyAll(
+				ContainSubstring("interface Bad[T int | ~<unsupported type struct{ x int }>]\n"),
+				ContainSubstring("    - m: map[string]<unsupported type struct{ y int }>\n"),
+			))
+		})
+	})
+
+	Context("generated code for a corpus of interfaces", func() {
+		corpusDir := filepath.Join("test_data", "corpus")
+
+		BeforeEach(func() {
+			Expect(filehandling.GenerateMockFile(
+				[]string{filepath.Join(corpusDir, "corpus.go")},
+				filepath.Join(corpusDir, "mock_corpus_test.go"),
+				filehandling.Options{Options: mockgen.Options{PackageOut: "corpus_test", GenerateBuilders: true}, GenerateMatchers: true})).To(Succeed())
+			Expect(filehandling.GenerateMockFileInOutputDir(
+				[]string{filepath.Join(corpusDir, "narrow_test.go")},
+				".", "", "", filehandling.Options{})).To(Succeed())
+			Expect(filehandling.GenerateMockFile(
+				[]string{"github.com/petergtz/pegomock/mockgen/test_data/corpus", "Counter"},
+				filepath.Join(corpusDir, "mock_counter_test.go"),
+				filehandling.Options{Options: mockgen.Options{PackageOut: "corpus_test", GenerateBuilders: true}})).To(Succeed())
+			Expect(filehandling.GenerateMockFile(
+				[]string{"github.com/petergtz/pegomock/mockgen/test_data/corpus", "Voids,Fluent"},
+				filepath.Join(corpusDir, "mock_gomock_style_test.go"),
+				filehandling.Options{Options: mockgen.Options{
+					PackageOut: "corpus_test",
+					NameTemplates: mockgen.NameTemplates{
+						Mock:                "Gomock{{.Interface}}",
+						Verifier:            "GomockVerifier{{.Interface}}",
+						OngoingVerification: "Gomock{{.Interface}}_{{.Method}}_OngoingVerification",
+					},
+					Style: mockgen.GomockStyle,
+				}})).To(Succeed())
+			Expect(filehandling.GenerateMockFile(
+				[]string{"github.com/petergtz/pegomock/mockgen/test_data/corpus", "Voids,Fluent"},
+				filepath.Join(corpusDir, "mock_fake_style_test.go"),
+				filehandling.Options{Options: mockgen.Options{PackageOut: "corpus_test", Style: mockgen.FakeStyle}})).To(Succeed())
+			Expect(filehandling.GenerateMockFile(
+				[]string{"github.com/petergtz/pegomock/mockgen/test_data/corpus", "Returns"},
+				filepath.Join(corpusDir, "mock_subset_test.go"),
+				filehandling.Options{Options: mockgen.Options{
+					PackageOut: "corpus_test",
+					NameTemplates: mockgen.NameTemplates{
+						Mock:                "Subset{{.Interface}}",
+						Verifier:            "SubsetVerifier{{.Interface}}",
+						OngoingVerification: "Subset{{.Interface}}_{{.Method}}_OngoingVerification",
+					},
+					Methods: []string{"Single", "Error"},
+				}})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.Remove(filepath.Join(corpusDir, "mock_corpus_test.go"))).To(Succeed())
+			Expect(os.Remove(filepath.Join(corpusDir, "mock_narrow_test_interfaces_test.go"))).To(Succeed())
+			Expect(os.Remove(filepath.Join(corpusDir, "mock_counter_test.go"))).To(Succeed())
+			Expect(os.Remove(filepath.Join(corpusDir, "mock_gomock_style_test.go"))).To(Succeed())
+			Expect(os.Remove(filepath.Join(corpusDir, "mock_fake_style_test.go"))).To(Succeed())
+			Expect(os.Remove(filepath.Join(corpusDir, "mock_subset_test.go"))).To(Succeed())
+			Expect(os.RemoveAll(filepath.Join(corpusDir, "matchers"))).To(Succeed())
+		})
+
+		It("passes go vet", func() {
+			expectNoFindings(corpusDir, "go", "vet", "./...")
+		})
+
+		It("generates generic mocks that work with the runtime DSL", func() {
+			testFile := filepath.Join(corpusDir, "generic_mocks_test.go")
+			Expect(ioutil.WriteFile(testFile, []byte(genericMocksTest), 0644)).To(Succeed())
+			defer os.Remove(testFile)
+
+			expectNoFindings(corpusDir, "go", "test", ".")
+		})
+
+		It("generates function type mocks that work with the runtime DSL", func() {
+			testFile := filepath.Join(corpusDir, "func_type_mocks_test.go")
+			Expect(ioutil.WriteFile(testFile, []byte(funcTypeMocksTest), 0644)).To(Succeed())
+			defer os.Remove(testFile)
+
+			expectNoFindings(corpusDir, "go", "test", ".")
+		})
+
+		It("generates spies that call through to the wrapped instance unless stubbed", func() {
+			testFile := filepath.Join(corpusDir, "spies_test.go")
+			Expect(ioutil.WriteFile(testFile, []byte(spiesTest), 0644)).To(Succeed())
+			defer os.Remove(testFile)
+
+			expectNoFindings(corpusDir, "go", "test", ".")
+		})
+
+		It("generates gomock style mocks with a working EXPECT() API", func() {
+			testFile := filepath.Join(corpusDir, "gomock_style_mocks_test.go")
+			Expect(ioutil.WriteFile(testFile, []byte(gomockStyleMocksTest), 0644)).To(Succeed())
+			defer os.Remove(testFile)
+
+			expectNoFindings(corpusDir, "go", "test", ".")
+		})
+
+		It("generates counterfeiter-like fakes", func() {
+			testFile := filepath.Join(corpusDir, "fake_style_mocks_test.go")
+			Expect(ioutil.WriteFile(testFile, []byte(fakeStyleMocksTest), 0644)).To(Succeed())
+			defer os.Remove(testFile)
+
+			expectNoFindings(corpusDir, "go", "test", ".")
+		})
+
+		It("generates mocks of method subsets that panic on the other methods", func() {
+			testFile := filepath.Join(corpusDir, "subset_mocks_test.go")
+			Expect(ioutil.WriteFile(testFile, []byte(subsetMocksTest), 0644)).To(Succeed())
+			defer os.Remove(testFile)
+
+			expectNoFindings(corpusDir, "go", "test", ".")
+		})
+
+		It("passes staticcheck", func() {
+			if _, e := exec.LookPath("staticcheck"); e != nil {
+				Skip("staticcheck not found in PATH")
+			}
+			expectNoFindings(corpusDir, "staticcheck", "-tests", "./...")
+		})
+	})
+
+	Context("Generate", func() {
+		var ast *model.Package
+
+		BeforeEach(func() {
+			ast = &model.Package{
+				Name: "test_package",
+				Interfaces: []*model.Interface{
+					&model.Interface{Name: "Display", Methods: []*model.Method{
+						&model.Method{Name: "Show", In: []*model.Parameter{&model.Parameter{Name: "s", Type: model.PredeclaredType("string")}}},
+					}},
+				},
+			}
+		})
+
+		It("generates the same code as GenerateOutput", func() {
+			output, e := mockgen.Generate(ast, mockgen.Options{Source: "irrelevant", PackageOut: "test_package"})
+			Expect(e).NotTo(HaveOccurred())
+
+			expectedOutput, _, e := mockgen.GenerateOutput(ast, "irrelevant", "test_package", "")
+			Expect(e).NotTo(HaveOccurred())
+			Expect(output).To(Equal(expectedOutput))
+		})
+
+		It("passes nil params and return types for methods without them and imports reflect only when needed", func() {
+			ast.Interfaces[0].Methods = append(ast.Interfaces[0].Methods, &model.Method{Name: "Clear"})
+
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`pegomock.GetGenericMockFrom(mock).Invoke("Clear", nil, nil)`),
+				ContainSubstring(`pegomock.GetGenericMockFrom(mock).Invoke("Show", params, nil)`),
+				Not(ContainSubstring(`"reflect"`)),
+			))
+		})
+
+		It("imports vendored packages by their paths within the innermost vendor directory", func() {
+			ast.Interfaces[0].Methods = append(ast.Interfaces[0].Methods,
+				&model.Method{Name: "Put", In: []*model.Parameter{&model.Parameter{Name: "t", Type: &model.NamedType{Package: "example.com/app/vendor/example.com/lib/vendor/github.com/org/things", Type: "Thing"}}}},
+				&model.Method{Name: "Parse", In: []*model.Parameter{&model.Parameter{Name: "m", Type: &model.PointerType{Type: &model.NamedType{Package: "vendor/golang.org/x/net/dns/dnsmessage", Type: "Message"}}}}})
+
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`things "github.com/org/things"`),
+				ContainSubstring(`dnsmessage "golang.org/x/net/dns/dnsmessage"`),
+				Not(ContainSubstring("vendor/")),
+			))
+		})
+
+		It("names mocks according to MockNameFormat", func() {
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", MockNameFormat: "Fake%sMock"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("type FakeDisplayMock struct"),
+				ContainSubstring("func NewFakeDisplayMock(options ...pegomock.Option) *FakeDisplayMock"),
+				ContainSubstring("pegomock.RegisterMockFactory(func() pegomock.Mock { return NewFakeDisplayMock() })"),
+				ContainSubstring("func (mock *FakeDisplayMock) Show(s string)"),
+				ContainSubstring("func (mock *FakeDisplayMock) VerifyWasCalledOnce() *VerifierDisplay"),
+				ContainSubstring("mock *FakeDisplayMock"),
+				Not(ContainSubstring("MockDisplay")),
+			))
+		})
+
+		It("names mocks, verifiers and ongoing verifications according to NameTemplates", func() {
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", NameTemplates: mockgen.NameTemplates{
+				Mock:                "{{.Interface}}Fake",
+				Verifier:            "{{.Interface}}FakeVerifier",
+				OngoingVerification: "{{.Interface}}{{.Method}}Verification",
+			}})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("type DisplayFake struct"),
+				ContainSubstring("func NewDisplayFake(options ...pegomock.Option) *DisplayFake"),
+				ContainSubstring("func (mock *DisplayFake) VerifyWasCalledOnce() *DisplayFakeVerifier"),
+				ContainSubstring("type DisplayFakeVerifier struct"),
+				ContainSubstring("func (verifier *DisplayFakeVerifier) Show(s string) *DisplayShowVerification"),
+				ContainSubstring("type DisplayShowVerification struct"),
+				Not(ContainSubstring("MockDisplay")),
+				Not(ContainSubstring("VerifierDisplay")),
+				Not(ContainSubstring("OngoingVerification")),
+			))
+		})
+
+		It("reports invalid NameTemplates", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", NameTemplates: mockgen.NameTemplates{Mock: "{{.Interface"}})
+			Expect(e).To(MatchError(ContainSubstring("Invalid Options.NameTemplates.Mock")))
+
+			_, e = mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", NameTemplates: mockgen.NameTemplates{Verifier: "{{.Interfaces}}"}})
+			Expect(e).To(MatchError(ContainSubstring("Invalid Options.NameTemplates.Verifier")))
+
+			_, e = mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", NameTemplates: mockgen.NameTemplates{OngoingVerification: "{{.Interface}}Verification"}})
+			Expect(e).To(MatchError(`Options.NameTemplates.OngoingVerification "{{.Interface}}Verification" must use .Interface and .Method`))
+
+			_, e = mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", NameTemplates: mockgen.NameTemplates{Mock: "Verifier{{.Interface}}"}})
+			Expect(e).To(MatchError(`Options.NameTemplates result in the same name "VerifierX" for mocks and verifiers`))
+
+			_, e = mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", NameTemplates: mockgen.NameTemplates{Mock: "{{.Interface}}-Fake"}})
+			Expect(e).To(MatchError(`Options.NameTemplates do not produce valid identifiers, e.g. "X-Fake"`))
+		})
+
+		It("generates builders when asked to, renaming them on collisions", func() {
+			ast.Interfaces = append(ast.Interfaces, &model.Interface{Name: "DisplayBuilder", Methods: []*model.Method{
+				&model.Method{Name: "Build", Out: []*model.Parameter{&model.Parameter{Type: model.PredeclaredType("string")}}},
+			}})
+
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", GenerateBuilders: true})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("type MockDisplayBuilder struct"),
+				ContainSubstring("type MockDisplayBuilder0 struct"),
+				ContainSubstring("func (builder *MockDisplayBuilder0) WithShow(answer func(s string)) *MockDisplayBuilder0"),
+				ContainSubstring("func (builder *MockDisplayBuilder0) Build() *MockDisplay"),
+				ContainSubstring("type MockDisplayBuilderBuilder struct"),
+				ContainSubstring("func (builder *MockDisplayBuilderBuilder) ReturningOnBuild(ret0 string) *MockDisplayBuilderBuilder"),
+				ContainSubstring("func (builder *MockDisplayBuilderBuilder) Build() *MockDisplayBuilder"),
+			))
+		})
+
+		It("generates no builders by default", func() {
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).NotTo(ContainSubstring("Builder"))
+		})
+
+		It("replaces the default header of mocks and matchers with Header, keeping them marked as generated", func() {
+			ast.Interfaces[0].Methods = append(ast.Interfaces[0].Methods, &model.Method{Name: "Render", In: []*model.Parameter{
+				&model.Parameter{Name: "t", Type: &model.NamedType{Package: "time", Type: "Time"}},
+			}})
+
+			output, matchers, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{
+				PackageOut: "test_package",
+				Source:     "display.go",
+				Header:     "Copyright 2024 ACME Corp.\n\n// All rights reserved.\n",
+			})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(HavePrefix("// Copyright 2024 ACME Corp.\n//\n// All rights reserved.\n" +
+				"// Code generated by pegomock. DO NOT EDIT.\n// Source: display.go\n"))
+			Expect(matchers["time_time"]).To(HavePrefix("// Copyright 2024 ACME Corp.\n//\n// All rights reserved.\n" +
+				"// Code generated by pegomock. DO NOT EDIT.\npackage matchers\n"))
+
+			output, e = mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", Header: "// Code generated by acme-gen. DO NOT EDIT."})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(HavePrefix("// Code generated by acme-gen. DO NOT EDIT.\n// Source:\n"))
+		})
+
+		It("writes BuildTags as build constraints at the top of mocks and matchers", func() {
+			ast.Interfaces[0].Methods = append(ast.Interfaces[0].Methods, &model.Method{Name: "Render", In: []*model.Parameter{
+				&model.Parameter{Name: "t", Type: &model.NamedType{Package: "time", Type: "Time"}},
+			}})
+
+			output, matchers, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{PackageOut: "test_package", BuildTags: "integration && !windows"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(HavePrefix("//go:build integration && !windows\n// +build integration,!windows\n\n// Code generated by pegomock. DO NOT EDIT.\n"))
+			Expect(matchers["time_time"]).To(HavePrefix("//go:build integration && !windows\n// +build integration,!windows\n\n// Code generated by pegomock. DO NOT EDIT.\n"))
+		})
+
+		It("reports invalid BuildTags", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", BuildTags: "integration &&"})
+
+			Expect(e).To(MatchError(ContainSubstring(`Options.BuildTags "integration &&" is not a valid build constraint`)))
+		})
+
+		It("generates the same code byte for byte on every run, even for colliding package names", func() {
+			for i, packagePath := range []string{"text/template", "html/template", "example.com/template", "example.com/b/template", "net/http"} {
+				ast.Interfaces[0].Methods = append(ast.Interfaces[0].Methods, &model.Method{
+					Name: fmt.Sprintf("Use%v", i),
+					In:   []*model.Parameter{&model.Parameter{Name: "t", Type: &model.NamedType{Package: packagePath, Type: "Template"}}},
+				})
+			}
+			firstOutput, firstMatchers, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{PackageOut: "test_package"})
+			Expect(e).NotTo(HaveOccurred())
+
+			for i := 0; i < 20; i++ {
+				output, matchers, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{PackageOut: "test_package"})
+				Expect(e).NotTo(HaveOccurred())
+				Expect(string(output)).To(Equal(string(firstOutput)))
+				Expect(matchers).To(Equal(firstMatchers))
+			}
+			Expect(string(firstOutput)).To(SatisfyAll(
+				ContainSubstring(`template "example.com/b/template"`),
+				ContainSubstring(`template0 "example.com/template"`),
+				ContainSubstring(`template1 "html/template"`),
+				ContainSubstring(`template2 "text/template"`),
+			))
+		})
+
+		It("reports a missing PackageOut", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{})
+
+			Expect(e).To(MatchError("Options.PackageOut must not be empty"))
+		})
+
+		It("reports an invalid MockNameFormat", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", MockNameFormat: "Mock"})
+			Expect(e).To(MatchError(`Options.MockNameFormat must contain exactly one %s, but is "Mock"`))
+
+			_, e = mockgen.Generate(ast, mockgen.Options{PackageOut: "test_package", MockNameFormat: "Mock-%s"})
+			Expect(e).To(MatchError(`Options.MockNameFormat "Mock-%s" does not produce valid identifiers, e.g. "Mock-X"`))
+		})
+	})
+
+	Context("Generator", func() {
+		It("generates mocks for the interfaces of a package in ReflectMode", func() {
+			generator := mockgen.NewGenerator(mockgen.GeneratorOptions{
+				Mode:    mockgen.ReflectMode,
+				Options: mockgen.Options{PackageOut: "test_package", NameTemplates: mockgen.NameTemplates{Mock: "Fake{{.Interface}}"}},
+			})
+
+			output, matchers, e := generator.GenerateWithMatchers("github.com/petergtz/pegomock/test_interface", "Display")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("// Source: github.com/petergtz/pegomock/test_interface (interfaces: Display)"),
+				ContainSubstring("type FakeDisplay struct"),
+			))
+			Expect(matchers).To(HaveKey("http_request"))
+		})
+
+		It("generates Any and Eq matchers for all non built-in parameter and return types", func() {
+			_, matchers, e := mockgen.NewGenerator(mockgen.GeneratorOptions{Options: mockgen.Options{PackageOut: "test_package"}}).
+				GenerateWithMatchers("github.com/petergtz/pegomock/test_interface", "Display")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(matchers).To(SatisfyAll(
+				HaveLen(7),
+				HaveKey("http_request"),
+				HaveKey("ptr_to_http_request"),
+				HaveKey("io_readcloser"),
+				HaveKey("slice_of_string"),
+				HaveKey("map_of_string_to_http_request"),
+				HaveKey("map_of_string_to_interface"),
+				HaveKey("test_interface_display"),
+			))
+			Expect(matchers["http_request"]).To(SatisfyAll(
+				ContainSubstring("func AnyHttpRequest() http.Request {"),
+				ContainSubstring("func EqHttpRequest(value http.Request) http.Request {"),
+			))
+		})
+
+		It("generates mocks for all exported interfaces of a package in ReflectMode without interface names", func() {
+			output, e := mockgen.NewGenerator(mockgen.GeneratorOptions{Options: mockgen.Options{PackageOut: "test_package"}}).
+				Generate("github.com/petergtz/pegomock/test_interface")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(ContainSubstring("type MockDisplay struct"))
+		})
+
+		It("generates mocks for all interfaces of a file in SourceMode", func() {
+			generator := mockgen.NewGenerator(mockgen.GeneratorOptions{
+				Mode:    mockgen.SourceMode,
+				Options: mockgen.Options{PackageOut: "test_package", Header: "Copyright Example"},
+			})
+
+			output, e := generator.Generate("../modelgen/test_data/default_test_interface/display.go")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				HavePrefix("// Copyright Example\n"),
+				ContainSubstring("// Source: ../modelgen/test_data/default_test_interface/display.go"),
+				ContainSubstring("type MockDisplay struct"),
+			))
+		})
+
+		It("reports interface names in SourceMode", func() {
+			_, e := mockgen.NewGenerator(mockgen.GeneratorOptions{Mode: mockgen.SourceMode, Options: mockgen.Options{PackageOut: "test_package"}}).
+				Generate("display.go", "Display")
+
+			Expect(e).To(MatchError("SourceMode generates mocks for all interfaces in display.go, but got interfaces Display"))
+		})
+
+		It("reports invalid options", func() {
+			_, e := mockgen.NewGenerator(mockgen.GeneratorOptions{}).Generate("github.com/petergtz/pegomock/test_interface", "Display")
+
+			Expect(e).To(MatchError("Options.PackageOut must not be empty"))
+		})
+	})
+
+	Context("self-referential interfaces", func() {
+		var ast *model.Package
+
+		BeforeEach(func() {
+			queryType := &model.NamedType{Package: "example.com/query", Type: "Query"}
+			ast = &model.Package{
+				Name:    "query",
+				PkgPath: "example.com/query",
+				Interfaces: []*model.Interface{
+					&model.Interface{Name: "Query", Methods: []*model.Method{
+						&model.Method{
+							Name: "Where",
+							In:   []*model.Parameter{&model.Parameter{Name: "cond", Type: model.PredeclaredType("string")}},
+							Out:  []*model.Parameter{&model.Parameter{Type: queryType}},
+						},
+						&model.Method{
+							Name: "Union",
+							In:   []*model.Parameter{&model.Parameter{Name: "other", Type: queryType}},
+							Out:  []*model.Parameter{&model.Parameter{Type: queryType}},
+						},
+					}},
+				},
+			}
+		})
+
+		It("qualifies the interface's own type and asserts that the mock implements the interface", func() {
+			output, _, e := mockgen.GenerateOutput(ast, "irrelevant", "query_test", "")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`query "example.com/query"`),
+				ContainSubstring("func (mock *MockQuery) Where(cond string) query.Query {"),
+				ContainSubstring("func (mock *MockQuery) Union(other query.Query) query.Query {"),
+				ContainSubstring("var _ query.Query = (*MockQuery)(nil)"),
+			))
+		})
+
+		It("does not qualify the interface's own type when generating into the interface's package", func() {
+			output, _, e := mockgen.GenerateOutput(ast, "irrelevant", "query", "")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				Not(ContainSubstring(`"example.com/query"`)),
+				ContainSubstring("func (mock *MockQuery) Where(cond string) Query {"),
+				ContainSubstring("var _ Query = (*MockQuery)(nil)"),
+			))
+		})
+
+		It("omits the assertion when the interface's package is unknown", func() {
+			ast.PkgPath = ""
+
+			output, _, e := mockgen.GenerateOutput(ast, "irrelevant", "query_test", "")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).NotTo(ContainSubstring("var _ "))
+		})
+	})
+
+	Context("generic instantiations in signatures", func() {
+		It("qualifies each type argument on its own, also in captured arguments, reflect expressions and matchers", func() {
+			userType := &model.NamedType{Package: "example.com/models", Type: "User"}
+			ast := &model.Package{
+				Name: "store",
+				Interfaces: []*model.Interface{
+					&model.Interface{Name: "Store", Methods: []*model.Method{
+						&model.Method{
+							Name: "Find",
+							In: []*model.Parameter{&model.Parameter{Name: "byName", Type: &model.MapType{
+								Key: model.PredeclaredType("string"),
+								Value: &model.NamedType{Package: "example.com/result", Type: "Result", TypeArgs: []model.Type{
+									&model.ArrayType{Len: -1, Type: &model.PointerType{Type: userType}},
+								}},
+							}}},
+							Out: []*model.Parameter{&model.Parameter{Type: &model.NamedType{Package: "example.com/pair", Type: "Pair", TypeArgs: []model.Type{
+								model.PredeclaredType("string"), userType,
+							}}}},
+						},
+					}},
+				},
+			}
+
+			output, matcherSourceCodes, e := mockgen.GenerateOutput(ast, "irrelevant", "store_test", "")
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`models "example.com/models"`),
+				ContainSubstring(`result "example.com/result"`),
+				ContainSubstring(`pair "example.com/pair"`),
+				ContainSubstring("func (mock *MockStore) Find(byName map[string]result.Result[[]*models.User]) pair.Pair[string, models.User] {"),
+				ContainSubstring("reflect.TypeOf((*pair.Pair[string, models.User])(nil)).Elem()"),
+				ContainSubstring("GetAllCapturedArguments() (_param0 []map[string]result.Result[[]*models.User])"),
+				ContainSubstring("_param0[u] = param.(map[string]result.Result[[]*models.User])"),
+			))
+			Expect(matcherSourceCodes).To(SatisfyAll(
+				HaveKeyWithValue("map_of_string_to_result_result_of_slice_of_ptr_to_models_user", SatisfyAll(
+					ContainSubstring(`result "example.com/result"`),
+					ContainSubstring(`models "example.com/models"`),
+					ContainSubstring("func AnyMapOfStringToResultResultOfSliceOfPtrToModelsUser() map[string]result.Result[[]*models.User]"),
+				)),
+			))
+		})
+	})
+
+	Context("generic interfaces", func() {
+		It("carries the type parameters and their constraints through to all generated types", func() {
+			ast := &model.Package{
+				Name:    "store",
+				PkgPath: "example.com/store",
+				Interfaces: []*model.Interface{
+					&model.Interface{
+						Name: "Repository",
+						TypeParams: []*model.TypeParam{
+							&model.TypeParam{Name: "K", Constraint: model.PredeclaredType("comparable")},
+							&model.TypeParam{Name: "V", Constraint: &model.UnionType{Terms: []*model.UnionTerm{
+								&model.UnionTerm{Tilde: true, Type: model.PredeclaredType("int")},
+								&model.UnionTerm{Type: &model.NamedType{Package: "example.com/models", Type: "Amount"}},
+							}}},
+						},
+						Methods: []*model.Method{
+							&model.Method{
+								Name: "Find",
+								In:   []*model.Parameter{&model.Parameter{Name: "keys", Type: &model.ArrayType{Len: -1, Type: model.TypeParamType("K")}}},
+								Out:  []*model.Parameter{&model.Parameter{Type: model.TypeParamType("V")}},
+							},
+						},
+					},
+				},
+			}
+
+			output, matcherSourceCodes, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{PackageOut: "store_test", GenerateBuilders: true})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`models "example.com/models"`),
+				ContainSubstring("type MockRepository[K comparable, V ~int | models.Amount] struct {"),
+				ContainSubstring("func NewMockRepository[K comparable, V ~int | models.Amount](options ...pegomock.Option) *MockRepository[K, V] {"),
+				ContainSubstring("func (mock *MockRepository[K, V]) Find(keys []K) V {"),
+				ContainSubstring("reflect.TypeOf((*V)(nil)).Elem()"),
+				ContainSubstring("func (verifier *VerifierRepository[K, V]) Find(keys []K) *Repository_Find_OngoingVerification[K, V] {"),
+				ContainSubstring("func (c *Repository_Find_OngoingVerification[K, V]) GetAllCapturedArguments() (_param0 [][]K) {"),
+				ContainSubstring("func (builder *MockRepositoryBuilder[K, V]) Build() *MockRepository[K, V] {"),
+				Not(ContainSubstring("var _ ")),
+				Not(ContainSubstring("RegisterMockFactory")),
+			))
+			Expect(matcherSourceCodes).To(BeEmpty())
+		})
+	})
+
+	Context("struct types", func() {
+		It("generates a spy that answers unstubbed invocations with the wrapped instance", func() {
+			ast := &model.Package{
+				Name:    "counting",
+				PkgPath: "example.com/counting",
+				Interfaces: []*model.Interface{
+					&model.Interface{
+						Name:     "Counter",
+						IsStruct: true,
+						Methods: []*model.Method{
+							&model.Method{
+								Name:     "Add",
+								Variadic: &model.Parameter{Name: "deltas", Type: model.PredeclaredType("int")},
+								Out:      []*model.Parameter{&model.Parameter{Type: model.PredeclaredType("int")}},
+							},
+							&model.Method{Name: "Reset"},
+						},
+					},
+				},
+			}
+
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "counting_test", GenerateBuilders: true})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`counting "example.com/counting"`),
+				ContainSubstring("func NewMockCounter(wrapped *counting.Counter, options ...pegomock.Option) *MockCounter {"),
+				ContainSubstring("pegomock.SetDefaultAnswer(mock, mock.callWrapped)"),
+				ContainSubstring("ret0 := mock.wrapped.Add(_arg0...)"),
+				ContainSubstring("mock.wrapped.Reset()"),
+				ContainSubstring("func (verifier *VerifierCounter) Add(deltas ...int) *Counter_Add_OngoingVerification {"),
+				Not(ContainSubstring("var _ ")),
+				Not(ContainSubstring("Builder")),
+			))
+		})
+
+		It("reports spies for struct types that cannot be referred to as unsupported", func() {
+			ast := &model.Package{
+				Name:       "counting",
+				PkgPath:    "example.com/counting",
+				Interfaces: []*model.Interface{&model.Interface{Name: "counter", IsStruct: true}},
+			}
+
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "counting_test"})
+
+			Expect(e).To(MatchError(ContainSubstring("a spy must refer to the struct type it wraps")))
+		})
+	})
+
+	Context("function types", func() {
+		It("generates a mock with a Call method and a Func method returning it as the function type", func() {
+			ast := &model.Package{
+				Name:    "events",
+				PkgPath: "example.com/events",
+				Interfaces: []*model.Interface{
+					&model.Interface{
+						Name:       "Handler",
+						TypeParams: []*model.TypeParam{&model.TypeParam{Name: "T", Constraint: model.PredeclaredType("any")}},
+						IsFuncType: true,
+						Methods: []*model.Method{
+							&model.Method{
+								Name: model.FuncTypeMethod,
+								In:   []*model.Parameter{&model.Parameter{Name: "event", Type: model.TypeParamType("T")}},
+								Out:  []*model.Parameter{&model.Parameter{Type: model.PredeclaredType("error")}},
+							},
+						},
+					},
+				},
+			}
+
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "events_test"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`events "example.com/events"`),
+				ContainSubstring("func (mock *MockHandler[T]) Call(event T) error {"),
+				ContainSubstring("func (mock *MockHandler[T]) Func() events.Handler[T] {\n\treturn mock.Call\n}"),
+				ContainSubstring("func (verifier *VerifierHandler[T]) Call(event T) *Handler_Call_OngoingVerification[T] {"),
+				Not(ContainSubstring("var _ ")),
+			))
+		})
+
+		It("omits the Func method when the function type cannot be referred to", func() {
+			ast := &model.Package{
+				Name: "events",
+				Interfaces: []*model.Interface{
+					&model.Interface{Name: "Handler", IsFuncType: true, Methods: []*model.Method{&model.Method{Name: model.FuncTypeMethod}}},
+				},
+			}
+
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "events_test"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("func (mock *MockHandler) Call() {"),
+				Not(ContainSubstring("Func()")),
+			))
+		})
+	})
+
+	Context("method subsets", func() {
+		var ast *model.Package
+
+		BeforeEach(func() {
+			ast = &model.Package{
+				Name:    "storage",
+				PkgPath: "example.com/storage",
+				Interfaces: []*model.Interface{
+					&model.Interface{
+						Name: "Store",
+						Methods: []*model.Method{
+							&model.Method{
+								Name: "Get",
+								In:   []*model.Parameter{&model.Parameter{Name: "key", Type: model.PredeclaredType("string")}},
+								Out:  []*model.Parameter{&model.Parameter{Type: &model.NamedType{Package: "example.com/storage", Type: "Item"}}},
+							},
+							&model.Method{
+								Name: "Watch",
+								In:   []*model.Parameter{&model.Parameter{Name: "ch", Type: &model.ChanType{Type: model.PredeclaredType("int")}}},
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("generates only the given methods and embeds the interface for the others", func() {
+			output, matchers, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{PackageOut: "storage_test", Methods: []string{"Get"}})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("type MockStore struct {\n\tfail func(message string, callerSkip ...int)\n\tstorage.Store\n}"),
+				ContainSubstring("func (mock *MockStore) Get(key string) storage.Item {"),
+				ContainSubstring("var _ storage.Store = (*MockStore)(nil)"),
+				Not(ContainSubstring("Watch")),
+			))
+			Expect(matchers).To(HaveKey("storage_item"))
+			Expect(matchers).NotTo(HaveKey("chan_of_int"))
+		})
+
+		It("reports methods that none of the interfaces declares", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test", Methods: []string{"Get", "Put"}})
+
+			Expect(e).To(MatchError("None of the interfaces declares a method Put"))
+		})
+
+		It("reports interfaces that cannot be embedded as unsupported", func() {
+			ast.Interfaces[0].Name = "store"
+
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test", Methods: []string{"Get"}})
+
+			Expect(e).To(MatchError(ContainSubstring("a mock of a subset of the methods must embed the interface")))
+		})
+
+		It("rejects Template", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test", Methods: []string{"Get"}, Template: "{{.}}"})
+
+			Expect(e).To(MatchError("Options.Template cannot be combined with Options.Methods"))
+		})
+	})
+
+	Context("doc comments", func() {
+		ast := &model.Package{
+			Name:    "storage",
+			PkgPath: "example.com/storage",
+			Interfaces: []*model.Interface{
+				&model.Interface{
+					Name: "Store",
+					Doc:  "Store stores items.\n\nIt is safe for concurrent use.",
+					Methods: []*model.Method{
+						&model.Method{
+							Name: "Get",
+							Doc:  "Get returns the item stored under key.",
+							In:   []*model.Parameter{&model.Parameter{Name: "key", Type: model.PredeclaredType("string")}},
+						},
+						&model.Method{Name: "Flush"},
+					},
+				},
+			},
+		}
+
+		It("carries the doc comments of the interface and its methods into the mock", func() {
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("\n// MockStore is a mock of Store.\n//\n// Store stores items.\n//\n// It is safe for concurrent use.\ntype MockStore struct {"),
+				ContainSubstring("\n// Get returns the item stored under key.\nfunc (mock *MockStore) Get(key string) {"),
+				ContainSubstring("\n\nfunc (mock *MockStore) Flush() {"),
+			))
+		})
+
+		It("carries them into mocks of all styles", func() {
+			for _, style := range []string{mockgen.TestifyStyle, mockgen.GomockStyle, mockgen.FakeStyle} {
+				output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test", Style: style})
+
+				Expect(e).NotTo(HaveOccurred())
+				Expect(string(output)).To(SatisfyAll(
+					MatchRegexp(`\n// \w+Store is a (mock|fake) of Store.\n//\n// Store stores items.\n//\n// It is safe for concurrent use.\ntype \w+Store struct \{`),
+					MatchRegexp(`// Get returns the item stored under key.\nfunc \(\w+ \*\w+\) Get\(key string\)`),
+				), style)
+			}
+		})
+
+		It("changes the input hash, but not the interface hash", func() {
+			withoutDocs := &model.Package{
+				Name:    ast.Name,
+				PkgPath: ast.PkgPath,
+				Interfaces: []*model.Interface{&model.Interface{
+					Name:    "Store",
+					Methods: []*model.Method{&model.Method{Name: "Get", In: ast.Interfaces[0].Methods[0].In}, &model.Method{Name: "Flush"}},
+				}},
+			}
+			options := mockgen.Options{PackageOut: "storage_test", RecordInputHash: true}
+
+			Expect(mockgen.InterfaceHash(withoutDocs)).To(Equal(mockgen.InterfaceHash(ast)))
+			Expect(mockgen.InputHash(withoutDocs, options)).NotTo(Equal(mockgen.InputHash(ast, options)))
+		})
+	})
+
+	Context("testify style", func() {
+		ast := &model.Package{
+			Name:    "storage",
+			PkgPath: "example.com/storage",
+			Interfaces: []*model.Interface{
+				&model.Interface{
+					Name: "Store",
+					Methods: []*model.Method{
+						&model.Method{
+							Name: "Get",
+							In:   []*model.Parameter{&model.Parameter{Name: "key", Type: model.PredeclaredType("string")}},
+							Out: []*model.Parameter{
+								&model.Parameter{Type: model.PredeclaredType("int")},
+								&model.Parameter{Type: model.PredeclaredType("error")},
+							},
+						},
+						&model.Method{
+							Name:     "Delete",
+							Variadic: &model.Parameter{Name: "keys", Type: model.PredeclaredType("string")},
+						},
+					},
+				},
+			},
+		}
+
+		It("generates mocks embedding testify's mock.Mock", func() {
+			output, matchers, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{PackageOut: "storage_test", Style: mockgen.TestifyStyle})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(matchers).To(BeEmpty())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`"github.com/stretchr/testify/mock"`),
+				ContainSubstring("type MockStore struct {\n\tmock.Mock\n}"),
+				ContainSubstring("func NewMockStore(t interface {\n\tmock.TestingT\n\tCleanup(func())\n}) *MockStore {"),
+				ContainSubstring("t.Cleanup(func() { m.Mock.AssertExpectations(t) })"),
+				ContainSubstring("var _ storage.Store = (*MockStore)(nil)"),
+				ContainSubstring("func (_m *MockStore) Get(key string) (int, error) {\n\t_ret := _m.Mock.Called(key)"),
+				ContainSubstring("if _rf, ok := _ret.Get(0).(func(string) int); ok {\n\t\t_r0 = _rf(key)\n\t} else if _ret.Get(0) != nil {\n\t\t_r0 = _ret.Get(0).(int)\n\t}"),
+				ContainSubstring("} else {\n\t\t_r1 = _ret.Error(1)\n\t}"),
+				ContainSubstring("_ca := []interface{}{}\n\tfor _, _va := range keys {\n\t\t_ca = append(_ca, _va)\n\t}\n\t_m.Mock.Called(_ca...)"),
+				Not(ContainSubstring(`"github.com/petergtz/pegomock"`)),
+				Not(ContainSubstring("Verifier")),
+			))
+		})
+
+		It("reports spies of struct types as unsupported", func() {
+			_, e := mockgen.Generate(&model.Package{
+				Name:       "counting",
+				PkgPath:    "example.com/counting",
+				Interfaces: []*model.Interface{&model.Interface{Name: "Counter", IsStruct: true}},
+			}, mockgen.Options{PackageOut: "counting_test", Style: mockgen.TestifyStyle})
+
+			Expect(e).To(MatchError(ContainSubstring("spies of struct types are not supported in the testify style")))
+		})
+
+		It("rejects builders", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test", Style: mockgen.TestifyStyle, GenerateBuilders: true})
+
+			Expect(e).To(MatchError(`Options.GenerateBuilders is not supported with Options.Style "testify"`))
+		})
+
+		It("rejects unknown styles", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test", Style: "mockery"})
+
+			Expect(e).To(MatchError(`Options.Style must be "pegomock", "testify", "gomock" or "fake", but is "mockery"`))
+		})
+	})
+
+	Context("fake style", func() {
+		It("generates counterfeiter-like fakes named Fake<Interface>", func() {
+			ast := &model.Package{
+				Name:    "storage",
+				PkgPath: "example.com/storage",
+				Interfaces: []*model.Interface{
+					&model.Interface{
+						Name: "Store",
+						Methods: []*model.Method{
+							&model.Method{
+								Name: "Get",
+								In:   []*model.Parameter{&model.Parameter{Name: "key", Type: model.PredeclaredType("string")}},
+								Out: []*model.Parameter{
+									&model.Parameter{Type: model.PredeclaredType("int")},
+									&model.Parameter{Type: model.PredeclaredType("error")},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			output, matchers, e := mockgen.GenerateWithMatchers(ast, mockgen.Options{PackageOut: "storage_test", Style: mockgen.FakeStyle})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(matchers).To(BeEmpty())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("type FakeStore struct {"),
+				ContainSubstring("GetStub        func(key string) (int, error)"),
+				ContainSubstring("var _ storage.Store = (*FakeStore)(nil)"),
+				ContainSubstring("func (fake *FakeStore) GetCallCount() int {"),
+				ContainSubstring("func (fake *FakeStore) GetArgsForCall(i int) string {"),
+				ContainSubstring("func (fake *FakeStore) GetReturns(result1 int, result2 error) {"),
+				ContainSubstring("func (fake *FakeStore) GetReturnsOnCall(i int, result1 int, result2 error) {"),
+				ContainSubstring("func (fake *FakeStore) Invocations() map[string][][]interface{} {"),
+				Not(ContainSubstring(`"github.com/petergtz/pegomock"`)),
+			))
+		})
+	})
+
+	Context("dependency injection", func() {
+		ast := &model.Package{
+			Name:    "storage",
+			PkgPath: "example.com/storage",
+			Interfaces: []*model.Interface{
+				&model.Interface{
+					Name:    "Store",
+					Methods: []*model.Method{&model.Method{Name: "Flush"}},
+				},
+				&model.Interface{
+					Name:       "Cache",
+					TypeParams: []*model.TypeParam{&model.TypeParam{Name: "T", Constraint: model.PredeclaredType("any")}},
+					Methods:    []*model.Method{&model.Method{Name: "Clear"}},
+				},
+			},
+		}
+
+		It("generates a provider function and a ProviderSet for wire", func() {
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "mocks", DependencyInjection: mockgen.WireInjection})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`wire "github.com/google/wire"`),
+				ContainSubstring("func ProvideMockStore() *MockStore {\n\treturn NewMockStore()\n}"),
+				ContainSubstring("var MockStoreProviderSet = wire.NewSet(ProvideMockStore, wire.Bind(new(storage.Store), new(*MockStore)))"),
+				Not(ContainSubstring("MockCacheProviderSet")),
+			))
+		})
+
+		It("generates an fx.Option", func() {
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "mocks", DependencyInjection: mockgen.FxInjection})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring(`fx "go.uber.org/fx"`),
+				ContainSubstring("var MockStoreModule = fx.Provide(\n\tfunc() *MockStore { return NewMockStore() },\n\tfunc(mock *MockStore) storage.Store { return mock },\n)"),
+				Not(ContainSubstring("MockCacheModule")),
+			))
+		})
+
+		It("rejects other styles than pegomock", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "mocks", Style: mockgen.FakeStyle, DependencyInjection: mockgen.WireInjection})
+
+			Expect(e).To(MatchError(`Options.DependencyInjection is not supported with Options.Style "fake"`))
+		})
+
+		It("rejects unknown frameworks", func() {
+			_, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "mocks", DependencyInjection: "dig"})
+
+			Expect(e).To(MatchError(`Options.DependencyInjection must be empty, "wire" or "fx", but is "dig"`))
+		})
+	})
+
+	Context("JSON model", func() {
+		roundTripped := func(pkg *model.Package) *model.Package {
+			content, e := json.Marshal(pkg)
+			Expect(e).NotTo(HaveOccurred())
+			var result model.Package
+			Expect(json.Unmarshal(content, &result)).To(Succeed())
+			return &result
+		}
+
+		It("generates the same mocks from a model read back from JSON", func() {
+			for _, args := range [][]string{
+				{filepath.Join("test_data", "corpus", "corpus.go")},
+				{"github.com/petergtz/pegomock/test_interface", "Display"},
+			} {
+				pkg, _, e := filehandling.LoadModel(args, false)
+				Expect(pkg).NotTo(BeNil(), fmt.Sprint(e))
+				options := mockgen.Options{PackageOut: "mocks", GenerateBuilders: true}
+
+				expected, expectedMatchers, expectedErr := mockgen.GenerateWithMatchers(pkg, options)
+				actual, actualMatchers, actualErr := mockgen.GenerateWithMatchers(roundTripped(pkg), options)
+
+				Expect(string(actual)).To(Equal(string(expected)))
+				Expect(actualMatchers).To(Equal(expectedMatchers))
+				Expect(fmt.Sprint(actualErr)).To(Equal(fmt.Sprint(expectedErr)))
+			}
+		})
+
+		It("rejects unknown kinds of types", func() {
+			var pkg model.Package
+			e := json.Unmarshal([]byte(`{"name": "storage", "interfaces": [{"name": "Store", "methods": [
+				{"name": "Get", "in": [{"name": "key", "type": {"kind": "tuple"}}]}]}]}`), &pkg)
+
+			Expect(e).To(MatchError(`interface Store: method Get: unknown kind of type "tuple"`))
+		})
+	})
+
+	Context("input hash", func() {
+		storeWithFlush := func(paramType model.Type) *model.Package {
+			return &model.Package{
+				Name:    "storage",
+				PkgPath: "example.com/storage",
+				Interfaces: []*model.Interface{&model.Interface{
+					Name:    "Store",
+					Methods: []*model.Method{&model.Method{Name: "Flush", In: []*model.Parameter{&model.Parameter{Name: "key", Type: paramType}}}},
+				}},
+			}
+		}
+
+		It("records the input hash in the header", func() {
+			options := mockgen.Options{PackageOut: "mocks", RecordInputHash: true}
+			output, e := mockgen.Generate(storeWithFlush(model.PredeclaredType("string")), options)
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(ContainSubstring("\n// Input hash: " + mockgen.InputHash(storeWithFlush(model.PredeclaredType("string")), options) + "\n"))
+			Expect(mockgen.RecordedInputHash(output)).To(Equal(mockgen.InputHash(storeWithFlush(model.PredeclaredType("string")), options)))
+		})
+
+		It("always records the generator and the interface hash", func() {
+			output, e := mockgen.Generate(storeWithFlush(model.PredeclaredType("string")), mockgen.Options{PackageOut: "mocks"})
+
+			Expect(e).NotTo(HaveOccurred())
+			version, generatedCodeVersion := mockgen.RecordedGenerator(output)
+			Expect(version).To(Equal(mockgen.Version()))
+			Expect(generatedCodeVersion).To(Equal(mockgen.GeneratedCodeVersion))
+			Expect(mockgen.RecordedInterfaceHash(output)).To(Equal(mockgen.InterfaceHash(storeWithFlush(model.PredeclaredType("string")))))
+			Expect(string(output)).To(ContainSubstring("\nconst _ = pegomock.SupportsGeneratedCodeVersion2\n"))
+		})
+
+		It("hashes the interfaces independently of the options", func() {
+			Expect(mockgen.InterfaceHash(storeWithFlush(model.PredeclaredType("string")))).
+				NotTo(Equal(mockgen.InterfaceHash(storeWithFlush(model.PredeclaredType("int")))))
+			output, e := mockgen.Generate(storeWithFlush(model.PredeclaredType("string")), mockgen.Options{PackageOut: "othermocks", RecordInputHash: true})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(mockgen.RecordedInterfaceHash(output)).To(Equal(mockgen.InterfaceHash(storeWithFlush(model.PredeclaredType("string")))))
+		})
+
+		It("does not record the input hash by default", func() {
+			output, e := mockgen.Generate(storeWithFlush(model.PredeclaredType("string")), mockgen.Options{PackageOut: "mocks"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(mockgen.RecordedInputHash(output)).To(BeEmpty())
+		})
+
+		It("changes with the method set, the packages of types and the options", func() {
+			options := mockgen.Options{PackageOut: "mocks", RecordInputHash: true}
+			hash := mockgen.InputHash(storeWithFlush(model.PredeclaredType("string")), options)
+
+			Expect(mockgen.InputHash(storeWithFlush(model.PredeclaredType("int")), options)).NotTo(Equal(hash))
+			Expect(mockgen.InputHash(storeWithFlush(&model.NamedType{Package: "example.com/a", Type: "Key"}), options)).
+				NotTo(Equal(mockgen.InputHash(storeWithFlush(&model.NamedType{Package: "example.com/b", Type: "Key"}), options)))
+			Expect(mockgen.InputHash(storeWithFlush(model.PredeclaredType("string")), mockgen.Options{PackageOut: "othermocks", RecordInputHash: true})).
+				NotTo(Equal(hash))
+		})
+	})
+
+	Context("header", func() {
+		It("identifies code generated by pegomock and its source", func() {
+			output, e := mockgen.Generate(&model.Package{
+				Name:       "storage",
+				PkgPath:    "example.com/storage",
+				Interfaces: []*model.Interface{&model.Interface{Name: "Store", Methods: []*model.Method{&model.Method{Name: "Flush"}}}},
+			}, mockgen.Options{PackageOut: "mocks", Source: "example.com/storage (interfaces: Store)", Header: "Copyright ACME"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(mockgen.GeneratedByPegomock(output)).To(BeTrue())
+			Expect(mockgen.RecordedSource(output)).To(Equal("example.com/storage (interfaces: Store)"))
+			Expect(mockgen.GeneratedByPegomock([]byte("package mocks\n\n// Code generated by pegomock. DO NOT EDIT.\n"))).To(BeFalse())
+			Expect(mockgen.RecordedSource([]byte("package mocks\n"))).To(BeEmpty())
+		})
+	})
+
+	Context("imports", func() {
+		It("does not import reflect for mocks whose methods return nothing", func() {
+			output, e := mockgen.Generate(&model.Package{
+				Name:    "storage",
+				PkgPath: "example.com/storage",
+				Interfaces: []*model.Interface{&model.Interface{
+					Name:    "Store",
+					Methods: []*model.Method{&model.Method{Name: "Flush", In: []*model.Parameter{&model.Parameter{Name: "key", Type: model.PredeclaredType("string")}}}},
+				}},
+			}, mockgen.Options{PackageOut: "storage_test"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("func (mock *MockStore) Flush(key string) {"),
+				Not(ContainSubstring(`"reflect"`)),
+			))
+		})
+
+		It("removes unused imports, but never adds any", func() {
+			output, e := mockgen.Generate(&model.Package{Name: "storage"}, mockgen.Options{PackageOut: "stubs", Template: `package {{.PackageOut}}
+
+import (
+	"fmt"
+	"reflect"
+	"math/rand/v2"
+	keys "example.com/keys"
+	"example.com/unknown"
+)
+
+var _ = fmt.Sprint(strings.ToUpper("x"))
+`})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(Equal("package stubs\n\nimport (\n\t\"example.com/unknown\"\n\t\"fmt\"\n)\n\nvar _ = fmt.Sprint(strings.ToUpper(\"x\"))\n"))
+		})
+	})
+
+	Context("template", func() {
+		store := &model.Package{
+			Name:    "storage",
+			PkgPath: "example.com/storage",
+			Interfaces: []*model.Interface{&model.Interface{
+				Name: "Store",
+				Methods: []*model.Method{
+					&model.Method{
+						Name: "Get",
+						In:   []*model.Parameter{&model.Parameter{Name: "key", Type: &model.NamedType{Package: "example.com/keys", Type: "Key"}}},
+						Out:  []*model.Parameter{&model.Parameter{Type: model.PredeclaredType("string")}, &model.Parameter{Type: model.PredeclaredType("error")}},
+					},
+					&model.Method{Name: "Flush", Variadic: &model.Parameter{Name: "keys", Type: model.PredeclaredType("string")}},
+				},
+			}},
+		}
+
+		It("generates code from the template instead of the built-in style", func() {
+			output, e := mockgen.Generate(store, mockgen.Options{PackageOut: "stubs", Source: "storage.go", Template: `{{.Header}}
+package {{.PackageOut}}
+
+import (
+{{range .Imports}}	{{.Name}} "{{.Path}}"
+{{end}})
+{{range .Interfaces}}
+var _ {{.Type}} = &{{.Interface.Name}}Stub{}
+
+type {{.Interface.Name}}Stub struct{}
+{{$stub := printf "%vStub" .Interface.Name}}{{range .Methods}}
+func (*{{$stub}}) {{.Name}}({{.Params}}) {{.Results}} { panic("{{lower .Name}}({{.Args}})") }
+{{end}}{{end}}`})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(MatchRegexp("^// Code generated by pegomock. DO NOT EDIT.\n// Source: storage.go\n// Generator: pegomock .*\n// Interface hash: [0-9a-f]+\npackage stubs\n"))
+			Expect(string(output)).To(ContainSubstring("\tkeys \"example.com/keys\"\n\tstorage \"example.com/storage\"\n"))
+			Expect(string(output)).To(ContainSubstring("var _ storage.Store = &StoreStub{}\n"))
+			Expect(string(output)).To(ContainSubstring("func (*StoreStub) Get(key keys.Key) (string, error) { panic(\"get(key)\") }\n"))
+			Expect(string(output)).To(ContainSubstring("func (*StoreStub) Flush(keys ...string) { panic(\"flush(keys...)\") }\n"))
+		})
+
+		It("leaves output that is not Go code as it is", func() {
+			output, e := mockgen.Generate(store, mockgen.Options{PackageOut: "stubs", Template: `{{range .Interfaces}}# {{.Interface.Name}}
+{{range .Methods}}* {{.Name}}{{range .In}} {{.Name}} {{type .Type}}{{end}}
+{{end}}{{end}}`})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(Equal("# Store\n* Get key keys.Key\n* Flush\n"))
+		})
+
+		It("reports invalid templates", func() {
+			_, e := mockgen.Generate(store, mockgen.Options{PackageOut: "stubs", Template: "{{.Interfaces"})
+
+			Expect(e).To(MatchError(ContainSubstring("Invalid Options.Template: ")))
+		})
+
+		It("rejects the options of the built-in styles", func() {
+			_, e := mockgen.Generate(store, mockgen.Options{PackageOut: "stubs", Template: "{{.PackageOut}}", Style: mockgen.FakeStyle})
+
+			Expect(e).To(MatchError("Options.Template cannot be combined with Options.Style, Options.GenerateBuilders or Options.DependencyInjection"))
+		})
+	})
+
+	Context("emitters", func() {
+		store := &model.Package{
+			Name:       "storage",
+			PkgPath:    "example.com/storage",
+			Interfaces: []*model.Interface{&model.Interface{Name: "Store", Methods: []*model.Method{&model.Method{Name: "Flush"}}}},
+		}
+		mockgen.RegisterEmitter("test-noop", mockgen.EmitterFunc(func(pkg *model.Package, opts mockgen.Options) (map[string][]byte, error) {
+			files := make(map[string][]byte)
+			for _, iface := range pkg.Interfaces {
+				files["noop_"+iface.Name+".go"] = []byte(fmt.Sprintf("package %v\n\ntype Noop%v struct{}\n", opts.PackageOut, iface.Name))
+			}
+			return files, nil
+		}))
+
+		It("runs registered emitters", func() {
+			files, e := mockgen.Emit(store, mockgen.Options{PackageOut: "mocks"}, []string{"test-noop"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(files).To(Equal(map[string][]byte{"noop_Store.go": []byte("package mocks\n\ntype NoopStore struct{}\n")}))
+			Expect(mockgen.RegisteredEmitters()).To(ContainElement("test-noop"))
+		})
+
+		It("runs executables named after emitters that are not registered", func() {
+			binDir, e := ioutil.TempDir("", "pegomock-emitters")
+			Expect(e).NotTo(HaveOccurred())
+			defer os.RemoveAll(binDir)
+			Expect(ioutil.WriteFile(filepath.Join(binDir, mockgen.ExecutableEmitterPrefix+"test-exec"), []byte(`#!/bin/sh
+grep -q '"packageOut":"mocks"' && echo '{"store.txt": "Store"}'
+`), 0755)).To(Succeed())
+			defer os.Setenv("PATH", os.Getenv("PATH"))
+			os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			files, e := mockgen.Emit(store, mockgen.Options{PackageOut: "mocks"}, []string{"test-exec"})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(files).To(Equal(map[string][]byte{"store.txt": []byte("Store")}))
+		})
+
+		It("reports unknown emitters", func() {
+			_, e := mockgen.Emit(store, mockgen.Options{PackageOut: "mocks"}, []string{"test-unknown"})
+
+			Expect(e).To(MatchError(`Unknown emitter "test-unknown": it is neither registered nor is there an executable pegomock-emitter-test-unknown`))
+		})
+
+		It("reports emitters generating the same file", func() {
+			mockgen.RegisterEmitter("test-noop-again", mockgen.EmitterFunc(func(pkg *model.Package, opts mockgen.Options) (map[string][]byte, error) {
+				return map[string][]byte{"noop_Store.go": nil}, nil
+			}))
+
+			_, e := mockgen.Emit(store, mockgen.Options{PackageOut: "mocks"}, []string{"test-noop", "test-noop-again"})
+
+			Expect(e).To(MatchError(`Emitters "test-noop" and "test-noop-again" both generate noop_Store.go`))
+		})
+
+		It("rejects registering an emitter twice", func() {
+			Expect(func() { mockgen.RegisterEmitter("test-noop", mockgen.EmitterFunc(nil)) }).To(Panic())
+		})
+	})
+
+	Context("gomock style", func() {
+		It("generates mocks created with a Controller and EXPECT() recorders", func() {
+			ast := &model.Package{
+				Name:    "storage",
+				PkgPath: "example.com/storage",
+				Interfaces: []*model.Interface{
+					&model.Interface{
+						Name:       "Store",
+						TypeParams: []*model.TypeParam{&model.TypeParam{Name: "T", Constraint: model.PredeclaredType("any")}},
+						Methods: []*model.Method{
+							&model.Method{
+								Name: "Get",
+								In:   []*model.Parameter{&model.Parameter{Name: "key", Type: model.PredeclaredType("string")}},
+								Out:  []*model.Parameter{&model.Parameter{Type: model.TypeParamType("T")}},
+							},
+						},
+					},
+				},
+			}
+
+			output, e := mockgen.Generate(ast, mockgen.Options{PackageOut: "storage_test", Style: mockgen.GomockStyle})
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(string(output)).To(SatisfyAll(
+				ContainSubstring("func NewMockStore[T any](ctrl *pegomock.Controller, options ...pegomock.Option) *MockStore[T] {"),
+				ContainSubstring("ctrl.RegisterMock(mock)"),
+				ContainSubstring("func (mock *MockStore[T]) EXPECT() *MockStoreMockRecorder[T] {"),
+				ContainSubstring("func (recorder *MockStoreMockRecorder[T]) Get(key interface{}) *pegomock.Call {\n\t"+
+					`return recorder.mock.ctrl.RecordCall(recorder.mock, "Get", []reflect.Type{reflect.TypeOf((*T)(nil)).Elem()}, key)`),
+				ContainSubstring("func (verifier *VerifierStore[T]) Get(key string)"),
 			))
 		})
 	})
 })
+
+func expectNoFindings(dir string, command string, args ...string) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	output, e := cmd.CombinedOutput()
+	Expect(e).NotTo(HaveOccurred(), command+" reported:\n"+string(output))
+}
+
+const genericMocksTest = `package corpus_test
+
+import (
+	"testing"
+
+	"github.com/petergtz/pegomock"
+	"github.com/petergtz/pegomock/mockgen/test_data/corpus"
+)
+
+func TestGenericMocks(t *testing.T) {
+	pegomock.RegisterMockTestingT(t)
+
+	var repository corpus.Repository[string] = NewMockRepository[string]()
+	pegomock.When(repository.Find("1")).ThenReturn("one", nil)
+	if value, err := repository.Find("1"); value != "one" || err != nil {
+		t.Errorf("Find returned %q, %v", value, err)
+	}
+	repository.Save("a", "b")
+	repository.(*MockRepository[string]).VerifyWasCalledOnce().Find("1")
+	if items := repository.(*MockRepository[string]).VerifyWasCalledOnce().Save(pegomock.AnyString(), pegomock.AnyString()).GetCapturedArguments(); len(items) != 2 || items[1] != "b" {
+		t.Errorf("captured %v", items)
+	}
+
+	cache := NewMockCacheBuilder[string, float64]().ReturningOnGet(1.5, true).Build()
+	if value, ok := cache.Get("x"); value != 1.5 || !ok {
+		t.Errorf("Get returned %v, %v", value, ok)
+	}
+	cache.Put("x", 2.5)
+	if key, value := cache.VerifyWasCalledOnce().Put(pegomock.AnyString(), pegomock.AnyFloat64()).GetCapturedArguments(); key != "x" || value != 2.5 {
+		t.Errorf("captured %v, %v", key, value)
+	}
+}
+`
+
+const funcTypeMocksTest = `package corpus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/petergtz/pegomock"
+	"github.com/petergtz/pegomock/mockgen/test_data/corpus"
+	"github.com/petergtz/pegomock/mockgen/test_data/corpus/matchers"
+)
+
+func TestFuncTypeMocks(t *testing.T) {
+	pegomock.RegisterMockTestingT(t)
+
+	handler := NewMockHandler()
+	pegomock.When(handler.Call(context.Background(), "failing")).ThenReturn(errors.New("failed"))
+	var handle corpus.Handler = handler.Func()
+	if err := handle(context.Background(), "failing"); err == nil || err.Error() != "failed" {
+		t.Errorf("handle returned %v", err)
+	}
+	if err := handle(context.Background(), "other"); err != nil {
+		t.Errorf("handle returned %v", err)
+	}
+	if _, event := handler.VerifyWasCalled(pegomock.Times(2)).Call(matchers.AnyContextContext(), pegomock.AnyString()).GetCapturedArguments(); event != "other" {
+		t.Errorf("captured %v", event)
+	}
+
+	transform := NewMockTransform[int]()
+	pegomock.When(transform.Call(1, 2)).ThenReturn([]int{3})
+	var apply corpus.Transform[int] = transform.Func()
+	if items := apply(1, 2); len(items) != 1 || items[0] != 3 {
+		t.Errorf("apply returned %v", items)
+	}
+	transform.VerifyWasCalledOnce().Call(1, 2)
+}
+`
+
+const spiesTest = `package corpus_test
+
+import (
+	"testing"
+
+	"github.com/petergtz/pegomock"
+	"github.com/petergtz/pegomock/mockgen/test_data/corpus"
+)
+
+func TestSpies(t *testing.T) {
+	pegomock.RegisterMockTestingT(t)
+
+	counter := &corpus.Counter{}
+	spy := NewMockCounter(counter)
+	if count := spy.Add(1, 2); count != 3 || counter.Count() != 3 {
+		t.Errorf("Add returned %v, wrapped count is %v", count, counter.Count())
+	}
+	pegomock.When(spy.Count()).ThenReturn(42)
+	if count := spy.Count(); count != 42 {
+		t.Errorf("stubbed Count returned %v", count)
+	}
+	spy.Reset()
+	if counter.Count() != 0 {
+		t.Errorf("Reset did not reach the wrapped instance")
+	}
+	if deltas := spy.VerifyWasCalledOnce().Add(pegomock.AnyInt(), pegomock.AnyInt()).GetCapturedArguments(); len(deltas) != 2 || deltas[1] != 2 {
+		t.Errorf("captured %v", deltas)
+	}
+	spy.VerifyWasCalledOnce().Reset()
+}
+`
+
+const gomockStyleMocksTest = `package corpus_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/petergtz/pegomock"
+	"github.com/petergtz/pegomock/mockgen/test_data/corpus"
+)
+
+func TestGomockStyleMocks(t *testing.T) {
+	ctrl := pegomock.NewController(t)
+	fluent := NewGomockFluent(ctrl)
+	fluent.EXPECT().Where("a").Return(fluent).Times(2)
+	fluent.EXPECT().Union().DoAndReturn(func(others ...corpus.Fluent) corpus.Fluent { return nil }).AnyTimes()
+	voids := NewGomockVoids(ctrl)
+	var prefixes []string
+	voids.EXPECT().MixedVariadic("x", 1, anything{}).Do(func(prefix string, values ...interface{}) {
+		prefixes = append(prefixes, prefix)
+	})
+	voids.EXPECT().NamedParams(pegomock.AnyString(), pegomock.EqInt(2))
+
+	if result := fluent.Where("a").Where("a"); result != fluent {
+		t.Errorf("Where returned %v", result)
+	}
+	if result := fluent.Union(); result != nil {
+		t.Errorf("Union returned %v", result)
+	}
+	voids.MixedVariadic("x", 1, "anything")
+	voids.NamedParams("s", 2)
+	if len(prefixes) != 1 || prefixes[0] != "x" {
+		t.Errorf("Do got %v", prefixes)
+	}
+	voids.VerifyWasCalledOnce().NamedParams("s", 2)
+}
+
+func TestGomockStyleMocksReportUnexpectedAndMissingCalls(t *testing.T) {
+	fakeT := &recordingT{}
+	voids := NewGomockVoids(pegomock.NewController(fakeT))
+	voids.EXPECT().NamedParams("s", 1)
+	voids.EXPECT().NoParams()
+
+	voids.NamedParams("s", 2)
+	voids.OnlyVariadic("a")
+	fakeT.finish()
+
+	failures := strings.Join(fakeT.failures, "\n")
+	for _, expected := range []string{
+		"Unexpected call to NamedParams(\"s\", 2)",
+		"NamedParams(Eq(s), Eq(1)), expected at gomock_style_mocks_test.go:",
+		"OnlyVariadic(\"a\") on a strict mock",
+		"Missing calls:",
+		"NoParams(), expected at gomock_style_mocks_test.go:",
+	} {
+		if !strings.Contains(failures, expected) {
+			t.Errorf("Failures do not contain %q:\n%v", expected, failures)
+		}
+	}
+}
+
+func TestGomockStyleMocksReturnValuesOfCallsWithDo(t *testing.T) {
+	ctrl := pegomock.NewController(t)
+	fluent := NewGomockFluent(ctrl)
+	var wheres []string
+	fluent.EXPECT().Where("a").Return(fluent).Do(func(condition string) { wheres = append(wheres, condition) })
+	fluent.EXPECT().Where("b").Do(func(condition string) { wheres = append(wheres, condition) }).Return(fluent)
+
+	if result := fluent.Where("a"); result != fluent {
+		t.Errorf("Where with Do after Return returned %v", result)
+	}
+	if result := fluent.Where("b"); result != fluent {
+		t.Errorf("Where with Do before Return returned %v", result)
+	}
+	if len(wheres) != 2 {
+		t.Errorf("Do got %v", wheres)
+	}
+}
+
+func TestGomockStyleMocksKeepExplicitLimitsOfTimes(t *testing.T) {
+	fakeT := &recordingT{}
+	voids := NewGomockVoids(pegomock.NewController(fakeT))
+	voids.EXPECT().NoParams().Times(1).MinTimes(0)
+	voids.EXPECT().OnlyVariadic().Times(3).MaxTimes(4)
+
+	voids.NoParams()
+	voids.NoParams()
+	voids.OnlyVariadic()
+	fakeT.finish()
+
+	failures := strings.Join(fakeT.failures, "\n")
+	for _, expected := range []string{
+		"Unexpected call to NoParams()",
+		"OnlyVariadic(), expected at gomock_style_mocks_test.go:",
+		"called 1 times, but expected at least 3",
+	} {
+		if !strings.Contains(failures, expected) {
+			t.Errorf("Failures do not contain %q:\n%v", expected, failures)
+		}
+	}
+}
+
+type anything struct{}
+
+func (anything) Matches(x interface{}) bool { return true }
+func (anything) String() string             { return "is anything" }
+
+type recordingT struct {
+	failures []string
+	cleanups []func()
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Cleanup(cleanup func()) { t.cleanups = append(t.cleanups, cleanup) }
+
+func (t *recordingT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+`
+
+const subsetMocksTest = `package corpus_test
+
+import (
+	"testing"
+
+	"github.com/petergtz/pegomock"
+	"github.com/petergtz/pegomock/mockgen/test_data/corpus"
+)
+
+func TestSubsetMocks(t *testing.T) {
+	pegomock.RegisterMockTestingT(t)
+
+	var returns corpus.Returns = NewSubsetReturns()
+	pegomock.When(returns.Single()).ThenReturn("stubbed")
+	if single := returns.Single(); single != "stubbed" {
+		t.Errorf("stubbed Single returned %v", single)
+	}
+	returns.(*SubsetReturns).VerifyWasCalledOnce().Single()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("calling a method that is not mocked did not panic")
+		}
+	}()
+	returns.Slice()
+}
+`
+
+const fakeStyleMocksTest = `package corpus_test
+
+import (
+	"testing"
+)
+
+func TestFakeStyleMocks(t *testing.T) {
+	fluent := &FakeFluent{}
+	fluent.WhereReturns(fluent)
+	other := &FakeFluent{}
+	fluent.WhereReturnsOnCall(1, other)
+
+	if result := fluent.Where("a"); result != fluent {
+		t.Errorf("first Where returned %v", result)
+	}
+	if result := fluent.Where("b"); result != other {
+		t.Errorf("second Where returned %v", result)
+	}
+	if count := fluent.WhereCallCount(); count != 2 {
+		t.Errorf("Where was called %v times", count)
+	}
+	if cond := fluent.WhereArgsForCall(1); cond != "b" {
+		t.Errorf("second Where got %v", cond)
+	}
+
+	voids := &FakeVoids{}
+	var prefixes []string
+	voids.MixedVariadicCalls(func(prefix string, values ...interface{}) { prefixes = append(prefixes, prefix) })
+	values := []interface{}{1, 2}
+	voids.MixedVariadic("x", values...)
+	values[0] = 3
+	if prefix, recorded := voids.MixedVariadicArgsForCall(0); prefix != "x" || len(recorded) != 2 || recorded[0] != 1 {
+		t.Errorf("MixedVariadic got %v, %v", prefix, recorded)
+	}
+	if len(prefixes) != 1 {
+		t.Errorf("stub got %v", prefixes)
+	}
+	if invocations := voids.Invocations()["MixedVariadic"]; len(invocations) != 1 {
+		t.Errorf("invocations are %v", invocations)
+	}
+}
+`
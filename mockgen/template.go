@@ -0,0 +1,156 @@
+package mockgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/petergtz/pegomock/model"
+)
+
+// TemplateData is what an Options.Template is executed with.
+type TemplateData struct {
+	// Header holds the comment lines the built-in styles start with: build constraints, the
+	// "Code generated" comment or Options.Header, the source and command of the code, and the
+	// versions and hashes pegomock records.
+	Header string
+	// PackageOut is the package of the generated code.
+	PackageOut string
+	// Imports are the packages the types of the interfaces refer to, including the package of
+	// the interfaces unless it is PackageOut, sorted by path. If the output is Go code, unused
+	// ones are removed.
+	Imports []TemplateImport
+	// Interfaces are the interfaces without unsupported constructs.
+	Interfaces []*TemplateInterface
+}
+
+// TemplateImport is an imported package and the name the generated code refers to it by.
+type TemplateImport struct {
+	Name string
+	Path string
+}
+
+// TemplateInterface is an interface with everything a template needs to generate code for it.
+type TemplateInterface struct {
+	*model.Interface
+	// MockName is the name of the mock according to Options.NameTemplates.
+	MockName string
+	// Type refers to the interface in PackageOut, e.g. "io.Reader" or "Store[T]". It is empty
+	// if the interface cannot be referred to, e.g. because it is unexported.
+	Type string
+	// TypeParams is the type parameter list of a generic interface, e.g. "[T any]", and
+	// TypeArgs the type parameters as type arguments, e.g. "[T]". Both are empty otherwise.
+	TypeParams, TypeArgs string
+	Methods              []*TemplateMethod
+}
+
+// TemplateMethod is a method with its signature rendered as Go code in PackageOut.
+type TemplateMethod struct {
+	*model.Method
+	// Params is the parameter list, e.g. "key string, values ...int", with names made up for
+	// unnamed parameters.
+	Params string
+	// Args passes the parameters on, e.g. "key, values...".
+	Args string
+	// ParamTypes are the types of the parameters; a variadic one is a slice, e.g. "[]int".
+	ParamTypes []string
+	// Results is the result list, e.g. "(int, error)", or "" for methods without results.
+	Results     string
+	ResultTypes []string
+}
+
+// generateFromTemplate generates code for the interfaces of pkg by executing text as
+// text/template with TemplateData. The result is formatted if it is Go code.
+func (g *generator) generateFromTemplate(text string, source string, pkg *model.Package, pkgName, selfPackage string) ([]byte, model.UnsupportedConstructErrors, error) {
+	if selfPackage == "" && pkgName == pkg.Name {
+		selfPackage = pkg.PkgPath
+	}
+	interfacesPkgPath := pkg.PkgPath
+	if pkgName != pkg.Name && strings.HasSuffix(pkg.Name, "_test") {
+		interfacesPkgPath = ""
+	}
+
+	var supportedInterfaces []*model.Interface
+	var unsupported model.UnsupportedConstructErrors
+	for _, iface := range pkg.Interfaces {
+		if errs := unsupportedConstructsIn(iface); len(errs) != 0 {
+			unsupported = append(unsupported, errs...)
+			continue
+		}
+		supportedInterfaces = append(supportedInterfaces, iface)
+	}
+
+	importPaths := (&model.Package{Interfaces: supportedInterfaces}).Imports()
+	if interfacesPkgPath != "" {
+		importPaths[interfacesPkgPath] = true
+	}
+	delete(importPaths, selfPackage)
+	packageMap, _ := generateUniquePackageNamesFor(importPaths)
+
+	header := []string{g.header, "// Source: " + source}
+	if g.command != "" {
+		header = append(header, "// Command: "+g.command)
+	}
+	header = append(header,
+		fmt.Sprintf("// Generator: pegomock %v, generated code version %v", Version(), GeneratedCodeVersion),
+		"// Interface hash: "+g.interfaceHash)
+	if g.inputHash != "" && len(unsupported) == 0 {
+		header = append(header, "// Input hash: "+g.inputHash)
+	}
+	data := TemplateData{Header: strings.Join(header, "\n"), PackageOut: pkgName}
+	for importPath, name := range packageMap {
+		data.Imports = append(data.Imports, TemplateImport{Name: name, Path: importPath})
+	}
+	sort.Slice(data.Imports, func(i, j int) bool { return data.Imports[i].Path < data.Imports[j].Path })
+	for _, iface := range supportedInterfaces {
+		templateInterface := &TemplateInterface{
+			Interface:  iface,
+			MockName:   g.names.mock(iface.Name),
+			TypeParams: model.TypeParamsString(iface.TypeParams, packageMap, selfPackage),
+			TypeArgs:   model.TypeArgsString(iface.TypeParams),
+		}
+		if canReferTo(iface, interfacesPkgPath, pkgName == pkg.Name) {
+			templateInterface.Type = typeOf(iface, interfacesPkgPath).String(packageMap, selfPackage)
+		}
+		for _, method := range iface.Methods {
+			args, argNames, argTypes, resultTypes := argDataFor(method, packageMap, selfPackage)
+			callArgs := join(argNames)
+			if method.Variadic != nil {
+				callArgs += "..."
+			}
+			results := ""
+			if len(resultTypes) != 0 {
+				results = "(" + join(resultTypes) + ")"
+			}
+			templateInterface.Methods = append(templateInterface.Methods, &TemplateMethod{
+				Method:      method,
+				Params:      join(args),
+				Args:        callArgs,
+				ParamTypes:  argTypes,
+				Results:     results,
+				ResultTypes: resultTypes,
+			})
+		}
+		data.Interfaces = append(data.Interfaces, templateInterface)
+	}
+
+	tmpl, err := template.New("mock").Funcs(template.FuncMap{
+		"lower":      strings.ToLower,
+		"lowerFirst": lowerFirst,
+		"join":       strings.Join,
+		"type":       func(t model.Type) string { return t.String(packageMap, selfPackage) },
+	}).Parse(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid Options.Template: %v", err)
+	}
+	var output bytes.Buffer
+	if err := tmpl.Execute(&output, data); err != nil {
+		return nil, nil, fmt.Errorf("Executing Options.Template failed: %v", err)
+	}
+	if formatted, err := formatSource(output.Bytes()); err == nil {
+		return formatted, unsupported, nil
+	}
+	return output.Bytes(), unsupported, nil
+}
@@ -0,0 +1,110 @@
+// Package corpus contains interfaces covering the shapes the generator has to deal with.
+// The generated mocks and matchers must pass go vet and staticcheck without findings.
+package corpus
+
+import (
+	"context"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"text/template"
+)
+
+type Empty interface{}
+
+type Voids interface {
+	NoParams()
+	UnnamedParams(string, int)
+	NamedParams(s string, i int)
+	OnlyVariadic(values ...string)
+	MixedVariadic(prefix string, values ...interface{})
+}
+
+type Returns interface {
+	Single() string
+	Multiple() (string, int, error)
+	Named() (n int, err error)
+	Pointer() *http.Request
+	Slice() []byte
+	Map() map[string][]int
+	ReceiveChan() <-chan int
+	SendChan() chan<- string
+	Func() func(int) (string, error)
+	Interface() interface{}
+	Error() error
+}
+
+type Imports interface {
+	Context(ctx context.Context) error
+	Reader(r io.Reader) (io.ReadCloser, error)
+	Templates(t *template.Template, h *htmltemplate.Template)
+	MapOfSamePackage(m map[htmltemplate.HTML]htmltemplate.JS)
+	MapOfDifferentPackages(m map[template.ExecError]io.Reader)
+}
+
+type Fluent interface {
+	Where(cond string) Fluent
+	Union(others ...Fluent) Fluent
+}
+
+type Embedding interface {
+	Voids
+	Extra(b bool) bool
+}
+
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Instantiations interface {
+	Load(key string) (Result[http.Request], error)
+	Batch(items []Pair[string, int]) error
+	Nested(m map[string]Result[[]*http.Request]) Pair[io.Reader, Result[*atomic.Pointer[template.Template]]]
+	Variadic(pairs ...Pair[context.Context, *Result[int]])
+}
+
+type Repository[T any] interface {
+	Find(id string) (T, error)
+	FindAll(filter func(T) bool) []T
+	Save(items ...T) error
+	Pairs() map[string]Pair[string, T]
+}
+
+type Number interface {
+	~int | ~int64 | float64
+}
+
+type Cache[K comparable, V Number] interface {
+	Get(key K) (V, bool)
+	Put(key K, value V)
+	Sum(keys []K, initial *V) Result[V]
+	Reader() io.Reader
+}
+
+type Handler func(ctx context.Context, event string) error
+
+type Transform[T any] func(items ...T) []T
+
+type Counter struct {
+	count int
+}
+
+func (c *Counter) Add(deltas ...int) int {
+	for _, delta := range deltas {
+		c.count += delta
+	}
+	return c.count
+}
+
+func (c Counter) Count() int { return c.count }
+
+func (c *Counter) Reset() { c.count = 0 }
+
+func (c *Counter) snapshot() Counter { return *c }
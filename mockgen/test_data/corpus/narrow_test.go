@@ -0,0 +1,9 @@
+package corpus_test
+
+import "github.com/petergtz/pegomock/mockgen/test_data/corpus"
+
+// Narrow is only declared in the external test package, so its mocks must be generated into it.
+type Narrow interface {
+	Wrap(f corpus.Fluent) Narrow
+	Returns() corpus.Returns
+}
@@ -0,0 +1,107 @@
+package mockgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/petergtz/pegomock/model"
+)
+
+const testifyMockImportPath = "github.com/stretchr/testify/mock"
+
+// generateTestifyMockFor generates a mock of iface in TestifyStyle, which embeds testify's
+// mock.Mock and answers invocations with the return values registered with On.
+func (g *generator) generateTestifyMockFor(iface *model.Interface, pkgPath, selfPackage string, inSamePackage bool) {
+	mockTypeName := g.names.mock(iface.Name)
+	mockPackage := g.packageMap[testifyMockImportPath]
+	g.typeParams = model.TypeParamsString(iface.TypeParams, g.packageMap, selfPackage)
+	g.typeArgs = model.TypeArgsString(iface.TypeParams)
+	g.
+		emptyLine().
+		generateTypeDoc(mockTypeName, "a mock", iface).
+		p("type %v%v struct {", mockTypeName, g.typeParams).
+		p("	%v.Mock", mockPackage)
+	g.generateEmbeddedType(iface, selfPackage)
+	g.
+		p("}").
+		emptyLine().
+		p("// New%v creates a %v that asserts its expectations when t finishes.", mockTypeName, mockTypeName).
+		p("func New%v%v(t interface {", mockTypeName, g.typeParams).
+		p("	%v.TestingT", mockPackage).
+		p("	Cleanup(func())").
+		p("}) *%v%v {", mockTypeName, g.typeArgs).
+		p("	m := &%v%v{}", mockTypeName, g.typeArgs).
+		p("	m.Mock.Test(t)").
+		p("	t.Cleanup(func() { m.Mock.AssertExpectations(t) })").
+		p("	return m").
+		p("}").
+		emptyLine()
+	if canAssertImplementationOf(iface, pkgPath) {
+		interfaceType := &model.NamedType{Package: pkgPath, Type: iface.Name}
+		g.p("var _ %v = (*%v)(nil)", interfaceType.String(g.packageMap, selfPackage), mockTypeName)
+		g.emptyLine()
+	}
+	if iface.IsFuncType && canReferTo(iface, pkgPath, inSamePackage) {
+		g.generateFuncMethod(iface, mockTypeName, pkgPath, selfPackage)
+	}
+	for _, method := range iface.Methods {
+		g.generateTestifyMockMethod(mockTypeName, method, selfPackage)
+		g.emptyLine()
+	}
+}
+
+// generateTestifyMockMethod generates a method that records the invocation with Called and
+// returns the registered return values. Like mocks generated by mockery, a registered return
+// value can also be a function with the method's parameters that computes it.
+func (g *generator) generateTestifyMockMethod(mockTypeName string, method *model.Method, selfPackage string) {
+	args, argNames, argTypes, returnTypes := argDataFor(method, g.packageMap, selfPackage)
+	g.generateDoc(method.Doc)
+	g.p("func (_m *%v%v) %v(%v) (%v) {", mockTypeName, g.typeArgs, method.Name, join(args), join(returnTypes))
+
+	callArgs := join(argNames)
+	funcArgTypes := argTypes
+	if method.Variadic != nil {
+		funcArgTypes = append(append([]string{}, argTypes[:len(argTypes)-1]...), "..."+strings.TrimPrefix(argTypes[len(argTypes)-1], "[]"))
+		g.p("_ca := []interface{}{%v}", join(argNames[:len(argNames)-1]))
+		g.p("for _, _va := range %v {", argNames[len(argNames)-1])
+		g.p("_ca = append(_ca, _va)")
+		g.p("}")
+		callArgs = join(argNames[:len(argNames)-1])
+		if callArgs != "" {
+			callArgs += ", "
+		}
+		callArgs += argNames[len(argNames)-1] + "..."
+	}
+	calledArgs := join(argNames)
+	if method.Variadic != nil {
+		calledArgs = "_ca..."
+	}
+	if len(returnTypes) == 0 {
+		g.p("_m.Mock.Called(%v)", calledArgs)
+		g.p("}")
+		return
+	}
+
+	g.p("_ret := _m.Mock.Called(%v)", calledArgs)
+	returnValues := make([]string, len(returnTypes))
+	for i, returnType := range returnTypes {
+		returnValues[i] = fmt.Sprintf("_r%v", i)
+		g.
+			p("var _r%v %v", i, returnType).
+			p("if _rf, ok := _ret.Get(%v).(func(%v) %v); ok {", i, join(funcArgTypes), returnType).
+			p("_r%v = _rf(%v)", i, callArgs)
+		if returnType == "error" {
+			g.
+				p("} else {").
+				p("_r%v = _ret.Error(%v)", i, i)
+		} else {
+			g.
+				p("} else if _ret.Get(%v) != nil {", i).
+				p("_r%v = _ret.Get(%v).(%v)", i, i, returnType)
+		}
+		g.p("}")
+	}
+	g.
+		p("return %v", join(returnValues)).
+		p("}")
+}
@@ -0,0 +1,51 @@
+package mockgen
+
+import (
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// GeneratedCodeVersion is the version of the code pegomock generates. It is increased whenever
+// generated mocks need a newer pegomock runtime. Generated mocks refer to the constant
+// pegomock.SupportsGeneratedCodeVersion<GeneratedCodeVersion>, so they don't compile with
+// runtimes that don't support them.
+const GeneratedCodeVersion = 2
+
+const modulePathPrefix = "github.com/petergtz/pegomock"
+
+// Version returns the version of pegomock the running program was built with, as recorded by
+// the go command, or "(devel)" if it is not known, e.g. for programs built from a checkout.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if strings.HasPrefix(info.Main.Path, modulePathPrefix) && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if strings.HasPrefix(dep.Path, modulePathPrefix) {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+var generatorPattern = regexp.MustCompile(`(?m)^// Generator: pegomock (\S+), generated code version (\d+)\r?$`)
+
+// RecordedGenerator returns the version of pegomock and of the generated code recorded in
+// source, code generated by pegomock, or "" and 0 if there are none, as in code generated by
+// versions of pegomock that didn't record them.
+func RecordedGenerator(source []byte) (version string, generatedCodeVersion int) {
+	match := generatorPattern.FindSubmatch(source)
+	if match == nil {
+		return "", 0
+	}
+	generatedCodeVersion, err := strconv.Atoi(string(match[2]))
+	if err != nil {
+		return "", 0
+	}
+	return string(match[1]), generatedCodeVersion
+}
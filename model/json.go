@@ -0,0 +1,353 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// The JSON representation of a Package is meant for other tools, e.g. doc generators, and
+// therefore spells out what the Go types leave implicit: every Type is an object whose "kind"
+// tells which of the other fields it has, and the imports of the package are listed. It can be
+// read back, e.g. from a model written by another tool, in which case the imports are ignored,
+// because they follow from the types.
+
+func (pkg *Package) MarshalJSON() ([]byte, error) {
+	imports := make([]string, 0)
+	for importPath := range pkg.Imports() {
+		imports = append(imports, importPath)
+	}
+	sort.Strings(imports)
+	return json.Marshal(struct {
+		Name       string       `json:"name"`
+		PkgPath    string       `json:"pkgPath"`
+		Imports    []string     `json:"imports"`
+		DotImports []string     `json:"dotImports,omitempty"`
+		Interfaces []*Interface `json:"interfaces"`
+	}{pkg.Name, pkg.PkgPath, imports, pkg.DotImports, nonNil(pkg.Interfaces)})
+}
+
+func (intf *Interface) MarshalJSON() ([]byte, error) {
+	kind := "interface"
+	if intf.IsFuncType {
+		kind = "func"
+	} else if intf.IsStruct {
+		kind = "struct"
+	}
+	return json.Marshal(struct {
+		Name       string       `json:"name"`
+		Doc        string       `json:"doc,omitempty"`
+		Kind       string       `json:"kind"`
+		TypeParams []*TypeParam `json:"typeParams,omitempty"`
+		Methods    []*Method    `json:"methods"`
+	}{intf.Name, intf.Doc, kind, intf.TypeParams, nonNil(intf.Methods)})
+}
+
+func (tp *TypeParam) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name       string `json:"name"`
+		Constraint Type   `json:"constraint"`
+	}{tp.Name, tp.Constraint})
+}
+
+func (m *Method) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name     string       `json:"name"`
+		Doc      string       `json:"doc,omitempty"`
+		In       []*Parameter `json:"in"`
+		Variadic *Parameter   `json:"variadic,omitempty"`
+		Out      []*Parameter `json:"out"`
+	}{m.Name, m.Doc, nonNil(m.In), m.Variadic, nonNil(m.Out)})
+}
+
+func (p *Parameter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name string `json:"name,omitempty"`
+		Type Type   `json:"type"`
+	}{p.Name, p.Type})
+}
+
+func (at *ArrayType) MarshalJSON() ([]byte, error) {
+	if at.Len == -1 {
+		return json.Marshal(struct {
+			Kind string `json:"kind"`
+			Elem Type   `json:"elem"`
+		}{"slice", at.Type})
+	}
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		Len  int    `json:"len"`
+		Elem Type   `json:"elem"`
+	}{"array", at.Len, at.Type})
+}
+
+func (ct *ChanType) MarshalJSON() ([]byte, error) {
+	dir := "both"
+	if ct.Dir == RecvDir {
+		dir = "recv"
+	} else if ct.Dir == SendDir {
+		dir = "send"
+	}
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		Dir  string `json:"dir"`
+		Elem Type   `json:"elem"`
+	}{"chan", dir, ct.Type})
+}
+
+func (ft *FuncType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind     string       `json:"kind"`
+		In       []*Parameter `json:"in"`
+		Variadic *Parameter   `json:"variadic,omitempty"`
+		Out      []*Parameter `json:"out"`
+	}{"func", nonNil(ft.In), ft.Variadic, nonNil(ft.Out)})
+}
+
+func (mt *MapType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind  string `json:"kind"`
+		Key   Type   `json:"key"`
+		Value Type   `json:"value"`
+	}{"map", mt.Key, mt.Value})
+}
+
+func (nt *NamedType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind     string `json:"kind"`
+		Package  string `json:"package,omitempty"`
+		Name     string `json:"name"`
+		TypeArgs []Type `json:"typeArgs,omitempty"`
+	}{"named", nt.Package, nt.Type, nt.TypeArgs})
+}
+
+func (pt *PointerType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		Elem Type   `json:"elem"`
+	}{"pointer", pt.Type})
+}
+
+func (ut *UnsupportedType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind        string `json:"kind"`
+		Description string `json:"description"`
+	}{"unsupported", ut.Description})
+}
+
+func (tpt TypeParamType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	}{"typeParam", string(tpt)})
+}
+
+func (ut *UnionType) MarshalJSON() ([]byte, error) {
+	type term struct {
+		Tilde bool `json:"tilde,omitempty"`
+		Type  Type `json:"type"`
+	}
+	terms := make([]term, len(ut.Terms))
+	for i, unionTerm := range ut.Terms {
+		terms[i] = term{unionTerm.Tilde, unionTerm.Type}
+	}
+	return json.Marshal(struct {
+		Kind  string `json:"kind"`
+		Terms []term `json:"terms"`
+	}{"union", terms})
+}
+
+func (pt PredeclaredType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	}{"predeclared", string(pt)})
+}
+
+// nonNil turns nil slices into empty ones, so they become [] instead of null in JSON.
+func nonNil[T any](slice []T) []T {
+	if slice == nil {
+		return []T{}
+	}
+	return slice
+}
+
+func (pkg *Package) UnmarshalJSON(data []byte) error {
+	var jsonPackage struct {
+		Name       string       `json:"name"`
+		PkgPath    string       `json:"pkgPath"`
+		DotImports []string     `json:"dotImports"`
+		Interfaces []*Interface `json:"interfaces"`
+	}
+	if err := json.Unmarshal(data, &jsonPackage); err != nil {
+		return err
+	}
+	if jsonPackage.Name == "" {
+		return fmt.Errorf("package without name")
+	}
+	*pkg = Package{Name: jsonPackage.Name, PkgPath: jsonPackage.PkgPath, DotImports: jsonPackage.DotImports, Interfaces: jsonPackage.Interfaces}
+	return nil
+}
+
+func (intf *Interface) UnmarshalJSON(data []byte) error {
+	var jsonInterface struct {
+		Name       string       `json:"name"`
+		Doc        string       `json:"doc"`
+		Kind       string       `json:"kind"`
+		TypeParams []*TypeParam `json:"typeParams"`
+		Methods    []*Method    `json:"methods"`
+	}
+	if err := json.Unmarshal(data, &jsonInterface); err != nil {
+		return fmt.Errorf("interface %v: %v", jsonInterface.Name, err)
+	}
+	if jsonInterface.Kind != "" && jsonInterface.Kind != "interface" && jsonInterface.Kind != "func" && jsonInterface.Kind != "struct" {
+		return fmt.Errorf("interface %v has unknown kind %q", jsonInterface.Name, jsonInterface.Kind)
+	}
+	*intf = Interface{
+		Name:       jsonInterface.Name,
+		Doc:        jsonInterface.Doc,
+		TypeParams: jsonInterface.TypeParams,
+		Methods:    jsonInterface.Methods,
+		IsFuncType: jsonInterface.Kind == "func",
+		IsStruct:   jsonInterface.Kind == "struct",
+	}
+	return nil
+}
+
+func (tp *TypeParam) UnmarshalJSON(data []byte) error {
+	var jsonTypeParam struct {
+		Name       string          `json:"name"`
+		Constraint json.RawMessage `json:"constraint"`
+	}
+	if err := json.Unmarshal(data, &jsonTypeParam); err != nil {
+		return err
+	}
+	constraint, err := typeFromJSON(jsonTypeParam.Constraint)
+	if err != nil {
+		return fmt.Errorf("constraint of type parameter %v: %v", jsonTypeParam.Name, err)
+	}
+	*tp = TypeParam{Name: jsonTypeParam.Name, Constraint: constraint}
+	return nil
+}
+
+func (m *Method) UnmarshalJSON(data []byte) error {
+	var jsonMethod struct {
+		Name     string       `json:"name"`
+		Doc      string       `json:"doc"`
+		In       []*Parameter `json:"in"`
+		Variadic *Parameter   `json:"variadic"`
+		Out      []*Parameter `json:"out"`
+	}
+	if err := json.Unmarshal(data, &jsonMethod); err != nil {
+		return fmt.Errorf("method %v: %v", jsonMethod.Name, err)
+	}
+	*m = Method{Name: jsonMethod.Name, Doc: jsonMethod.Doc, In: jsonMethod.In, Variadic: jsonMethod.Variadic, Out: jsonMethod.Out}
+	return nil
+}
+
+func (p *Parameter) UnmarshalJSON(data []byte) error {
+	var jsonParameter struct {
+		Name string          `json:"name"`
+		Type json.RawMessage `json:"type"`
+	}
+	if err := json.Unmarshal(data, &jsonParameter); err != nil {
+		return err
+	}
+	t, err := typeFromJSON(jsonParameter.Type)
+	if err != nil {
+		return err
+	}
+	*p = Parameter{Name: jsonParameter.Name, Type: t}
+	return nil
+}
+
+// typeFromJSON reads a Type from its JSON representation, an object whose "kind" tells which
+// of the other fields it has.
+func typeFromJSON(data json.RawMessage) (Type, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, fmt.Errorf("missing type")
+	}
+	var jsonType struct {
+		Kind        string            `json:"kind"`
+		Name        string            `json:"name"`
+		Package     string            `json:"package"`
+		TypeArgs    []json.RawMessage `json:"typeArgs"`
+		Len         int               `json:"len"`
+		Dir         string            `json:"dir"`
+		Elem        json.RawMessage   `json:"elem"`
+		Key         json.RawMessage   `json:"key"`
+		Value       json.RawMessage   `json:"value"`
+		In          []*Parameter      `json:"in"`
+		Variadic    *Parameter        `json:"variadic"`
+		Out         []*Parameter      `json:"out"`
+		Description string            `json:"description"`
+		Terms       []struct {
+			Tilde bool            `json:"tilde"`
+			Type  json.RawMessage `json:"type"`
+		} `json:"terms"`
+	}
+	if err := json.Unmarshal(data, &jsonType); err != nil {
+		return nil, err
+	}
+	switch jsonType.Kind {
+	case "predeclared":
+		return PredeclaredType(jsonType.Name), nil
+	case "typeParam":
+		return TypeParamType(jsonType.Name), nil
+	case "unsupported":
+		return &UnsupportedType{Description: jsonType.Description}, nil
+	case "named":
+		namedType := &NamedType{Package: jsonType.Package, Type: jsonType.Name}
+		for _, typeArg := range jsonType.TypeArgs {
+			t, err := typeFromJSON(typeArg)
+			if err != nil {
+				return nil, err
+			}
+			namedType.TypeArgs = append(namedType.TypeArgs, t)
+		}
+		return namedType, nil
+	case "pointer", "slice", "array", "chan":
+		elem, err := typeFromJSON(jsonType.Elem)
+		if err != nil {
+			return nil, err
+		}
+		switch jsonType.Kind {
+		case "pointer":
+			return &PointerType{Type: elem}, nil
+		case "slice":
+			return &ArrayType{Len: -1, Type: elem}, nil
+		case "array":
+			return &ArrayType{Len: jsonType.Len, Type: elem}, nil
+		}
+		dirs := map[string]ChanDir{"both": 0, "": 0, "recv": RecvDir, "send": SendDir}
+		dir, known := dirs[jsonType.Dir]
+		if !known {
+			return nil, fmt.Errorf("unknown channel direction %q", jsonType.Dir)
+		}
+		return &ChanType{Dir: dir, Type: elem}, nil
+	case "map":
+		key, err := typeFromJSON(jsonType.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := typeFromJSON(jsonType.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &MapType{Key: key, Value: value}, nil
+	case "func":
+		return &FuncType{In: jsonType.In, Variadic: jsonType.Variadic, Out: jsonType.Out}, nil
+	case "union":
+		unionType := &UnionType{}
+		for _, term := range jsonType.Terms {
+			t, err := typeFromJSON(term.Type)
+			if err != nil {
+				return nil, err
+			}
+			unionType.Terms = append(unionType.Terms, &UnionTerm{Tilde: term.Tilde, Type: t})
+		}
+		return unionType, nil
+	default:
+		return nil, fmt.Errorf("unknown kind of type %q", jsonType.Kind)
+	}
+}
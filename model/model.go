@@ -24,6 +24,7 @@ import (
 // Package is a Go package. It may be a subset.
 type Package struct {
 	Name       string
+	PkgPath    string // import path of the package; empty if unknown
 	Interfaces []*Interface
 	DotImports []string
 }
@@ -44,28 +45,79 @@ func (pkg *Package) Imports() map[string]bool {
 	return im
 }
 
-// Interface is a Go interface.
+// FuncTypeMethod is the name of the single method of an Interface that stands for a function type.
+const FuncTypeMethod = "Call"
+
+// Interface is a Go interface, or a function type that is mocked like an interface with
+// the single method FuncTypeMethod, or the exported method set of a pointer to a struct type,
+// whose mock is a spy wrapping such a pointer.
 type Interface struct {
-	Name    string
-	Methods []*Method
+	Name       string
+	Doc        string       // the text of the doc comment of the declaration, empty if there is none
+	TypeParams []*TypeParam // empty unless the interface is generic
+	Methods    []*Method
+	IsFuncType bool
+	IsStruct   bool
 }
 
 func (intf *Interface) Print(w io.Writer) {
-	fmt.Fprintf(w, "interface %s\n", intf.Name)
+	kind := "interface"
+	if intf.IsFuncType {
+		kind = "func"
+	} else if intf.IsStruct {
+		kind = "struct"
+	}
+	fmt.Fprintf(w, "%s %s%s\n", kind, intf.Name, TypeParamsString(intf.TypeParams, nil, ""))
 	for _, m := range intf.Methods {
 		m.Print(w)
 	}
 }
 
 func (intf *Interface) addImports(im map[string]bool) {
+	for _, tp := range intf.TypeParams {
+		tp.Constraint.addImports(im)
+	}
 	for _, m := range intf.Methods {
 		m.addImports(im)
 	}
 }
 
+// TypeParam is a type parameter of a generic interface, e.g. T in Repository[T any].
+type TypeParam struct {
+	Name       string
+	Constraint Type
+}
+
+// TypeParamsString renders the type parameter list of a generic type declaration,
+// e.g. "[K comparable, V any]". It is empty if there are no type parameters.
+func TypeParamsString(typeParams []*TypeParam, pm map[string]string, pkgOverride string) string {
+	if len(typeParams) == 0 {
+		return ""
+	}
+	params := make([]string, len(typeParams))
+	for i, tp := range typeParams {
+		params[i] = tp.Name + " " + tp.Constraint.String(pm, pkgOverride)
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// TypeArgsString renders the type parameters as type arguments of a generic type,
+// e.g. "[K, V]". It is empty if there are no type parameters.
+func TypeArgsString(typeParams []*TypeParam) string {
+	if len(typeParams) == 0 {
+		return ""
+	}
+	names := make([]string, len(typeParams))
+	for i, tp := range typeParams {
+		names[i] = tp.Name
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
 // Method is a single method of an interface.
 type Method struct {
 	Name     string
+	Doc      string // the text of the doc comment of the declaration, empty if there is none
 	In, Out  []*Parameter
 	Variadic *Parameter // may be nil
 }
@@ -120,6 +172,8 @@ func (p *Parameter) Print(w io.Writer) {
 type Type interface {
 	String(pm map[string]string, pkgOverride string) string
 	addImports(im map[string]bool)
+	// unsupported returns the first construct in the type that cannot be rendered, or nil.
+	unsupported() *UnsupportedType
 }
 
 // ArrayType is an array or slice type.
@@ -137,6 +191,7 @@ func (at *ArrayType) String(pm map[string]string, pkgOverride string) string {
 }
 
 func (at *ArrayType) addImports(im map[string]bool) { at.Type.addImports(im) }
+func (at *ArrayType) unsupported() *UnsupportedType { return at.Type.unsupported() }
 
 // ChanType is a channel type.
 type ChanType struct {
@@ -156,6 +211,7 @@ func (ct *ChanType) String(pm map[string]string, pkgOverride string) string {
 }
 
 func (ct *ChanType) addImports(im map[string]bool) { ct.Type.addImports(im) }
+func (ct *ChanType) unsupported() *UnsupportedType { return ct.Type.unsupported() }
 
 // ChanDir is a channel direction.
 type ChanDir int
@@ -204,6 +260,19 @@ func (ft *FuncType) addImports(im map[string]bool) {
 	}
 }
 
+func (ft *FuncType) unsupported() *UnsupportedType {
+	params := append(append([]*Parameter{}, ft.In...), ft.Out...)
+	if ft.Variadic != nil {
+		params = append(params, ft.Variadic)
+	}
+	for _, p := range params {
+		if ut := p.Type.unsupported(); ut != nil {
+			return ut
+		}
+	}
+	return nil
+}
+
 // MapType is a map type.
 type MapType struct {
 	Key, Value Type
@@ -218,23 +287,55 @@ func (mt *MapType) addImports(im map[string]bool) {
 	mt.Value.addImports(im)
 }
 
+func (mt *MapType) unsupported() *UnsupportedType {
+	if ut := mt.Key.unsupported(); ut != nil {
+		return ut
+	}
+	return mt.Value.unsupported()
+}
+
 // NamedType is an exported type in a package.
 type NamedType struct {
-	Package string // may be empty
-	Type    string // TODO: should this be typed Type?
+	Package  string // may be empty
+	Type     string // TODO: should this be typed Type?
+	TypeArgs []Type // type arguments of an instantiated generic type; empty otherwise
 }
 
 func (nt *NamedType) String(pm map[string]string, pkgOverride string) string {
 	// TODO: is this right?
 	if pkgOverride == nt.Package {
-		return nt.Type
+		return nt.Type + nt.typeArgsString(pm, pkgOverride)
 	}
-	return pm[nt.Package] + "." + nt.Type
+	return pm[nt.Package] + "." + nt.Type + nt.typeArgsString(pm, pkgOverride)
 }
+
+func (nt *NamedType) typeArgsString(pm map[string]string, pkgOverride string) string {
+	if len(nt.TypeArgs) == 0 {
+		return ""
+	}
+	args := make([]string, len(nt.TypeArgs))
+	for i, arg := range nt.TypeArgs {
+		args[i] = arg.String(pm, pkgOverride)
+	}
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
 func (nt *NamedType) addImports(im map[string]bool) {
 	if nt.Package != "" {
 		im[nt.Package] = true
 	}
+	for _, arg := range nt.TypeArgs {
+		arg.addImports(im)
+	}
+}
+
+func (nt *NamedType) unsupported() *UnsupportedType {
+	for _, arg := range nt.TypeArgs {
+		if ut := arg.unsupported(); ut != nil {
+			return ut
+		}
+	}
+	return nil
 }
 
 // PointerType is a pointer to another type.
@@ -246,9 +347,132 @@ func (pt *PointerType) String(pm map[string]string, pkgOverride string) string {
 	return "*" + pt.Type.String(pm, pkgOverride)
 }
 func (pt *PointerType) addImports(im map[string]bool) { pt.Type.addImports(im) }
+func (pt *PointerType) unsupported() *UnsupportedType { return pt.Type.unsupported() }
+
+// UnsupportedType stands in for a type construct that could be parsed, but
+// cannot be rendered as part of a mock. It renders as its description in angle
+// brackets, which is no Go code, so code generators check types with CheckType
+// before rendering them.
+type UnsupportedType struct {
+	Description string
+}
+
+func (ut *UnsupportedType) String(pm map[string]string, pkgOverride string) string {
+	return "<unsupported type " + ut.Description + ">"
+}
+func (ut *UnsupportedType) addImports(im map[string]bool) {}
+func (ut *UnsupportedType) unsupported() *UnsupportedType { return ut }
+
+// UnsupportedTypeError is returned when a type cannot be rendered as Go source code.
+type UnsupportedTypeError struct {
+	Description string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "unsupported type " + e.Description
+}
+
+// CheckType returns an *UnsupportedTypeError if t is missing or contains a
+// construct that cannot be rendered as Go source code.
+func CheckType(t Type) error {
+	if t == nil {
+		return &UnsupportedTypeError{Description: "<missing type>"}
+	}
+	if ut := t.unsupported(); ut != nil {
+		return &UnsupportedTypeError{Description: ut.Description}
+	}
+	return nil
+}
+
+// TypeString is like t.String, but returns the error of CheckType instead
+// of rendering types that cannot be rendered as Go source code.
+func TypeString(t Type, pm map[string]string, pkgOverride string) (string, error) {
+	if err := CheckType(t); err != nil {
+		return "", err
+	}
+	return t.String(pm, pkgOverride), nil
+}
+
+// UnsupportedConstructError describes a construct in an interface that the
+// generator cannot handle.
+type UnsupportedConstructError struct {
+	Interface string
+	Method    string // may be empty
+	Position  string // e.g. "parameter 1"; may be empty
+	Reason    string
+}
+
+func (e *UnsupportedConstructError) Error() string {
+	location := e.Interface
+	if e.Method != "" {
+		location += "." + e.Method
+	}
+	if e.Position != "" {
+		location += ", " + e.Position
+	}
+	return location + ": " + e.Reason
+}
+
+// UnsupportedConstructErrors collects all unsupported constructs found in one run,
+// so they can be reported at once.
+type UnsupportedConstructErrors []*UnsupportedConstructError
+
+func (errs UnsupportedConstructErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "\t" + err.Error()
+	}
+	return "Could not generate mocks for some interfaces due to unsupported constructs:\n" + strings.Join(lines, "\n")
+}
+
+// TypeParamType is a use of a type parameter of the enclosing generic interface.
+type TypeParamType string
+
+func (tpt TypeParamType) String(pm map[string]string, pkgOverride string) string { return string(tpt) }
+func (tpt TypeParamType) addImports(im map[string]bool)                          {}
+func (tpt TypeParamType) unsupported() *UnsupportedType                          { return nil }
+
+// UnionType is a union of type terms in a constraint, e.g. ~int | string.
+type UnionType struct {
+	Terms []*UnionTerm
+}
+
+// UnionTerm is a term of a UnionType. With Tilde set, it stands for all types whose
+// underlying type is Type.
+type UnionTerm struct {
+	Tilde bool
+	Type  Type
+}
+
+func (ut *UnionType) String(pm map[string]string, pkgOverride string) string {
+	terms := make([]string, len(ut.Terms))
+	for i, term := range ut.Terms {
+		terms[i] = term.Type.String(pm, pkgOverride)
+		if term.Tilde {
+			terms[i] = "~" + terms[i]
+		}
+	}
+	return strings.Join(terms, " | ")
+}
+
+func (ut *UnionType) addImports(im map[string]bool) {
+	for _, term := range ut.Terms {
+		term.Type.addImports(im)
+	}
+}
+
+func (ut *UnionType) unsupported() *UnsupportedType {
+	for _, term := range ut.Terms {
+		if unsupported := term.Type.unsupported(); unsupported != nil {
+			return unsupported
+		}
+	}
+	return nil
+}
 
 // PredeclaredType is a predeclared type such as "int".
 type PredeclaredType string
 
 func (pt PredeclaredType) String(pm map[string]string, pkgOverride string) string { return string(pt) }
 func (pt PredeclaredType) addImports(im map[string]bool)                          {}
+func (pt PredeclaredType) unsupported() *UnsupportedType                          { return nil }
@@ -0,0 +1,240 @@
+package gomock
+
+// This file contains the derivation of import paths from directories and the lookup of vendored
+// packages, in GOPATH and module mode.
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ImportPathOfDir returns the import path of the package in dir. In module mode, it is derived
+// from dir's location in the module cache, or from the module path declared in the go.mod file
+// closest to dir, and only otherwise from dir's location in GOPATH. With GO111MODULE=off,
+// GOPATH comes first. It returns "" if none applies.
+func ImportPathOfDir(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	env := goEnv("GO111MODULE", "GOMODCACHE")
+	if env[0] != "off" {
+		if importPath := moduleCacheImportPathOf(dir, moduleCacheOf(env[1])); importPath != "" {
+			return importPath
+		}
+		if importPath := moduleImportPathOf(dir); importPath != "" {
+			return importPath
+		}
+	}
+	if buildPkg, err := build.ImportDir(dir, build.FindOnly); err == nil && buildPkg.ImportPath != "." {
+		return buildPkg.ImportPath
+	}
+	return moduleImportPathOf(dir)
+}
+
+// goEnv returns the values of the go environment variables names. Asking the go command takes
+// settings made with "go env -w" into account; without it, the process environment is used.
+func goEnv(names ...string) []string {
+	output, err := exec.Command("go", append([]string{"env"}, names...)...).Output()
+	values := strings.Split(string(output), "\n")
+	if err != nil || len(values) < len(names) {
+		values = make([]string, len(names))
+		for i, name := range names {
+			values[i] = os.Getenv(name)
+		}
+	}
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values[:len(names)]
+}
+
+// moduleCacheOf returns the module cache, gomodcache unless it is empty.
+func moduleCacheOf(gomodcache string) string {
+	if gomodcache == "" && build.Default.GOPATH != "" {
+		return filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+	}
+	return gomodcache
+}
+
+// moduleImportPathOf derives the import path of the package in dir from the module path declared
+// in the go.mod file closest to dir, or returns "" if there is none. Like for the go command in
+// module mode, the import paths of packages in the vendor directory of the module lack the
+// module path and "vendor/".
+func moduleImportPathOf(dir string) string {
+	moduleDir := moduleDirOf(dir)
+	if moduleDir == "" {
+		return ""
+	}
+	relativePath, err := filepath.Rel(moduleDir, dir)
+	if err != nil {
+		return ""
+	}
+	if vendoredPath := strings.TrimPrefix(filepath.ToSlash(relativePath), "vendor/"); vendoredPath != filepath.ToSlash(relativePath) {
+		return vendoredPath
+	}
+	return path.Join(directiveIn(filepath.Join(moduleDir, "go.mod"), "module"), filepath.ToSlash(relativePath))
+}
+
+// moduleDirOf returns the closest directory to dir, including dir itself, that has a go.mod
+// file declaring a module path, or "" if there is none.
+func moduleDirOf(dir string) string {
+	for moduleDir := dir; ; moduleDir = filepath.Dir(moduleDir) {
+		if directiveIn(filepath.Join(moduleDir, "go.mod"), "module") != "" {
+			return moduleDir
+		}
+		if filepath.Dir(moduleDir) == moduleDir {
+			return ""
+		}
+	}
+}
+
+// directiveIn returns the argument of the directive name in goModFile, e.g. the module path for
+// "module", or "" if there is none.
+func directiveIn(goModFile string, name string) string {
+	content, err := ioutil.ReadFile(goModFile)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(strings.SplitN(line, "//", 2)[0])
+		if len(fields) == 2 && fields[0] == name {
+			if argument, err := strconv.Unquote(fields[1]); err == nil {
+				return argument
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// vendorDirsOf returns the vendor directories in which the go command looks up the imports of
+// the package in dir, innermost first. In GOPATH mode, these are the ones of dir and its parent
+// directories within GOPATH. In module mode, it is the one of the main module if the go command
+// is in vendor mode: with -mod=vendor in GOFLAGS or, unless GOFLAGS sets another -mod, if the
+// module requires go 1.14 or later and has a vendor/modules.txt file.
+func vendorDirsOf(dir string) []string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	env := goEnv("GO111MODULE", "GOFLAGS")
+	if env[0] == "off" || env[0] == "auto" && moduleDirOf(dir) == "" {
+		var vendorDirs []string
+		for _, srcDir := range build.Default.SrcDirs() {
+			relativePath, err := filepath.Rel(srcDir, dir)
+			if err != nil || strings.HasPrefix(relativePath, "..") {
+				continue
+			}
+			for parentDir := dir; parentDir != srcDir; parentDir = filepath.Dir(parentDir) {
+				if info, err := os.Stat(filepath.Join(parentDir, "vendor")); err == nil && info.IsDir() {
+					vendorDirs = append(vendorDirs, filepath.Join(parentDir, "vendor"))
+				}
+			}
+		}
+		return vendorDirs
+	}
+
+	moduleDir := moduleDirOf(dir)
+	if moduleDir == "" || !vendorMode(moduleDir, env[1]) {
+		return nil
+	}
+	return []string{filepath.Join(moduleDir, "vendor")}
+}
+
+// vendorMode reports whether the go command is in vendor mode for the main module in moduleDir,
+// given goflags.
+func vendorMode(moduleDir string, goflags string) bool {
+	for _, flag := range strings.Fields(goflags) {
+		if strings.HasPrefix(flag, "-mod=") {
+			return flag == "-mod=vendor"
+		}
+	}
+	if _, err := os.Stat(filepath.Join(moduleDir, "vendor", "modules.txt")); err != nil {
+		return false
+	}
+	return goVersionAtLeast(directiveIn(filepath.Join(moduleDir, "go.mod"), "go"), 14)
+}
+
+// goVersionAtLeast reports whether version, e.g. "1.21" or "1.21.3", is at least 1.minor.
+func goVersionAtLeast(version string, minor int) bool {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return false
+	}
+	versionMinor, err := strconv.Atoi(parts[1])
+	return err == nil && versionMinor >= minor
+}
+
+// packageNameIn returns the name of the package in dir according to the package clause of its
+// first Go file that matches the build context, or "" if there is none.
+func packageNameIn(dir string) string {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if fileInfo.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err == nil {
+			return file.Name.Name
+		}
+	}
+	return ""
+}
+
+// moduleCacheImportPathOf derives the import path of the package in dir from its location in
+// moduleCache, e.g. "github.com/Foo/bar/v2/baz" from
+// "<moduleCache>/github.com/!foo/bar/v2@v2.1.0/baz". Unlike go.mod files, this also works for
+// modules without one. It returns "" if dir is not in moduleCache.
+func moduleCacheImportPathOf(dir string, moduleCache string) string {
+	if moduleCache == "" {
+		return ""
+	}
+	relativePath, err := filepath.Rel(moduleCache, dir)
+	if err != nil || relativePath == "." || strings.HasPrefix(relativePath, "..") {
+		return ""
+	}
+	elements := strings.Split(filepath.ToSlash(relativePath), "/")
+	for i, element := range elements {
+		if at := strings.Index(element, "@"); at != -1 {
+			elements[i] = element[:at]
+			return path.Join(append([]string{unescapeModulePath(path.Join(elements[:i+1]...))}, elements[i+1:]...)...)
+		}
+	}
+	return ""
+}
+
+// unescapeModulePath reverses the escaping of upper-case letters in paths of the module cache,
+// e.g. "github.com/!foo" to "github.com/Foo".
+func unescapeModulePath(escapedPath string) string {
+	var unescaped strings.Builder
+	upper := false
+	for _, r := range escapedPath {
+		switch {
+		case r == '!':
+			upper = true
+		case upper:
+			unescaped.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			unescaped.WriteRune(r)
+		}
+	}
+	return unescaped.String()
+}
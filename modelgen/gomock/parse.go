@@ -20,13 +20,17 @@ import (
 	"flag"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
-	"log"
+	"io/ioutil"
 	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/petergtz/pegomock/internal/doccomment"
 	"github.com/petergtz/pegomock/model"
 )
 
@@ -37,17 +41,25 @@ var (
 
 // TODO: simplify error reporting
 
+// ParseFile builds the model for all interfaces in source. If some of them use constructs
+// that cannot be mocked, the returned package still contains the remaining interfaces
+// and the error is a model.UnsupportedConstructErrors. All other problems are returned
+// as errors, too.
 func ParseFile(source string) (*model.Package, error) {
 	fs := token.NewFileSet()
-	file, err := parser.ParseFile(fs, source, nil, 0)
+	file, err := parser.ParseFile(fs, source, nil, parser.ParseComments)
 	if err != nil {
 		return nil, fmt.Errorf("failed parsing source file %v: %v", source, err)
 	}
 
 	p := &fileParser{
-		fileSet:       fs,
-		imports:       make(map[string]string),
-		auxInterfaces: make(map[string]map[string]*ast.InterfaceType),
+		fileSet:        fs,
+		imports:        make(map[string]string),
+		auxInterfaces:  make(map[string]map[string]*ast.InterfaceType),
+		srcFile:        source,
+		srcPackageName: file.Name.Name,
+		vendorDirs:     vendorDirsOf(filepath.Dir(source)),
+		siblingImports: make(map[*ast.InterfaceType]map[string]string),
 	}
 
 	// Handle -imports.
@@ -55,6 +67,9 @@ func ParseFile(source string) (*model.Package, error) {
 	if *imports != "" {
 		for _, kv := range strings.Split(*imports, ",") {
 			eq := strings.Index(kv, "=")
+			if eq < 0 {
+				return nil, fmt.Errorf("bad imports spec: %v", kv)
+			}
 			k, v := kv[:eq], kv[eq+1:]
 			if k == "." {
 				// TODO: Catch dupes?
@@ -72,23 +87,56 @@ func ParseFile(source string) (*model.Package, error) {
 	}
 	p.addAuxInterfacesFromFile("", file) // this file
 
+	// Unqualified types could also stem from dot imports, so only qualify them
+	// with the source package if there are none.
+	if len(dotImports) == 0 {
+		p.srcPackage = importPathOf(source)
+		if p.srcPackage != "" && IsExternalTestPackage(source, file.Name.Name) {
+			// Like the go tool, name external test packages by their directory's import path
+			// suffixed with _test. They cannot be imported, but this recognizes their own types.
+			p.srcPackage += "_test"
+		}
+	}
+
 	pkg, err := p.parseFile(file)
-	if err != nil {
+	if _, ok := err.(model.UnsupportedConstructErrors); !ok && err != nil {
 		return nil, err
 	}
 	pkg.DotImports = make([]string, 0, len(dotImports))
 	for path := range dotImports {
 		pkg.DotImports = append(pkg.DotImports, path)
 	}
-	return pkg, nil
+	sort.Strings(pkg.DotImports)
+	return pkg, err
+}
+
+// IsExternalTestPackage reports whether the package packageName declared in source is
+// an external test package, i.e. one that is only visible to the tests of its directory.
+func IsExternalTestPackage(source string, packageName string) bool {
+	return strings.HasSuffix(source, "_test.go") && strings.HasSuffix(packageName, "_test")
+}
+
+// importPathOf returns the import path of the package source belongs to,
+// or "" if it cannot be determined. See ImportPathOfDir.
+func importPathOf(source string) string {
+	return ImportPathOfDir(filepath.Dir(source))
 }
 
 type fileParser struct {
-	fileSet *token.FileSet
-	imports map[string]string // package name => import path
+	fileSet    *token.FileSet
+	imports    map[string]string // package name => import path
+	srcPackage string            // import path of the source file's package; may be empty
 
 	auxFiles      []*ast.File
 	auxInterfaces map[string]map[string]*ast.InterfaceType // package (or "") => name => interface
+
+	srcFile        string
+	srcPackageName string
+	siblingsParsed bool
+	siblingImports map[*ast.InterfaceType]map[string]string // interface from a sibling file => imports of that file
+	vendorDirs     []string                                 // where the go command looks up vendored imports, innermost first
+
+	typeParams map[string]bool // names of the type parameters of the interface being parsed
 }
 
 func (p *fileParser) errorf(pos token.Pos, format string, args ...interface{}) error {
@@ -108,7 +156,7 @@ func (p *fileParser) parseAuxFiles(auxFiles string) error {
 		if len(parts) != 2 {
 			return fmt.Errorf("bad aux file spec: %v", kv)
 		}
-		file, err := parser.ParseFile(p.fileSet, parts[1], nil, 0)
+		file, err := parser.ParseFile(p.fileSet, parts[1], nil, parser.ParseComments)
 		if err != nil {
 			return err
 		}
@@ -128,7 +176,10 @@ func (p *fileParser) addAuxInterfacesFromFile(pkg string, file *ast.File) {
 }
 
 func (p *fileParser) parseFile(file *ast.File) (*model.Package, error) {
-	allImports := importsOfFile(file)
+	allImports, err := p.importsOfFile(file)
+	if err != nil {
+		return nil, err
+	}
 	// Don't stomp imports provided by -imports. Those should take precedence.
 	for pkg, path := range allImports {
 		if _, ok := p.imports[pkg]; !ok {
@@ -138,7 +189,11 @@ func (p *fileParser) parseFile(file *ast.File) (*model.Package, error) {
 	// Add imports from auxiliary files, which might be needed for embedded interfaces.
 	// Don't stomp any other imports.
 	for _, f := range p.auxFiles {
-		for pkg, path := range importsOfFile(f) {
+		auxImports, err := p.importsOfFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for pkg, path := range auxImports {
 			if _, ok := p.imports[pkg]; !ok {
 				p.imports[pkg] = path
 			}
@@ -146,17 +201,141 @@ func (p *fileParser) parseFile(file *ast.File) (*model.Package, error) {
 	}
 
 	var is []*model.Interface
-	for ni := range iterInterfaces(file) {
-		i, err := p.parseInterface(ni.name.String(), "", ni.it)
+	var unsupported model.UnsupportedConstructErrors
+	add := func(name string, i *model.Interface, err error) error {
+		if constructErr, ok := err.(*model.UnsupportedConstructError); ok {
+			constructErr.Interface = name
+			unsupported = append(unsupported, constructErr)
+			return nil
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 		is = append(is, i)
+		return nil
+	}
+	for ni := range iterInterfaces(file) {
+		if isConstraint(ni.it) {
+			continue
+		}
+		i, err := p.parseGenericInterface(ni.name.String(), p.srcPackage, ni.typeParams, ni.it)
+		if i != nil {
+			i.Doc = doccomment.Text(ni.doc)
+		}
+		if err := add(ni.name.String(), i, err); err != nil {
+			return nil, err
+		}
 	}
-	return &model.Package{
+	for _, nf := range exportedFuncTypesOf(file) {
+		i, err := p.parseFuncType(nf.name.String(), p.srcPackage, nf.typeParams, nf.ft)
+		if i != nil {
+			i.Doc = doccomment.Text(nf.doc)
+		}
+		if err := add(nf.name.String(), i, err); err != nil {
+			return nil, err
+		}
+	}
+	pkg := &model.Package{
 		Name:       file.Name.String(),
+		PkgPath:    p.srcPackage,
 		Interfaces: is,
-	}, nil
+	}
+	if len(unsupported) != 0 {
+		// Still return the package, so mocks for the remaining interfaces can be generated.
+		return pkg, unsupported
+	}
+	return pkg, nil
+}
+
+func (p *fileParser) parseGenericInterface(name, pkg string, typeParams *ast.FieldList, it *ast.InterfaceType) (*model.Interface, error) {
+	p.typeParams = make(map[string]bool)
+	defer func() { p.typeParams = nil }()
+	tps, err := p.parseTypeParams(pkg, typeParams)
+	if err != nil {
+		return nil, err
+	}
+	intf, err := p.parseInterface(name, pkg, it)
+	if err != nil {
+		return nil, err
+	}
+	intf.TypeParams = tps
+	return intf, nil
+}
+
+// parseFuncType returns the model of a function type as an interface with the single method
+// model.FuncTypeMethod.
+func (p *fileParser) parseFuncType(name, pkg string, typeParams *ast.FieldList, ft *ast.FuncType) (*model.Interface, error) {
+	p.typeParams = make(map[string]bool)
+	defer func() { p.typeParams = nil }()
+	tps, err := p.parseTypeParams(pkg, typeParams)
+	if err != nil {
+		return nil, err
+	}
+	m := &model.Method{Name: model.FuncTypeMethod}
+	m.In, m.Variadic, m.Out, err = p.parseFunc(pkg, ft)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Interface{Name: name, TypeParams: tps, Methods: []*model.Method{m}, IsFuncType: true}, nil
+}
+
+// parseTypeParams returns the model of typeParams, which may be nil, and makes them
+// known to the parsing of the types that follow.
+func (p *fileParser) parseTypeParams(pkg string, typeParams *ast.FieldList) ([]*model.TypeParam, error) {
+	if typeParams == nil {
+		return nil, nil
+	}
+	var tps []*model.TypeParam
+	for _, field := range typeParams.List {
+		for _, name := range field.Names {
+			p.typeParams[name.Name] = true
+			tps = append(tps, &model.TypeParam{Name: name.Name})
+		}
+	}
+	i := 0
+	for _, field := range typeParams.List {
+		constraint, err := p.parseConstraint(pkg, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		for range field.Names {
+			tps[i].Constraint = constraint
+			i++
+		}
+	}
+	return tps, nil
+}
+
+func (p *fileParser) parseConstraint(pkg string, constraint ast.Expr) (model.Type, error) {
+	union := &model.UnionType{}
+	if err := p.addUnionTerms(pkg, constraint, union); err != nil {
+		return nil, err
+	}
+	if len(union.Terms) == 1 && !union.Terms[0].Tilde {
+		return union.Terms[0].Type, nil
+	}
+	return union, nil
+}
+
+func (p *fileParser) addUnionTerms(pkg string, expr ast.Expr, union *model.UnionType) error {
+	if binaryExpr, ok := expr.(*ast.BinaryExpr); ok && binaryExpr.Op == token.OR {
+		if err := p.addUnionTerms(pkg, binaryExpr.X, union); err != nil {
+			return err
+		}
+		return p.addUnionTerms(pkg, binaryExpr.Y, union)
+	}
+	term := &model.UnionTerm{}
+	if unaryExpr, ok := expr.(*ast.UnaryExpr); ok && unaryExpr.Op == token.TILDE {
+		term.Tilde = true
+		expr = unaryExpr.X
+	}
+	t, err := p.parseType(pkg, expr)
+	if err != nil {
+		return err
+	}
+	term.Type = t
+	union.Terms = append(union.Terms, term)
+	return nil
 }
 
 func (p *fileParser) parseInterface(name, pkg string, it *ast.InterfaceType) (*model.Interface, error) {
@@ -169,6 +348,7 @@ func (p *fileParser) parseInterface(name, pkg string, it *ast.InterfaceType) (*m
 			}
 			m := &model.Method{
 				Name: field.Names[0].String(),
+				Doc:  doccomment.Text(field.Doc),
 			}
 			var err error
 			m.In, m.Variadic, m.Out, err = p.parseFunc(pkg, v)
@@ -179,10 +359,16 @@ func (p *fileParser) parseInterface(name, pkg string, it *ast.InterfaceType) (*m
 		case *ast.Ident:
 			// Embedded interface in this package.
 			ei := p.auxInterfaces[""][v.String()]
+			if ei == nil {
+				if err := p.parseSiblingFiles(); err != nil {
+					return nil, err
+				}
+				ei = p.auxInterfaces[""][v.String()]
+			}
 			if ei == nil {
 				return nil, p.errorf(v.Pos(), "unknown embedded interface %s", v.String())
 			}
-			eintf, err := p.parseInterface(v.String(), pkg, ei)
+			eintf, err := p.parsePackageLocalInterface(v.String(), pkg, ei)
 			if err != nil {
 				return nil, err
 			}
@@ -212,12 +398,68 @@ func (p *fileParser) parseInterface(name, pkg string, it *ast.InterfaceType) (*m
 				intf.Methods = append(intf.Methods, m)
 			}
 		default:
-			return nil, fmt.Errorf("don't know how to mock method of type %T", field.Type)
+			return nil, &model.UnsupportedConstructError{
+				Position: "embedded element",
+				Reason:   fmt.Sprintf("don't know how to mock embedded element of type %T", field.Type),
+			}
 		}
 	}
 	return intf, nil
 }
 
+// parsePackageLocalInterface parses an interface of the source file's package. Interfaces
+// from sibling files are parsed with the imports of their own file.
+func (p *fileParser) parsePackageLocalInterface(name, pkg string, it *ast.InterfaceType) (*model.Interface, error) {
+	if imports, isFromSibling := p.siblingImports[it]; isFromSibling {
+		defer func(imports map[string]string) { p.imports = imports }(p.imports)
+		p.imports = imports
+	}
+	return p.parseInterface(name, pkg, it)
+}
+
+// parseSiblingFiles adds the interfaces of the other files of the source file's package
+// to the ones that can be embedded. It is only called once an embedded interface cannot
+// be found otherwise, so unrelated files of the package cannot get in the way.
+func (p *fileParser) parseSiblingFiles() error {
+	if p.siblingsParsed {
+		return nil
+	}
+	p.siblingsParsed = true
+	dir := filepath.Dir(p.srcFile)
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if fileInfo.IsDir() || filepath.Ext(name) != ".go" || name == filepath.Base(p.srcFile) ||
+			(strings.HasSuffix(name, "_test.go") && !strings.HasSuffix(p.srcFile, "_test.go")) {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+		file, err := parser.ParseFile(p.fileSet, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("failed parsing source file %v: %v", filepath.Join(dir, name), err)
+		}
+		if file.Name.Name != p.srcPackageName {
+			continue
+		}
+		fileImports, err := p.importsOfFile(file)
+		if err != nil {
+			return err
+		}
+		for ni := range iterInterfaces(file) {
+			if _, exists := p.auxInterfaces[""][ni.name.Name]; !exists {
+				p.auxInterfaces[""][ni.name.Name] = ni.it
+				p.siblingImports[ni.it] = fileImports
+			}
+		}
+	}
+	return nil
+}
+
 func (p *fileParser) parseFunc(pkg string, f *ast.FuncType) (in []*model.Parameter, variadic *model.Parameter, out []*model.Parameter, err error) {
 	if f.Params != nil {
 		regParams := f.Params.List
@@ -273,6 +515,10 @@ func (p *fileParser) parseFieldList(pkg string, fields []*ast.Field) ([]*model.P
 		}
 		for _, name := range f.Names {
 			ps[i] = &model.Parameter{Name: name.Name, Type: t}
+			if name.Name == "_" {
+				// Mocks refer to all of their parameters, which blank ones cannot be.
+				ps[i].Name = ""
+			}
 			i++
 		}
 	}
@@ -318,6 +564,9 @@ func (p *fileParser) parseType(pkg string, typ ast.Expr) (model.Type, error) {
 		}
 		return &model.FuncType{In: in, Out: out, Variadic: variadic}, nil
 	case *ast.Ident:
+		if p.typeParams[v.Name] {
+			return model.TypeParamType(v.Name), nil
+		}
 		if v.IsExported() {
 			// assume type in this package
 			return &model.NamedType{Package: pkg, Type: v.Name}, nil
@@ -325,9 +574,13 @@ func (p *fileParser) parseType(pkg string, typ ast.Expr) (model.Type, error) {
 			// assume predeclared type
 			return model.PredeclaredType(v.Name), nil
 		}
+	case *ast.IndexExpr:
+		return p.parseInstantiatedType(pkg, v.X, []ast.Expr{v.Index})
+	case *ast.IndexListExpr:
+		return p.parseInstantiatedType(pkg, v.X, v.Indices)
 	case *ast.InterfaceType:
 		if v.Methods != nil && len(v.Methods.List) > 0 {
-			return nil, p.errorf(v.Pos(), "can't handle non-empty unnamed interface types")
+			return &model.UnsupportedType{Description: "non-empty unnamed interface type"}, nil
 		}
 		return model.PredeclaredType("interface{}"), nil
 	case *ast.MapType:
@@ -355,21 +608,43 @@ func (p *fileParser) parseType(pkg string, typ ast.Expr) (model.Type, error) {
 		return &model.PointerType{Type: t}, nil
 	case *ast.StructType:
 		if v.Fields != nil && len(v.Fields.List) > 0 {
-			return nil, p.errorf(v.Pos(), "can't handle non-empty unnamed struct types")
+			return &model.UnsupportedType{Description: "non-empty unnamed struct type"}, nil
 		}
 		return model.PredeclaredType("struct{}"), nil
 	}
 
-	return nil, fmt.Errorf("don't know how to parse type %T", typ)
+	return &model.UnsupportedType{Description: fmt.Sprintf("%T", typ)}, nil
+}
+
+// parseInstantiatedType parses a generic type instantiated with typeArgs, e.g. result.Result[[]*User].
+func (p *fileParser) parseInstantiatedType(pkg string, genericType ast.Expr, typeArgs []ast.Expr) (model.Type, error) {
+	t, err := p.parseType(pkg, genericType)
+	if err != nil {
+		return nil, err
+	}
+	namedType, ok := t.(*model.NamedType)
+	if !ok {
+		return &model.UnsupportedType{Description: fmt.Sprintf("instantiation of %T", genericType)}, nil
+	}
+	instantiated := &model.NamedType{Package: namedType.Package, Type: namedType.Type}
+	for _, typeArg := range typeArgs {
+		arg, err := p.parseType(pkg, typeArg)
+		if err != nil {
+			return nil, err
+		}
+		instantiated.TypeArgs = append(instantiated.TypeArgs, arg)
+	}
+	return instantiated, nil
 }
 
 // importsOfFile returns a map of package name to import path
 // of the imports in file.
-func importsOfFile(file *ast.File) map[string]string {
+func (p *fileParser) importsOfFile(file *ast.File) (map[string]string, error) {
 	/* We have to make guesses about some imports, because imports are not required
-	 * to have names. Named imports are always certain. Unnamed imports are guessed
-	 * to have a name of the last path component; if the last path component has dots,
-	 * the first dot-delimited field is used as the name.
+	 * to have names. Named imports are always certain. Unnamed imports of vendored
+	 * packages get the name declared in the vendor directory. Other unnamed imports
+	 * are guessed to have a name of the last path component; if the last path
+	 * component has dots, the first dot-delimited field is used as the name.
 	 */
 
 	m := make(map[string]string)
@@ -391,17 +666,51 @@ func importsOfFile(file *ast.File) map[string]string {
 					continue
 				}
 				pkg = removeDot(is.Name.Name)
+			} else if name := p.vendoredPackageNameOf(importPath); name != "" {
+				pkg = name
 			} else {
-				_, last := path.Split(importPath)
-				pkg = strings.SplitN(last, ".", 2)[0]
+				pkg = guessPackageNameOf(importPath)
 			}
 			if _, ok := m[pkg]; ok {
-				log.Fatalf("imported package collision: %q imported twice", pkg)
+				return nil, fmt.Errorf("imported package collision: %q imported twice", pkg)
 			}
 			m[pkg] = importPath
 		}
 	}
-	return m
+	return m, nil
+}
+
+// vendoredPackageNameOf returns the name of the package importPath if the go command would
+// take it from one of p.vendorDirs, or "" otherwise.
+func (p *fileParser) vendoredPackageNameOf(importPath string) string {
+	for _, vendorDir := range p.vendorDirs {
+		if name := packageNameIn(filepath.Join(vendorDir, filepath.FromSlash(importPath))); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// guessPackageNameOf returns the last element of importPath up to its first dot. Major
+// version suffixes of modules, e.g. the "v2" in "github.com/org/lib/v2", are skipped.
+func guessPackageNameOf(importPath string) string {
+	dir, last := path.Split(importPath)
+	if isMajorVersionSuffix(last) && dir != "" {
+		_, last = path.Split(strings.TrimSuffix(dir, "/"))
+	}
+	return strings.SplitN(last, ".", 2)[0]
+}
+
+func isMajorVersionSuffix(element string) bool {
+	if len(element) < 2 || element[0] != 'v' {
+		return false
+	}
+	for _, r := range element[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func removeDot(s string) string {
@@ -412,8 +721,10 @@ func removeDot(s string) string {
 }
 
 type namedInterface struct {
-	name *ast.Ident
-	it   *ast.InterfaceType
+	name       *ast.Ident
+	doc        *ast.CommentGroup
+	typeParams *ast.FieldList // nil unless the interface is generic
+	it         *ast.InterfaceType
 }
 
 // Create an iterator over all interfaces in file.
@@ -435,7 +746,7 @@ func iterInterfaces(file *ast.File) <-chan namedInterface {
 					continue
 				}
 
-				ch <- namedInterface{ts.Name, it}
+				ch <- namedInterface{ts.Name, doccomment.OfType(gd, ts), ts.TypeParams, it}
 			}
 		}
 		close(ch)
@@ -443,6 +754,52 @@ func iterInterfaces(file *ast.File) <-chan namedInterface {
 	return ch
 }
 
+type namedFuncType struct {
+	name       *ast.Ident
+	doc        *ast.CommentGroup
+	typeParams *ast.FieldList // nil unless the function type is generic
+	ft         *ast.FuncType
+}
+
+// exportedFuncTypesOf returns all exported function types declared in file.
+func exportedFuncTypesOf(file *ast.File) (funcTypes []namedFuncType) {
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || !ts.Name.IsExported() {
+				continue
+			}
+			if ft, ok := ts.Type.(*ast.FuncType); ok {
+				funcTypes = append(funcTypes, namedFuncType{ts.Name, doccomment.OfType(gd, ts), ts.TypeParams, ft})
+			}
+		}
+	}
+	return
+}
+
+// isConstraint returns whether the interface has type elements like ~int | string or comparable,
+// which means it can only be used as a constraint and cannot be mocked.
+func isConstraint(it *ast.InterfaceType) bool {
+	for _, field := range it.Methods.List {
+		if len(field.Names) != 0 {
+			continue
+		}
+		switch v := field.Type.(type) {
+		case *ast.BinaryExpr, *ast.UnaryExpr:
+			return true
+		case *ast.Ident:
+			if v.Name == "comparable" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // isVariadic returns whether the function is variadic.
 func isVariadic(f *ast.FuncType) bool {
 	nargs := len(f.Params.List)
@@ -14,146 +14,370 @@
 
 package gomock
 
-// This file contains the model construction by reflection.
+// This file contains the model construction from type information of compiled packages.
 
 import (
-	"bytes"
-	"encoding/gob"
-	"flag"
+	"context"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"os"
-	"os/exec"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"path/filepath"
-	"runtime"
-	"text/template"
+	"strings"
 
+	"github.com/petergtz/pegomock/internal/doccomment"
 	"github.com/petergtz/pegomock/model"
+	"github.com/petergtz/pegomock/modelgen/typesmodel"
+	"golang.org/x/tools/go/packages"
 )
 
-var (
-	progOnly = flag.Bool("prog_only", false, "(reflect mode) Only generate the reflection program; write it to stdout.")
-	execOnly = flag.String("exec_only", "", "(reflect mode) If set, execute this reflection program.")
-)
-
+// Reflect builds the model for the interfaces named by symbols in the package importPath
+// from the type information of that package.
 func Reflect(importPath string, symbols []string) (*model.Package, error) {
-	// TODO: sanity check arguments
-	progPath := *execOnly
-	if *execOnly == "" {
-		workingDir, err := os.Getwd()
-		if err != nil {
-			return nil, err
+	return ReflectContext(context.Background(), importPath, symbols)
+}
+
+// ReflectContext is like Reflect, but stops loading the package when ctx is done, e.g.
+// because of a timeout.
+func ReflectContext(ctx context.Context, importPath string, symbols []string) (*model.Package, error) {
+	loadedPkg, err := loadPackage(ctx, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &model.Package{
+		Name: loadedPkg.Name,
+		// The path as seen by the type checker, which includes vendor directories.
+		PkgPath: loadedPkg.PkgPath,
+	}
+	sources := &sourceFiles{fset: token.NewFileSet(), files: make(map[string]*ast.File)}
+	for _, symbol := range symbols {
+		typeName, isTypeName := loadedPkg.Types.Scope().Lookup(symbol).(*types.TypeName)
+		if !isTypeName {
+			return nil, &UndeclaredTypeError{ImportPath: importPath, Name: symbol}
 		}
-		tmpDir, err := ioutil.TempDir(workingDir, ".tmp_gomock_reflect_")
+		intf, err := typesmodel.Interface(typeName)
 		if err != nil {
 			return nil, err
 		}
-		defer func() { os.RemoveAll(tmpDir) }()
-		const progSource = "prog.go"
-		var progBinary = "prog.bin"
-		if runtime.GOOS == "windows" {
-			// Windows won't execute a program unless it has a ".exe" suffix.
-			progBinary += ".exe"
-		}
+		sources.completeFromSource(loadedPkg.Fset, typeName, intf)
+		pkg.Interfaces = append(pkg.Interfaces, intf)
+	}
+	return pkg, nil
+}
 
-		// Generate program.
-		var program bytes.Buffer
-		data := reflectData{
-			ImportPath: importPath,
-			Symbols:    symbols,
+// sourceFiles parses the source files of declarations at most once.
+type sourceFiles struct {
+	fset  *token.FileSet
+	files map[string]*ast.File
+}
+
+// completeFromSource adds what the type information of typeName lacks to intf, where the source
+// files declaring typeName and its methods are available: the doc comments, and the names of
+// parameters and results that have none, e.g. because the type information was loaded from
+// export data.
+func (sources *sourceFiles) completeFromSource(fset *token.FileSet, typeName *types.TypeName, intf *model.Interface) {
+	if file, position := sources.fileAt(fset.Position(typeName.Pos())); file != nil {
+		intf.Doc = doccomment.Text(typeDeclarationDoc(file, position))
+	}
+	for _, method := range intf.Methods {
+		object, _, _ := types.LookupFieldOrMethod(typeName.Type(), true, typeName.Pkg(), method.Name)
+		function, isFunc := object.(*types.Func)
+		if !isFunc {
+			continue
 		}
-		if err := reflectProgram.Execute(&program, &data); err != nil {
-			return nil, err
+		file, position := sources.fileAt(fset.Position(function.Pos()))
+		if file == nil {
+			continue
 		}
-		if *progOnly {
-			io.Copy(os.Stdout, &program)
-			os.Exit(0)
+		doc, funcType := methodDeclaration(file, position)
+		method.Doc = doccomment.Text(doc)
+		if funcType != nil {
+			params := method.In
+			if method.Variadic != nil {
+				params = append(params[:len(params):len(params)], method.Variadic)
+			}
+			nameAfter(params, funcType.Params)
+			nameAfter(method.Out, funcType.Results)
 		}
-		if err := ioutil.WriteFile(filepath.Join(tmpDir, progSource), program.Bytes(), 0600); err != nil {
-			return nil, err
+	}
+}
+
+// fileAt returns the parsed source file of position and position in terms of the parsed file's
+// positions, or nil if the file cannot be parsed.
+func (sources *sourceFiles) fileAt(position token.Position) (*ast.File, token.Pos) {
+	if !position.IsValid() {
+		return nil, token.NoPos
+	}
+	file, parsed := sources.files[position.Filename]
+	if !parsed {
+		file, _ = parser.ParseFile(sources.fset, position.Filename, nil, parser.ParseComments)
+		sources.files[position.Filename] = file
+	}
+	if file == nil || position.Line > sources.fset.File(file.Pos()).LineCount() {
+		return nil, token.NoPos
+	}
+	return file, sources.fset.File(file.Pos()).LineStart(position.Line) + token.Pos(position.Column-1)
+}
+
+// typeDeclarationDoc returns the doc comment of the type whose name is at position in file.
+func typeDeclarationDoc(file *ast.File, position token.Pos) *ast.CommentGroup {
+	for _, decl := range file.Decls {
+		if genDecl, isGenDecl := decl.(*ast.GenDecl); isGenDecl {
+			for _, spec := range genDecl.Specs {
+				if typeSpec, isTypeSpec := spec.(*ast.TypeSpec); isTypeSpec && typeSpec.Name.Pos() == position {
+					return doccomment.OfType(genDecl, typeSpec)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// methodDeclaration returns the doc comment and the type of the method whose name is at
+// position in file, declared in an interface or as a function.
+func methodDeclaration(file *ast.File, position token.Pos) (doc *ast.CommentGroup, funcType *ast.FuncType) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch declaration := node.(type) {
+		case *ast.Field:
+			if len(declaration.Names) == 1 && declaration.Names[0].Pos() == position {
+				doc = declaration.Doc
+				funcType, _ = declaration.Type.(*ast.FuncType)
+			}
+		case *ast.FuncDecl:
+			if declaration.Name.Pos() == position {
+				doc, funcType = declaration.Doc, declaration.Type
+			}
 		}
+		return doc == nil && funcType == nil
+	})
+	return
+}
 
-		// Build the program.
-		cmd := exec.Command("go", "build", "-o", progBinary, progSource)
-		cmd.Dir = tmpDir
-		stderr := &bytes.Buffer{}
-		cmd.Stderr = stderr
-		if err := cmd.Run(); err != nil {
-			return nil, fmt.Errorf("%v caused by:\n%v", err, stderr.String())
+// nameAfter names the unnamed of params like the corresponding fields, unless these are blank
+// or unnamed, too.
+func nameAfter(params []*model.Parameter, fields *ast.FieldList) {
+	var names []string
+	if fields != nil {
+		for _, field := range fields.List {
+			if len(field.Names) == 0 {
+				names = append(names, "")
+			}
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	if len(names) != len(params) {
+		return
+	}
+	for i, param := range params {
+		if param.Name == "" && names[i] != "_" {
+			param.Name = names[i]
 		}
-		progPath = filepath.Join(tmpDir, progBinary)
 	}
+}
 
-	// Run it.
-	cmd := exec.Command(progPath)
-	var stdout bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return nil, err
+// UndeclaredTypeError is returned by Reflect for a symbol that the package doesn't declare as a
+// type.
+type UndeclaredTypeError struct {
+	ImportPath string
+	Name       string
+}
+
+func (err *UndeclaredTypeError) Error() string {
+	return fmt.Sprintf("%v does not declare a type %v", err.ImportPath, err.Name)
+}
+
+// ExportedInterfaces returns the import path of the package denoted by pattern, e.g.
+// "./mypkg", and the names of all exported interfaces declared in it. Interfaces that can
+// only be used as type constraints are left out.
+func ExportedInterfaces(pattern string) (importPath string, names []string, err error) {
+	importPath, declarations, err := ExportedInterfaceDeclarations(pattern)
+	for _, declaration := range declarations {
+		names = append(names, declaration.Name)
 	}
+	return importPath, names, err
+}
 
-	// Process output.
-	var pkg model.Package
-	if err := gob.NewDecoder(&stdout).Decode(&pkg); err != nil {
-		return nil, err
+// InterfaceDeclaration describes where an interface is declared and how many methods it has,
+// including those of embedded interfaces.
+type InterfaceDeclaration struct {
+	Name       string
+	File       string
+	NumMethods int
+}
+
+// ExportedInterfaceDeclarations is like ExportedInterfaces, but describes the declarations of
+// the interfaces.
+func ExportedInterfaceDeclarations(pattern string) (importPath string, declarations []InterfaceDeclaration, err error) {
+	pkg, err := loadPackage(context.Background(), pattern)
+	if err != nil {
+		return "", nil, err
 	}
-	return &pkg, nil
+	for _, name := range pkg.Types.Scope().Names() {
+		typeName, isTypeName := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+		if !isTypeName || !typeName.Exported() || typeName.IsAlias() {
+			continue
+		}
+		if it, isInterface := typeName.Type().Underlying().(*types.Interface); isInterface && it.IsMethodSet() {
+			declarations = append(declarations, InterfaceDeclaration{
+				Name:       name,
+				File:       pkg.Fset.Position(typeName.Pos()).Filename,
+				NumMethods: it.NumMethods(),
+			})
+		}
+	}
+	return pkg.PkgPath, declarations, nil
 }
 
-type reflectData struct {
-	ImportPath string
-	Symbols    []string
+// UndeclaredTypes returns those of names that the package denoted by pattern does not declare
+// as types, e.g. interfaces that were removed after mocks were generated for them.
+func UndeclaredTypes(pattern string, names []string) ([]string, error) {
+	pkg, err := loadPackage(context.Background(), pattern)
+	if err != nil {
+		return nil, err
+	}
+	var undeclared []string
+	for _, name := range names {
+		if _, isTypeName := pkg.Types.Scope().Lookup(name).(*types.TypeName); !isTypeName {
+			undeclared = append(undeclared, name)
+		}
+	}
+	return undeclared, nil
 }
 
-// This program reflects on an interface value, and prints the
-// gob encoding of a model.Package to standard output.
-// JSON doesn't work because of the model.Type interface.
-var reflectProgram = template.Must(template.New("program").Parse(`
-package main
+// PackageNameAndDir returns the name of the package denoted by pattern, e.g. "./mypkg", and the
+// directory of its Go files.
+func PackageNameAndDir(pattern string) (name string, dir string, err error) {
+	pkg, err := loadPackage(context.Background(), pattern)
+	if err != nil {
+		return "", "", err
+	}
+	if len(pkg.GoFiles) == 0 {
+		return "", "", fmt.Errorf("package %v has no Go files", pattern)
+	}
+	return pkg.Name, filepath.Dir(pkg.GoFiles[0]), nil
+}
 
-import (
-	"encoding/gob"
-	"fmt"
-	"os"
-	"path"
-	"reflect"
+func loadPackage(ctx context.Context, pattern string) (*packages.Package, error) {
+	// The go command refuses directories outside the main module, but loads packages of the
+	// module cache, e.g. of dependencies, by their import paths.
+	if build.IsLocalImport(pattern) || filepath.IsAbs(pattern) {
+		if dir, err := filepath.Abs(pattern); err == nil {
+			if importPath := moduleCacheImportPathOf(dir, moduleCacheOf(goEnv("GOMODCACHE")[0])); importPath != "" {
+				pattern = importPath
+			}
+		}
+	}
+	pkgs, err := packages.Load(&packages.Config{Context: ctx, Mode: packages.NeedName | packages.NeedFiles | packages.NeedTypes}, pattern)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("loading package %v: %v", pattern, ctx.Err())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package for %v, but found %v", pattern, len(pkgs))
+	}
+	if len(pkgs[0].Errors) != 0 {
+		return nil, fmt.Errorf("loading package %v: %v", pattern, pkgs[0].Errors[0])
+	}
+	return pkgs[0], nil
+}
 
-	"github.com/petergtz/pegomock/model"
-	"github.com/petergtz/pegomock/modelgen/gomock"
+// GRPCInterfaces returns the import path of the package denoted by pattern, e.g. "./gen/foo",
+// and the names of the interfaces protoc-gen-go-grpc generated in it: the client and server
+// interfaces of all services, and the interfaces of their streams.
+func GRPCInterfaces(pattern string) (importPath string, names []string, err error) {
+	pkg, err := loadPackage(context.Background(), pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	scope := pkg.Types.Scope()
+	isClient := func(name string) bool {
+		typeName, isTypeName := scope.Lookup(name).(*types.TypeName)
+		return isTypeName && isGRPCClient(typeName)
+	}
+	for _, name := range scope.Names() {
+		typeName, isTypeName := scope.Lookup(name).(*types.TypeName)
+		if !isTypeName || !typeName.Exported() {
+			continue
+		}
+		it, isInterface := typeName.Type().Underlying().(*types.Interface)
+		if !isInterface || !it.IsMethodSet() || it.NumMethods() == 0 {
+			continue
+		}
+		isServer := strings.HasSuffix(name, "Server") && !typeName.IsAlias() && !onlyHasUnexportedMethods(it) &&
+			(isClient(strings.TrimSuffix(name, "Server")+"Client") || hasMethod(it, "mustEmbedUnimplemented"+name))
+		if isGRPCClient(typeName) || isServer || isGRPCStream(it) {
+			names = append(names, name)
+		}
+	}
+	return pkg.PkgPath, names, nil
+}
 
-	pkg_ {{printf "%q" .ImportPath}}
-)
+// isGRPCClient reports whether typeName is a client interface, whose methods all take
+// grpc.CallOptions.
+func isGRPCClient(typeName *types.TypeName) bool {
+	it, isInterface := typeName.Type().Underlying().(*types.Interface)
+	if !isInterface || typeName.IsAlias() || it.NumMethods() == 0 {
+		return false
+	}
+	for i := 0; i < it.NumMethods(); i++ {
+		signature := it.Method(i).Type().(*types.Signature)
+		if !signature.Variadic() {
+			return false
+		}
+		lastParam := signature.Params().At(signature.Params().Len() - 1).Type().(*types.Slice).Elem()
+		if !isGRPCType(lastParam, "CallOption") {
+			return false
+		}
+	}
+	return true
+}
 
-func main() {
-	its := []struct{
-		sym string
-		typ reflect.Type
-	}{
-		{{range .Symbols}}
-		{ {{printf "%q" .}}, reflect.TypeOf((*pkg_.{{.}})(nil)).Elem()},
-		{{end}}
+// isGRPCStream reports whether it is the interface of a stream, which includes the methods
+// of grpc.ClientStream or grpc.ServerStream.
+func isGRPCStream(it *types.Interface) bool {
+	for i := 0; i < it.NumEmbeddeds(); i++ {
+		if isGRPCType(it.EmbeddedType(i), "ClientStream") || isGRPCType(it.EmbeddedType(i), "ServerStream") {
+			return true
+		}
+		if embedded, isInterface := it.EmbeddedType(i).Underlying().(*types.Interface); isInterface && isGRPCStream(embedded) {
+			return true
+		}
 	}
-	pkg := &model.Package{
-		// NOTE: This behaves contrary to documented behaviour if the
-		// package name is not the final component of the import path.
-		// The reflect package doesn't expose the package name, though.
-		Name: path.Base({{printf "%q" .ImportPath}}),
+	return false
+}
+
+// isGRPCType reports whether t is the type called name of the package google.golang.org/grpc,
+// which may be vendored.
+func isGRPCType(t types.Type, name string) bool {
+	named, isNamed := t.(*types.Named)
+	if !isNamed || named.Obj().Name() != name || named.Obj().Pkg() == nil {
+		return false
 	}
+	path := named.Obj().Pkg().Path()
+	return path == "google.golang.org/grpc" || strings.HasSuffix(path, "/vendor/google.golang.org/grpc")
+}
 
-	for _, it := range its {
-		intf, err := gomock.InterfaceFromInterfaceType(it.typ)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Reflection: %v\n", err)
-			os.Exit(1)
+func hasMethod(it *types.Interface, name string) bool {
+	for i := 0; i < it.NumMethods(); i++ {
+		if it.Method(i).Name() == name {
+			return true
 		}
-		intf.Name = it.sym
-		pkg.Interfaces = append(pkg.Interfaces, intf)
 	}
-	if err := gob.NewEncoder(os.Stdout).Encode(pkg); err != nil {
-		fmt.Fprintf(os.Stderr, "gob encode: %v\n", err)
-		os.Exit(1)
+	return false
+}
+
+func onlyHasUnexportedMethods(it *types.Interface) bool {
+	for i := 0; i < it.NumMethods(); i++ {
+		if it.Method(i).Exported() {
+			return false
+		}
 	}
+	return true
 }
-`))
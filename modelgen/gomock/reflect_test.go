@@ -1,12 +1,18 @@
 package gomock_test
 
 import (
+	"context"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo"
 	"github.com/onsi/gomega"
 	. "github.com/onsi/gomega"
+	"github.com/petergtz/pegomock/model"
 	"github.com/petergtz/pegomock/modelgen/gomock"
 )
 
@@ -20,4 +26,312 @@ var _ = Describe("reflect", func() {
 		_, e := gomock.Reflect("github.com/petergtz/vendored_package", []string{"Interface"})
 		Expect(e).NotTo(HaveOccurred())
 	})
+
+	It("stops when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, e := gomock.ReflectContext(ctx, "github.com/petergtz/vendored_package", []string{"Interface"})
+
+		Expect(e).To(MatchError(ContainSubstring("context canceled")))
+	})
+
+	It("refers to types by the aliases the interface uses", func() {
+		origGO111MODULE := os.Getenv("GO111MODULE")
+		defer os.Setenv("GO111MODULE", origGO111MODULE)
+		Expect(os.Setenv("GO111MODULE", "off")).To(Succeed())
+		packageDir := filepath.Join(build.Default.GOPATH, "src", "github.com", "petergtz", "pegomock_aliases")
+		defer os.RemoveAll(packageDir)
+		Expect(os.MkdirAll(filepath.Join(packageDir, "ids"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(packageDir, "ids", "ids.go"), []byte(`package ids
+			import "net/url"
+			type ID = url.URL
+			type List[T any] = []T`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(packageDir, "api.go"), []byte(`package api
+			import "github.com/petergtz/pegomock_aliases/ids"
+			type id = ids.ID
+			type Store interface {
+				Get(id ids.ID) ids.List[string]
+				Put(id id, value any)
+			}`), 0644)).To(Succeed())
+
+		pkg, e := gomock.Reflect("github.com/petergtz/pegomock_aliases", []string{"Store"})
+
+		Expect(e).NotTo(HaveOccurred())
+		get, put := pkg.Interfaces[0].Methods[0], pkg.Interfaces[0].Methods[1]
+		Expect(get.In[0].Type).To(Equal(&model.NamedType{Package: "github.com/petergtz/pegomock_aliases/ids", Type: "ID"}))
+		Expect(get.Out[0].Type).To(Equal(&model.NamedType{
+			Package:  "github.com/petergtz/pegomock_aliases/ids",
+			Type:     "List",
+			TypeArgs: []model.Type{model.PredeclaredType("string")},
+		}))
+		Expect(put.In[0].Type).To(Equal(&model.NamedType{Package: "net/url", Type: "URL"}))
+		Expect(put.In[1].Type).To(Equal(model.PredeclaredType("interface{}")))
+	})
+
+	It("names parameters and results like the declarations of the methods", func() {
+		origGO111MODULE := os.Getenv("GO111MODULE")
+		defer os.Setenv("GO111MODULE", origGO111MODULE)
+		Expect(os.Setenv("GO111MODULE", "off")).To(Succeed())
+		packageDir := filepath.Join(build.Default.GOPATH, "src", "github.com", "petergtz", "pegomock_param_names")
+		defer os.RemoveAll(packageDir)
+		Expect(os.MkdirAll(packageDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(packageDir, "api.go"), []byte(`package api
+			type Closer interface { Close(force bool) error }
+			type Store interface {
+				Closer
+				Get(key string, version int) (value string, err error)
+				Put(key, value string, _ bool, options ...string)
+				Delete(string, int)
+			}`), 0644)).To(Succeed())
+
+		pkg, e := gomock.Reflect("github.com/petergtz/pegomock_param_names", []string{"Store"})
+
+		Expect(e).NotTo(HaveOccurred())
+		namesOf := func(params []*model.Parameter) (names []string) {
+			for _, param := range params {
+				names = append(names, param.Name)
+			}
+			return
+		}
+		methods := pkg.Interfaces[0].Methods
+		Expect(methods[0].Name).To(Equal("Close"))
+		Expect(namesOf(methods[0].In)).To(Equal([]string{"force"}))
+		Expect(namesOf(methods[0].Out)).To(Equal([]string{""}))
+		Expect(methods[1].Name).To(Equal("Delete"))
+		Expect(namesOf(methods[1].In)).To(Equal([]string{"", ""}))
+		Expect(methods[2].Name).To(Equal("Get"))
+		Expect(namesOf(methods[2].In)).To(Equal([]string{"key", "version"}))
+		Expect(namesOf(methods[2].Out)).To(Equal([]string{"value", "err"}))
+		Expect(methods[3].Name).To(Equal("Put"))
+		Expect(namesOf(methods[3].In)).To(Equal([]string{"key", "value", ""}))
+		Expect(methods[3].Variadic.Name).To(Equal("options"))
+	})
+
+	It("takes the doc comments of the interface and its methods from the declarations", func() {
+		origGO111MODULE := os.Getenv("GO111MODULE")
+		defer os.Setenv("GO111MODULE", origGO111MODULE)
+		Expect(os.Setenv("GO111MODULE", "off")).To(Succeed())
+		packageDir := filepath.Join(build.Default.GOPATH, "src", "github.com", "petergtz", "pegomock_docs")
+		defer os.RemoveAll(packageDir)
+		Expect(os.MkdirAll(packageDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(packageDir, "api.go"), []byte(`package api
+			type Closer interface {
+				// Close releases all resources.
+				Close() error
+			}
+
+			// Store stores items.
+			//
+			// It is safe for concurrent use.
+			type Store interface {
+				Closer
+				// Get returns the item stored under key.
+				Get(key string) string
+				Flush()
+			}`), 0644)).To(Succeed())
+
+		pkg, e := gomock.Reflect("github.com/petergtz/pegomock_docs", []string{"Store"})
+
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pkg.Interfaces[0].Doc).To(Equal("Store stores items.\n\nIt is safe for concurrent use."))
+		methods := pkg.Interfaces[0].Methods
+		Expect([]string{methods[0].Name, methods[1].Name, methods[2].Name}).To(Equal([]string{"Close", "Flush", "Get"}))
+		Expect([]string{methods[0].Doc, methods[1].Doc, methods[2].Doc}).To(Equal([]string{"Close releases all resources.", "", "Get returns the item stored under key."}))
+	})
+})
+
+var _ = Describe("parse", func() {
+	It("returns an error instead of exiting when imports collide", func() {
+		file, e := ioutil.TempFile("", "collision")
+		Expect(e).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+		_, e = file.WriteString(`package collision; import ( "text/template"; "html/template" ); type Display interface { Show() }`)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+
+		_, e = gomock.ParseFile(file.Name())
+
+		Expect(e).To(MatchError(`imported package collision: "template" imported twice`))
+	})
+
+	It("leaves blank parameters unnamed, because mocks have to refer to them", func() {
+		file, e := ioutil.TempFile("", "blank")
+		Expect(e).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+		_, e = file.WriteString(`package blank; type Store interface { Put(key, _ string, value int) }`)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+
+		pkg, e := gomock.ParseFile(file.Name())
+
+		Expect(e).NotTo(HaveOccurred())
+		params := pkg.Interfaces[0].Methods[0].In
+		Expect([]string{params[0].Name, params[1].Name, params[2].Name}).To(Equal([]string{"key", "", "value"}))
+	})
+
+	It("takes the doc comments of the interfaces and their methods from the file", func() {
+		file, e := ioutil.TempFile("", "docs")
+		Expect(e).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+		_, e = file.WriteString(`package docs
+
+			// Store stores items.
+			type Store interface {
+				// Get returns the item stored under key.
+				Get(key string) string
+			}
+
+			type (
+				// Handler handles events.
+				Handler func(event string)
+			)`)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+
+		pkg, e := gomock.ParseFile(file.Name())
+
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pkg.Interfaces[0].Doc).To(Equal("Store stores items."))
+		Expect(pkg.Interfaces[0].Methods[0].Doc).To(Equal("Get returns the item stored under key."))
+		Expect(pkg.Interfaces[1].Doc).To(Equal("Handler handles events."))
+	})
+
+	It("resolves embedded interfaces declared in other files of the same package", func() {
+		packageDir, e := ioutil.TempDir("", "siblings")
+		Expect(e).NotTo(HaveOccurred())
+		defer os.RemoveAll(packageDir)
+		Expect(ioutil.WriteFile(filepath.Join(packageDir, "reader.go"), []byte(`package siblings
+			import tmpl "text/template"
+			type Reader interface { Read(t *tmpl.Template) }`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(packageDir, "other_package.go"), []byte(`package other; type Writer interface { Other() }`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(packageDir, "writer.go"), []byte(`package siblings; type Writer interface { Write() }`), 0644)).To(Succeed())
+		source := filepath.Join(packageDir, "reader_writer.go")
+		Expect(ioutil.WriteFile(source, []byte(`package siblings
+			import tmpl "html/template"
+			type ReaderWriter interface { Reader; Writer; Render(t *tmpl.Template) }`), 0644)).To(Succeed())
+
+		pkg, e := gomock.ParseFile(source)
+
+		Expect(e).NotTo(HaveOccurred())
+		methods := pkg.Interfaces[0].Methods
+		Expect(methods).To(HaveLen(3))
+		Expect(methods[0].Name).To(Equal("Read"))
+		Expect(methods[0].In[0].Type).To(Equal(&model.PointerType{Type: &model.NamedType{Package: "text/template", Type: "Template"}}))
+		Expect(methods[1].Name).To(Equal("Write"))
+		Expect(methods[2].In[0].Type).To(Equal(&model.PointerType{Type: &model.NamedType{Package: "html/template", Type: "Template"}}))
+	})
+
+	It("determines import paths from go.mod outside of GOPATH, including major version suffixes", func() {
+		moduleDir, e := ioutil.TempDir("", "module")
+		Expect(e).NotTo(HaveOccurred())
+		defer os.RemoveAll(moduleDir)
+		Expect(ioutil.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module example.com/lib/v2 // the library\n\ngo 1.12\n"), 0644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(moduleDir, "api"), 0755)).To(Succeed())
+		source := filepath.Join(moduleDir, "api", "api.go")
+		Expect(ioutil.WriteFile(source, []byte(`package api
+			import "example.com/other/v3"
+			type Fluent interface { Chain(t other.Thing) Fluent }`), 0644)).To(Succeed())
+
+		pkg, e := gomock.ParseFile(source)
+
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pkg.PkgPath).To(Equal("example.com/lib/v2/api"))
+		Expect(pkg.Interfaces[0].Methods[0].In[0].Type).To(Equal(&model.NamedType{Package: "example.com/other/v3", Type: "Thing"}))
+		Expect(pkg.Interfaces[0].Methods[0].Out[0].Type).To(Equal(&model.NamedType{Package: "example.com/lib/v2/api", Type: "Fluent"}))
+	})
+
+	It("takes the names of unnamed imports from the vendor directory in GOPATH mode", func() {
+		origGO111MODULE := os.Getenv("GO111MODULE")
+		defer os.Setenv("GO111MODULE", origGO111MODULE)
+		Expect(os.Setenv("GO111MODULE", "off")).To(Succeed())
+		packageDir := filepath.Join(build.Default.GOPATH, "src", "github.com", "petergtz", "pegomock_vendoring")
+		defer os.RemoveAll(packageDir)
+		writeVendoredThings(filepath.Join(packageDir, "vendor"))
+		source := filepath.Join(packageDir, "api", "api.go")
+		Expect(os.MkdirAll(filepath.Dir(source), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(source, []byte(`package api
+			import "github.com/org/go-things"
+			type Store interface { Put(t things.Thing) }`), 0644)).To(Succeed())
+
+		pkg, e := gomock.ParseFile(source)
+
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pkg.Interfaces[0].Methods[0].In[0].Type).To(Equal(&model.NamedType{Package: "github.com/org/go-things", Type: "Thing"}))
+	})
+
+	Context("in module mode", func() {
+		var origGO111MODULE, origGOMODCACHE, origGOFLAGS string
+
+		BeforeEach(func() {
+			origGO111MODULE, origGOMODCACHE, origGOFLAGS = os.Getenv("GO111MODULE"), os.Getenv("GOMODCACHE"), os.Getenv("GOFLAGS")
+			Expect(os.Setenv("GO111MODULE", "on")).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.Setenv("GO111MODULE", origGO111MODULE)).To(Succeed())
+			Expect(os.Setenv("GOMODCACHE", origGOMODCACHE)).To(Succeed())
+			Expect(os.Setenv("GOFLAGS", origGOFLAGS)).To(Succeed())
+		})
+
+		It("takes the names of unnamed imports from the vendor directory in vendor mode", func() {
+			moduleDir, e := ioutil.TempDir("", "vendoring")
+			Expect(e).NotTo(HaveOccurred())
+			defer os.RemoveAll(moduleDir)
+			Expect(ioutil.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module example.com/app\n\ngo 1.14\n"), 0644)).To(Succeed())
+			writeVendoredThings(filepath.Join(moduleDir, "vendor"))
+			Expect(ioutil.WriteFile(filepath.Join(moduleDir, "vendor", "modules.txt"), []byte("# github.com/org/go-things v1.0.0\n"), 0644)).To(Succeed())
+			source := filepath.Join(moduleDir, "api.go")
+			Expect(ioutil.WriteFile(source, []byte(`package app
+				import "github.com/org/go-things"
+				type Store interface { Put(t things.Thing) }`), 0644)).To(Succeed())
+
+			pkg, e := gomock.ParseFile(source)
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(pkg.Interfaces[0].Methods[0].In[0].Type).To(Equal(&model.NamedType{Package: "github.com/org/go-things", Type: "Thing"}))
+			Expect(gomock.ImportPathOfDir(filepath.Join(moduleDir, "vendor", "github.com", "org", "go-things"))).To(Equal("github.com/org/go-things"))
+
+			Expect(os.Setenv("GOFLAGS", "-mod=mod")).To(Succeed())
+			_, e = gomock.ParseFile(source)
+			Expect(e).To(MatchError(ContainSubstring(`unknown package "things"`)))
+		})
+
+		It("determines import paths from go.mod even within GOPATH", func() {
+			moduleDir := filepath.Join(build.Default.GOPATH, "src", "github.com", "petergtz", "pegomock_module_in_gopath")
+			Expect(os.MkdirAll(filepath.Join(moduleDir, "api"), 0755)).To(Succeed())
+			defer os.RemoveAll(moduleDir)
+			Expect(ioutil.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module example.com/modular\n"), 0644)).To(Succeed())
+
+			Expect(gomock.ImportPathOfDir(filepath.Join(moduleDir, "api"))).To(Equal("example.com/modular/api"))
+
+			Expect(os.Setenv("GO111MODULE", "off")).To(Succeed())
+			Expect(gomock.ImportPathOfDir(filepath.Join(moduleDir, "api"))).To(Equal("github.com/petergtz/pegomock_module_in_gopath/api"))
+		})
+
+		It("determines import paths of packages in the module cache, also of modules without go.mod", func() {
+			moduleCache, e := ioutil.TempDir("", "modcache")
+			Expect(e).NotTo(HaveOccurred())
+			defer os.RemoveAll(moduleCache)
+			Expect(os.Setenv("GOMODCACHE", moduleCache)).To(Succeed())
+			packageDir := filepath.Join(moduleCache, "github.com", "!some!one", "lib", "v2@v2.1.0", "api")
+			Expect(os.MkdirAll(packageDir, 0755)).To(Succeed())
+			source := filepath.Join(packageDir, "api.go")
+			Expect(ioutil.WriteFile(source, []byte(`package api; type Fluent interface { Chain() Fluent }`), 0644)).To(Succeed())
+
+			pkg, e := gomock.ParseFile(source)
+
+			Expect(e).NotTo(HaveOccurred())
+			Expect(pkg.PkgPath).To(Equal("github.com/SomeOne/lib/v2/api"))
+			Expect(pkg.Interfaces[0].Methods[0].Out[0].Type).To(Equal(&model.NamedType{Package: "github.com/SomeOne/lib/v2/api", Type: "Fluent"}))
+		})
+	})
 })
+
+// writeVendoredThings writes the package github.com/org/go-things, whose name differs from its
+// last path element, to vendorDir.
+func writeVendoredThings(vendorDir string) {
+	thingsDir := filepath.Join(vendorDir, "github.com", "org", "go-things")
+	Expect(os.MkdirAll(thingsDir, 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(thingsDir, "things.go"), []byte("package things; type Thing int"), 0644)).To(Succeed())
+}
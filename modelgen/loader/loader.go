@@ -6,7 +6,9 @@ import (
 	"go/ast"
 	"go/types"
 
+	"github.com/petergtz/pegomock/internal/doccomment"
 	"github.com/petergtz/pegomock/model"
+	"github.com/petergtz/pegomock/modelgen/typesmodel"
 	"golang.org/x/tools/go/loader"
 )
 
@@ -15,7 +17,7 @@ func GenerateModel(importPath string, interfaceName string) (*model.Package, err
 	conf.Import(importPath)
 	program, e := conf.Load()
 	if e != nil {
-		panic(e)
+		return nil, e
 	}
 	info := program.Imported[importPath]
 
@@ -24,12 +26,37 @@ func GenerateModel(importPath string, interfaceName string) (*model.Package, err
 			interfacetype, ok := def.Obj.Decl.(*ast.TypeSpec).Type.(*ast.InterfaceType)
 			if ok {
 				g := &modelGenerator{info: info}
+				typeParams := typesmodel.TypeParams(info.Defs[def].Type().(*types.Named).TypeParams())
+				methods, err := g.modelMethodsFrom(interfacetype.Methods)
+				if err != nil {
+					err.Interface = interfaceName
+					return &model.Package{Name: info.Pkg.Name(), PkgPath: info.Pkg.Path()}, model.UnsupportedConstructErrors{err}
+				}
+				iface := &model.Interface{
+					Name:       interfaceName,
+					Doc:        doccomment.Text(typeDoc(info, def.Obj.Decl.(*ast.TypeSpec))),
+					TypeParams: typeParams,
+					Methods:    methods,
+				}
+				return &model.Package{
+					Name:       info.Pkg.Name(),
+					PkgPath:    info.Pkg.Path(),
+					Interfaces: []*model.Interface{iface},
+				}, nil
+			}
+			if funcType, ok := def.Obj.Decl.(*ast.TypeSpec).Type.(*ast.FuncType); ok {
+				g := &modelGenerator{info: info}
+				in, out, variadic := g.signatureFrom(funcType)
 				iface := &model.Interface{
-					Name:    interfaceName,
-					Methods: g.modelMethodsFrom(interfacetype.Methods),
+					Name:       interfaceName,
+					Doc:        doccomment.Text(typeDoc(info, def.Obj.Decl.(*ast.TypeSpec))),
+					TypeParams: typesmodel.TypeParams(info.Defs[def].Type().(*types.Named).TypeParams()),
+					Methods:    []*model.Method{{Name: model.FuncTypeMethod, In: in, Variadic: variadic, Out: out}},
+					IsFuncType: true,
 				}
 				return &model.Package{
 					Name:       info.Pkg.Name(),
+					PkgPath:    info.Pkg.Path(),
 					Interfaces: []*model.Interface{iface},
 				}, nil
 			}
@@ -39,12 +66,34 @@ func GenerateModel(importPath string, interfaceName string) (*model.Package, err
 	return nil, errors.New("Did not find interface name TODO")
 }
 
+// typeDoc returns the doc comment of the type declared by spec in one of the files of info.
+func typeDoc(info *loader.PackageInfo, spec *ast.TypeSpec) *ast.CommentGroup {
+	for _, file := range info.Files {
+		for _, decl := range file.Decls {
+			if genDecl, isGenDecl := decl.(*ast.GenDecl); isGenDecl {
+				for _, declSpec := range genDecl.Specs {
+					if declSpec == spec {
+						return doccomment.OfType(genDecl, spec)
+					}
+				}
+			}
+		}
+	}
+	return spec.Doc
+}
+
 type modelGenerator struct {
 	info *loader.PackageInfo
 }
 
-func (g *modelGenerator) modelMethodsFrom(astMethods *ast.FieldList) (modelMethods []*model.Method) {
+func (g *modelGenerator) modelMethodsFrom(astMethods *ast.FieldList) (modelMethods []*model.Method, err *model.UnsupportedConstructError) {
 	for _, astMethod := range astMethods.List {
+		if len(astMethod.Names) == 0 {
+			return nil, &model.UnsupportedConstructError{
+				Position: "embedded element",
+				Reason:   fmt.Sprintf("embedded %v is not supported by this model generator", g.info.TypeOf(astMethod.Type)),
+			}
+		}
 		modelMethods = append(modelMethods, g.modelMethodFrom(astMethod))
 	}
 	return
@@ -52,7 +101,7 @@ func (g *modelGenerator) modelMethodsFrom(astMethods *ast.FieldList) (modelMetho
 
 func (g *modelGenerator) modelMethodFrom(astMethod *ast.Field) *model.Method {
 	in, out, variadic := g.signatureFrom(astMethod.Type.(*ast.FuncType))
-	return &model.Method{Name: astMethod.Names[0].Name, In: in, Variadic: variadic, Out: out}
+	return &model.Method{Name: astMethod.Names[0].Name, Doc: doccomment.Text(astMethod.Doc), In: in, Variadic: variadic, Out: out}
 }
 
 func (g *modelGenerator) signatureFrom(astFuncType *ast.FuncType) (in, out []*model.Parameter, variadic *model.Parameter) {
@@ -96,84 +145,12 @@ func (g *modelGenerator) outParamsFrom(results *ast.FieldList) (out []*model.Par
 }
 
 func (g *modelGenerator) newParam(name string, typ ast.Expr) *model.Parameter {
+	if name == "_" {
+		// Mocks refer to all of their parameters, which blank ones cannot be.
+		name = ""
+	}
 	return &model.Parameter{
 		Name: name,
-		Type: g.modelTypeFrom(g.info.TypeOf(typ)),
-	}
-}
-
-func (g *modelGenerator) modelTypeFrom(typesType types.Type) model.Type {
-	switch typedTyp := typesType.(type) {
-	case *types.Basic:
-		if !predeclared(typedTyp.Kind()) {
-			panic(fmt.Sprintf("Unexpected Basic Type %v", typedTyp.Name()))
-		}
-		return model.PredeclaredType(typedTyp.Name())
-	case *types.Pointer:
-		return &model.PointerType{
-			Type: g.modelTypeFrom(typedTyp.Elem()),
-		}
-	case *types.Array:
-		return &model.ArrayType{
-			Len:  int(typedTyp.Len()),
-			Type: g.modelTypeFrom(typedTyp.Elem()),
-		}
-	case *types.Slice:
-		return &model.ArrayType{
-			Len:  -1,
-			Type: g.modelTypeFrom(typedTyp.Elem()),
-		}
-	case *types.Map:
-		return &model.MapType{
-			Key:   g.modelTypeFrom(typedTyp.Key()),
-			Value: g.modelTypeFrom(typedTyp.Elem()),
-		}
-	case *types.Chan:
-		return &model.ChanType{
-			Dir:  model.ChanDir(typedTyp.Dir()),
-			Type: g.modelTypeFrom(typedTyp.Elem()),
-		}
-	case *types.Named:
-		if typedTyp.Obj().Pkg() == nil {
-			return model.PredeclaredType(typedTyp.Obj().Name())
-		}
-		return &model.NamedType{
-			Package: typedTyp.Obj().Pkg().Path(),
-			Type:    typedTyp.Obj().Name(),
-		}
-	case *types.Interface:
-		return model.PredeclaredType(typedTyp.String())
-	case *types.Signature:
-		in, variadic := g.generateInParamsFrom(typedTyp.Params())
-		out := g.generateOutParamsFrom(typedTyp.Results())
-		return &model.FuncType{In: in, Out: out, Variadic: variadic}
-	default:
-		panic(fmt.Sprintf("Unknown types.Type: %v (%T)", typesType, typesType))
-	}
-}
-
-func (g *modelGenerator) generateInParamsFrom(params *types.Tuple) (in []*model.Parameter, variadic *model.Parameter) {
-	// TODO: variadic
-
-	for i := 0; i < params.Len(); i++ {
-		in = append(in, &model.Parameter{
-			Name: params.At(i).Name(),
-			Type: g.modelTypeFrom(params.At(i).Type()),
-		})
-	}
-	return
-}
-
-func (g *modelGenerator) generateOutParamsFrom(params *types.Tuple) (out []*model.Parameter) {
-	for i := 0; i < params.Len(); i++ {
-		out = append(out, &model.Parameter{
-			Name: params.At(i).Name(),
-			Type: g.modelTypeFrom(params.At(i).Type()),
-		})
+		Type: typesmodel.Type(g.info.TypeOf(typ)),
 	}
-	return
-}
-
-func predeclared(basicKind types.BasicKind) bool {
-	return basicKind >= types.Bool && basicKind <= types.String
 }
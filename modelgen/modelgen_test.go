@@ -83,6 +83,44 @@ var _ = Describe("modelgen/loader", func() {
 	})
 })
 
+var _ = Describe("self-referential interfaces", func() {
+	selfType := &model.NamedType{Package: "github.com/petergtz/pegomock/test_interface", Type: "Display"}
+
+	It("qualifies the interface's own type in source mode", func() {
+		pkg, e := gomock.ParseFile("../test_interface/display.go")
+		Expect(e).NotTo(HaveOccurred())
+
+		Expect(pkg.PkgPath).To(Equal("github.com/petergtz/pegomock/test_interface"))
+		Expect(methodNamed("WithPrefix", pkg.Interfaces[0].Methods).Out[0].Type).To(Equal(selfType))
+	})
+
+	It("qualifies the interface's own type in reflect mode", func() {
+		pkg, e := gomock.Reflect("github.com/petergtz/pegomock/test_interface", []string{"Display"})
+		Expect(e).NotTo(HaveOccurred())
+
+		Expect(pkg.PkgPath).To(Equal("github.com/petergtz/pegomock/test_interface"))
+		Expect(methodNamed("WithPrefix", pkg.Interfaces[0].Methods).Out[0].Type).To(Equal(selfType))
+	})
+
+	It("qualifies the interface's own type with modelgen/loader", func() {
+		pkg, e := loader.GenerateModel("github.com/petergtz/pegomock/test_interface", "Display")
+		Expect(e).NotTo(HaveOccurred())
+
+		Expect(pkg.PkgPath).To(Equal("github.com/petergtz/pegomock/test_interface"))
+		Expect(methodNamed("WithPrefix", pkg.Interfaces[0].Methods).Out[0].Type).To(Equal(selfType))
+	})
+})
+
+func methodNamed(name string, methods []*model.Method) *model.Method {
+	for _, method := range methods {
+		if method.Name == name {
+			return method
+		}
+	}
+	Fail("No method named " + name)
+	return nil
+}
+
 func expectMethodsEqual(actual, expected *model.Method) {
 	Expect(actual.Name).To(Equal(expected.Name))
 	expectParamsEqual(actual.Name, actual.In, expected.In)
@@ -97,3 +135,40 @@ func expectParamsEqual(methodName string, actual, expected []*model.Parameter) {
 		Expect(actual[i].Type).To(Equal(expected[i].Type))
 	}
 }
+
+var _ = Describe("function types", func() {
+	const importPath = "github.com/petergtz/pegomock/modelgen/test_data/func_types"
+
+	expectHandlerModel := func(pkg *model.Package) {
+		Expect(pkg.Interfaces).To(HaveLen(1))
+		Expect(pkg.Interfaces[0].Name).To(Equal("Handler"))
+		Expect(pkg.Interfaces[0].IsFuncType).To(BeTrue())
+		Expect(pkg.Interfaces[0].Methods).To(HaveLen(1))
+		method := pkg.Interfaces[0].Methods[0]
+		Expect(method.Name).To(Equal(model.FuncTypeMethod))
+		Expect(method.In[0].Type).To(Equal(&model.NamedType{Package: "context", Type: "Context"}))
+		Expect(method.Variadic.Type).To(Equal(model.PredeclaredType("string")))
+		Expect(method.Out[0].Type).To(Equal(model.PredeclaredType("error")))
+	}
+
+	It("models exported function types in source mode", func() {
+		pkg, e := gomock.ParseFile("test_data/func_types/handler.go")
+		Expect(e).NotTo(HaveOccurred())
+
+		expectHandlerModel(pkg)
+	})
+
+	It("models function types in reflect mode", func() {
+		pkg, e := gomock.Reflect(importPath, []string{"Handler"})
+		Expect(e).NotTo(HaveOccurred())
+
+		expectHandlerModel(pkg)
+	})
+
+	It("models function types with modelgen/loader", func() {
+		pkg, e := loader.GenerateModel(importPath, "Handler")
+		Expect(e).NotTo(HaveOccurred())
+
+		expectHandlerModel(pkg)
+	})
+})
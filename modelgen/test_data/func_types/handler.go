@@ -0,0 +1,7 @@
+package func_types
+
+import "context"
+
+type Handler func(ctx context.Context, events ...string) error
+
+type visit func()
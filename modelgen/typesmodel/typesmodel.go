@@ -0,0 +1,188 @@
+// Package typesmodel derives model types from go/types type information.
+package typesmodel
+
+import (
+	"fmt"
+	"go/types"
+
+	"github.com/petergtz/pegomock/model"
+)
+
+// Interface returns the model of the interface type named by name, including all methods
+// of embedded interfaces. Function types are modeled as interfaces with the single method
+// model.FuncTypeMethod, struct types as interfaces with the exported methods of a pointer
+// to the struct. Aliases of instantiated generic interfaces, like the stream types of gRPC,
+// are modeled as interfaces of their own.
+func Interface(name *types.TypeName) (*model.Interface, error) {
+	if alias, isAlias := name.Type().(*types.Alias); isAlias && alias.TypeParams().Len() == 0 {
+		if it, isInterface := alias.Underlying().(*types.Interface); isInterface {
+			return interfaceFrom(&model.Interface{Name: name.Name()}, it), nil
+		}
+	}
+	named, isNamed := name.Type().(*types.Named)
+	if !isNamed {
+		return nil, fmt.Errorf("%v is not a defined type", name.Name())
+	}
+	intf := &model.Interface{Name: name.Name(), TypeParams: TypeParams(named.TypeParams())}
+	if sig, isFuncType := named.Underlying().(*types.Signature); isFuncType {
+		method := &model.Method{Name: model.FuncTypeMethod}
+		method.In, method.Variadic, method.Out = Signature(sig)
+		intf.Methods = []*model.Method{method}
+		intf.IsFuncType = true
+		return intf, nil
+	}
+	if _, isStruct := named.Underlying().(*types.Struct); isStruct {
+		methodSet := types.NewMethodSet(types.NewPointer(named))
+		for i := 0; i < methodSet.Len(); i++ {
+			if !methodSet.At(i).Obj().Exported() {
+				continue
+			}
+			method := &model.Method{Name: methodSet.At(i).Obj().Name()}
+			method.In, method.Variadic, method.Out = Signature(methodSet.At(i).Type().(*types.Signature))
+			intf.Methods = append(intf.Methods, method)
+		}
+		intf.IsStruct = true
+		return intf, nil
+	}
+	it, isInterface := named.Underlying().(*types.Interface)
+	if !isInterface {
+		return nil, fmt.Errorf("%v is neither an interface, a function type nor a struct type", name.Name())
+	}
+	return interfaceFrom(intf, it), nil
+}
+
+func interfaceFrom(intf *model.Interface, it *types.Interface) *model.Interface {
+	for i := 0; i < it.NumMethods(); i++ {
+		method := &model.Method{Name: it.Method(i).Name()}
+		method.In, method.Variadic, method.Out = Signature(it.Method(i).Type().(*types.Signature))
+		intf.Methods = append(intf.Methods, method)
+	}
+	return intf
+}
+
+// TypeParams returns the model of the type parameters of a generic type.
+func TypeParams(typeParams *types.TypeParamList) (modelTypeParams []*model.TypeParam) {
+	for i := 0; i < typeParams.Len(); i++ {
+		modelTypeParams = append(modelTypeParams, &model.TypeParam{
+			Name:       typeParams.At(i).Obj().Name(),
+			Constraint: Type(typeParams.At(i).Constraint()),
+		})
+	}
+	return
+}
+
+// Signature returns the model of the parameters and results of sig.
+func Signature(sig *types.Signature) (in []*model.Parameter, variadic *model.Parameter, out []*model.Parameter) {
+	in = parametersFrom(sig.Params())
+	if sig.Variadic() {
+		variadic = in[len(in)-1]
+		variadic.Type = Type(sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice).Elem())
+		in = in[:len(in)-1]
+	}
+	out = parametersFrom(sig.Results())
+	return
+}
+
+func parametersFrom(tuple *types.Tuple) (params []*model.Parameter) {
+	for i := 0; i < tuple.Len(); i++ {
+		name := tuple.At(i).Name()
+		if name == "_" {
+			name = ""
+		}
+		params = append(params, &model.Parameter{Name: name, Type: Type(tuple.At(i).Type())})
+	}
+	return
+}
+
+// Type returns the model of t. Constructs that cannot be represented become a model.UnsupportedType.
+func Type(t types.Type) model.Type {
+	switch typedTyp := t.(type) {
+	case *types.Basic:
+		if !predeclared(typedTyp.Kind()) {
+			return &model.UnsupportedType{Description: typedTyp.Name()}
+		}
+		return model.PredeclaredType(typedTyp.Name())
+	case *types.Pointer:
+		return &model.PointerType{
+			Type: Type(typedTyp.Elem()),
+		}
+	case *types.Array:
+		return &model.ArrayType{
+			Len:  int(typedTyp.Len()),
+			Type: Type(typedTyp.Elem()),
+		}
+	case *types.Slice:
+		return &model.ArrayType{
+			Len:  -1,
+			Type: Type(typedTyp.Elem()),
+		}
+	case *types.Map:
+		return &model.MapType{
+			Key:   Type(typedTyp.Key()),
+			Value: Type(typedTyp.Elem()),
+		}
+	case *types.Chan:
+		return &model.ChanType{
+			Dir:  model.ChanDir(typedTyp.Dir()),
+			Type: Type(typedTyp.Elem()),
+		}
+	case *types.Named:
+		if typedTyp.Obj().Pkg() == nil {
+			return model.PredeclaredType(typedTyp.Obj().Name())
+		}
+		namedType := &model.NamedType{
+			Package: typedTyp.Obj().Pkg().Path(),
+			Type:    typedTyp.Obj().Name(),
+		}
+		for i := 0; i < typedTyp.TypeArgs().Len(); i++ {
+			namedType.TypeArgs = append(namedType.TypeArgs, Type(typedTyp.TypeArgs().At(i)))
+		}
+		return namedType
+	case *types.Interface:
+		if typedTyp.IsImplicit() && typedTyp.NumEmbeddeds() == 1 {
+			// A constraint like ~int | string, which is short for interface{ ~int | string }.
+			return Type(typedTyp.EmbeddedType(0))
+		}
+		return model.PredeclaredType(typedTyp.String())
+	case *types.Struct:
+		if typedTyp.NumFields() != 0 {
+			return &model.UnsupportedType{Description: fmt.Sprintf("%v (%T)", t, t)}
+		}
+		return model.PredeclaredType("struct{}")
+	case *types.Union:
+		union := &model.UnionType{}
+		for i := 0; i < typedTyp.Len(); i++ {
+			union.Terms = append(union.Terms, &model.UnionTerm{
+				Tilde: typedTyp.Term(i).Tilde(),
+				Type:  Type(typedTyp.Term(i).Type()),
+			})
+		}
+		return union
+	case *types.TypeParam:
+		return model.TypeParamType(typedTyp.Obj().Name())
+	case *types.Alias:
+		// Like the interface, the mocks refer to the alias, which may be all that its package
+		// exports. Unexported aliases cannot be referred to from other packages, and the
+		// predeclared any is modeled like interface{}, as before there were aliases in go/types.
+		if typedTyp.Obj().Pkg() == nil || !typedTyp.Obj().Exported() {
+			return Type(types.Unalias(typedTyp))
+		}
+		namedType := &model.NamedType{
+			Package: typedTyp.Obj().Pkg().Path(),
+			Type:    typedTyp.Obj().Name(),
+		}
+		for i := 0; i < typedTyp.TypeArgs().Len(); i++ {
+			namedType.TypeArgs = append(namedType.TypeArgs, Type(typedTyp.TypeArgs().At(i)))
+		}
+		return namedType
+	case *types.Signature:
+		in, variadic, out := Signature(typedTyp)
+		return &model.FuncType{In: in, Out: out, Variadic: variadic}
+	default:
+		return &model.UnsupportedType{Description: fmt.Sprintf("%v (%T)", t, t)}
+	}
+}
+
+func predeclared(basicKind types.BasicKind) bool {
+	return basicKind >= types.Bool && basicKind <= types.String
+}
@@ -0,0 +1,108 @@
+package filehandling
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/petergtz/pegomock/mockgen"
+	"github.com/petergtz/pegomock/model"
+	"github.com/petergtz/pegomock/modelgen/gomock"
+)
+
+// GeneratedFilesUnder returns the .go files in root and all its sub-directories that were
+// generated by pegomock, see mockgen.GeneratedByPegomock. It skips the same directories as
+// PackagesUnder.
+func GeneratedFilesUnder(root string) ([]string, error) {
+	var generatedFiles []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && (info.Name() == "vendor" || info.Name() == "testdata" ||
+				strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if mockgen.GeneratedByPegomock(content) {
+			generatedFiles = append(generatedFiles, path)
+		}
+		return nil
+	})
+	return generatedFiles, err
+}
+
+var packageSourcePattern = regexp.MustCompile(`^(.+) \(interfaces: (.+)\)$`)
+
+// recordedSourceArgs returns the args that generate content, the content of generatedFile,
+// again, according to its "// Source:" line, or nil if it has none. A relative source file is
+// looked up in the directory of generatedFile first.
+func recordedSourceArgs(generatedFile string, content []byte) []string {
+	source := mockgen.RecordedSource(content)
+	if source == "" {
+		return nil
+	}
+	if match := packageSourcePattern.FindStringSubmatch(source); match != nil {
+		return []string{match[1], match[2]}
+	}
+	if !filepath.IsAbs(source) {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(generatedFile), source)); err == nil {
+			return []string{filepath.Join(filepath.Dir(generatedFile), source)}
+		}
+	}
+	return []string{source}
+}
+
+// IsOrphaned tells whether the interfaces the code in generatedFile was generated from no
+// longer exist: their package or source file is gone, or the package doesn't declare one of
+// them anymore, or the source file no interface. A relative source file is looked up in the
+// directory of generatedFile first. Files without a recorded source, like matchers, are never
+// orphaned.
+func IsOrphaned(generatedFile string) (bool, error) {
+	content, err := ioutil.ReadFile(generatedFile)
+	if err != nil {
+		return false, err
+	}
+	args := recordedSourceArgs(generatedFile, content)
+	if args == nil {
+		return false, nil
+	}
+
+	if len(args) == 2 {
+		if _, err := build.Import(args[0], filepath.Dir(generatedFile), build.FindOnly); err != nil {
+			return true, nil
+		}
+		undeclared, err := gomock.UndeclaredTypes(args[0], strings.Split(args[1], ","))
+		if err != nil {
+			return false, err
+		}
+		return len(undeclared) != 0, nil
+	}
+
+	sourceFile := args[0]
+	if _, err := os.Stat(sourceFile); os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !strings.HasSuffix(sourceFile, ".go") {
+		return false, nil
+	}
+	pkg, err := gomock.ParseFile(sourceFile)
+	if _, unsupported := err.(model.UnsupportedConstructErrors); err != nil && !unsupported {
+		return false, err
+	}
+	return len(pkg.Interfaces) == 0 && err == nil, nil
+}
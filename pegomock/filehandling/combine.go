@@ -0,0 +1,77 @@
+package filehandling
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/petergtz/pegomock/model"
+	"github.com/petergtz/pegomock/modelgen/gomock"
+)
+
+// combinedModel builds the model of an interface called name that has the methods of all
+// qualifiedInterfaces, e.g. "io.Reader" or "./storage.Store", so one mock implements all of them.
+// Interfaces without a package, e.g. "Store", are taken from the package in the current
+// directory. Methods declared by several of the interfaces must have the same signatures.
+func combinedModel(name string, qualifiedInterfaces []string) (*model.Package, error) {
+	var importPaths []string
+	interfaceNames, givenNames := make(map[string][]string), make(map[string][]string)
+	for _, qualifiedInterface := range qualifiedInterfaces {
+		importPath, interfaceName := ".", qualifiedInterface
+		if dot := strings.LastIndex(qualifiedInterface, "."); dot > strings.LastIndex(qualifiedInterface, "/") {
+			importPath, interfaceName = qualifiedInterface[:dot], qualifiedInterface[dot+1:]
+		}
+		if _, exists := interfaceNames[importPath]; !exists {
+			importPaths = append(importPaths, importPath)
+		}
+		interfaceNames[importPath] = append(interfaceNames[importPath], interfaceName)
+		givenNames[importPath] = append(givenNames[importPath], qualifiedInterface)
+	}
+
+	combined := &model.Interface{Name: name}
+	methods := make(map[string]*model.Method)
+	for _, importPath := range importPaths {
+		pkg, err := gomock.Reflect(importPath, interfaceNames[importPath])
+		if err != nil {
+			return nil, err
+		}
+		for i, iface := range pkg.Interfaces {
+			if len(iface.TypeParams) != 0 || iface.IsFuncType || iface.IsStruct {
+				return nil, fmt.Errorf("%v cannot be combined, only non-generic interfaces can", givenNames[importPath][i])
+			}
+			for _, method := range iface.Methods {
+				if existing, exists := methods[method.Name]; exists {
+					if !sameSignature(existing, method) {
+						return nil, fmt.Errorf("%v declares the method %v with another signature than the interfaces before it", givenNames[importPath][i], method.Name)
+					}
+					continue
+				}
+				methods[method.Name] = method
+				combined.Methods = append(combined.Methods, method)
+			}
+		}
+	}
+	// The combination is declared nowhere, so the mock can neither refer to it nor live in its package.
+	return &model.Package{Interfaces: []*model.Interface{combined}}, nil
+}
+
+// sameSignature reports whether a and b have the same parameter and result types, regardless of
+// the names of the parameters.
+func sameSignature(a, b *model.Method) bool {
+	typesOf := func(params []*model.Parameter) []model.Type {
+		types := make([]model.Type, len(params))
+		for i, param := range params {
+			types[i] = param.Type
+		}
+		return types
+	}
+	variadicTypeOf := func(method *model.Method) model.Type {
+		if method.Variadic == nil {
+			return nil
+		}
+		return method.Variadic.Type
+	}
+	return reflect.DeepEqual(typesOf(a.In), typesOf(b.In)) &&
+		reflect.DeepEqual(typesOf(a.Out), typesOf(b.Out)) &&
+		reflect.DeepEqual(variadicTypeOf(a), variadicTypeOf(b))
+}
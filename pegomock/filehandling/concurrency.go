@@ -0,0 +1,62 @@
+package filehandling
+
+import (
+	"strings"
+	"sync"
+)
+
+// GenerationErrors are the errors of several generations run by GenerateConcurrently, in the
+// order of the generations.
+type GenerationErrors []error
+
+func (errs GenerationErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "\t" + strings.ReplaceAll(err.Error(), "\n", "\n\t")
+	}
+	return "Generating mocks failed:\n" + strings.Join(lines, "\n")
+}
+
+func (errs GenerationErrors) Unwrap() []error { return errs }
+
+// GenerateConcurrently runs generations on a pool of at most workers goroutines. Unlike stopping
+// at the first error, it runs all generations and returns their errors as GenerationErrors, or
+// nil if all succeeded.
+func GenerateConcurrently(generations []func() error, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		errs      = make([]error, len(generations))
+		indices   = make(chan int)
+		waitGroup sync.WaitGroup
+	)
+	for worker := 0; worker < workers && worker < len(generations); worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for i := range indices {
+				errs[i] = generations[i]()
+			}
+		}()
+	}
+	for i := range generations {
+		indices <- i
+	}
+	close(indices)
+	waitGroup.Wait()
+
+	var generationErrors GenerationErrors
+	for _, err := range errs {
+		if err != nil {
+			generationErrors = append(generationErrors, err)
+		}
+	}
+	if generationErrors == nil {
+		return nil
+	}
+	return generationErrors
+}
@@ -0,0 +1,229 @@
+package filehandling
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/petergtz/pegomock/mockgen"
+	"github.com/petergtz/pegomock/modelgen/gomock"
+	"github.com/petergtz/pegomock/pegomock/util"
+)
+
+// ProjectConfigFileName is the name of the file declaring all mocks of a project, which
+// "pegomock generate" regenerates when called without args.
+const ProjectConfigFileName = ".pegomock.yaml"
+
+// GenerateOptions are the options of the "generate" command that a project configuration can
+// set for all mocks and override for single mocks. Relative paths are relative to the directory
+// of the configuration file.
+type GenerateOptions struct {
+	OutputDir            string   `yaml:"output-dir"`
+	OutputNameTemplate   string   `yaml:"output-name-template"`
+	Package              string   `yaml:"package"`
+	GenerateBuilders     *bool    `yaml:"generate-builders"`
+	GenerateMatchers     *bool    `yaml:"generate-matchers"`
+	MatchersDir          string   `yaml:"matchers-dir"`
+	HeaderFile           string   `yaml:"header-file"`
+	BuildTags            string   `yaml:"build-tags"`
+	Style                string   `yaml:"style"`
+	DependencyInjection  string   `yaml:"dependency-injection"`
+	Incremental          *bool    `yaml:"incremental"`
+	AllowInternalImports *bool    `yaml:"allow-internal-imports"`
+	Template             string   `yaml:"template"`
+	Emitters             []string `yaml:"emitters"`
+	Methods              []string `yaml:"methods"`
+
+	MockNameTemplate                string `yaml:"name-template"`
+	VerifierNameTemplate            string `yaml:"verifier-name-template"`
+	OngoingVerificationNameTemplate string `yaml:"ongoing-verification-name-template"`
+}
+
+// NameTemplates returns the templates for the names of the generated types.
+func (options GenerateOptions) NameTemplates() mockgen.NameTemplates {
+	return mockgen.NameTemplates{
+		Mock:                options.MockNameTemplate,
+		Verifier:            options.VerifierNameTemplate,
+		OngoingVerification: options.OngoingVerificationNameTemplate,
+	}
+}
+
+// MockConfig declares the mocks for the interfaces denoted by Args, which are the same as the
+// args of the "generate" command. A relative package path like ./display is relative to the
+// directory of the configuration file, and so is a single interface name.
+type MockConfig struct {
+	Args            []string `yaml:"args"`
+	Output          string   `yaml:"output"`
+	GenerateOptions `yaml:",inline"`
+}
+
+// ProjectConfig is the content of a project configuration file.
+type ProjectConfig struct {
+	Dir             string `yaml:"-"`
+	GenerateOptions `yaml:",inline"`
+	Mocks           []MockConfig `yaml:"mocks"`
+}
+
+// FindProjectConfig returns the path of the project configuration file in dir or its closest
+// parent directory that has one, or "" if there is none.
+func FindProjectConfig(dir string) string {
+	for {
+		configPath := filepath.Join(dir, ProjectConfigFileName)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectConfig reads the project configuration file at configPath. Unknown keys are
+// reported as errors, so typos don't go unnoticed.
+func LoadProjectConfig(configPath string) (*ProjectConfig, error) {
+	content, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var config ProjectConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("Invalid project configuration %v: %v", configPath, err)
+	}
+	if len(config.Mocks) == 0 {
+		return nil, fmt.Errorf("Invalid project configuration %v: no mocks declared", configPath)
+	}
+	config.Dir, err = filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SourceArgs returns the args to generate mock, with source files turned into absolute paths
+// and relative package paths and single interface names resolved to the import paths of the
+// corresponding directories.
+func (config *ProjectConfig) SourceArgs(mock MockConfig) ([]string, error) {
+	if err := util.ValidateArgs(mock.Args); err != nil {
+		return nil, err
+	}
+	switch {
+	case util.SourceMode(mock.Args):
+		return []string{config.path(mock.Args[0])}, nil
+	case len(mock.Args) == 1:
+		packagePath, err := config.importPathOf(".")
+		return []string{packagePath, mock.Args[0]}, err
+	case len(mock.Args) == 2 && isRelative(mock.Args[0]):
+		packagePath, err := config.importPathOf(mock.Args[0])
+		return []string{packagePath, mock.Args[1]}, err
+	case len(mock.Args) == 2:
+		return mock.Args, nil
+	default:
+		return nil, fmt.Errorf("Please provide exactly 1 interface or 1 package + 1 interface, but got %v", strings.Join(mock.Args, " "))
+	}
+}
+
+func (config *ProjectConfig) importPathOf(dir string) (string, error) {
+	packagePath := gomock.ImportPathOfDir(config.path(dir))
+	if packagePath == "" {
+		return "", fmt.Errorf("Couldn't determine package path from directory: "+
+			"Directory is neither within a Go package path nor a Go module. dir: %v", config.path(dir))
+	}
+	return packagePath, nil
+}
+
+// OutputFilePath returns the output file declared for mock, or "" if there is none.
+func (config *ProjectConfig) OutputFilePath(mock MockConfig) string {
+	if mock.Output == "" {
+		return ""
+	}
+	return config.path(mock.Output)
+}
+
+// OptionsFor returns the options for mock, i.e. those set for mock and the project's for all
+// others. The output directory defaults to the directory of the configuration file.
+func (config *ProjectConfig) OptionsFor(mock MockConfig) GenerateOptions {
+	options := config.GenerateOptions
+	if mock.OutputDir != "" {
+		options.OutputDir = mock.OutputDir
+	}
+	if mock.OutputNameTemplate != "" {
+		options.OutputNameTemplate = mock.OutputNameTemplate
+	}
+	if mock.Package != "" {
+		options.Package = mock.Package
+	}
+	if mock.GenerateBuilders != nil {
+		options.GenerateBuilders = mock.GenerateBuilders
+	}
+	if mock.GenerateMatchers != nil {
+		options.GenerateMatchers = mock.GenerateMatchers
+	}
+	if mock.MatchersDir != "" {
+		options.MatchersDir = mock.MatchersDir
+	}
+	if mock.HeaderFile != "" {
+		options.HeaderFile = mock.HeaderFile
+	}
+	if mock.BuildTags != "" {
+		options.BuildTags = mock.BuildTags
+	}
+	if mock.Style != "" {
+		options.Style = mock.Style
+	}
+	if mock.DependencyInjection != "" {
+		options.DependencyInjection = mock.DependencyInjection
+	}
+	if mock.Incremental != nil {
+		options.Incremental = mock.Incremental
+	}
+	if mock.AllowInternalImports != nil {
+		options.AllowInternalImports = mock.AllowInternalImports
+	}
+	if mock.Template != "" {
+		options.Template = mock.Template
+	}
+	if mock.Emitters != nil {
+		options.Emitters = mock.Emitters
+	}
+	if mock.Methods != nil {
+		options.Methods = mock.Methods
+	}
+	if mock.MockNameTemplate != "" {
+		options.MockNameTemplate = mock.MockNameTemplate
+	}
+	if mock.VerifierNameTemplate != "" {
+		options.VerifierNameTemplate = mock.VerifierNameTemplate
+	}
+	if mock.OngoingVerificationNameTemplate != "" {
+		options.OngoingVerificationNameTemplate = mock.OngoingVerificationNameTemplate
+	}
+	options.OutputDir = config.path(options.OutputDir)
+	if options.MatchersDir != "" {
+		options.MatchersDir = config.path(options.MatchersDir)
+	}
+	if options.HeaderFile != "" {
+		options.HeaderFile = config.path(options.HeaderFile)
+	}
+	if options.Template != "" {
+		options.Template = config.path(options.Template)
+	}
+	return options
+}
+
+func (config *ProjectConfig) path(relativePath string) string {
+	if filepath.IsAbs(relativePath) {
+		return relativePath
+	}
+	return filepath.Join(config.Dir, relativePath)
+}
+
+func isRelative(packagePath string) bool {
+	return packagePath == "." || packagePath == ".." ||
+		strings.HasPrefix(packagePath, "./") || strings.HasPrefix(packagePath, "../")
+}
@@ -0,0 +1,36 @@
+package filehandling
+
+import "errors"
+
+// ErrorKind tells what went wrong in an Error, so the CLI can exit with a distinct code.
+type ErrorKind string
+
+const (
+	// ParseError means the interfaces could not be loaded, e.g. because their package doesn't
+	// compile or their source file has a syntax error.
+	ParseError ErrorKind = "parse"
+	// InterfaceNotFoundError means the package doesn't declare an interface to mock.
+	InterfaceNotFoundError ErrorKind = "interface-not-found"
+	// WriteError means generated files could not be written.
+	WriteError ErrorKind = "write"
+)
+
+// Error is an error of a known ErrorKind.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (err *Error) Error() string { return err.Err.Error() }
+
+func (err *Error) Unwrap() error { return err.Err }
+
+// KindOf returns the ErrorKind of the first Error in the tree of err, e.g. of the first of
+// GenerationErrors that has one, or "" if there is none.
+func KindOf(err error) ErrorKind {
+	var kindError *Error
+	if errors.As(err, &kindError) {
+		return kindError.Kind
+	}
+	return ""
+}
@@ -1,13 +1,20 @@
 package filehandling
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/petergtz/pegomock/mockgen"
 	"github.com/petergtz/pegomock/model"
@@ -16,75 +23,445 @@ import (
 	"github.com/petergtz/pegomock/pegomock/util"
 )
 
-func GenerateMockFileInOutputDir(
-	args []string,
-	outputDirPath string,
-	outputFilePathOverride string,
-	packageOut string,
-	selfPackage string,
-	debugParser bool,
-	out io.Writer,
-	useExperimentalModelGen bool,
-	shouldGenerateMatchers bool,
-	matchersDestination string) {
-
-	// if a file path override is specified
-	// ensure all directories in the path are created
-	if outputFilePathOverride != "" {
-		if err := os.MkdirAll(filepath.Dir(outputFilePathOverride), 0755); err != nil {
-			panic(fmt.Errorf("Failed to make output directory, error: %v", err))
+// Options configures the generation of mock files.
+type Options struct {
+	// Options configures the generated code. Source is derived from the args, MatchersPackage
+	// from the directory of the matchers, and RecordInputHash from Incremental.
+	mockgen.Options
+	// DebugParser prints the model of the interfaces to Out, which defaults to os.Stdout.
+	DebugParser bool
+	Out         io.Writer
+	// UseExperimentalModelGen builds the model with golang.org/x/tools/go/loader.
+	UseExperimentalModelGen bool
+	// GenerateMatchers additionally writes matchers for the types in the signatures of the
+	// mocked methods.
+	GenerateMatchers bool
+	// MatchersDestination is the directory of the matchers. Defaults to the directory
+	// "matchers" next to the mocks.
+	MatchersDestination string
+	// Emitters are the names of emitters whose files are written to the directory of the
+	// mocks, see mockgen.Emit.
+	Emitters []string
+	// Incremental skips generating mocks whose input hash is already recorded in the mock
+	// file, see GenerateMockSourceCode.
+	Incremental bool
+	// AllowInternalImports writes files even if they import an internal package that they
+	// must not import, see CheckInternalImports.
+	AllowInternalImports bool
+}
+
+// GenerateMockFileInOutputDir is GenerateMockFile with the path of the mock file and the
+// package of the mocks determined by OutputPackageAndFilePath.
+func GenerateMockFileInOutputDir(args []string, outputDirPath string, outputFilePathOverride string, outputNameTemplate string, options Options) error {
+	files, err := MockFilesInOutputDir(args, outputDirPath, outputFilePathOverride, outputNameTemplate, options)
+	if writeErr := WriteFiles(files); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// MockFilesInOutputDir returns the files GenerateMockFileInOutputDir would write, keyed by
+// their paths, without writing anything. Like GenerateMockFile, it returns the files for
+// all supported interfaces along with a model.UnsupportedConstructErrors for the others.
+func MockFilesInOutputDir(args []string, outputDirPath string, outputFilePathOverride string, outputNameTemplate string, options Options) (map[string][]byte, error) {
+	packageOut, outputFilePath, err := OutputPackageAndFilePath(args, outputDirPath, outputFilePathOverride, outputNameTemplate, options.PackageOut)
+	if err != nil {
+		return nil, err
+	}
+	options.PackageOut = packageOut
+	return MockFiles(args, outputFilePath, options)
+}
+
+// ReadHeaderFile returns the content of headerFile, or "" if headerFile is empty.
+func ReadHeaderFile(headerFile string) (string, error) {
+	if headerFile == "" {
+		return "", nil
+	}
+	header, err := ioutil.ReadFile(headerFile)
+	if err != nil {
+		return "", fmt.Errorf("Failed reading header file: %v", err)
+	}
+	return string(header), nil
+}
+
+// ReadTemplateFile returns the content of templateFile, or "" if templateFile is empty.
+func ReadTemplateFile(templateFile string) (string, error) {
+	if templateFile == "" {
+		return "", nil
+	}
+	mockTemplate, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return "", fmt.Errorf("Failed reading template file: %v", err)
+	}
+	return string(mockTemplate), nil
+}
+
+// OutputPackageAndFilePath determines the package of the generated code and where it gets
+// written to. An empty packageOut defaults to the package declared in a _test.go source file,
+// because interfaces declared there are only visible in that package, and likewise to the
+// package declaring the interfaces if any of them is unexported. Otherwise, it defaults to the
+// package of outputDirPath as determined by DefaultPackageOut. Mocks for a _test.go source file
+// or unexported interfaces can only be written to another directory if packageOut is given
+// explicitly.
+func OutputPackageAndFilePath(args []string, outputDirPath string, outputFilePathOverride string, outputNameTemplate string, packageOut string) (string, string, error) {
+	if packageOut != "" {
+		outputFilePath, err := OutputFilePath(args, outputDirPath, outputFilePathOverride, outputNameTemplate, packageOut)
+		return packageOut, outputFilePath, err
+	}
+
+	var sourceDir, visibility string
+	var err error
+	switch {
+	case isTestSource(args):
+		packageOut, err = packageNameOf(args[0])
+		if err != nil {
+			return "", "", err
+		}
+		sourceDir, err = filepath.Abs(filepath.Dir(args[0]))
+		if err != nil {
+			return "", "", err
 		}
+		visibility = fmt.Sprintf("Interfaces in %v are", args[0])
+	case namesUnexportedInterface(args):
+		packageOut, sourceDir, err = gomock.PackageNameAndDir(args[0])
+		if err != nil {
+			return "", "", &Error{Kind: ParseError, Err: fmt.Errorf("Loading input failed: %v", err)}
+		}
+		if outputFilePathOverride == "" && outputNameTemplate == "" {
+			outputDirPath = sourceDir
+		}
+		visibility = fmt.Sprintf("Unexported interfaces of %v are", args[0])
+	default:
+		absOutputDirPath, err := filepath.Abs(outputDirPath)
+		if err != nil {
+			return "", "", err
+		}
+		packageOut, err = DefaultPackageOut(args, outputDirPath, outputFilePathOverride, outputNameTemplate, filepath.Base(absOutputDirPath))
+		if err != nil {
+			return "", "", err
+		}
+		outputFilePath, err := OutputFilePath(args, outputDirPath, outputFilePathOverride, outputNameTemplate, packageOut)
+		return packageOut, outputFilePath, err
 	}
 
-	GenerateMockFile(
-		args,
-		OutputFilePath(args, outputDirPath, outputFilePathOverride),
-		packageOut,
-		selfPackage,
-		debugParser,
-		out,
-		useExperimentalModelGen,
-		shouldGenerateMatchers,
-		matchersDestination)
+	outputFilePath, err := OutputFilePath(args, outputDirPath, outputFilePathOverride, outputNameTemplate, packageOut)
+	if err != nil {
+		return "", "", err
+	}
+	inSourceDir, err := sameDir(filepath.Dir(outputFilePath), sourceDir)
+	if err != nil {
+		return "", "", err
+	}
+	if !inSourceDir {
+		return "", "", fmt.Errorf("%v only visible in package %v, but the mocks would be written to %v. "+
+			"Please specify the package of the generated code explicitly with --package.", visibility, packageOut, outputFilePath)
+	}
+	return packageOut, outputFilePath, nil
+}
+
+// DefaultPackageOut returns the package of mocks that aren't given one explicitly, packageName
+// suffixed with _test. If outputNameTemplate is used and produces a file name that doesn't end in
+// _test.go, it returns packageName itself instead, since such a file is compiled into the
+// package and not into its external test package.
+func DefaultPackageOut(args []string, outputDirPath string, outputFilePathOverride string, outputNameTemplate string, packageName string) (string, error) {
+	if outputFilePathOverride != "" || outputNameTemplate == "" {
+		return packageName + "_test", nil
+	}
+	outputFilePath, err := OutputFilePath(args, outputDirPath, outputFilePathOverride, outputNameTemplate, packageName+"_test")
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(outputFilePath, "_test.go") {
+		return packageName + "_test", nil
+	}
+	outputFilePath, err = OutputFilePath(args, outputDirPath, outputFilePathOverride, outputNameTemplate, packageName)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(outputFilePath, "_test.go") {
+		return "", fmt.Errorf("Output name template produces a _test.go file name for package %v, but not for package %v_test. "+
+			"Please specify the package of the generated code explicitly with --package.", packageName, packageName)
+	}
+	return packageName, nil
+}
+
+func isTestSource(args []string) bool {
+	return util.SourceMode(args) && strings.HasSuffix(args[0], "_test.go")
+}
+
+// namesUnexportedInterface reports whether args name a package and its interfaces, of which at
+// least one is unexported.
+func namesUnexportedInterface(args []string) bool {
+	if util.SourceMode(args) || util.ModelMode(args) || len(args) != 2 {
+		return false
+	}
+	for _, name := range strings.Split(args[1], ",") {
+		if !ast.IsExported(name) {
+			return true
+		}
+	}
+	return false
 }
 
-func OutputFilePath(args []string, outputDirPath string, outputFilePathOverride string) string {
+func packageNameOf(source string) (string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), source, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return "", fmt.Errorf("Failed parsing source file %v: %v", source, err)
+	}
+	return file.Name.Name, nil
+}
+
+// OutputNameTemplateData holds the fields available in an output name template.
+type OutputNameTemplateData struct {
+	InterfaceName string // the interface(s) as given on the command line, or the name of a combination; empty in source mode
+	PackageName   string // the package of the generated code
+	SourceBase    string // the source file name without ".go", the last element of the package path, or the name of a combination
+}
+
+// OutputFilePath determines where the mock gets written to. An override takes precedence over
+// outputNameTemplate, a text/template executed with OutputNameTemplateData. Without either,
+// it defaults to mock_<interface>_test.go, mock_<source file>_test.go or, for a combination of
+// interfaces, mock_<name>_test.go. For a source file
+// <name>_test.go, it defaults to mock_<name>_test_interfaces_test.go in the directory of the
+// source file, so it is in the same package and does not collide with the mocks for <name>.go.
+func OutputFilePath(args []string, outputDirPath string, outputFilePathOverride string, outputNameTemplate string, packageOut string) (string, error) {
 	if outputFilePathOverride != "" {
-		return outputFilePathOverride
-	} else if util.SourceMode(args) {
-		return filepath.Join(outputDirPath, "mock_"+strings.TrimSuffix(args[0], ".go")+"_test.go")
+		return outputFilePathOverride, nil
+	} else if outputNameTemplate != "" {
+		outputFileName, err := executeOutputNameTemplate(outputNameTemplate, outputNameTemplateDataFor(args, packageOut))
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(outputFileName) {
+			return outputFileName, nil
+		}
+		return filepath.Join(outputDirPath, outputFileName), nil
+	} else if isTestSource(args) {
+		sourceDir := filepath.Dir(args[0])
+		if !filepath.IsAbs(sourceDir) {
+			sourceDir = filepath.Join(outputDirPath, sourceDir)
+		}
+		return filepath.Join(sourceDir, "mock_"+strings.TrimSuffix(filepath.Base(args[0]), "_test.go")+"_test_interfaces_test.go"), nil
+	} else if util.SourceMode(args) || util.ModelMode(args) {
+		return filepath.Join(outputDirPath, "mock_"+strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))+"_test.go"), nil
+	} else if util.CombineMode(args) {
+		return filepath.Join(outputDirPath, "mock_"+strings.ToLower(args[1])+"_test.go"), nil
 	} else {
-		return filepath.Join(outputDirPath, "mock_"+strings.ToLower(args[len(args)-1])+"_test.go")
+		return filepath.Join(outputDirPath, "mock_"+strings.ToLower(args[len(args)-1])+"_test.go"), nil
+	}
+}
+
+func outputNameTemplateDataFor(args []string, packageOut string) OutputNameTemplateData {
+	if util.SourceMode(args) || util.ModelMode(args) {
+		return OutputNameTemplateData{
+			PackageName: packageOut,
+			SourceBase:  strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0])),
+		}
+	}
+	if util.CombineMode(args) {
+		return OutputNameTemplateData{
+			InterfaceName: args[1],
+			PackageName:   packageOut,
+			SourceBase:    args[1],
+		}
+	}
+	return OutputNameTemplateData{
+		InterfaceName: args[len(args)-1],
+		PackageName:   packageOut,
+		SourceBase:    path.Base(args[0]),
 	}
 }
 
-func GenerateMockFile(args []string, outputFilePath string, packageOut string, selfPackage string, debugParser bool, out io.Writer, useExperimentalModelGen bool, shouldGenerateMatchers bool, matchersDestination string) {
-	mockSourceCode, matcherSourceCodes := GenerateMockSourceCode(args, packageOut, selfPackage, debugParser, out, useExperimentalModelGen)
+func executeOutputNameTemplate(outputNameTemplate string, data OutputNameTemplateData) (string, error) {
+	tmpl, err := template.New("output-name").Funcs(template.FuncMap{"lower": strings.ToLower}).Parse(outputNameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("Invalid output name template: %v", err)
+	}
+	var outputFileName bytes.Buffer
+	if err := tmpl.Execute(&outputFileName, data); err != nil {
+		return "", fmt.Errorf("Invalid output name template: %v", err)
+	}
+	if !strings.HasSuffix(outputFileName.String(), ".go") {
+		return "", fmt.Errorf("Output name template must produce a file name ending in .go, but produced \"%v\"", outputFileName.String())
+	}
+	return outputFileName.String(), nil
+}
+
+// GenerateMockFile writes the mocks for all supported interfaces. If some interfaces
+// could not be mocked, it returns a model.UnsupportedConstructErrors describing them.
+// Matchers written to the directory of the mocks become part of options.PackageOut, in files
+// named matcher_<type>.go, or matcher_<type>_test.go if the mocks are in a _test.go file.
+// Unless options.AllowInternalImports, nothing is written if a file would import an internal
+// package that it must not import.
+func GenerateMockFile(args []string, outputFilePath string, options Options) error {
+	files, err := MockFiles(args, outputFilePath, options)
+	if writeErr := WriteFiles(files); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
 
-	err := ioutil.WriteFile(outputFilePath, mockSourceCode, 0664)
+// MockFiles returns the files GenerateMockFile would write, keyed by their paths, without
+// writing anything. The files are nil if no mock could be generated at all.
+func MockFiles(args []string, outputFilePath string, options Options) (map[string][]byte, error) {
+	matchersPath := filepath.Join(filepath.Dir(outputFilePath), "matchers")
+	if options.MatchersDestination != "" {
+		matchersPath = options.MatchersDestination
+	}
+	matchersInMockPackage, err := sameDir(matchersPath, filepath.Dir(outputFilePath))
 	if err != nil {
-		panic(fmt.Errorf("Failed writing to destination: %v", err))
+		return nil, err
+	}
+	options.MatchersPackage = ""
+	matcherFileName := "%v.go"
+	if matchersInMockPackage {
+		options.MatchersPackage, matcherFileName = options.PackageOut, "matcher_%v.go"
+		if strings.HasSuffix(outputFilePath, "_test.go") {
+			matcherFileName = "matcher_%v_test.go"
+		}
 	}
 
-	if shouldGenerateMatchers {
-		matchersPath := filepath.Join(filepath.Dir(outputFilePath), "matchers")
-		if matchersDestination != "" {
-			matchersPath = matchersDestination
+	previousMockFile := ""
+	if options.Incremental {
+		previousMockFile = outputFilePath
+	}
+	mockSourceCode, matcherSourceCodes, emittedFiles, err := generateSourceCode(args, previousMockFile, options)
+	if mockSourceCode == nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{outputFilePath: mockSourceCode}
+	for fileName, content := range emittedFiles {
+		files[filepath.Join(filepath.Dir(outputFilePath), fileName)] = content
+	}
+	if options.GenerateMatchers {
+		for matcherTypeName, matcherSourceCode := range matcherSourceCodes {
+			files[filepath.Join(matchersPath, fmt.Sprintf(matcherFileName, matcherTypeName))] = []byte(matcherSourceCode)
 		}
-		err = os.MkdirAll(matchersPath, 0755)
-		if err != nil {
-			panic(fmt.Errorf("Failed making dirs \"%v\": %v", matchersPath, err))
+	}
+	if !options.AllowInternalImports {
+		filePaths := make([]string, 0, len(files))
+		for filePath := range files {
+			filePaths = append(filePaths, filePath)
 		}
-		for matcherTypeName, matcherSourceCode := range matcherSourceCodes {
-			err := ioutil.WriteFile(filepath.Join(matchersPath, matcherTypeName+".go"), []byte(matcherSourceCode), 0664)
-			if err != nil {
-				panic(fmt.Errorf("Failed writing to destination: %v", err))
+		sort.Strings(filePaths)
+		for _, filePath := range filePaths {
+			if internalErr := CheckInternalImports(filePath, files[filePath]); internalErr != nil {
+				return nil, internalErr
 			}
 		}
 	}
+	return files, err
 }
 
-func GenerateMockSourceCode(args []string, packageOut string, selfPackage string, debugParser bool, out io.Writer, useExperimentalModelGen bool) ([]byte, map[string]string) {
+// WriteFiles writes files keyed by their paths, creating missing directories.
+func WriteFiles(files map[string][]byte) error {
+	for filePath, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return &Error{Kind: WriteError, Err: fmt.Errorf("Failed making dirs \"%v\": %v", filepath.Dir(filePath), err)}
+		}
+		if err := ioutil.WriteFile(filePath, content, 0664); err != nil {
+			return &Error{Kind: WriteError, Err: fmt.Errorf("Failed writing to destination: %v", err)}
+		}
+	}
+	return nil
+}
+
+// StaleFiles returns the sorted paths of files whose content on disk is missing or differs.
+func StaleFiles(files map[string][]byte) ([]string, error) {
+	var staleFilePaths []string
+	for filePath, content := range files {
+		existingContent, err := ioutil.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			staleFilePaths = append(staleFilePaths, filePath)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(existingContent, content) {
+			staleFilePaths = append(staleFilePaths, filePath)
+		}
+	}
+	sort.Strings(staleFilePaths)
+	return staleFilePaths, nil
+}
+
+func sameDir(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return absA == absB, nil
+}
+
+// GenerateMockSourceCode returns an error and no source code if the input cannot be loaded.
+// Interfaces with unsupported constructs are skipped and returned as
+// model.UnsupportedConstructErrors; the source code is nil if no mock could be generated at all.
+// An empty options.MatchersPackage defaults to "matchers". The options that are about files,
+// GenerateMatchers, MatchersDestination, Emitters, Incremental and AllowInternalImports, don't
+// apply.
+// With a non-empty previousMockFile, the input hash is recorded in the generated code, and if
+// previousMockFile already records the same one, its content is returned with no matchers
+// instead of generating the mocks again.
+func GenerateMockSourceCode(args []string, previousMockFile string, options Options) ([]byte, map[string]string, error) {
+	options.Emitters = nil
+	mockSourceCode, matcherSourceCodes, _, err := generateSourceCode(args, previousMockFile, options)
+	return mockSourceCode, matcherSourceCodes, err
+}
+
+// generateSourceCode is GenerateMockSourceCode that additionally runs options.Emitters on the
+// same model, see mockgen.Emit. They run even if the mocks need not be generated again.
+func generateSourceCode(args []string, previousMockFile string, options Options) ([]byte, map[string]string, map[string][]byte, error) {
+	ast, src, err := LoadModel(args, options.UseExperimentalModelGen)
+	unsupported, _ := err.(model.UnsupportedConstructErrors)
+	if err != nil && unsupported == nil {
+		return nil, nil, nil, err
+	}
+
+	if options.DebugParser {
+		out := options.Out
+		if out == nil {
+			out = os.Stdout
+		}
+		ast.Print(out)
+	}
+
+	generatorOptions := options.Options
+	generatorOptions.Source = src
+	generatorOptions.RecordInputHash = previousMockFile != ""
+	emittedFiles, err := mockgen.Emit(ast, generatorOptions, options.Emitters)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if previousMockFile != "" && unsupported == nil {
+		previousMockSourceCode, readErr := ioutil.ReadFile(previousMockFile)
+		if readErr == nil && mockgen.RecordedInputHash(previousMockSourceCode) == mockgen.InputHash(ast, generatorOptions) {
+			return previousMockSourceCode, nil, emittedFiles, nil
+		}
+	}
+
+	mockSourceCode, matcherSourceCodes, err := mockgen.GenerateWithMatchers(ast, generatorOptions)
+	if generatorUnsupported, ok := err.(model.UnsupportedConstructErrors); ok {
+		unsupported = append(unsupported, generatorUnsupported...)
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(unsupported) != 0 {
+		return mockSourceCode, matcherSourceCodes, emittedFiles, unsupported
+	}
+	return mockSourceCode, matcherSourceCodes, emittedFiles, nil
+}
+
+// LoadModel builds the model of the interfaces denoted by args, or of their combination for
+// util.CombineArgs, or reads it from a .json file written by WriteModel, and describes where
+// they come from. Like GenerateMockSourceCode, it returns the model of the supported interfaces along
+// with a model.UnsupportedConstructErrors for the others.
+func LoadModel(args []string, useExperimentalModelGen bool) (*model.Package, string, error) {
 	var err error
 
 	var ast *model.Package
@@ -92,9 +469,15 @@ func GenerateMockSourceCode(args []string, packageOut string, selfPackage string
 	if util.SourceMode(args) {
 		ast, err = gomock.ParseFile(args[0])
 		src = args[0]
+	} else if util.ModelMode(args) {
+		ast, err = readModel(args[0])
+		src = args[0]
+	} else if util.CombineMode(args) {
+		ast, err = combinedModel(args[1], strings.Split(args[2], ","))
+		src = fmt.Sprintf("combination of %v", args[2])
 	} else {
 		if len(args) != 2 {
-			log.Fatal("Expected exactly two arguments, but got " + fmt.Sprint(args))
+			return nil, "", fmt.Errorf("Expected exactly two arguments, but got %v", args)
 		}
 		if useExperimentalModelGen {
 			ast, err = loader.GenerateModel(args[0], args[1])
@@ -104,13 +487,49 @@ func GenerateMockSourceCode(args []string, packageOut string, selfPackage string
 		}
 		src = fmt.Sprintf("%v (interfaces: %v)", args[0], args[1])
 	}
-	if err != nil {
-		panic(fmt.Errorf("Loading input failed: %v", err))
+	if _, unsupported := err.(model.UnsupportedConstructErrors); err != nil && !unsupported {
+		kind := ParseError
+		if _, undeclared := err.(*gomock.UndeclaredTypeError); undeclared {
+			kind = InterfaceNotFoundError
+		}
+		return nil, "", &Error{Kind: kind, Err: fmt.Errorf("Loading input failed: %v", err)}
 	}
+	return ast, src, err
+}
 
-	if debugParser {
-		ast.Print(out)
+func readModel(modelFile string) (*model.Package, error) {
+	content, err := ioutil.ReadFile(modelFile)
+	if err != nil {
+		return nil, err
+	}
+	var pkg model.Package
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid model %v: %v", modelFile, err)
 	}
+	return &pkg, nil
+}
 
-	return mockgen.GenerateOutput(ast, src, packageOut, selfPackage)
+// WriteModel writes the model of the interfaces denoted by args as JSON to outputFilePath, or
+// to out if outputFilePath is "-". Interfaces with unsupported constructs are left out and
+// reported as a model.UnsupportedConstructErrors.
+func WriteModel(args []string, useExperimentalModelGen bool, outputFilePath string, out io.Writer) error {
+	ast, _, err := LoadModel(args, useExperimentalModelGen)
+	if ast == nil {
+		return err
+	}
+	content, marshalErr := json.MarshalIndent(ast, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	content = append(content, '\n')
+	if outputFilePath == "-" {
+		if _, writeErr := out.Write(content); writeErr != nil {
+			return writeErr
+		}
+		return err
+	}
+	if writeErr := WriteFiles(map[string][]byte{outputFilePath: content}); writeErr != nil {
+		return writeErr
+	}
+	return err
 }
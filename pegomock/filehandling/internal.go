@@ -0,0 +1,67 @@
+package filehandling
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/petergtz/pegomock/modelgen/gomock"
+)
+
+// CheckInternalImports returns an error if the Go code content, which is to be written to
+// filePath, imports an internal package that the package in the directory of filePath must not
+// import, because the directory is outside the tree rooted at the parent of the "internal"
+// directory. Such code would not compile. Nothing is checked if the import path of the
+// directory cannot be determined.
+func CheckInternalImports(filePath string, content []byte) error {
+	if !strings.HasSuffix(filePath, ".go") {
+		return nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), filePath, content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	importer := ""
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		root, isInternal := internalRootOf(importPath)
+		if !isInternal {
+			continue
+		}
+		if importer == "" {
+			importer = gomock.ImportPathOfDir(filepath.Dir(filePath))
+			if importer == "" {
+				return nil
+			}
+		}
+		if root == "" {
+			return fmt.Errorf("%v would import the internal package %v of the standard library. "+
+				"Please pass --allow-internal-imports to generate it anyway.", filePath, importPath)
+		}
+		if importer != root && !strings.HasPrefix(importer, root+"/") {
+			return fmt.Errorf("%v would import the internal package %v, but only packages within %v may import it. "+
+				"Please write the mocks to a directory within %v, e.g. with --output, "+
+				"or pass --allow-internal-imports to generate them anyway.", filePath, importPath, root, root)
+		}
+	}
+	return nil
+}
+
+// internalRootOf returns the import path of the parent of the innermost "internal" element of
+// importPath, which is the root of the tree of packages that may import it, and whether there is
+// such an element.
+func internalRootOf(importPath string) (string, bool) {
+	elements := strings.Split(importPath, "/")
+	for i := len(elements) - 1; i >= 0; i-- {
+		if elements[i] == "internal" {
+			return strings.Join(elements[:i], "/"), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,43 @@
+package filehandling
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/petergtz/pegomock/mockgen"
+	"github.com/petergtz/pegomock/model"
+)
+
+// OutdatedReason tells why the code in generatedFile is outdated, or returns "" if it isn't:
+// it was generated by a version of pegomock that didn't record its version, or with another
+// mockgen.GeneratedCodeVersion than this version of pegomock generates, or from interfaces
+// that changed since, according to the recorded mockgen.InterfaceHash. Files without a
+// recorded source, like matchers, are never outdated.
+func OutdatedReason(generatedFile string) (string, error) {
+	content, err := ioutil.ReadFile(generatedFile)
+	if err != nil {
+		return "", err
+	}
+	args := recordedSourceArgs(generatedFile, content)
+	if args == nil {
+		return "", nil
+	}
+
+	version, generatedCodeVersion := mockgen.RecordedGenerator(content)
+	if generatedCodeVersion == 0 {
+		return "generated by a version of pegomock that didn't record its version", nil
+	}
+	if generatedCodeVersion != mockgen.GeneratedCodeVersion {
+		return fmt.Sprintf("generated by pegomock %v with generated code version %v, but pegomock %v generates version %v",
+			version, generatedCodeVersion, mockgen.Version(), mockgen.GeneratedCodeVersion), nil
+	}
+
+	ast, _, err := LoadModel(args, false)
+	if _, unsupported := err.(model.UnsupportedConstructErrors); err != nil && !unsupported {
+		return "", err
+	}
+	if mockgen.InterfaceHash(ast) != mockgen.RecordedInterfaceHash(content) {
+		return "the interfaces changed since it was generated", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,40 @@
+package filehandling
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PackagesUnder returns the Go packages in root and all its sub-directories, skipping vendor
+// and testdata directories and directories whose names start with . or _, like the go tool
+// does for ./... patterns. Main packages are left out, because mocks in other packages
+// cannot refer to their interfaces.
+func PackagesUnder(root string) ([]*build.Package, error) {
+	var pkgs []*build.Package
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root && (info.Name() == "vendor" || info.Name() == "testdata" ||
+			strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_")) {
+			return filepath.SkipDir
+		}
+		pkg, err := build.ImportDir(path, 0)
+		if _, noGo := err.(*build.NoGoError); noGo {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if pkg.Name != "main" {
+			pkgs = append(pkgs, pkg)
+		}
+		return nil
+	})
+	return pkgs, err
+}
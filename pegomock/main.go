@@ -15,13 +15,27 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
-	"time"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/petergtz/pegomock/mockgen"
+	"github.com/petergtz/pegomock/modelgen/gomock"
 	"github.com/petergtz/pegomock/pegomock/filehandling"
 	"github.com/petergtz/pegomock/pegomock/util"
 	"github.com/petergtz/pegomock/pegomock/watch"
@@ -29,8 +43,44 @@ import (
 
 var (
 	app = kingpin.New("pegomock", "Generates mocks based on interfaces.")
+
+	// Exit terminates pegomock with the exit code of a failure; tests replace it.
+	Exit = os.Exit
+)
+
+// Exit codes of pegomock, so scripts can tell failures apart.
+const (
+	ExitCodeFailure           = 1 // a failure without a more specific exit code
+	ExitCodeUsage             = 2 // invalid command line
+	ExitCodeParseFailure      = 3 // the interfaces could not be loaded, see filehandling.ParseError
+	ExitCodeInterfaceNotFound = 4 // there is no interface to mock, see filehandling.InterfaceNotFoundError
+	ExitCodeWriteFailure      = 5 // generated files could not be written, see filehandling.WriteError
+	ExitCodeStale             = 6 // generate --check or outdated found mocks that are out of date
 )
 
+const (
+	usageError filehandling.ErrorKind = "usage"
+	staleError filehandling.ErrorKind = "stale"
+)
+
+var exitCodes = map[filehandling.ErrorKind]int{
+	usageError:                          ExitCodeUsage,
+	filehandling.ParseError:             ExitCodeParseFailure,
+	filehandling.InterfaceNotFoundError: ExitCodeInterfaceNotFound,
+	filehandling.WriteError:             ExitCodeWriteFailure,
+	staleError:                          ExitCodeStale,
+}
+
+// failure is what --error-format json writes before pegomock exits with a non-zero code.
+type failure struct {
+	// Kind is one of the filehandling.ErrorKind values, "usage", "stale" or "failure".
+	Kind     string `json:"kind"`
+	ExitCode int    `json:"exitCode"`
+	Message  string `json:"message"`
+	// Files are the mock files that are out of date or could not be cleaned.
+	Files []string `json:"files,omitempty"`
+}
+
 func main() {
 	Run(os.Args, os.Stderr, app, make(chan bool))
 }
@@ -41,62 +91,669 @@ func Run(cliArgs []string, out io.Writer, app *kingpin.Application, done chan bo
 	app.FatalIfError(err, "")
 
 	var (
-		generateCmd = app.Command("generate", "Generate mocks based on the args provided. ")
-		destination = generateCmd.Flag("output", "Output file; defaults to mock_<interface>_test.go.").Short('o').String()
-		packageOut  = generateCmd.Flag("package", "Package of the generated code; defaults to the package from which pegomock was executed suffixed with _test").Default(filepath.Base(workingDir) + "_test").String()
+		errorFormat = app.Flag("error-format", "Format of the error reported when pegomock fails: \"text\", or \"json\" for a single "+
+			"JSON object with the fields kind, exitCode, message and files, so scripts can react to failures.").Default("text").Enum("text", "json")
+
+		generateCmd        = app.Command("generate", "Generate mocks based on the args provided. ")
+		destination        = generateCmd.Flag("output", "Output file; defaults to mock_<interface>_test.go. With -, the mocks are written to stdout.").Short('o').String()
+		outputNameTemplate = generateCmd.Flag("output-name-template", "Go text/template for the output file name, used when --output is not given. "+
+			"Available fields: .InterfaceName, .PackageName, .SourceBase; available functions: lower. "+
+			"E.g. \"{{.InterfaceName | lower}}_mock_test.go\"").String()
+		packageOut = generateCmd.Flag("package", "Package of the generated code; defaults to the package from which pegomock was executed suffixed with _test, "+
+			"without the suffix if --output-name-template produces a file name that doesn't end in _test.go, "+
+			"or for a _test.go file to the package declared in it").String()
 		// TODO: self_package was taken as is from GoMock.
 		//       Still don't understand what it's really there for.
 		//       So for now it's not tested.
 		selfPackage            = generateCmd.Flag("self_package", "If set, the package this mock will be part of.").String()
 		debugParser            = generateCmd.Flag("debug", "Print debug information.").Short('d').Bool()
+		shouldGenerateBuilders = generateCmd.Flag("generate-builders", "Generate a builder for every mock, e.g. MockDisplayBuilder, "+
+			"with chainable methods to stub all invocations of a method.").Bool()
+		shouldRecordCommand = generateCmd.Flag("record-command", "Record the pegomock command in the header of the generated code, "+
+			"so users of the mocks can regenerate them.").Bool()
+		mockNameTemplate = generateCmd.Flag("name-template", "Go text/template for the names of the mock types, e.g. \"{{.Interface}}Fake\"; "+
+			"defaults to \"Mock{{.Interface}}\".").String()
+		verifierNameTemplate = generateCmd.Flag("verifier-name-template", "Go text/template for the names of the verifier types; "+
+			"defaults to \"Verifier{{.Interface}}\".").String()
+		ongoingVerificationNameTemplate = generateCmd.Flag("ongoing-verification-name-template", "Go text/template for the names of the types "+
+			"returned by verifier methods, with the additional field .Method; defaults to \"{{.Interface}}_{{.Method}}_OngoingVerification\".").String()
+		buildTags = generateCmd.Flag("build-tags", "Build constraint expression, e.g. \"integration && !windows\", written at the top of "+
+			"the generated code as //go:build and // +build lines, so the mocks are only part of builds satisfying it.").String()
+		style = generateCmd.Flag("style", "Style of the generated mocks: \"pegomock\"; \"testify\" for mocks embedding testify's mock.Mock, "+
+			"which are stubbed with On and verified with AssertExpectations; \"gomock\" for pegomock mocks that are created with a "+
+			"pegomock.Controller and additionally have gomock's EXPECT() API; or \"fake\" for counterfeiter-like fakes.").
+			Default(mockgen.PegomockStyle).Enum(mockgen.PegomockStyle, mockgen.TestifyStyle, mockgen.GomockStyle, mockgen.FakeStyle)
+		dependencyInjection = generateCmd.Flag("dependency-injection", "Additionally generate glue for a dependency injection framework "+
+			"that provides each mock as itself and as its interface: \"wire\" for a Provide<Mock> function and a <Mock>ProviderSet, "+
+			"or \"fx\" for a <Mock>Module. Only supported with --style pegomock.").Enum(mockgen.WireInjection, mockgen.FxInjection)
+		templateFile = generateCmd.Flag("template", "Go text/template file that generates the output from the interfaces instead of "+
+			"the built-in styles, e.g. for mocks of another style, stubs or documentation. See mockgen.TemplateData for what it is "+
+			"executed with. Output that is Go code is formatted. Cannot be used with --style, --generate-builders or --dependency-injection.").String()
+		emitters = generateCmd.Flag("emitter", "Additionally run the emitter of this name on the interfaces, which generates further "+
+			"files next to the mocks, e.g. tracing decorators. Emitters are registered with mockgen.RegisterEmitter or are executables "+
+			"named "+mockgen.ExecutableEmitterPrefix+"<name> in PATH. Can be repeated.").Strings()
+		headerFile = generateCmd.Flag("header-file", "File whose content replaces the \"Code generated by pegomock\" comment at the top of "+
+			"the generated code, e.g. a license banner. Lines that are not comments yet are turned into // comments.").String()
 		shouldGenerateMatchers = generateCmd.Flag("generate-matchers", "Generate matchers for all non built-in types in a \"matchers\" "+
 			"directory in the same directory where the mock file gets generated.").Short('m').Default("false").Bool()
 		matchersDestination = generateCmd.Flag("matchers-dir", "Generate matchers in the specified directory; defaults to "+
-			filepath.Join("<mockdir>", "matchers")).Short('p').String()
+			filepath.Join("<mockdir>", "matchers")+". If it is <mockdir>, the matchers become part of the package of the mocks.").Short('p').String()
 		useExperimentalModelGen = generateCmd.Flag("use-experimental-model-gen", "pegomock includes a new experimental source parser based on "+
 			"golang.org/x/tools/go/loader. It's currently experimental, but should be more powerful "+
 			"than the current reflect-based modelgen. E.g. reflect cannot detect method parameter names,"+
 			" and has to generate them based on a pattern. In a code editor with code assistence, this doesn't provide good help. "+
 			"\n\nThis option only works when specifying package path + interface, not with .go source files. Also, you can only specify *one* interface. This option cannot be used with the watch command.").Bool()
-		generateCmdArgs = generateCmd.Arg("args", "A (optional) Go package path + space-separated interface or a .go file").Required().Strings()
+		interfacePattern = generateCmd.Flag("interface-pattern", "With --all-interfaces, ./... or grpc, only generate mocks for interfaces "+
+			"whose names match this regular expression.").String()
+		allInterfaces = generateCmd.Flag("all-interfaces", "Generate mocks for all exported interfaces of the package given as the only arg, e.g. ./mypkg. "+
+			"The mocks are written to one file, which defaults to mock_<package>_test.go.").Bool()
+		merge = generateCmd.Flag("merge", "Generate the mocks for a comma-separated list of interfaces, e.g. \"./mypkg Display,Store\", "+
+			"into one file with a shared import block, which defaults to mock_<package>_test.go. Without it, each interface gets a file "+
+			"of its own, unless --output is given.").Bool()
+		combine = generateCmd.Flag("combine", "Generate one mock implementing all interfaces in this comma-separated list, "+
+			"e.g. \"io.Reader,io.Closer\", named after --name, for code that type-asserts for optional capabilities. "+
+			"Interfaces are qualified by their package paths, e.g. \"./storage.Store\", or are taken from the current directory's package.").String()
+		combinedName = generateCmd.Flag("name", "With --combine, the name of the mock, e.g. \"ReadCloserMock\". "+
+			"The output file defaults to mock_<name>_test.go.").String()
+		methods = generateCmd.Flag("methods", "Only mock the methods in this comma-separated list, e.g. \"Get,Put\", for gigantic interfaces "+
+			"of which tests only exercise a few methods. The mocks embed their interfaces, so calling other methods panics.").String()
+		check = generateCmd.Flag("check", "Don't write anything, but exit with a non-zero code and list the mock files "+
+			"that are missing or differ from what would be generated, e.g. to verify in CI that all mocks are up to date.").Bool()
+		emitModel = generateCmd.Flag("emit-model", "Instead of generating mocks, write the model of the interfaces as parsed by pegomock "+
+			"as JSON to this file, e.g. for doc generators or contract checkers. With -, the model is written to stdout.").String()
+		fromModel = generateCmd.Flag("from-model", "Generate the mocks from a model of interfaces in a JSON file as written by --emit-model, "+
+			"instead of from Go code, e.g. to cache parsing separately or to feed interfaces from other tools into pegomock. "+
+			"The output file defaults to mock_<model file name>_test.go. Giving the .json file as the only arg does the same.").String()
+		incremental = generateCmd.Flag("incremental", "Record a hash of the interfaces, the options and pegomock itself in the "+
+			"generated mocks, and don't generate mocks again whose mock file already records the same hash.").Bool()
+		allowInternalImports = generateCmd.Flag("allow-internal-imports", "Generate mocks even if they import internal packages "+
+			"that the package of the generated code must not import, e.g. because --output is outside of the internal package's tree.").Bool()
+		jobs = generateCmd.Flag("jobs", "Number of mock files loaded and generated concurrently with ./... or a project configuration; "+
+			"defaults to the number of CPUs. With --debug, mock files are generated one at a time.").Short('j').Default(strconv.Itoa(runtime.NumCPU())).Int()
+		projectConfig = generateCmd.Flag("config", "Project configuration file declaring the mocks to generate when no args are given; "+
+			"defaults to "+filehandling.ProjectConfigFileName+" in the current directory or its closest parent directory that has one.").String()
+		generateCmdArgs = generateCmd.Arg("args", "A (optional) Go package path + space-separated interface or a .go file, "+
+			"or a pattern like ./... to generate mocks for all exported interfaces of all packages below a directory, next to each package, "+
+			"or grpc and a package, e.g. \"grpc ./gen/foo\", to generate mocks for the gRPC client, server and stream interfaces of the package. "+
+			"Without args, all mocks declared in the project configuration file are generated").Strings()
 
 		watchCmd       = app.Command("watch", "Watch ")
 		watchRecursive = watchCmd.Flag("recursive", "Recursively watch sub-directories as well.").Short('r').Bool()
-		watchPackages  = watchCmd.Arg("directories...", "One or more directories of Go packages to watch").Strings()
+		watchDebounce  = watchCmd.Flag("debounce", "How long to wait after a change for further changes before regenerating the mocks, "+
+			"so a burst of saves triggers a single regeneration.").Default("300ms").Duration()
+		watchPackages = watchCmd.Arg("directories...", "One or more directories of Go packages to watch").Strings()
+
+		generateAllCmd = app.Command("generate-all", "Generate all mocks listed in the interfaces_to_mock files once. "+
+			"Continues past failures, prints a summary and exits with a non-zero code if any mock failed.")
+		generateAllRecursive = generateAllCmd.Flag("recursive", "Recursively process sub-directories as well.").Short('r').Bool()
+		generateAllReport    = generateAllCmd.Flag("report", "Additionally write the summary as JSON to this file.").String()
+		generateAllPackages  = generateAllCmd.Arg("directories...", "One or more directories of Go packages to generate mocks for").Strings()
+
+		listCmd = app.Command("list", "List the exported interfaces of packages with the files declaring them, their numbers of methods, "+
+			"and their mock files, marked as stale if they differ from what \"pegomock generate\" would generate for them.")
+		listPackages = listCmd.Arg("packages", "Packages, or patterns like ./... for all packages below a directory; defaults to ./...").Strings()
+
+		cleanCmd = app.Command("clean", "Delete the files generated by pegomock, recognized by their \"Code generated by pegomock\" comment, "+
+			"in directories and all their sub-directories.")
+		cleanOrphaned    = cleanCmd.Flag("orphaned", "Only delete mocks whose interfaces, source files or packages no longer exist.").Bool()
+		cleanDryRun      = cleanCmd.Flag("dry-run", "Only list the files that would be deleted.").Bool()
+		cleanDirectories = cleanCmd.Arg("directories...", "Directories to clean; defaults to the current directory").Strings()
+
+		outdatedCmd = app.Command("outdated", "List the mocks in directories and all their sub-directories that were generated by an "+
+			"incompatible version of pegomock or from interfaces that changed since, according to their headers, "+
+			"and exit with a non-zero code if there are any.")
+		outdatedDirectories = outdatedCmd.Arg("directories...", "Directories to check; defaults to the current directory").Strings()
 	)
 
 	app.Writer(out)
-	switch kingpin.MustParse(app.Parse(cliArgs[1:])) {
+
+	// fail reports a failure of kind in the --error-format and exits with the exit code of kind.
+	fail := func(kind filehandling.ErrorKind, files []string, format string, args ...interface{}) {
+		exitCode, known := exitCodes[kind]
+		if !known {
+			kind, exitCode = "failure", ExitCodeFailure
+		}
+		if *errorFormat == "json" {
+			encoded, err := json.Marshal(failure{Kind: string(kind), ExitCode: exitCode, Message: fmt.Sprintf(format, args...), Files: files})
+			if err != nil {
+				panic(err)
+			}
+			fmt.Fprintln(out, string(encoded))
+		} else {
+			app.Errorf(format, args...)
+			if kind == usageError {
+				app.Usage(nil)
+			}
+		}
+		Exit(exitCode)
+	}
+	failIfError := func(err error) {
+		if err != nil {
+			fail(filehandling.KindOf(err), nil, "%v", err)
+		}
+	}
+	failUsage := func(format string, args ...interface{}) {
+		fail(usageError, nil, format, args...)
+	}
+
+	command, err := app.Parse(cliArgs[1:])
+	if err != nil {
+		failUsage("%v, try --help", err)
+	}
+	switch command {
 
 	case generateCmd.FullCommand():
-		if err := util.ValidateArgs(*generateCmdArgs); err != nil {
-			app.FatalUsage(err.Error())
+		if len(*generateCmdArgs) != 0 {
+			if err := util.ValidateArgs(*generateCmdArgs); err != nil {
+				failUsage("%v", err)
+			}
 		}
-		sourceArgs, err := util.SourceArgs(*generateCmdArgs)
+		interfaceFilter, err := regexp.Compile(*interfacePattern)
 		if err != nil {
-			app.FatalUsage(err.Error())
+			failUsage("Invalid --interface-pattern: %v", err)
 		}
 
-		filehandling.GenerateMockFileInOutputDir(
-			sourceArgs,
-			workingDir,
-			*destination,
-			*packageOut,
-			*selfPackage,
-			*debugParser,
-			out,
-			*useExperimentalModelGen,
-			*shouldGenerateMatchers,
-			*matchersDestination)
+		command := ""
+		if *shouldRecordCommand {
+			command = commandLine(append([]string{"pegomock"}, cliArgs[1:]...))
+		}
 
-	case watchCmd.FullCommand():
-		var targetPaths []string
-		if len(*watchPackages) == 0 {
-			targetPaths = []string{workingDir}
-		} else {
-			targetPaths = *watchPackages
+		projectConfigMode := len(*generateCmdArgs) == 0 && *fromModel == "" && *combine == ""
+		if *destination == "-" && (*check || *shouldGenerateMatchers || len(*emitters) != 0 || projectConfigMode) {
+			failUsage("--output - cannot be used with --check, --generate-matchers, --emitter or a project configuration")
 		}
+		if *emitModel != "" && (*check || projectConfigMode || util.RecursiveMode(*generateCmdArgs)) {
+			failUsage("--emit-model cannot be used with --check, ./... or a project configuration")
+		}
+
+		header, err := filehandling.ReadHeaderFile(*headerFile)
+		failIfError(err)
+		mockTemplate, err := filehandling.ReadTemplateFile(*templateFile)
+		failIfError(err)
+		var methodNames []string
+		if *methods != "" {
+			methodNames = strings.Split(*methods, ",")
+		}
+		nameTemplates := mockgen.NameTemplates{
+			Mock:                *mockNameTemplate,
+			Verifier:            *verifierNameTemplate,
+			OngoingVerification: *ongoingVerificationNameTemplate,
+		}
+		if *combine != "" && nameTemplates.Mock == "" {
+			// The combination has no name of its own, so --name is the name of the mock.
+			nameTemplates.Mock = "{{.Interface}}"
+		}
+
+		var (
+			staleFiles      []string
+			staleFilesMutex sync.Mutex
+		)
+		writeOrCheck := func(files map[string][]byte, err error) error {
+			if *destination == "-" {
+				for _, content := range files {
+					if _, writeErr := os.Stdout.Write(content); writeErr != nil {
+						return &filehandling.Error{Kind: filehandling.WriteError, Err: writeErr}
+					}
+				}
+				return err
+			}
+			if !*check {
+				if writeErr := filehandling.WriteFiles(files); writeErr != nil {
+					return writeErr
+				}
+				return err
+			}
+			stale, checkErr := filehandling.StaleFiles(files)
+			if checkErr != nil {
+				return checkErr
+			}
+			staleFilesMutex.Lock()
+			defer staleFilesMutex.Unlock()
+			staleFiles = append(staleFiles, stale...)
+			return err
+		}
+
+		generate := func(sourceArgs []string, outputDir string, outputNameTemplate string, packageOut string) error {
+			if *emitModel != "" {
+				return filehandling.WriteModel(sourceArgs, *useExperimentalModelGen, *emitModel, os.Stdout)
+			}
+			return writeOrCheck(filehandling.MockFilesInOutputDir(sourceArgs, outputDir, *destination, outputNameTemplate, filehandling.Options{
+				Options: mockgen.Options{
+					PackageOut:          packageOut,
+					SelfPackage:         *selfPackage,
+					GenerateBuilders:    *shouldGenerateBuilders,
+					Command:             command,
+					Header:              header,
+					NameTemplates:       nameTemplates,
+					BuildTags:           *buildTags,
+					Style:               *style,
+					DependencyInjection: *dependencyInjection,
+					Template:            mockTemplate,
+					Methods:             methodNames,
+				},
+				DebugParser:             *debugParser,
+				Out:                     out,
+				UseExperimentalModelGen: *useExperimentalModelGen,
+				GenerateMatchers:        *shouldGenerateMatchers,
+				MatchersDestination:     *matchersDestination,
+				Emitters:                *emitters,
+				Incremental:             *incremental,
+				AllowInternalImports:    *allowInternalImports,
+			}))
+		}
+
+		workers := *jobs
+		if *debugParser {
+			workers = 1
+		}
+
+		outputNameTemplate := *outputNameTemplate
+		if (*allInterfaces || *merge || util.RecursiveMode(*generateCmdArgs) || util.GRPCMode(*generateCmdArgs)) && *destination == "" && outputNameTemplate == "" {
+			outputNameTemplate = "mock_{{.SourceBase | lower}}_test.go"
+		}
+
+		switch {
+		case *combine != "" || *combinedName != "":
+			if *combine == "" || *combinedName == "" || len(*generateCmdArgs) != 0 || *fromModel != "" || *allInterfaces || *useExperimentalModelGen {
+				failUsage("--combine requires --name and cannot be used with args, --from-model, --all-interfaces or --use-experimental-model-gen")
+			}
+			if !token.IsIdentifier(*combinedName) {
+				failUsage("--name must be a Go identifier, but is %q", *combinedName)
+			}
+			failIfError(generate(util.CombineArgs(*combinedName, *combine), workingDir, outputNameTemplate, *packageOut))
+
+		case *fromModel != "":
+			if len(*generateCmdArgs) != 0 || *allInterfaces || *useExperimentalModelGen {
+				failUsage("--from-model cannot be used with args, --all-interfaces or --use-experimental-model-gen")
+			}
+			failIfError(generate([]string{*fromModel}, workingDir, outputNameTemplate, *packageOut))
+
+		case projectConfigMode:
+			configPath := *projectConfig
+			if configPath == "" {
+				configPath = filehandling.FindProjectConfig(workingDir)
+			}
+			if configPath == "" {
+				failUsage("You must specify either exactly one source filename ending with .go, or at least one go interface name, "+
+					"or declare the mocks in a %v file.", filehandling.ProjectConfigFileName)
+			}
+			config, err := filehandling.LoadProjectConfig(configPath)
+			failIfError(err)
+			generations := make([]func() error, len(config.Mocks))
+			for i, mock := range config.Mocks {
+				mock := mock
+				generations[i] = func() error {
+					sourceArgs, err := config.SourceArgs(mock)
+					if err != nil {
+						return errorFor(strings.Join(mock.Args, " "), err)
+					}
+					options := config.OptionsFor(mock)
+					header, err := filehandling.ReadHeaderFile(options.HeaderFile)
+					if err != nil {
+						return errorFor(strings.Join(mock.Args, " "), err)
+					}
+					mockTemplate, err := filehandling.ReadTemplateFile(options.Template)
+					if err != nil {
+						return errorFor(strings.Join(mock.Args, " "), err)
+					}
+					return errorFor(strings.Join(mock.Args, " "), writeOrCheck(filehandling.MockFilesInOutputDir(
+						sourceArgs, options.OutputDir, config.OutputFilePath(mock), options.OutputNameTemplate, filehandling.Options{
+							Options: mockgen.Options{
+								PackageOut:          options.Package,
+								SelfPackage:         *selfPackage,
+								GenerateBuilders:    isSet(options.GenerateBuilders),
+								Command:             command,
+								Header:              header,
+								NameTemplates:       options.NameTemplates(),
+								BuildTags:           options.BuildTags,
+								Style:               options.Style,
+								DependencyInjection: options.DependencyInjection,
+								Template:            mockTemplate,
+								Methods:             options.Methods,
+							},
+							DebugParser:          *debugParser,
+							Out:                  out,
+							GenerateMatchers:     isSet(options.GenerateMatchers),
+							MatchersDestination:  options.MatchersDir,
+							Emitters:             options.Emitters,
+							Incremental:          isSet(options.Incremental),
+							AllowInternalImports: isSet(options.AllowInternalImports),
+						})))
+				}
+			}
+			failIfError(filehandling.GenerateConcurrently(generations, workers))
+
+		case util.RecursiveMode(*generateCmdArgs):
+			if *destination != "" || *useExperimentalModelGen {
+				failUsage("%v cannot be used with --output or --use-experimental-model-gen", (*generateCmdArgs)[0])
+			}
+			root := strings.TrimSuffix((*generateCmdArgs)[0], "...")
+			if root == "" {
+				root = "."
+			}
+			pkgs, err := filehandling.PackagesUnder(root)
+			failIfError(err)
+			generations := make([]func() error, len(pkgs))
+			for i, pkg := range pkgs {
+				pkg := pkg
+				generations[i] = func() error {
+					sourceArgs, err := exportedInterfacesArgs(localPattern(pkg.Dir), interfaceFilter)
+					if err != nil || sourceArgs == nil {
+						return err
+					}
+					packageOut := *packageOut
+					if packageOut == "" {
+						packageOut, err = filehandling.DefaultPackageOut(sourceArgs, pkg.Dir, *destination, outputNameTemplate, pkg.Name)
+						if err != nil {
+							return errorFor(relativeTo(workingDir, pkg.Dir), err)
+						}
+					}
+					return errorFor(relativeTo(workingDir, pkg.Dir), generate(sourceArgs, pkg.Dir, outputNameTemplate, packageOut))
+				}
+			}
+			failIfError(filehandling.GenerateConcurrently(generations, workers))
+
+		case util.GRPCMode(*generateCmdArgs):
+			if len(*generateCmdArgs) != 2 || *allInterfaces || *useExperimentalModelGen {
+				failUsage("grpc requires exactly one package and cannot be used with --all-interfaces or --use-experimental-model-gen")
+			}
+			sourceArgs, err := grpcInterfacesArgs((*generateCmdArgs)[1], interfaceFilter)
+			failIfError(err)
+			if sourceArgs == nil {
+				fail(filehandling.InterfaceNotFoundError, nil, "Package %v declares no gRPC interfaces matching %q", (*generateCmdArgs)[1], *interfacePattern)
+			}
+			failIfError(generate(sourceArgs, workingDir, outputNameTemplate, *packageOut))
+
+		case *allInterfaces:
+			if len(*generateCmdArgs) != 1 || util.SourceMode(*generateCmdArgs) || *useExperimentalModelGen {
+				failUsage("--all-interfaces requires exactly one package and cannot be used with --use-experimental-model-gen")
+			}
+			sourceArgs, err := exportedInterfacesArgs((*generateCmdArgs)[0], interfaceFilter)
+			failIfError(err)
+			if sourceArgs == nil {
+				fail(filehandling.InterfaceNotFoundError, nil, "Package %v declares no exported interfaces matching %q", (*generateCmdArgs)[0], *interfacePattern)
+			}
+			failIfError(generate(sourceArgs, workingDir, outputNameTemplate, *packageOut))
+
+		default:
+			sourceArgs, err := util.SourceArgs(*generateCmdArgs)
+			if err != nil {
+				failUsage("%v", err)
+			}
+			if *merge && (util.SourceMode(sourceArgs) || util.ModelMode(sourceArgs)) {
+				failUsage("--merge requires a package and a comma-separated list of interfaces")
+			}
+			var interfaceNames []string
+			if len(sourceArgs) == 2 {
+				interfaceNames = strings.Split(sourceArgs[1], ",")
+			}
+			if *merge || len(interfaceNames) < 2 || *destination != "" || *emitModel != "" {
+				failIfError(generate(sourceArgs, workingDir, outputNameTemplate, *packageOut))
+			} else {
+				generations := make([]func() error, len(interfaceNames))
+				for i, interfaceName := range interfaceNames {
+					interfaceName := interfaceName
+					generations[i] = func() error {
+						return errorFor(interfaceName, generate([]string{sourceArgs[0], interfaceName}, workingDir, outputNameTemplate, *packageOut))
+					}
+				}
+				failIfError(filehandling.GenerateConcurrently(generations, workers))
+			}
+		}
+
+		if len(staleFiles) != 0 {
+			sort.Strings(staleFiles)
+			for i, staleFile := range staleFiles {
+				staleFiles[i] = relativeTo(workingDir, staleFile)
+			}
+			if *errorFormat == "text" {
+				fmt.Fprintln(out, "Mock files that are out of date:")
+				for _, staleFile := range staleFiles {
+					fmt.Fprintln(out, "  "+staleFile)
+				}
+			}
+			fail(staleError, staleFiles, "Mocks are out of date. Please regenerate them by running the same command without --check.")
+		}
+
+	case watchCmd.FullCommand():
+		targetPaths := targetPathsOrWorkingDir(*watchPackages, workingDir)
 		watch.CreateWellKnownInterfaceListFilesIfNecessary(targetPaths)
-		util.Ticker(watch.NewMockFileUpdater(targetPaths, *watchRecursive).Update, 2*time.Second, done)
+		failIfError(watch.NewMockFileUpdater(targetPaths, *watchRecursive).Watch(*watchDebounce, done))
+
+	case generateAllCmd.FullCommand():
+		report := watch.NewMockFileUpdater(targetPathsOrWorkingDir(*generateAllPackages, workingDir), *generateAllRecursive).UpdateWithReport()
+		report.WriteSummary(out)
+		if *generateAllReport != "" {
+			failIfError(report.WriteJSONFile(*generateAllReport))
+		}
+		if failed := report.Count(watch.StatusFailed); failed != 0 {
+			fail("", nil, "%v of %v mocks failed to generate", failed, len(report))
+		}
+
+	case listCmd.FullCommand():
+		patterns := *listPackages
+		if len(patterns) == 0 {
+			patterns = []string{"./..."}
+		}
+		var packagePatterns []string
+		for _, pattern := range patterns {
+			if !util.RecursiveMode([]string{pattern}) {
+				packagePatterns = append(packagePatterns, pattern)
+				continue
+			}
+			root := strings.TrimSuffix(pattern, "...")
+			if root == "" {
+				root = "."
+			}
+			pkgs, err := filehandling.PackagesUnder(root)
+			failIfError(err)
+			for _, pkg := range pkgs {
+				packagePatterns = append(packagePatterns, localPattern(pkg.Dir))
+			}
+		}
+		table := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "INTERFACE\tFILE\tMETHODS\tMOCK")
+		for _, pattern := range packagePatterns {
+			importPath, declarations, err := gomock.ExportedInterfaceDeclarations(pattern)
+			failIfError(err)
+			for _, declaration := range declarations {
+				fmt.Fprintf(table, "%v.%v\t%v\t%v\t%v\n", importPath, declaration.Name, relativeTo(workingDir, declaration.File),
+					declaration.NumMethods, mockStatusOf(importPath, declaration, declarations, workingDir))
+			}
+		}
+		failIfError(table.Flush())
+
+	case cleanCmd.FullCommand():
+		var generatedFiles []string
+		for _, dir := range targetPathsOrWorkingDir(*cleanDirectories, workingDir) {
+			filesInDir, err := filehandling.GeneratedFilesUnder(dir)
+			failIfError(err)
+			generatedFiles = append(generatedFiles, filesInDir...)
+		}
+		var failed []string
+		for _, generatedFile := range generatedFiles {
+			if *cleanOrphaned {
+				orphaned, err := filehandling.IsOrphaned(generatedFile)
+				if err != nil {
+					fmt.Fprintf(out, "Kept %v: %v\n", relativeTo(workingDir, generatedFile), err)
+					failed = append(failed, generatedFile)
+					continue
+				}
+				if !orphaned {
+					continue
+				}
+			}
+			if *cleanDryRun {
+				fmt.Fprintf(out, "Would delete %v\n", relativeTo(workingDir, generatedFile))
+				continue
+			}
+			if err := os.Remove(generatedFile); err != nil {
+				fmt.Fprintf(out, "Kept %v: %v\n", relativeTo(workingDir, generatedFile), err)
+				failed = append(failed, generatedFile)
+				continue
+			}
+			fmt.Fprintf(out, "Deleted %v\n", relativeTo(workingDir, generatedFile))
+		}
+		if len(failed) != 0 {
+			for i, failedFile := range failed {
+				failed[i] = relativeTo(workingDir, failedFile)
+			}
+			fail("", failed, "%v of %v generated files could not be cleaned", len(failed), len(generatedFiles))
+		}
+
+	case outdatedCmd.FullCommand():
+		var generatedFiles []string
+		for _, dir := range targetPathsOrWorkingDir(*outdatedDirectories, workingDir) {
+			filesInDir, err := filehandling.GeneratedFilesUnder(dir)
+			failIfError(err)
+			generatedFiles = append(generatedFiles, filesInDir...)
+		}
+		var outdatedFiles []string
+		for _, generatedFile := range generatedFiles {
+			reason, err := filehandling.OutdatedReason(generatedFile)
+			if err != nil {
+				reason = "could not be checked: " + err.Error()
+			}
+			if reason != "" {
+				if *errorFormat == "text" {
+					fmt.Fprintf(out, "%v: %v\n", relativeTo(workingDir, generatedFile), reason)
+				}
+				outdatedFiles = append(outdatedFiles, relativeTo(workingDir, generatedFile))
+			}
+		}
+		if len(outdatedFiles) != 0 {
+			fail(staleError, outdatedFiles, "%v mock files are outdated. Please regenerate them.", len(outdatedFiles))
+		}
+	}
+}
+
+// mockStatusOf describes the mock file of the interface declared by declaration in the package
+// importPath: where "pegomock generate" writes it by default for the interface alone or for all
+// interfaces of the package with ./..., whether it is stale, or "-" if there is none.
+func mockStatusOf(importPath string, declaration gomock.InterfaceDeclaration, declarations []gomock.InterfaceDeclaration, workingDir string) string {
+	var names []string
+	for _, otherDeclaration := range declarations {
+		names = append(names, otherDeclaration.Name)
+	}
+	dir := filepath.Dir(declaration.File)
+	candidates := []struct {
+		args               []string
+		outputNameTemplate string
+	}{
+		{[]string{importPath, declaration.Name}, ""},
+		{[]string{importPath, strings.Join(names, ",")}, "mock_{{.SourceBase | lower}}_test.go"},
+	}
+	mockType := regexp.MustCompile(`(?m)^type Mock` + declaration.Name + `\b`)
+	for _, candidate := range candidates {
+		mockFilePath, err := filehandling.OutputFilePath(candidate.args, dir, "", candidate.outputNameTemplate, "")
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		content, err := ioutil.ReadFile(mockFilePath)
+		if err != nil || !mockType.Match(content) {
+			continue
+		}
+		// The mocks may have been generated with --package, so they are compared with mocks in the same package.
+		file, err := parser.ParseFile(token.NewFileSet(), mockFilePath, content, parser.PackageClauseOnly)
+		if err != nil {
+			return relativeTo(workingDir, mockFilePath) + " (error: " + err.Error() + ")"
+		}
+		packageOut := file.Name.Name
+		files, err := filehandling.MockFiles(candidate.args, mockFilePath, filehandling.Options{
+			Options:              mockgen.Options{PackageOut: packageOut, Style: mockgen.PegomockStyle},
+			AllowInternalImports: true,
+		})
+		if files == nil {
+			return relativeTo(workingDir, mockFilePath) + " (error: " + err.Error() + ")"
+		}
+		if !bytes.Equal(files[mockFilePath], content) {
+			return relativeTo(workingDir, mockFilePath) + " (stale)"
+		}
+		return relativeTo(workingDir, mockFilePath)
+	}
+	return "-"
+}
+
+// exportedInterfacesArgs returns the args to generate mocks for all exported interfaces of the
+// package denoted by pattern whose names match filter, or nil if there are none.
+func exportedInterfacesArgs(pattern string, filter *regexp.Regexp) ([]string, error) {
+	importPath, interfaceNames, err := gomock.ExportedInterfaces(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return filteredInterfacesArgs(importPath, interfaceNames, filter), nil
+}
+
+// filteredInterfacesArgs returns the args to generate mocks for the interfaces of the package
+// importPath whose names match filter, or nil if there are none.
+func filteredInterfacesArgs(importPath string, interfaceNames []string, filter *regexp.Regexp) []string {
+	var matchingNames []string
+	for _, name := range interfaceNames {
+		if filter.MatchString(name) {
+			matchingNames = append(matchingNames, name)
+		}
+	}
+	if len(matchingNames) == 0 {
+		return nil
+	}
+	return []string{importPath, strings.Join(matchingNames, ",")}
+}
+
+// grpcInterfacesArgs returns the args to generate mocks for all gRPC client, server and stream
+// interfaces of the package denoted by pattern whose names match filter, or nil if there are none.
+func grpcInterfacesArgs(pattern string, filter *regexp.Regexp) ([]string, error) {
+	importPath, interfaceNames, err := gomock.GRPCInterfaces(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return filteredInterfacesArgs(importPath, interfaceNames, filter), nil
+}
+
+// localPattern turns a relative directory into a package pattern, e.g. "sub" into "./sub".
+func localPattern(dir string) string {
+	if filepath.IsAbs(dir) || filepath.Clean(dir) == "." {
+		return filepath.ToSlash(filepath.Clean(dir))
+	}
+	return "./" + filepath.ToSlash(filepath.Clean(dir))
+}
+
+// relativeTo returns filePath relative to dir if it is within dir, and filePath otherwise.
+func relativeTo(dir string, filePath string) string {
+	relativePath, err := filepath.Rel(dir, filePath)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return filePath
+	}
+	return relativePath
+}
+
+// errorFor prefixes err with the source of the mocks whose generation failed, so errors of
+// concurrent generations can be told apart.
+func errorFor(source string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%v: %w", source, err)
+}
+
+func isSet(flag *bool) bool {
+	return flag != nil && *flag
+}
+
+func targetPathsOrWorkingDir(targetPaths []string, workingDir string) []string {
+	if len(targetPaths) == 0 {
+		return []string{workingDir}
+	}
+	return targetPaths
+}
+
+// commandLine joins args, quoting those that a shell would otherwise split or expand.
+func commandLine(args []string) string {
+	quotedArgs := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`*?[]{}()<>|&;#~!") {
+			arg = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+		}
+		quotedArgs[i] = arg
 	}
+	return strings.Join(quotedArgs, " ")
 }
@@ -16,15 +16,21 @@ package main_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"go/build"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/petergtz/pegomock/mockgen"
+	"github.com/petergtz/pegomock/model"
 	main "github.com/petergtz/pegomock/pegomock"
 	. "github.com/petergtz/pegomock/pegomock/testutil"
 
@@ -74,6 +80,7 @@ var _ = Describe("CLI", func() {
 
 		app = kingpin.New("pegomock", "Generates mocks based on interfaces.")
 		app.Terminate(func(int) { panic("Unexpected terminate") })
+		main.Exit = func(exitCode int) { panic(exitCode) }
 	})
 
 	AfterEach(func() {
@@ -124,6 +131,186 @@ var _ = Describe("CLI", func() {
 			})
 		})
 
+		Context(`with args "pegomocktest/subpackage SubDisplay,SubStore"`, func() {
+			BeforeEach(func() {
+				WriteFile(joinPath(subPackageDir, "substore.go"), "package subpackage; type SubStore interface { Get(key string) string }")
+			})
+
+			It(`generates a mock file for each interface`, func() {
+				main.Run(cmd("pegomock generate pegomocktest/subpackage SubDisplay,SubStore"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_subdisplay_test.go")).To(SatisfyAll(
+					BeAFileContainingSubString("type MockSubDisplay struct"),
+					Not(BeAFileContainingSubString("MockSubStore"))))
+				Expect(joinPath(packageDir, "mock_substore_test.go")).To(BeAFileContainingSubString("type MockSubStore struct"))
+			})
+
+			It(`generates the mocks into mock_subpackage_test.go with --merge`, func() {
+				main.Run(cmd("pegomock generate --merge pegomocktest/subpackage SubDisplay,SubStore"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_subpackage_test.go")).To(SatisfyAll(
+					BeAFileContainingSubString("type MockSubDisplay struct"),
+					BeAFileContainingSubString("type MockSubStore struct")))
+				Expect(joinPath(packageDir, "mock_subdisplay_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`names the merged file after --output-name-template`, func() {
+				main.Run(cmd("pegomock generate --merge --output-name-template {{.SourceBase}}_mocks_test.go pegomocktest/subpackage SubDisplay,SubStore"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "subpackage_mocks_test.go")).To(BeAFileContainingSubString("type MockSubStore struct"))
+			})
+
+			It(`reports an error for --merge with a .go file`, func() {
+				var buf bytes.Buffer
+				Expect(func() { main.Run(cmd("pegomock generate --merge mydisplay.go"), &buf, app, done) }).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("--merge requires a package and a comma-separated list of interfaces"))
+			})
+		})
+
+		Context(`with args "--all-interfaces ./subpackage"`, func() {
+			It(`generates mocks for all exported interfaces of the package into mock_subpackage_test.go`, func() {
+				WriteFile(joinPath(subPackageDir, "more.go"), `package subpackage
+					type SubPrinter interface { Print() }
+					type hidden interface { Hide() }
+					type Number interface { ~int | ~float64 }`)
+
+				main.Run(cmd("pegomock generate --all-interfaces ./subpackage"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_subpackage_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package pegomocktest_test"),
+					BeAFileContainingSubString("type MockSubDisplay struct"),
+					BeAFileContainingSubString("type MockSubPrinter struct"),
+					Not(BeAFileContainingSubString("Mockhidden")),
+					Not(BeAFileContainingSubString("MockNumber"))))
+			})
+
+			It(`reports an error and the usage when given more than the package`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate --all-interfaces ./subpackage SubDisplay"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("--all-interfaces requires exactly one package"))
+			})
+		})
+
+		Context(`with args "./..."`, func() {
+			It(`generates mocks for all exported interfaces next to each package, skipping vendor directories`, func() {
+				main.Run(cmd("pegomock generate ./..."), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_pegomocktest_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package pegomocktest_test"),
+					BeAFileContainingSubString("type MockMyDisplay struct"),
+					BeAFileContainingSubString("type MockVendorDisplay struct")))
+				Expect(joinPath(subPackageDir, "mock_subpackage_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package subpackage_test"),
+					BeAFileContainingSubString("type MockSubDisplay struct")))
+				Expect(joinPath(vendorPackageDir, "mock_vendored_package_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`only generates mocks for interfaces matching --interface-pattern`, func() {
+				main.Run(cmd("pegomock generate --interface-pattern ^My ./..."), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_pegomocktest_test.go")).To(SatisfyAll(
+					BeAFileContainingSubString("type MockMyDisplay struct"),
+					Not(BeAFileContainingSubString("MockVendorDisplay"))))
+				Expect(joinPath(subPackageDir, "mock_subpackage_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`generates the mocks into each package itself when --output-name-template does not produce a _test.go file`, func() {
+				main.Run(cmd("pegomock generate --output-name-template {{.SourceBase}}_mocks.go ./..."), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "pegomocktest_mocks.go")).To(BeAFileContainingSubString("package pegomocktest\n"))
+				Expect(joinPath(subPackageDir, "subpackage_mocks.go")).To(BeAFileContainingSubString("package subpackage\n"))
+			})
+
+			It(`generates the packages concurrently and reports the errors of all of them`, func() {
+				for _, name := range []string{"bad1", "bad2"} {
+					Expect(os.MkdirAll(joinPath(packageDir, name), 0755)).To(Succeed())
+					WriteFile(joinPath(packageDir, name, "bad.go"), "package "+name+
+						"; type Bad interface { Show(something struct{ x int }) }")
+				}
+
+				var buf bytes.Buffer
+				Expect(func() { main.Run(cmd("pegomock generate -j 4 ./..."), &buf, app, done) }).To(Panic())
+
+				Expect(buf.String()).To(SatisfyAll(
+					ContainSubstring("Generating mocks failed:"),
+					ContainSubstring("bad1: Could not generate mocks"),
+					ContainSubstring("bad2: Could not generate mocks")))
+				Expect(joinPath(packageDir, "mock_pegomocktest_test.go")).To(BeAFileContainingSubString("type MockMyDisplay struct"))
+				Expect(joinPath(subPackageDir, "mock_subpackage_test.go")).To(BeAFileContainingSubString("type MockSubDisplay struct"))
+			})
+		})
+
+		Context(`with args "grpc ./gen/routeguide"`, func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(joinPath(packageDir, "vendor", "google.golang.org", "grpc"), 0755)).To(Succeed())
+				Expect(os.MkdirAll(joinPath(packageDir, "gen", "routeguide"), 0755)).To(Succeed())
+				WriteFile(joinPath(packageDir, "vendor", "google.golang.org", "grpc", "grpc.go"), `package grpc
+					import "context"
+					type CallOption interface{ before() }
+					type ClientStream interface { Context() context.Context; CloseSend() error; SendMsg(m any) error; RecvMsg(m any) error }
+					type ServerStream interface { Context() context.Context; SendMsg(m any) error; RecvMsg(m any) error }
+					type ServerStreamingClient[Res any] interface { Recv() (*Res, error); ClientStream }`)
+				WriteFile(joinPath(packageDir, "gen", "routeguide", "route_guide_grpc.pb.go"), `package routeguide
+					import ( "context"; "google.golang.org/grpc" )
+					type Point struct{}
+					type Feature struct{}
+					type RouteGuideClient interface {
+						GetFeature(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Feature, error)
+						ListFeatures(ctx context.Context, in *Point, opts ...grpc.CallOption) (RouteGuide_ListFeaturesClient, error)
+						RecordRoute(ctx context.Context, opts ...grpc.CallOption) (RouteGuide_RecordRouteClient, error)
+					}
+					type RouteGuide_ListFeaturesClient = grpc.ServerStreamingClient[Feature]
+					type RouteGuide_RecordRouteClient interface { Send(*Point) error; CloseAndRecv() (*Feature, error); grpc.ClientStream }
+					type RouteGuideServer interface {
+						GetFeature(context.Context, *Point) (*Feature, error)
+						ListFeatures(*Point, RouteGuide_ListFeaturesServer) error
+						mustEmbedUnimplementedRouteGuideServer()
+					}
+					type UnimplementedRouteGuideServer struct{}
+					func (UnimplementedRouteGuideServer) GetFeature(context.Context, *Point) (*Feature, error) { return nil, nil }
+					func (UnimplementedRouteGuideServer) ListFeatures(*Point, RouteGuide_ListFeaturesServer) error { return nil }
+					func (UnimplementedRouteGuideServer) mustEmbedUnimplementedRouteGuideServer() {}
+					type UnsafeRouteGuideServer interface { mustEmbedUnimplementedRouteGuideServer() }
+					type RouteGuide_ListFeaturesServer interface { Send(*Feature) error; grpc.ServerStream }
+					type Unrelated interface { Do() }`)
+			})
+
+			It(`generates mocks for the client, server and stream interfaces of the package that compile`, func() {
+				main.Run(cmd("pegomock generate grpc ./gen/routeguide"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_routeguide_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package pegomocktest_test"),
+					BeAFileContainingSubString("type MockRouteGuideClient struct"),
+					BeAFileContainingSubString("type MockRouteGuideServer struct {\n\tfail func(message string, callerSkip ...int)\n\trouteguide.UnimplementedRouteGuideServer\n}"),
+					BeAFileContainingSubString("var _ routeguide.RouteGuideServer = (*MockRouteGuideServer)(nil)"),
+					BeAFileContainingSubString("type MockRouteGuide_ListFeaturesClient struct"),
+					BeAFileContainingSubString("type MockRouteGuide_RecordRouteClient struct"),
+					BeAFileContainingSubString("type MockRouteGuide_ListFeaturesServer struct"),
+					Not(BeAFileContainingSubString("mustEmbedUnimplemented")),
+					Not(BeAFileContainingSubString("MockUnsafeRouteGuideServer")),
+					Not(BeAFileContainingSubString("MockUnrelated"))))
+				output, e := exec.Command("go", "vet", ".").CombinedOutput()
+				Expect(e).NotTo(HaveOccurred(), string(output))
+			})
+
+			It(`reports an error and the usage when given more than the package`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate grpc ./gen/routeguide RouteGuideClient"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("grpc requires exactly one package"))
+			})
+		})
+
 		Context("with args mydisplay.go", func() {
 			It(`generates a file mock_mydisplay_test.go that contains "package pegomocktest_test"`, func() {
 				main.Run(cmd("pegomock generate mydisplay.go"), os.Stdout, app, done)
@@ -134,6 +321,86 @@ var _ = Describe("CLI", func() {
 			})
 		})
 
+		Context("with args for a _test.go file of an external test package", func() {
+			BeforeEach(func() {
+				WriteFile(joinPath(packageDir, "mydisplay_test.go"), `package pegomocktest_test
+					import "pegomocktest"
+					type TestDisplay interface { Show(d pegomocktest.MyDisplay) TestDisplay }`)
+			})
+
+			It(`generates a file mock_mydisplay_test_interfaces_test.go in the external test package`, func() {
+				main.Run(cmd("pegomock generate mydisplay_test.go"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_mydisplay_test_interfaces_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package pegomocktest_test"),
+					BeAFileContainingSubString(`pegomocktest "pegomocktest"`),
+					BeAFileContainingSubString("func (mock *MockTestDisplay) Show(d pegomocktest.MyDisplay) TestDisplay"),
+					BeAFileContainingSubString("var _ TestDisplay = (*MockTestDisplay)(nil)")))
+			})
+
+			It(`reports an error if the mocks would be written to another directory`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate -o subpackage/mock_test.go mydisplay_test.go"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("Interfaces in mydisplay_test.go are only visible in package pegomocktest_test"))
+				Expect(joinPath(subPackageDir, "mock_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`writes the mocks to another directory when --package is given explicitly`, func() {
+				main.Run(cmd("pegomock generate -o subpackage/mock_test.go --package subpackage_test mydisplay_test.go"), os.Stdout, app, done)
+
+				Expect(joinPath(subPackageDir, "mock_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package subpackage_test")))
+			})
+		})
+
+		Context("with args for an unexported interface", func() {
+			BeforeEach(func() {
+				WriteFile(joinPath(subPackageDir, "store.go"), "package subpackage; type store interface { Get(key string) string }")
+			})
+
+			It(`generates a file mock_store_test.go in the package declaring the interface`, func() {
+				main.Run(cmd("pegomock generate pegomocktest/subpackage store"), os.Stdout, app, done)
+
+				Expect(joinPath(subPackageDir, "mock_store_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package subpackage\n"),
+					BeAFileContainingSubString("func (mock *Mockstore) Get(key string) string")))
+				Expect(joinPath(packageDir, "mock_store_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`reports an error if the mocks would be written to another directory`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate -o mock_store_test.go pegomocktest/subpackage store"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("Unexported interfaces of pegomocktest/subpackage are only visible in package subpackage"))
+				Expect(joinPath(packageDir, "mock_store_test.go")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Context("with args for generating an importable package of mocks and matchers", func() {
+			It(`generates mocks and matchers in one package that compiles and records the command`, func() {
+				main.Run(cmd("pegomock generate -o mocks/mocks.go --package mocks --record-command -m -p mocks vendordisplay.go"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mocks", "mocks.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("// Command: pegomock generate -o mocks/mocks.go --package mocks --record-command -m -p mocks vendordisplay.go\n"),
+					BeAFileContainingSubString("package mocks"),
+					BeAFileContainingSubString("var _ pegomocktest.VendorDisplay = (*MockVendorDisplay)(nil)")))
+				Expect(joinPath(packageDir, "mocks", "matcher_vendored_package_interface.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package mocks")))
+				output, e := exec.Command("go", "vet", "./mocks").CombinedOutput()
+				Expect(e).NotTo(HaveOccurred(), string(output))
+			})
+		})
+
 		Context("with args -d mydisplay.go", func() {
 			It(`prints out debug information on stdout`, func() {
 				var buf bytes.Buffer
@@ -152,6 +419,295 @@ var _ = Describe("CLI", func() {
 			})
 		})
 
+		Context("with args -o -", func() {
+			It(`writes the mocks to stdout instead of a file`, func() {
+				reader, writer, e := os.Pipe()
+				Expect(e).NotTo(HaveOccurred())
+				origStdout := os.Stdout
+				os.Stdout = writer
+				main.Run(cmd("pegomock generate -o - MyDisplay"), ioutil.Discard, app, done)
+				os.Stdout = origStdout
+				Expect(writer.Close()).To(Succeed())
+
+				output, e := ioutil.ReadAll(reader)
+				Expect(e).NotTo(HaveOccurred())
+				Expect(string(output)).To(SatisfyAll(
+					HavePrefix("// Code generated by pegomock. DO NOT EDIT."),
+					ContainSubstring("package pegomocktest_test"),
+					ContainSubstring("type MockMyDisplay struct")))
+				Expect(joinPath(packageDir, "-")).NotTo(BeAnExistingFile())
+			})
+
+			It(`reports an error and the usage when also generating matchers`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate -o - -m MyDisplay"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("--output - cannot be used with"))
+			})
+		})
+
+		Context("with args --header-file", func() {
+			It(`puts the content of the header file at the top of the mocks and matchers`, func() {
+				WriteFile(joinPath(packageDir, "license.txt"), "Copyright ACME Corp.\n")
+
+				main.Run(cmd("pegomock generate --header-file license.txt -m VendorDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_vendordisplay_test.go")).To(
+					BeAFileContainingSubString("// Copyright ACME Corp.\n// Code generated by pegomock. DO NOT EDIT.\n"))
+				Expect(joinPath(packageDir, "matchers", "vendored_package_interface.go")).To(
+					BeAFileContainingSubString("// Copyright ACME Corp.\n// Code generated by pegomock. DO NOT EDIT.\n"))
+			})
+		})
+
+		Context("with args --emit-model", func() {
+			It(`writes the model of the interfaces as JSON instead of generating mocks`, func() {
+				main.Run(cmd("pegomock generate --emit-model model.json MyDisplay"), os.Stdout, app, done)
+
+				content, e := ioutil.ReadFile(joinPath(packageDir, "model.json"))
+				Expect(e).NotTo(HaveOccurred())
+				Expect(string(content)).To(MatchJSON(`{
+					"name": "pegomocktest",
+					"pkgPath": "pegomocktest",
+					"imports": [],
+					"interfaces": [{
+						"name": "MyDisplay",
+						"kind": "interface",
+						"methods": [{
+							"name": "Show",
+							"in": [{"name": "something", "type": {"kind": "predeclared", "name": "string"}}],
+							"out": []
+						}]
+					}]
+				}`))
+				Expect(joinPath(packageDir, "mock_mydisplay_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`describes types from other packages with their import paths`, func() {
+				main.Run(cmd("pegomock generate --emit-model model.json vendordisplay.go"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "model.json")).To(SatisfyAll(
+					BeAFileContainingSubString(`"imports": [
+    "github.com/petergtz/vendored_package"
+  ]`),
+					BeAFileContainingSubString(`"type": {
+                "kind": "named",
+                "package": "github.com/petergtz/vendored_package",
+                "name": "Interface"
+              }`)))
+			})
+
+			It(`writes a model that --from-model generates the same mocks from`, func() {
+				main.Run(cmd("pegomock generate --emit-model model.json MyDisplay"), os.Stdout, app, done)
+				main.Run(cmd("pegomock generate --from-model model.json"), os.Stdout, app, done)
+				main.Run(cmd("pegomock generate MyDisplay"), os.Stdout, app, done)
+
+				fromModel, e := ioutil.ReadFile(joinPath(packageDir, "mock_model_test.go"))
+				Expect(e).NotTo(HaveOccurred())
+				fromCode, e := ioutil.ReadFile(joinPath(packageDir, "mock_mydisplay_test.go"))
+				Expect(e).NotTo(HaveOccurred())
+				Expect(strings.Replace(string(fromModel), "// Source: model.json", "// Source: pegomocktest (interfaces: MyDisplay)", 1)).
+					To(Equal(string(fromCode)))
+			})
+
+			It(`reports an error and the usage when used with ./...`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate --emit-model model.json ./..."), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("--emit-model cannot be used with --check, ./... or a project configuration"))
+			})
+		})
+
+		Context("with args --incremental", func() {
+			It(`only generates the mocks again when the interface changed`, func() {
+				main.Run(cmd("pegomock generate --incremental MyDisplay"), os.Stdout, app, done)
+				Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(BeAFileContainingSubString("// Input hash: "))
+				mockFile, e := os.OpenFile(joinPath(packageDir, "mock_mydisplay_test.go"), os.O_APPEND|os.O_WRONLY, 0)
+				Expect(e).NotTo(HaveOccurred())
+				_, e = mockFile.WriteString("// not generated again\n")
+				Expect(e).NotTo(HaveOccurred())
+				Expect(mockFile.Close()).To(Succeed())
+
+				main.Run(cmd("pegomock generate --incremental MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(BeAFileContainingSubString("// not generated again"))
+
+				WriteFile(joinPath(packageDir, "mydisplay.go"),
+					"package pegomocktest; type MyDisplay interface {  Show(something string); Flash(something string) }")
+				main.Run(cmd("pegomock generate --incremental MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(SatisfyAll(
+					BeAFileContainingSubString("func (mock *MockMyDisplay) Flash("),
+					Not(BeAFileContainingSubString("// not generated again"))))
+			})
+		})
+
+		Context("with args --template", func() {
+			It(`generates the mock file from the template`, func() {
+				WriteFile(joinPath(packageDir, "stub.tmpl"), `{{.Header}}
+package {{.PackageOut}}
+{{range $iface := .Interfaces}}
+type Stub{{.Name}} struct{}
+{{range .Methods}}
+func (Stub{{$iface.Name}}) {{.Name}}({{.Params}}) {{.Results}} {}
+{{end}}{{end}}`)
+				main.Run(cmd("pegomock generate --template stub.tmpl MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(SatisfyAll(
+					BeAFileContainingSubString("type StubMyDisplay struct{}\n"),
+					BeAFileContainingSubString("func (StubMyDisplay) Show(something string) {}\n"),
+					Not(BeAFileContainingSubString("MockMyDisplay"))))
+			})
+		})
+
+		Context("with args --emitter", func() {
+			It(`writes the files of the emitter next to the mock file`, func() {
+				mockgen.RegisterEmitter("test-interface-list", mockgen.EmitterFunc(func(pkg *model.Package, opts mockgen.Options) (map[string][]byte, error) {
+					var names []string
+					for _, iface := range pkg.Interfaces {
+						names = append(names, iface.Name)
+					}
+					return map[string][]byte{"interfaces.txt": []byte(strings.Join(names, "\n"))}, nil
+				}))
+
+				main.Run(cmd("pegomock generate --emitter test-interface-list -o mocks/mocks.go --package mocks MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mocks", "mocks.go")).To(BeAFileContainingSubString("type MockMyDisplay struct"))
+				Expect(joinPath(packageDir, "mocks", "interfaces.txt")).To(BeAFileContainingSubString("MyDisplay"))
+			})
+		})
+
+		Context("with args --build-tags", func() {
+			It(`generates mocks that are only part of builds satisfying the constraint`, func() {
+				main.Run(cmd("pegomock generate --build-tags mocks -o mocks/mocks.go --package mocks MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mocks", "mocks.go")).To(BeAFileContainingSubString("//go:build mocks\n"))
+				listFiles := func(args ...string) string {
+					output, e := exec.Command("go", append([]string{"list", "-e", "-f", "{{.GoFiles}}"}, args...)...).CombinedOutput()
+					Expect(e).NotTo(HaveOccurred(), string(output))
+					return string(output)
+				}
+				Expect(listFiles("./mocks")).To(ContainSubstring("[]"))
+				Expect(listFiles("-tags", "mocks", "./mocks")).To(ContainSubstring("[mocks.go]"))
+			})
+		})
+
+		Context("with args --methods", func() {
+			It(`generates a mock for only these methods that embeds the interface`, func() {
+				WriteFile(joinPath(packageDir, "bigstore.go"), `package pegomocktest
+					type BigStore interface { Get(key string) string; Put(key string, value string); Delete(key string) }`)
+
+				main.Run(cmd("pegomock generate --methods Get,Delete BigStore"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_bigstore_test.go")).To(SatisfyAll(
+					BeAFileContainingSubString("\tpegomocktest.BigStore\n"),
+					BeAFileContainingSubString("func (mock *MockBigStore) Get(key string) string"),
+					BeAFileContainingSubString("func (mock *MockBigStore) Delete(key string)"),
+					Not(BeAFileContainingSubString("Put"))))
+			})
+		})
+
+		Context("with args --combine", func() {
+			It(`generates one mock implementing all interfaces, with each method once`, func() {
+				main.Run(cmd("pegomock generate --combine io.ReadCloser,io.Reader,MyDisplay --name ReadCloserMock"), os.Stdout, app, done)
+
+				mockFile := joinPath(packageDir, "mock_readclosermock_test.go")
+				Expect(mockFile).To(SatisfyAll(
+					BeAFileContainingSubString("type ReadCloserMock struct"),
+					BeAFileContainingSubString("func (mock *ReadCloserMock) Close() error"),
+					BeAFileContainingSubString("func (mock *ReadCloserMock) Show(something string)")))
+				content, e := ioutil.ReadFile(mockFile)
+				Expect(e).NotTo(HaveOccurred())
+				Expect(strings.Count(string(content), "func (mock *ReadCloserMock) Read(")).To(Equal(1))
+				output, e := exec.Command("go", "vet", ".").CombinedOutput()
+				Expect(e).NotTo(HaveOccurred(), string(output))
+			})
+
+			It(`reports methods declared with different signatures`, func() {
+				WriteFile(joinPath(packageDir, "otherreader.go"), "package pegomocktest; type OtherReader interface { Read() }")
+
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate --combine io.Reader,OtherReader --name ReadMock"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("OtherReader declares the method Read with another signature than the interfaces before it"))
+			})
+
+			It(`reports a missing --name`, func() {
+				var buf bytes.Buffer
+				Expect(func() { main.Run(cmd("pegomock generate --combine io.Reader,io.Closer"), &buf, app, done) }).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("--combine requires --name"))
+			})
+		})
+
+		Context("with args --name-template", func() {
+			It(`names the mock types according to the template`, func() {
+				main.Run(cmd("pegomock generate --name-template {{.Interface}}Fake --verifier-name-template {{.Interface}}FakeVerifier MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(SatisfyAll(
+					BeAFileContainingSubString("type MyDisplayFake struct"),
+					BeAFileContainingSubString("type MyDisplayFakeVerifier struct"),
+					BeAFileContainingSubString("type MyDisplay_Show_OngoingVerification struct")))
+			})
+		})
+
+		Context("with args --output-name-template", func() {
+			It(`uses the template to determine the output file in source mode`, func() {
+				main.Run(cmd("pegomock generate --output-name-template=testdata/mocks/{{.SourceBase}}_mock_test.go mydisplay.go"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "testdata", "mocks", "mydisplay_mock_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package pegomocktest_test")))
+			})
+
+			It(`uses the template to determine the output file in reflect mode`, func() {
+				main.Run(cmd("pegomock generate --output-name-template={{.InterfaceName|lower}}_mock_{{.PackageName}}.go MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mydisplay_mock_pegomocktest_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("type MockMyDisplay struct")))
+			})
+
+			It(`generates the mocks into the package itself when the template does not produce a _test.go file`, func() {
+				main.Run(cmd("pegomock generate --output-name-template={{.InterfaceName|lower}}_mock.go MyDisplay"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mydisplay_mock.go")).To(BeAFileContainingSubString("package pegomocktest\n"))
+			})
+
+			It(`reports an error when the template produces a _test.go file depending on the package`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run([]string{"pegomock", "generate",
+						`--output-name-template={{if eq .PackageName "pegomocktest"}}mock_test.go{{else}}mock.go{{end}}`, "MyDisplay"}, &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("Please specify the package of the generated code explicitly with --package."))
+				Expect(joinPath(packageDir, "mock.go")).NotTo(BeAnExistingFile())
+				Expect(joinPath(packageDir, "mock_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`is ignored when --output is given`, func() {
+				main.Run(cmd("pegomock generate --output-name-template={{.SourceBase}}_mock_test.go -o override_test.go mydisplay.go"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "override_test.go")).To(BeAnExistingFile())
+				Expect(joinPath(packageDir, "mydisplay_mock_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`reports an error when the template does not produce a .go file`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate --output-name-template={{.SourceBase}}_mock mydisplay.go"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring(`must produce a file name ending in .go, but produced "mydisplay_mock"`))
+			})
+		})
+
 		Context("with args for specifying matcher directory", func() {
 			It(`creates matchers in the specified directory`, func() {
 				var buf bytes.Buffer
@@ -162,6 +718,65 @@ var _ = Describe("CLI", func() {
 			})
 		})
 
+		Context("with args for a .go file containing an interface with unsupported constructs", func() {
+			It(`generates mocks for the other interfaces, reports the unsupported constructs and exits`, func() {
+				WriteFile(joinPath(packageDir, "mixed.go"), `package pegomocktest
+					type Good interface { Show(something string) }
+					type Bad interface { Show(something struct{ x int }) }`)
+
+				var buf bytes.Buffer
+				Expect(func() { main.Run(cmd("pegomock generate mixed.go"), &buf, app, done) }).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("Bad.Show, parameter 0: unsupported type non-empty unnamed struct type"))
+				Expect(joinPath(packageDir, "mock_mixed_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("type MockGood struct")))
+			})
+		})
+
+		Context("with args for a package that cannot be loaded", func() {
+			It(`reports the error without a stack trace and exits`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate github.com/petergtz/does_not_exist Display"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(HavePrefix("error: Loading input failed: loading package github.com/petergtz/does_not_exist"))
+				Expect(joinPath(packageDir, "mock_display_test.go")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Context("with args for an interface using types of an internal package", func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(joinPath(packageDir, "lib", "internal", "secret"), 0755)).To(Succeed())
+				WriteFile(joinPath(packageDir, "lib", "internal", "secret", "secret.go"), "package secret; type Key int")
+				WriteFile(joinPath(packageDir, "lib", "store.go"), `package lib
+					import "pegomocktest/lib/internal/secret"
+					type Store interface { Get(key secret.Key) }`)
+			})
+
+			It(`reports an error instead of writing mocks outside of the internal package's tree`, func() {
+				var buf bytes.Buffer
+				Expect(func() { main.Run(cmd("pegomock generate pegomocktest/lib Store"), &buf, app, done) }).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("mock_store_test.go would import the internal package pegomocktest/lib/internal/secret, " +
+					"but only packages within pegomocktest/lib may import it. Please write the mocks to a directory within pegomocktest/lib"))
+				Expect(joinPath(packageDir, "mock_store_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`generates mocks within the internal package's tree`, func() {
+				main.Run(cmd("pegomock generate -o lib/mock_store_test.go pegomocktest/lib Store"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "lib", "mock_store_test.go")).To(BeAnExistingFile())
+			})
+
+			It(`generates mocks anyway with --allow-internal-imports`, func() {
+				main.Run(cmd("pegomock generate --allow-internal-imports pegomocktest/lib Store"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mock_store_test.go")).To(BeAFileContainingSubString(`"pegomocktest/lib/internal/secret"`))
+			})
+		})
+
 		Context("with too many args", func() {
 
 			It(`reports an error and the usage`, func() {
@@ -175,6 +790,114 @@ var _ = Describe("CLI", func() {
 			})
 		})
 
+		Context("with args --check", func() {
+			It(`lists missing and changed mock files without writing anything and exits`, func() {
+				main.Run(cmd("pegomock generate -m VendorDisplay"), os.Stdout, app, done)
+				WriteFile(joinPath(packageDir, "vendordisplay.go"), `package pegomocktest
+					import ( "github.com/petergtz/vendored_package" )
+					type VendorDisplay interface { Show(something vendored_package.Interface); Flash() }`)
+
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate --check -m VendorDisplay"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("Mock files that are out of date:\n  mock_vendordisplay_test.go\n"))
+				Expect(buf.String()).NotTo(ContainSubstring("vendored_package_interface.go"))
+				Expect(buf.String()).To(ContainSubstring("Mocks are out of date"))
+				Expect(joinPath(packageDir, "mock_vendordisplay_test.go")).NotTo(BeAFileContainingSubString("Flash"))
+
+				buf.Reset()
+				Expect(func() {
+					main.Run(cmd("pegomock generate --check MyDisplay"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("  mock_mydisplay_test.go\n"))
+				Expect(joinPath(packageDir, "mock_mydisplay_test.go")).NotTo(BeAnExistingFile())
+			})
+
+			It(`exits normally when all mock files are up to date`, func() {
+				main.Run(cmd("pegomock generate ./..."), os.Stdout, app, done)
+
+				var buf bytes.Buffer
+				main.Run(cmd("pegomock generate --check ./..."), &buf, app, done)
+
+				Expect(buf.String()).NotTo(ContainSubstring("out of date"))
+			})
+
+			It(`finds the committed mock of the http package up to date`, func() {
+				httpDir := joinPath(origWorkingDir, "..", "http")
+				source, e := ioutil.ReadFile(joinPath(httpDir, "request_matchers.go"))
+				Expect(e).NotTo(HaveOccurred())
+				generateCommand := regexp.MustCompile(`//go:generate (pegomock generate .*)`).FindSubmatch(source)
+				Expect(generateCommand).NotTo(BeNil())
+				os.Chdir(httpDir)
+
+				var buf bytes.Buffer
+				Expect(exitCodeOf(func() {
+					main.Run(cmd(strings.Replace(string(generateCommand[1]), "generate", "generate --check", 1)), &buf, app, done)
+				})).To(Equal(0), buf.String())
+			})
+		})
+
+		Context("without args", func() {
+			It(`generates all mocks declared in the closest .pegomock.yaml file`, func() {
+				WriteFile(joinPath(packageDir, ".pegomock.yaml"), `
+# mocks shared by the whole project
+package: mocks
+generate-builders: true
+mocks:
+  - args: [MyDisplay]
+    output-dir: mocks
+  - args: [./subpackage, SubDisplay]
+    output: mocks/sub_display.go
+    generate-builders: false
+  - args:
+      - vendordisplay.go
+    output-dir: othermocks
+    package: othermocks
+`)
+				os.Chdir(subPackageDir)
+
+				main.Run(cmd("pegomock generate"), os.Stdout, app, done)
+
+				Expect(joinPath(packageDir, "mocks", "mock_mydisplay_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package mocks"),
+					BeAFileContainingSubString("type MockMyDisplayBuilder struct")))
+				Expect(joinPath(packageDir, "mocks", "sub_display.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package mocks"),
+					BeAFileContainingSubString("type MockSubDisplay struct"),
+					Not(BeAFileContainingSubString("Builder"))))
+				Expect(joinPath(packageDir, "othermocks", "mock_vendordisplay_test.go")).To(SatisfyAll(
+					BeAnExistingFile(),
+					BeAFileContainingSubString("package othermocks")))
+			})
+
+			It(`reports unknown keys in the project configuration`, func() {
+				WriteFile(joinPath(packageDir, ".pegomock.yaml"), "mocks:\n  - args: [MyDisplay]\n    ouptut: mock.go\n")
+
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("Invalid project configuration"))
+				Expect(buf.String()).To(ContainSubstring("ouptut"))
+			})
+
+			It(`reports an error and the usage when there is no project configuration`, func() {
+				var buf bytes.Buffer
+				Expect(func() {
+					main.Run(cmd("pegomock generate"), &buf, app, done)
+				}).To(Panic())
+
+				Expect(buf.String()).To(ContainSubstring("or declare the mocks in a .pegomock.yaml file"))
+				Expect(buf.String()).To(ContainSubstring("usage"))
+			})
+		})
+
 	})
 
 	Describe(`"watch" command`, func() {
@@ -202,19 +925,192 @@ var _ = Describe("CLI", func() {
 
 	})
 
+	Describe(`"generate-all" command`, func() {
+		It(`generates all mocks, prints a summary and the report, and exits when some failed`, func() {
+			WriteFile(joinPath(packageDir, "interfaces_to_mock"), "missing.go\nmydisplay.go")
+			WriteFile(joinPath(subPackageDir, "interfaces_to_mock"), "subdisplay.go")
+
+			var buf bytes.Buffer
+			Expect(func() {
+				main.Run(cmd("pegomock generate-all -r --report report.json"), &buf, app, done)
+			}).To(Panic())
+
+			Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(BeAnExistingFile())
+			Expect(joinPath(subPackageDir, "mock_subdisplay_test.go")).To(BeAnExistingFile())
+			Expect(buf.String()).To(MatchRegexp(`(?s)generated .*mydisplay.go.*generated .*subdisplay.go.*failed .*missing.go`))
+			Expect(buf.String()).To(ContainSubstring("2 generated, 0 unchanged, 1 failed"))
+			Expect(buf.String()).To(ContainSubstring("1 of 3 mocks failed to generate"))
+			Expect(joinPath(packageDir, "report.json")).To(SatisfyAll(
+				BeAnExistingFile(),
+				BeAFileContainingSubString(`"failed": 1`)))
+		})
+
+		It(`reports unchanged mocks and exits normally when none failed`, func() {
+			WriteFile(joinPath(packageDir, "interfaces_to_mock"), "mydisplay.go")
+			main.Run(cmd("pegomock generate-all"), &bytes.Buffer{}, app, done)
+
+			var buf bytes.Buffer
+			main.Run(cmd("pegomock generate-all"), &buf, app, done)
+
+			Expect(buf.String()).To(ContainSubstring("0 generated, 1 unchanged, 0 failed"))
+		})
+	})
+
+	Describe(`"list" command`, func() {
+		It(`lists the exported interfaces below the working directory with their mock files`, func() {
+			main.Run(cmd("pegomock generate MyDisplay"), os.Stdout, app, done)
+			main.Run(cmd("pegomock generate ./subpackage/..."), os.Stdout, app, done)
+			WriteFile(joinPath(subPackageDir, "subdisplay.go"),
+				"package subpackage; type SubDisplay interface {  ShowMe(); HideMe() }")
+
+			var buf bytes.Buffer
+			main.Run(cmd("pegomock list"), &buf, app, done)
+
+			Expect(buf.String()).To(SatisfyAll(
+				MatchRegexp(`INTERFACE\s+FILE\s+METHODS\s+MOCK\n`),
+				MatchRegexp(`pegomocktest\.MyDisplay\s+mydisplay\.go\s+1\s+mock_mydisplay_test\.go\n`),
+				MatchRegexp(`pegomocktest\.VendorDisplay\s+vendordisplay\.go\s+1\s+-\n`),
+				MatchRegexp(`pegomocktest/subpackage\.SubDisplay\s+subpackage/subdisplay\.go\s+2\s+subpackage/mock_subpackage_test\.go \(stale\)\n`)))
+		})
+	})
+
+	Describe(`"clean" command`, func() {
+		It(`deletes all files generated by pegomock`, func() {
+			main.Run(cmd("pegomock generate -m VendorDisplay"), os.Stdout, app, done)
+			main.Run(cmd("pegomock generate ./subpackage/..."), os.Stdout, app, done)
+
+			var buf bytes.Buffer
+			main.Run(cmd("pegomock clean"), &buf, app, done)
+
+			Expect(buf.String()).To(SatisfyAll(
+				ContainSubstring("Deleted mock_vendordisplay_test.go\n"),
+				ContainSubstring("Deleted "+joinPath("matchers", "vendored_package_interface.go")+"\n"),
+				ContainSubstring("Deleted "+joinPath("subpackage", "mock_subpackage_test.go")+"\n")))
+			Expect(joinPath(packageDir, "mock_vendordisplay_test.go")).NotTo(BeAnExistingFile())
+			Expect(joinPath(packageDir, "matchers", "vendored_package_interface.go")).NotTo(BeAnExistingFile())
+			Expect(joinPath(subPackageDir, "mock_subpackage_test.go")).NotTo(BeAnExistingFile())
+			Expect(joinPath(packageDir, "vendordisplay.go")).To(BeAnExistingFile())
+		})
+
+		It(`with --orphaned only deletes mocks whose interfaces no longer exist`, func() {
+			main.Run(cmd("pegomock generate MyDisplay"), os.Stdout, app, done)
+			main.Run(cmd("pegomock generate VendorDisplay"), os.Stdout, app, done)
+			main.Run(cmd("pegomock generate -o mock_file_test.go mydisplay.go"), os.Stdout, app, done)
+			main.Run(cmd("pegomock generate pegomocktest/subpackage SubDisplay"), os.Stdout, app, done)
+			WriteFile(joinPath(packageDir, "mydisplay.go"), "package pegomocktest; func Show(something string) {}")
+			Expect(os.RemoveAll(subPackageDir)).To(Succeed())
+
+			var buf bytes.Buffer
+			main.Run(cmd("pegomock clean --orphaned --dry-run"), &buf, app, done)
+
+			Expect(buf.String()).To(SatisfyAll(
+				ContainSubstring("Would delete mock_mydisplay_test.go\n"),
+				ContainSubstring("Would delete mock_file_test.go\n"),
+				ContainSubstring("Would delete mock_subdisplay_test.go\n"),
+				Not(ContainSubstring("mock_vendordisplay_test.go"))))
+			Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(BeAnExistingFile())
+
+			main.Run(cmd("pegomock clean --orphaned"), &bytes.Buffer{}, app, done)
+
+			Expect(joinPath(packageDir, "mock_mydisplay_test.go")).NotTo(BeAnExistingFile())
+			Expect(joinPath(packageDir, "mock_file_test.go")).NotTo(BeAnExistingFile())
+			Expect(joinPath(packageDir, "mock_subdisplay_test.go")).NotTo(BeAnExistingFile())
+			Expect(joinPath(packageDir, "mock_vendordisplay_test.go")).To(BeAnExistingFile())
+		})
+	})
+
+	Describe(`"outdated" command`, func() {
+		It(`lists mocks generated by incompatible versions or from changed interfaces`, func() {
+			main.Run(cmd("pegomock generate MyDisplay"), os.Stdout, app, done)
+			main.Run(cmd("pegomock generate VendorDisplay"), os.Stdout, app, done)
+			main.Run(cmd("pegomock outdated"), os.Stdout, app, done)
+
+			WriteFile(joinPath(packageDir, "mydisplay.go"),
+				"package pegomocktest; type MyDisplay interface {  Show(something string); Flash(something string) }")
+			WriteFile(joinPath(packageDir, "mock_old_test.go"), "// Code generated by pegomock. DO NOT EDIT.\n"+
+				"// Source: pegomocktest (interfaces: VendorDisplay)\n\npackage pegomocktest_test\n")
+			WriteFile(joinPath(packageDir, "mock_future_test.go"), "// Code generated by pegomock. DO NOT EDIT.\n"+
+				"// Source: pegomocktest (interfaces: VendorDisplay)\n// Generator: pegomock v99.0.0, generated code version 99\n\npackage pegomocktest_test\n")
+
+			var buf bytes.Buffer
+			Expect(func() { main.Run(cmd("pegomock outdated"), &buf, app, done) }).To(Panic())
+
+			Expect(buf.String()).To(SatisfyAll(
+				ContainSubstring("mock_mydisplay_test.go: the interfaces changed since it was generated\n"),
+				ContainSubstring("mock_old_test.go: generated by a version of pegomock that didn't record its version\n"),
+				ContainSubstring("mock_future_test.go: generated by pegomock v99.0.0 with generated code version 99, but pegomock "),
+				Not(ContainSubstring("mock_vendordisplay_test.go")),
+				ContainSubstring("3 mock files are outdated")))
+		})
+	})
+
 	Context("with some unknown command", func() {
 		It(`reports an error and the usage`, func() {
 			var buf bytes.Buffer
 			kingpin.CommandLine.Terminate(nil)
 			kingpin.CommandLine.Writer(&buf)
 
-			main.Run(cmd("pegomock some unknown command"), &buf, app, done)
+			Expect(exitCodeOf(func() { main.Run(cmd("pegomock some unknown command"), &buf, app, done) })).To(Equal(main.ExitCodeUsage))
 			Expect(buf.String()).To(ContainSubstring("error"))
 		})
 	})
 
+	Describe("failures", func() {
+		It(`exit with a distinct code per kind of failure`, func() {
+			var buf bytes.Buffer
+			Expect(exitCodeOf(func() {
+				main.Run(cmd("pegomock generate github.com/petergtz/does_not_exist Display"), &buf, app, done)
+			})).To(Equal(main.ExitCodeParseFailure))
+			Expect(exitCodeOf(func() { main.Run(cmd("pegomock generate . NoSuchDisplay"), &buf, app, done) })).
+				To(Equal(main.ExitCodeInterfaceNotFound))
+			Expect(exitCodeOf(func() { main.Run(cmd("pegomock generate -j 2 ./..."), &buf, app, done) })).
+				To(Equal(0))
+			Expect(exitCodeOf(func() {
+				main.Run(cmd("pegomock generate -o mydisplay.go/mock_mydisplay_test.go MyDisplay"), &buf, app, done)
+			})).To(Equal(main.ExitCodeWriteFailure))
+			Expect(exitCodeOf(func() { main.Run(cmd("pegomock generate --check MyDisplay"), &buf, app, done) })).
+				To(Equal(main.ExitCodeStale))
+			Expect(exitCodeOf(func() { main.Run(cmd("pegomock generate --no-such-flag MyDisplay"), &buf, app, done) })).
+				To(Equal(main.ExitCodeUsage))
+		})
+
+		It(`are reported as JSON with --error-format json`, func() {
+			var buf bytes.Buffer
+			Expect(exitCodeOf(func() {
+				main.Run(cmd("pegomock --error-format json generate --check MyDisplay"), &buf, app, done)
+			})).To(Equal(main.ExitCodeStale))
+
+			var failure map[string]interface{}
+			Expect(json.Unmarshal(buf.Bytes(), &failure)).To(Succeed())
+			Expect(failure).To(Equal(map[string]interface{}{
+				"kind":     "stale",
+				"exitCode": float64(main.ExitCodeStale),
+				"message":  "Mocks are out of date. Please regenerate them by running the same command without --check.",
+				"files":    []interface{}{"mock_mydisplay_test.go"},
+			}))
+
+			buf.Reset()
+			Expect(exitCodeOf(func() { main.Run(cmd("pegomock --error-format json generate . NoSuchDisplay"), &buf, app, done) })).
+				To(Equal(main.ExitCodeInterfaceNotFound))
+			Expect(json.Unmarshal(buf.Bytes(), &failure)).To(Succeed())
+			Expect(failure["kind"]).To(Equal("interface-not-found"))
+			Expect(failure["message"]).To(ContainSubstring("does not declare a type NoSuchDisplay"))
+		})
+	})
+
 })
 
+// exitCodeOf runs f and returns the exit code it exits with, or 0 if it returns normally.
+func exitCodeOf(f func()) (exitCode int) {
+	defer func() {
+		if r := recover(); r != nil {
+			exitCode = r.(int)
+		}
+	}()
+	f()
+	return 0
+}
+
 func cmd(line string) []string {
 	return strings.Split(line, " ")
 }
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"go/build"
 	"os"
-	"path/filepath"
 	"strings"
+
+	"github.com/petergtz/pegomock/modelgen/gomock"
 )
 
 func ValidateArgs(args []string) error {
@@ -27,16 +28,17 @@ func ValidateArgs(args []string) error {
 }
 
 func SourceArgs(args []string) ([]string, error) {
-	if SourceMode(args) {
+	if SourceMode(args) || ModelMode(args) {
 		return args[:], nil
 	} else if len(args) == 1 {
 		workingDir, e := os.Getwd()
 		if e != nil {
 			panic(e)
 		}
-		packagePath, err := packagePathFromDirectory(build.Default.GOPATH, workingDir)
-		if err != nil {
-			return nil, fmt.Errorf("Couldn't determine package path from directory: %v", err)
+		packagePath := gomock.ImportPathOfDir(workingDir)
+		if packagePath == "" {
+			return nil, fmt.Errorf("Couldn't determine package path from directory: "+
+				"Directory is neither within a Go package path nor a Go module. GOPATH: %v; dir: %v", build.Default.GOPATH, workingDir)
 		}
 		return []string{packagePath, args[0]}, nil
 	} else if len(args) == 2 {
@@ -53,10 +55,31 @@ func SourceMode(args []string) bool {
 	return false
 }
 
-func packagePathFromDirectory(gopath, dir string) (string, error) {
-	relativePackagePath, err := filepath.Rel(filepath.Join(gopath, "src"), dir)
-	if err != nil {
-		return "", errors.New("Directory is not within a Go package path. GOPATH:" + gopath + "; dir: " + dir)
-	}
-	return relativePackagePath, nil
+// ModelMode reports whether args is a single .json file with a model of interfaces, as written
+// with --emit-model, to generate the mocks from.
+func ModelMode(args []string) bool {
+	return len(args) == 1 && strings.HasSuffix(args[0], ".json")
+}
+
+// RecursiveMode reports whether args is a single pattern like ./... that denotes all packages
+// in and below a directory.
+func RecursiveMode(args []string) bool {
+	return len(args) == 1 && strings.HasSuffix(args[0], "...")
+}
+
+// GRPCMode reports whether args ask for the mocks of the gRPC interfaces of a package, as in
+// "grpc ./gen/foo".
+func GRPCMode(args []string) bool {
+	return len(args) >= 1 && args[0] == "grpc"
+}
+
+// CombineArgs returns the args for one mock called name that implements all interfaces in the
+// comma-separated list qualifiedInterfaces, e.g. "io.Reader,io.Closer".
+func CombineArgs(name string, qualifiedInterfaces string) []string {
+	return []string{"combine", name, qualifiedInterfaces}
+}
+
+// CombineMode reports whether args were returned by CombineArgs.
+func CombineMode(args []string) bool {
+	return len(args) == 3 && args[0] == "combine"
 }
@@ -0,0 +1,91 @@
+// Copyright 2016 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch regenerates the mocks once and then again whenever Go files or interfaces_to_mock
+// files in the target paths change, as reported by the operating system. A burst of changes
+// that are less than debounce apart triggers a single regeneration. Watch returns when a
+// value is sent to done.
+func (updater *MockFileUpdater) Watch(debounce time.Duration, done chan bool) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	for _, targetPath := range updater.targetPaths {
+		if err := updater.watchDirs(watcher, targetPath); err != nil {
+			return err
+		}
+	}
+	updater.Update()
+
+	var regeneration <-chan time.Time
+	for {
+		select {
+		case <-done:
+			return nil
+		case event := <-watcher.Events:
+			if updater.recursive && event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := updater.watchDirs(watcher, event.Name); err != nil {
+						fmt.Println("Error while trying to watch", event.Name, ":", err)
+					}
+				}
+			}
+			if triggersRegeneration(event) {
+				regeneration = time.After(debounce)
+			}
+		case <-regeneration:
+			regeneration = nil
+			updater.Update()
+		case err := <-watcher.Errors:
+			fmt.Println("Error while watching for changes:", err)
+		}
+	}
+}
+
+// watchDirs adds dir and, when watching recursively, all its sub-directories to watcher.
+// Directories are added with absolute paths, because regenerating mocks changes the working directory.
+func (updater *MockFileUpdater) watchDirs(watcher *fsnotify.Watcher, dir string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if !updater.recursive {
+		return watcher.Add(absDir)
+	}
+	return filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		return watcher.Add(path)
+	})
+}
+
+func triggersRegeneration(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	return filepath.Ext(event.Name) == ".go" || filepath.Base(event.Name) == wellKnownInterfaceListFile
+}
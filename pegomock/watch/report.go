@@ -0,0 +1,107 @@
+// Copyright 2016 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type Status string
+
+const (
+	StatusGenerated Status = "generated"
+	StatusUnchanged Status = "unchanged"
+	StatusFailed    Status = "failed"
+)
+
+var statusOrder = map[Status]int{StatusGenerated: 0, StatusUnchanged: 1, StatusFailed: 2}
+
+// TargetResult is the outcome of generating the mock for one line of an interfaces_to_mock file.
+type TargetResult struct {
+	Dir      string `json:"dir"`
+	Target   string `json:"target"`
+	MockFile string `json:"mockFile,omitempty"`
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report holds the results of a run in the order the targets were processed.
+type Report []TargetResult
+
+func (report Report) Count(status Status) int {
+	count := 0
+	for _, result := range report {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Sorted returns the results ordered by status, with failures last.
+func (report Report) Sorted() Report {
+	sorted := make(Report, len(report))
+	copy(sorted, report)
+	sort.SliceStable(sorted, func(i, j int) bool { return statusOrder[sorted[i].Status] < statusOrder[sorted[j].Status] })
+	return sorted
+}
+
+func (report Report) summary() string {
+	return fmt.Sprintf("%v generated, %v unchanged, %v failed",
+		report.Count(StatusGenerated), report.Count(StatusUnchanged), report.Count(StatusFailed))
+}
+
+// WriteSummary writes a table of all results, followed by the number of results per status.
+func (report Report) WriteSummary(out io.Writer) {
+	table := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "STATUS\tDIR\tTARGET\tMOCK FILE / ERROR")
+	for _, result := range report.Sorted() {
+		outcome := result.MockFile
+		if result.Status == StatusFailed {
+			outcome = strings.Replace(result.Error, "\n", " ", -1)
+		}
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\n", result.Status, result.Dir, result.Target, outcome)
+	}
+	table.Flush()
+	fmt.Fprintln(out, report.summary())
+}
+
+// WriteJSONFile writes the counts per status and the sorted results as JSON to filePath.
+func (report Report) WriteJSONFile(filePath string) error {
+	content, err := json.MarshalIndent(struct {
+		Generated int            `json:"generated"`
+		Unchanged int            `json:"unchanged"`
+		Failed    int            `json:"failed"`
+		Results   []TargetResult `json:"results"`
+	}{
+		Generated: report.Count(StatusGenerated),
+		Unchanged: report.Count(StatusUnchanged),
+		Failed:    report.Count(StatusFailed),
+		Results:   report.Sorted(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filePath, append(content, '\n'), 0664); err != nil {
+		return fmt.Errorf("Failed writing report: %v", err)
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/petergtz/pegomock/mockgen"
 	"github.com/petergtz/pegomock/pegomock/filehandling"
 	"github.com/petergtz/pegomock/pegomock/util"
 )
@@ -46,60 +47,143 @@ func NewMockFileUpdater(targetPaths []string, recursive bool) *MockFileUpdater {
 	}
 }
 
+// Update regenerates the mocks listed in the interfaces_to_mock files of all target paths.
 func (updater *MockFileUpdater) Update() {
+	updater.UpdateWithReport()
+}
+
+// UpdateWithReport is like Update, but also returns the result for every listed mock.
+// A failing mock does not stop the remaining ones from being generated.
+func (updater *MockFileUpdater) UpdateWithReport() Report {
+	var report Report
+	updateMockFiles := func(targetPath string) {
+		report = append(report, updater.updateMockFiles(targetPath)...)
+	}
 	for _, targetPath := range updater.targetPaths {
 		if updater.recursive {
 			filepath.Walk(targetPath, func(path string, info os.FileInfo, err error) error {
 				if err == nil && info.IsDir() {
-					util.WithinWorkingDir(path, updater.updateMockFiles)
+					util.WithinWorkingDir(path, updateMockFiles)
 				}
 				return nil
 			})
 		} else {
-			util.WithinWorkingDir(targetPath, updater.updateMockFiles)
+			util.WithinWorkingDir(targetPath, updateMockFiles)
 		}
 	}
+	return report
 }
 
-func (updater *MockFileUpdater) updateMockFiles(targetPath string) {
+func (updater *MockFileUpdater) updateMockFiles(targetPath string) (results []TargetResult) {
 	if _, err := os.Stat(wellKnownInterfaceListFile); os.IsNotExist(err) {
 		return
 	}
 	for _, lineParts := range linesIn(wellKnownInterfaceListFile) {
-		lineCmd := kingpin.New("What should go in here", "And what should go in here")
-		destination := lineCmd.Flag("output", "Output file; defaults to mock_<interface>_test.go.").Short('o').String()
-		packageOut := lineCmd.Flag("package", "Package of the generated code; defaults to the package from which pegomock was executed suffixed with _test").Default(filepath.Base(targetPath) + "_test").String()
-		selfPackage := lineCmd.Flag("self_package", "If set, the package this mock will be part of.").String()
-		lineArgs := lineCmd.Arg("args", "A (optional) Go package path + space-separated interface or a .go file").Required().Strings()
-
-		_, parseErr := lineCmd.Parse(lineParts)
-		if parseErr != nil {
-			fmt.Println("Error while trying to generate mock for line", join(lineParts, " "), ":", parseErr)
-			continue
-		}
-		defer func() {
-			err := recover()
-			if err != nil {
-				if updater.lastErrors[errorKey(*lineArgs)] != fmt.Sprint(err) {
-					fmt.Println("Error while trying to generate mock for", join(lineParts, " "), ":", err)
-					updater.lastErrors[errorKey(*lineArgs)] = fmt.Sprint(err)
-				}
+		results = append(results, updater.updateMockFile(targetPath, lineParts))
+	}
+	return
+}
+
+func (updater *MockFileUpdater) updateMockFile(targetPath string, lineParts []string) (result TargetResult) {
+	result = TargetResult{Dir: targetPath, Target: join(lineParts, " ")}
+
+	lineCmd := kingpin.New("What should go in here", "And what should go in here")
+	destination := lineCmd.Flag("output", "Output file; defaults to mock_<interface>_test.go.").Short('o').String()
+	outputNameTemplate := lineCmd.Flag("output-name-template", "Go text/template for the output file name, used when --output is not given.").String()
+	packageOut := lineCmd.Flag("package", "Package of the generated code; defaults to the package from which pegomock was executed suffixed with _test, "+
+		"or for a _test.go file to the package declared in it").String()
+	selfPackage := lineCmd.Flag("self_package", "If set, the package this mock will be part of.").String()
+	shouldGenerateBuilders := lineCmd.Flag("generate-builders", "Generate a builder for every mock.").Bool()
+	headerFile := lineCmd.Flag("header-file", "File whose content replaces the default header of the generated code.").String()
+	buildTags := lineCmd.Flag("build-tags", "Build constraint expression written at the top of the generated code.").String()
+	style := lineCmd.Flag("style", "Style of the generated mocks, \"pegomock\", \"testify\", \"gomock\" or \"fake\".").
+		Default(mockgen.PegomockStyle).Enum(mockgen.PegomockStyle, mockgen.TestifyStyle, mockgen.GomockStyle, mockgen.FakeStyle)
+	dependencyInjection := lineCmd.Flag("dependency-injection", "Additionally generate glue for \"wire\" or \"fx\" that provides the mocks.").
+		Enum(mockgen.WireInjection, mockgen.FxInjection)
+	templateFile := lineCmd.Flag("template", "Go text/template file that generates the output instead of the built-in styles.").String()
+	incremental := lineCmd.Flag("incremental", "Don't generate mocks again whose input hash is recorded in the mock file already.").Bool()
+	allowInternalImports := lineCmd.Flag("allow-internal-imports", "Generate mocks even if they import internal packages they must not import.").Bool()
+	methods := lineCmd.Flag("methods", "Only mock the methods in this comma-separated list.").String()
+	mockNameTemplate := lineCmd.Flag("name-template", "Go text/template for the names of the mock types.").String()
+	verifierNameTemplate := lineCmd.Flag("verifier-name-template", "Go text/template for the names of the verifier types.").String()
+	ongoingVerificationNameTemplate := lineCmd.Flag("ongoing-verification-name-template", "Go text/template for the names of the types returned by verifier methods.").String()
+	lineArgs := lineCmd.Arg("args", "A (optional) Go package path + space-separated interface or a .go file").Required().Strings()
+
+	_, parseErr := lineCmd.Parse(lineParts)
+	if parseErr != nil {
+		fmt.Println("Error while trying to generate mock for line", join(lineParts, " "), ":", parseErr)
+		result.Status, result.Error = StatusFailed, parseErr.Error()
+		return
+	}
+	defer func() {
+		err := recover()
+		if err != nil {
+			if updater.lastErrors[errorKey(*lineArgs)] != fmt.Sprint(err) {
+				fmt.Println("Error while trying to generate mock for", join(lineParts, " "), ":", err)
+				updater.lastErrors[errorKey(*lineArgs)] = fmt.Sprint(err)
 			}
-		}()
+			result.Status, result.Error = StatusFailed, fmt.Sprint(err)
+		}
+	}()
 
-		util.PanicOnError(util.ValidateArgs(*lineArgs))
-		sourceArgs, err := util.SourceArgs(*lineArgs)
-		util.PanicOnError(err)
+	util.PanicOnError(util.ValidateArgs(*lineArgs))
+	sourceArgs, err := util.SourceArgs(*lineArgs)
+	util.PanicOnError(err)
 
-		generatedMockSourceCode, _ := filehandling.GenerateMockSourceCode(sourceArgs, *packageOut, *selfPackage, false, os.Stdout, false)
-		mockFilePath := filehandling.OutputFilePath(sourceArgs, ".", *destination)
-		hasChanged := util.WriteFileIfChanged(mockFilePath, generatedMockSourceCode)
+	resolvedPackageOut, mockFilePath, err := filehandling.OutputPackageAndFilePath(sourceArgs, ".", *destination, *outputNameTemplate, *packageOut)
+	util.PanicOnError(err)
+	header, err := filehandling.ReadHeaderFile(*headerFile)
+	util.PanicOnError(err)
+	mockTemplate, err := filehandling.ReadTemplateFile(*templateFile)
+	util.PanicOnError(err)
+	previousMockFile := ""
+	if *incremental {
+		previousMockFile = mockFilePath
+	}
+	var methodNames []string
+	if *methods != "" {
+		methodNames = strings.Split(*methods, ",")
+	}
+	generatedMockSourceCode, _, unsupportedErr := filehandling.GenerateMockSourceCode(sourceArgs, previousMockFile, filehandling.Options{
+		Options: mockgen.Options{
+			PackageOut:       resolvedPackageOut,
+			SelfPackage:      *selfPackage,
+			GenerateBuilders: *shouldGenerateBuilders,
+			Header:           header,
+			NameTemplates: mockgen.NameTemplates{
+				Mock:                *mockNameTemplate,
+				Verifier:            *verifierNameTemplate,
+				OngoingVerification: *ongoingVerificationNameTemplate,
+			},
+			BuildTags:           *buildTags,
+			Style:               *style,
+			DependencyInjection: *dependencyInjection,
+			Template:            mockTemplate,
+			Methods:             methodNames,
+		},
+	})
+	if generatedMockSourceCode == nil {
+		panic(unsupportedErr)
+	}
+	if !*allowInternalImports {
+		util.PanicOnError(filehandling.CheckInternalImports(mockFilePath, generatedMockSourceCode))
+	}
+	hasChanged := util.WriteFileIfChanged(mockFilePath, generatedMockSourceCode)
+	result.MockFile = mockFilePath
 
-		if hasChanged || updater.lastErrors[errorKey(*lineArgs)] != "" {
-			fmt.Println("(Re)generated mock for", errorKey(*lineArgs), "in", mockFilePath)
-		}
-		delete(updater.lastErrors, errorKey(*lineArgs))
+	if hasChanged || (unsupportedErr == nil && updater.lastErrors[errorKey(*lineArgs)] != "") {
+		fmt.Println("(Re)generated mock for", errorKey(*lineArgs), "in", mockFilePath)
+	}
+	// Mocks for the supported interfaces are written, but the skipped ones still need reporting.
+	util.PanicOnError(unsupportedErr)
+	delete(updater.lastErrors, errorKey(*lineArgs))
+
+	if hasChanged {
+		result.Status = StatusGenerated
+	} else {
+		result.Status = StatusUnchanged
 	}
+	return
 }
 
 func errorKey(args []string) string {
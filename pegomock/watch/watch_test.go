@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -170,6 +171,41 @@ var _ = Describe("NewMockFileUpdater", func() {
 
 	})
 
+	Context("after populating interfaces_to_mock with a failing line followed by others", func() {
+		It(`generates the mocks for the other lines and reports the result for each of them`, func() {
+			WriteFile(joinPath(packageDir, "interfaces_to_mock"), "missing.go\nmydisplay.go\n--unknown-flag mydisplay.go")
+
+			report := watch.NewMockFileUpdater([]string{packageDir}, false).UpdateWithReport()
+
+			Expect(joinPath(packageDir, "mock_mydisplay_test.go")).To(BeAnExistingFile())
+			Expect(report).To(HaveLen(3))
+			Expect(report[0].Status).To(Equal(watch.StatusFailed))
+			Expect(report[0].Error).To(ContainSubstring("missing.go"))
+			Expect(report[1]).To(Equal(watch.TargetResult{
+				Dir: packageDir, Target: "mydisplay.go", MockFile: "mock_mydisplay_test.go", Status: watch.StatusGenerated}))
+			Expect(report[2].Status).To(Equal(watch.StatusFailed))
+			Expect(report.Sorted()[0].Target).To(Equal("mydisplay.go"))
+		})
+	})
+
+	Context("when watching for changes", func() {
+		It(`regenerates the mocks after a burst of changes to the interfaces`, func() {
+			WriteFile(joinPath(packageDir, "interfaces_to_mock"), "mydisplay.go")
+			done := make(chan bool)
+			go watch.NewMockFileUpdater([]string{packageDir}, false).Watch(50*time.Millisecond, done)
+			defer func() { done <- true }()
+			Eventually(joinPath(packageDir, "mock_mydisplay_test.go"), "3s").Should(BeAnExistingFile())
+
+			for _, method := range []string{"Hide", "Flash", "Blink"} {
+				WriteFile(joinPath(packageDir, "mydisplay.go"), "package pegomocktest; type MyDisplay interface { "+method+"() }")
+			}
+
+			Eventually(joinPath(packageDir, "mock_mydisplay_test.go"), "3s").Should(SatisfyAll(
+				BeAFileContainingSubString("func (mock *MockMyDisplay) Blink()"),
+				Not(BeAFileContainingSubString("func (mock *MockMyDisplay) Show()"))))
+		})
+	})
+
 	Context("after populating interfaces_to_mock with a Go file", func() {
 		It(`Eventually creates a file mock_mydisplay_test.go starting with "package pegomocktest_test"`, func() {
 			WriteFile(joinPath(packageDir, "interfaces_to_mock"), "mydisplay.go")
@@ -0,0 +1,95 @@
+package pegomock
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/petergtz/pegomock/internal/verify"
+)
+
+// Registry holds the mocks of a test with many collaborators, one per interface type, so they
+// can be set up in one place, and reset or verified all at once:
+//
+//	registry := NewRegistry()
+//	display := GetMock[Display](registry).(*MockDisplay)
+//	service := NewService(GetMock[Display](registry), GetMock[PhoneBook](registry))
+//	...
+//	registry.VerifyNoMoreInteractions()
+type Registry struct {
+	options        []Option
+	mocks          map[reflect.Type]Mock
+	interfaceTypes []reflect.Type
+	sync.Mutex
+}
+
+// NewRegistry returns an empty Registry. The mocks it creates are created with options.
+func NewRegistry(options ...Option) *Registry {
+	return &Registry{options: options, mocks: make(map[reflect.Type]Mock)}
+}
+
+// GetMock returns the mock of the interface T stored in registry. If there is none, it creates
+// one with the mock registered for T with RegisterMockFactory, which generated mocks do
+// themselves, and stores it, so later calls return the same mock.
+func GetMock[T any](registry *Registry) T {
+	interfaceType := interfaceTypeOf[T]()
+	registry.Lock()
+	defer registry.Unlock()
+	if mock, exists := registry.mocks[interfaceType]; exists {
+		return mock.(T)
+	}
+	create := mockFactoryFor(interfaceType)
+	verify.Argument(create != nil, "No mock registered for %v", interfaceType)
+	mock := create()
+	for _, option := range registry.options {
+		option(mock)
+	}
+	registry.store(interfaceType, mock)
+	return mock.(T)
+}
+
+// PutMock stores mock in registry as the mock of the interface T, e.g. a mock created with
+// options of its own, or a generic mock, which is not registered with RegisterMockFactory.
+func PutMock[T any](registry *Registry, mock T) {
+	interfaceType := interfaceTypeOf[T]()
+	registry.Lock()
+	defer registry.Unlock()
+	registry.store(interfaceType, mock)
+}
+
+func interfaceTypeOf[T any]() reflect.Type {
+	interfaceType := reflect.TypeOf((*T)(nil)).Elem()
+	verify.Argument(interfaceType.Kind() == reflect.Interface && interfaceType.NumMethod() > 0,
+		"Registry can only hold mocks of interfaces with methods, but got %v", interfaceType)
+	return interfaceType
+}
+
+func (registry *Registry) store(interfaceType reflect.Type, mock Mock) {
+	if _, exists := registry.mocks[interfaceType]; !exists {
+		registry.interfaceTypes = append(registry.interfaceTypes, interfaceType)
+	}
+	registry.mocks[interfaceType] = mock
+}
+
+// Mocks returns the mocks in registry in the order their interface types were first stored.
+func (registry *Registry) Mocks() []Mock {
+	registry.Lock()
+	defer registry.Unlock()
+	mocks := make([]Mock, len(registry.interfaceTypes))
+	for i, interfaceType := range registry.interfaceTypes {
+		mocks[i] = registry.mocks[interfaceType]
+	}
+	return mocks
+}
+
+// Reset resets all mocks in registry. See the function Reset.
+func (registry *Registry) Reset() {
+	for _, mock := range registry.Mocks() {
+		Reset(mock)
+	}
+}
+
+// VerifyNoMoreInteractions verifies that no mock in registry has invocations that no
+// verification matched. See the function VerifyNoMoreInteractions.
+func (registry *Registry) VerifyNoMoreInteractions() {
+	VerifyNoMoreInteractions(registry.Mocks()...)
+}
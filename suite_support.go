@@ -0,0 +1,44 @@
+package pegomock
+
+import "testing"
+
+// SuiteMocks plugs the mocks of a test suite built with testify's suite package into its
+// SetupTest and TearDownTest:
+//
+//	type FetcherSuite struct {
+//		suite.Suite
+//		mocks   pegomock.SuiteMocks
+//		fetcher *MockFetcher
+//	}
+//
+//	func (s *FetcherSuite) SetupSuite()   { s.fetcher = NewMockFetcher() }
+//	func (s *FetcherSuite) SetupTest()    { s.mocks.SetupTest(s.T(), s.fetcher) }
+//	func (s *FetcherSuite) TearDownTest() { s.mocks.TearDownTest() }
+//
+// It works for any test that has setup and teardown functions, though.
+type SuiteMocks struct {
+	// VerifyNoMoreInteractions makes TearDownTest verify that the test verified all invocations
+	// of the mocks. See the function VerifyNoMoreInteractions.
+	VerifyNoMoreInteractions bool
+	mocks                    []Mock
+}
+
+// SetupTest makes mocks report their failures to t, see RegisterMockTestingT, and resets them,
+// so mocks created once for the whole suite start every test without stubbings and invocations.
+func (suiteMocks *SuiteMocks) SetupTest(t *testing.T, mocks ...Mock) {
+	RegisterMockTestingT(t)
+	for _, mock := range mocks {
+		Reset(mock)
+	}
+	suiteMocks.mocks = mocks
+}
+
+// TearDownTest reports the failures of the mocks passed to SetupTest that could not be reported
+// right away, and verifies that there are no more interactions with them if
+// VerifyNoMoreInteractions is set.
+func (suiteMocks *SuiteMocks) TearDownTest() {
+	ReportPendingFailures()
+	if suiteMocks.VerifyNoMoreInteractions {
+		VerifyNoMoreInteractions(suiteMocks.mocks...)
+	}
+}
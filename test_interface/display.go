@@ -21,6 +21,7 @@ import "io"
 type Display interface {
 	Flash(_param0 string, _param1 int)
 	Show(_param0 string)
+	Clear()
 	SomeValue() string
 	MultipleValues() (string, int, float32)
 	MultipleParamsAndReturnValue(s string, i int) string
@@ -30,6 +31,7 @@ type Display interface {
 	InterfaceParam(interface{})
 	InterfaceReturnValue() interface{}
 	ErrorReturnValue() error
+	MultipleValuesAndError() (string, int, error)
 	ErrorParam(e error)
 	NetHttpRequestParam(r http.Request)
 	NetHttpRequestPtrParam(r *http.Request)
@@ -38,4 +40,5 @@ type Display interface {
 	NormalAndVariadicParam(s string, i int, v ...string)
 	CamelCaseTypeParam(camelCaseParam io.ReadCloser)
 	MapOfStringToInterfaceParam(m map[string]interface{})
+	WithPrefix(prefix string) Display
 }
@@ -31,8 +31,17 @@ type testingT interface {
 	Errorf(format string, args ...interface{})
 }
 
+// helper is implemented by *testing.T and the like. Fail handlers mark themselves as helpers,
+// so they don't show up as the location of failures.
+type helper interface {
+	Helper()
+}
+
 func BuildTestingTGomegaFailHandler(t testingT) FailHandler {
 	return func(message string, callerSkip ...int) {
+		if h, ok := t.(helper); ok {
+			h.Helper()
+		}
 		skip := 1
 		if len(callerSkip) > 0 {
 			skip = callerSkip[0]
@@ -0,0 +1,102 @@
+// Copyright 2015 Peter Goetz
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pegomock_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/petergtz/pegomock"
+)
+
+func TestRegisterMockTestingTRestoresPreviousFailHandlerWhenTestFinishes(t *testing.T) {
+	originalHandler := pegomock.GlobalFailHandler
+	defer func() { pegomock.GlobalFailHandler = originalHandler }()
+
+	var failures []string
+	pegomock.RegisterMockFailHandler(func(message string, callerSkip ...int) { failures = append(failures, message) })
+	t.Run("registering", func(t *testing.T) {
+		pegomock.RegisterMockTestingT(t)
+	})
+	pegomock.GlobalFailHandler("after the test")
+
+	if len(failures) != 1 || failures[0] != "after the test" {
+		t.Errorf("Expected the previous fail handler to be restored, but it got %v", failures)
+	}
+}
+
+func TestMocksCanBeUsedInParallelTests(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		i := i
+		t.Run(fmt.Sprintf("test %v", i), func(t *testing.T) {
+			t.Parallel()
+			pegomock.RegisterMockTestingT(t)
+			display := NewMockDisplay()
+			message := fmt.Sprintf("message %v", i)
+
+			for j := 0; j < 100; j++ {
+				pegomock.When(display.MultipleParamsAndReturnValue(pegomock.EqString(message), pegomock.AnyInt())).ThenReturn(message)
+				if result := display.MultipleParamsAndReturnValue(message, j); result != message {
+					t.Fatalf("Expected %v, but got %v", message, result)
+				}
+				display.Flash(message, j)
+				display.VerifyWasCalledOnce().Flash(pegomock.EqString(message), pegomock.EqInt(j))
+			}
+		})
+	}
+}
+
+type helperT struct {
+	errors      []string
+	helperCalls int
+}
+
+func (t *helperT) Errorf(format string, args ...interface{}) { t.errors = append(t.errors, format) }
+func (t *helperT) Helper()                                   { t.helperCalls++ }
+
+func TestFailHandlersMarkThemselvesAsHelpers(t *testing.T) {
+	handlerT := &helperT{}
+	pegomock.BuildTestingTGomegaFailHandler(handlerT)("failure")
+	if handlerT.helperCalls == 0 {
+		t.Errorf("Expected BuildTestingTGomegaFailHandler's handler to call Helper")
+	}
+
+	aggregatingT := &helperT{}
+	handler, flush := pegomock.BuildAggregatingTestingTFailHandler(aggregatingT)
+	handler("failure")
+	flush()
+	if aggregatingT.helperCalls == 0 || len(aggregatingT.errors) != 1 {
+		t.Errorf("Expected BuildAggregatingTestingTFailHandler's handler to call Helper and report one failure")
+	}
+}
+
+func TestSuiteMocksResetMocksForEachTestAndVerifyNoMoreInteractions(t *testing.T) {
+	display := NewMockDisplay()
+	suiteMocks := pegomock.SuiteMocks{VerifyNoMoreInteractions: true}
+	for i := 0; i < 2; i++ {
+		t.Run(fmt.Sprintf("test %v", i), func(t *testing.T) {
+			suiteMocks.SetupTest(t, display)
+			defer suiteMocks.TearDownTest()
+
+			if result := display.SomeValue(); result != "" {
+				t.Errorf("Expected the stubbing of the previous test to be reset, but got %v", result)
+			}
+			pegomock.When(display.SomeValue()).ThenReturn("stubbed")
+			display.Show("Hello")
+			display.VerifyWasCalledOnce().Show("Hello")
+			display.VerifyWasCalledOnce().SomeValue()
+		})
+	}
+}
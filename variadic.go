@@ -0,0 +1,213 @@
+package pegomock
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// VariadicArgs makes the matcher given as its argument match all arguments passed for a
+// variadic parameter at once, as a slice, instead of a single one of them. Spread the result
+// into the parameter:
+//
+//	When(display.NormalAndVariadicParam(AnyString(), AnyInt(), VariadicArgs(AnyStringSlice())...)).ThenReturn()
+//
+// This matches invocations with any number of variadic arguments, including none, for which the
+// matcher gets a nil slice. Without VariadicArgs, there has to be one matcher per variadic
+// argument, and only invocations with exactly as many variadic arguments match. Captors used
+// with VariadicArgs capture the variadic arguments of each invocation as a slice.
+func VariadicArgs[T any](matcher []T) []T {
+	RegisterMatcher(&variadicMatcher{
+		Matcher:   popMatchers("VariadicArgs", 1)[0],
+		sliceType: reflect.TypeOf((*[]T)(nil)).Elem(),
+	})
+	return nil
+}
+
+// AnyVarargs matches any variadic arguments, including none, e.g.
+//
+//	logger.VerifyWasCalledOnce().Logf(EqString("%v failed"), AnyVarargs[interface{}]()...)
+func AnyVarargs[T any]() []T {
+	return VariadicArgs(ArgThat[[]T](NewAnyMatcher(reflect.TypeOf((*[]T)(nil)).Elem())))
+}
+
+// VarargsEq matches variadic arguments that equal values, in the same order, e.g.
+//
+//	logger.VerifyWasCalledOnce().Logf(AnyString(), VarargsEq[interface{}]("db", 3)...)
+//
+// Without values, it matches invocations without variadic arguments.
+func VarargsEq[T any](values ...T) []T {
+	return VariadicArgs(ArgThat[[]T](&VarargsEqMatcher[T]{Values: values}))
+}
+
+// VarargsContaining matches variadic arguments that include each of values, in any order and
+// among any others, e.g.
+//
+//	logger.VerifyWasCalled(AtLeast(1)).Logf(AnyString(), VarargsContaining[interface{}]("db")...)
+func VarargsContaining[T any](values ...T) []T {
+	return VariadicArgs(ArgThat[[]T](&VarargsContainingMatcher[T]{Values: values}))
+}
+
+// VarargsEqMatcher matches variadic arguments, given as a []T, that equal Values. See VarargsEq.
+type VarargsEqMatcher[T any] struct {
+	Values []T
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *VarargsEqMatcher[T]) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	args, _ := param.([]T)
+	if len(args) != len(matcher.Values) {
+		return false
+	}
+	for i, value := range matcher.Values {
+		if !reflect.DeepEqual(value, args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (matcher *VarargsEqMatcher[T]) FailureMessage() string {
+	return fmt.Sprintf("Expected: %v; but got: %#v", matcher, matcher.actual)
+}
+
+func (matcher *VarargsEqMatcher[T]) String() string {
+	return fmt.Sprintf("VarargsEq(%v)", formatValues(matcher.Values))
+}
+
+// VarargsContainingMatcher matches variadic arguments, given as a []T, that include each of
+// Values. See VarargsContaining.
+type VarargsContainingMatcher[T any] struct {
+	Values []T
+	actual Param
+	sync.Mutex
+}
+
+func (matcher *VarargsContainingMatcher[T]) Matches(param Param) bool {
+	matcher.Lock()
+	defer matcher.Unlock()
+
+	matcher.actual = param
+	args, _ := param.([]T)
+	for _, value := range matcher.Values {
+		if !containsDeepEqual(args, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (matcher *VarargsContainingMatcher[T]) FailureMessage() string {
+	return fmt.Sprintf("Expected: %v; but got: %#v", matcher, matcher.actual)
+}
+
+func (matcher *VarargsContainingMatcher[T]) String() string {
+	return fmt.Sprintf("VarargsContaining(%v)", formatValues(matcher.Values))
+}
+
+func containsDeepEqual[T any](values []T, value T) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValues[T any](values []T) string {
+	params := make([]Param, len(values))
+	for i, value := range values {
+		params[i] = value
+	}
+	return formatParams(params)
+}
+
+// variadicMatcher matches the variadic arguments of an invocation as a slice of sliceType.
+// It can only be the last of the matchers of an invocation.
+type variadicMatcher struct {
+	Matcher
+	sliceType reflect.Type
+}
+
+func (matcher *variadicMatcher) String() string {
+	return fmt.Sprintf("%v...", matcher.Matcher)
+}
+
+func (matcher *variadicMatcher) usedInStubbing(method *mockedMethod, stubbing *Stubbing, paramIndex int, _ bool) {
+	if m, ok := matcher.Matcher.(stubbingAware); ok {
+		m.usedInStubbing(method, stubbing, paramIndex, true)
+	}
+}
+
+func (matcher *variadicMatcher) startVerificationPass() {
+	startVerificationPass([]Matcher{matcher.Matcher})
+}
+
+func (matcher *variadicMatcher) invocationMatched(matched bool) {
+	notifyInvocationMatched([]Matcher{matcher.Matcher}, matched)
+}
+
+// variadicMatcherOf returns the last of matchers if it is a variadicMatcher.
+func variadicMatcherOf(matchers []Matcher) (*variadicMatcher, bool) {
+	if len(matchers) == 0 {
+		return nil, false
+	}
+	matcher, isVariadic := matchers[len(matchers)-1].(*variadicMatcher)
+	return matcher, isVariadic
+}
+
+// withVariadicArgsAsSlice returns params with the arguments for the variadic parameter matched
+// by the last of matchers, if it is a variadicMatcher, replaced by a single slice of them. The
+// result can then be matched by matchers one by one.
+func withVariadicArgsAsSlice(matchers []Matcher, params []Param) []Param {
+	variadic, isVariadic := variadicMatcherOf(matchers)
+	if !isVariadic || len(params) < len(matchers)-1 {
+		return params
+	}
+	numFixed := len(matchers) - 1
+	return append(params[:numFixed:numFixed], sliceOf(variadic.sliceType, params[numFixed:]))
+}
+
+// sliceOf returns args as a slice of sliceType, or a nil one if there are no args, like Go
+// passes them to a variadic parameter.
+func sliceOf(sliceType reflect.Type, args []Param) Param {
+	if len(args) == 0 {
+		return reflect.Zero(sliceType).Interface()
+	}
+	slice := reflect.MakeSlice(sliceType, len(args), len(args))
+	for i, arg := range args {
+		if arg != nil {
+			slice.Index(i).Set(reflect.ValueOf(arg))
+		}
+	}
+	return slice.Interface()
+}
+
+// GetVariadicInvocationParams is like GenericMock.GetInvocationParams for methods whose last
+// parameter is variadic and follows numFixed other ones. The arguments passed for the variadic
+// parameter are returned separately, one slice per invocation, because their number may differ
+// between invocations.
+func GetVariadicInvocationParams(methodInvocations []MethodInvocation, numFixed int) (params [][]Param, variadicArgs [][]Param) {
+	if len(methodInvocations) == 0 {
+		return nil, nil
+	}
+	params = make([][]Param, numFixed)
+	for i := range params {
+		params[i] = make([]Param, len(methodInvocations))
+	}
+	variadicArgs = make([][]Param, len(methodInvocations))
+	for u, invocation := range methodInvocations {
+		for i := 0; i < numFixed && i < len(invocation.params); i++ {
+			params[i][u] = invocation.params[i]
+		}
+		if len(invocation.params) > numFixed {
+			variadicArgs[u] = invocation.params[numFixed:]
+		}
+	}
+	return params, variadicArgs
+}